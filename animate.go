@@ -0,0 +1,146 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/AndrewSav/sudocoo/pkg/animation"
+	"github.com/AndrewSav/sudocoo/pkg/format"
+	"github.com/AndrewSav/sudocoo/pkg/logic"
+	"github.com/AndrewSav/sudocoo/pkg/parser"
+	"github.com/AndrewSav/sudocoo/pkg/solver"
+)
+
+type animateFlags struct {
+	InputFile  string        // input can come from a file
+	Input      string        // or form a string
+	Mode       string        // search or logic
+	Format     string        // cast or gif
+	OutputFile string        // file to write to instead of stdout
+	Delay      time.Duration // between frames
+	MaxFrames  int           // longer solves are thinned out to that many frames
+}
+
+// Records a solve of a puzzle, either the backtracking search trying digits or the logic engine
+// taking its steps, as an asciinema cast or an animated GIF of the grid filling in
+func runAnimate(args []string) {
+	var flags animateFlags
+
+	fs := flag.NewFlagSet("animate", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Println("Records the solve of a puzzle as an asciinema cast or an animated GIF")
+		fmt.Printf("Usage: %s animate [FLAGS...]\n", filepath.Base(os.Args[0]))
+		fmt.Println("Flags:")
+		fs.PrintDefaults()
+	}
+	addInputFlags(fs, &flags.InputFile, &flags.Input)
+	fs.StringVar(&flags.Mode, "mode", "search", "what to record: search, the backtracking search trying digits, or logic, the steps of the logic engine")
+	fs.StringVar(&flags.Format, "format", "cast", "output format: cast, an asciicast v2 file for asciinema, or gif. GIFs show the grid without the captions")
+	fs.StringVar(&flags.OutputFile, "o", "", "write to this file instead of the standard output")
+	fs.DurationVar(&flags.Delay, "delay", 100*time.Millisecond, "time between frames")
+	fs.IntVar(&flags.MaxFrames, "max-frames", 1000, "longer solves are thinned out evenly to this many frames")
+	parseFlags(fs, args)
+
+	if flags.Mode != "search" && flags.Mode != "logic" {
+		fmt.Printf("invalid mode %s, want search or logic\n", flags.Mode)
+		fs.Usage()
+		os.Exit(2)
+	}
+	if flags.Format != "cast" && flags.Format != "gif" {
+		fmt.Printf("invalid format %s, want cast or gif\n", flags.Format)
+		fs.Usage()
+		os.Exit(2)
+	}
+	if flags.Delay <= 0 || flags.MaxFrames < 2 {
+		fmt.Println("the delay has to be positive and the frames at least 2")
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	out := newOutput(false)
+	defer out.Flush()
+
+	scanner := parser.CreateInputScanner(openInput(fs, flags.InputFile, flags.Input))
+	puzzle, err := parser.ReadNextPuzzleInput(scanner)
+	if err != nil {
+		out.fail(err)
+	}
+	if _, err := parser.ReadNextPuzzleInput(scanner); !errors.Is(err, io.EOF) {
+		out.fail(fmt.Errorf("animate takes a single puzzle"))
+	}
+
+	var frames []animation.Frame
+	if flags.Mode == "search" {
+		frames, err = recordSearch(puzzle, flags.MaxFrames)
+	} else {
+		frames, err = recordLogic(puzzle, flags.MaxFrames)
+	}
+	if err != nil {
+		out.fail(err)
+	}
+
+	var w io.Writer = out
+	if flags.OutputFile != "" {
+		file, err := os.Create(flags.OutputFile)
+		if err != nil {
+			out.fail(err)
+		}
+		defer file.Close()
+		w = file
+	}
+	if flags.Format == "cast" {
+		err = animation.WriteCast(w, puzzle, frames, flags.Delay, format.Inline(puzzle))
+	} else {
+		err = animation.WriteGIF(w, puzzle, frames, flags.Delay)
+	}
+	if err != nil {
+		out.fail(err)
+	}
+}
+
+// Records the search up to the first solution, a frame per iteration
+func recordSearch(puzzle [9][9]int, maxFrames int) ([]animation.Frame, error) {
+	s, err := solver.NewSolver(puzzle)
+	if err != nil {
+		return nil, err
+	}
+	r := animation.NewRecorder(maxFrames - 1)
+	r.Add(animation.Frame{Grid: puzzle, Caption: "Start"})
+	iteration := 0
+	s.Observe(func(grid [9][9]int) {
+		iteration++
+		r.Add(animation.Frame{Grid: grid, Caption: fmt.Sprintf("Iteration %d", iteration)})
+	})
+	if !s.Solve() {
+		return append(r.Frames(), animation.Frame{Grid: puzzle, Caption: fmt.Sprintf("No solution, %d iterations", s.Iterations())}), nil
+	}
+	caption := fmt.Sprintf("Solved in %d iterations, %d dead ends", s.Iterations(), s.DeadEnds())
+	return append(r.Frames(), animation.Frame{Grid: s.Solution(), Caption: caption}), nil
+}
+
+// Records the steps of the logic engine, a frame per step
+func recordLogic(puzzle [9][9]int, maxFrames int) ([]animation.Frame, error) {
+	result, err := logic.Solve(puzzle)
+	if err != nil {
+		return nil, err
+	}
+	r := animation.NewRecorder(maxFrames - 1)
+	grid := puzzle
+	r.Add(animation.Frame{Grid: grid, Caption: "Start"})
+	for _, step := range result.Steps {
+		for _, p := range step.Placements {
+			grid[p.Row][p.Column] = p.Digit
+		}
+		r.Add(animation.Frame{Grid: grid, Caption: step.String()})
+	}
+	caption := fmt.Sprintf("Solved in %d steps", len(result.Steps))
+	if !result.Solved {
+		caption = fmt.Sprintf("Stuck after %d steps", len(result.Steps))
+	}
+	return append(r.Frames(), animation.Frame{Grid: result.Grid, Caption: caption}), nil
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/AndrewSav/sudocoo/pkg/analysis"
+	"github.com/AndrewSav/sudocoo/pkg/batch"
+	"github.com/AndrewSav/sudocoo/pkg/format"
+	"github.com/AndrewSav/sudocoo/pkg/logic"
+	"github.com/AndrewSav/sudocoo/pkg/parser"
+)
+
+type auditFlags struct {
+	InputFile string  // input can come from a file
+	Input     string  // or form a string
+	Threshold float64 // how far apart the two percentiles have to be to flag a puzzle
+	Orders    int     // random search orders to average over
+	Seed      uint64  // seed for the search orders
+	Cache     string  // file to keep the search measures in for the next runs
+}
+
+// Both ratings of a puzzle
+type auditResult struct {
+	puzzle   string
+	level    int    // the hardest technique the logic engine needed, higher than all of them if it got stuck
+	logic    string // the same in words
+	deadEnds float64
+}
+
+// Rates the puzzles with both the logic engine and the search based proxy, and prints the puzzles
+// the two disagree on. The ratings are on different scales, so each puzzle gets a percentile for both
+// within the input, and a puzzle is flagged when the percentiles are further apart than the threshold
+func runAudit(args []string) {
+	var flags auditFlags
+
+	fs := flag.NewFlagSet("audit", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Println("Rates puzzles with the logic engine and the search hardness, and prints the puzzles where the two disagree")
+		fmt.Printf("Usage: %s audit [FLAGS...]\n", filepath.Base(os.Args[0]))
+		fmt.Println("Flags:")
+		fs.PrintDefaults()
+	}
+	addInputFlags(fs, &flags.InputFile, &flags.Input)
+	fs.Float64Var(&flags.Threshold, "threshold", 0.5, "flag the puzzles whose percentiles in the two ratings are further apart than this, from 0 to 1")
+	fs.IntVar(&flags.Orders, "orders", analysis.DefaultSearchOrders, "the number of random search orders to average over")
+	fs.Uint64Var(&flags.Seed, "seed", 1, "seed for the random search orders, the same seed gives the same results")
+	addCacheFlag(fs, &flags.Cache)
+	parseFlags(fs, args)
+
+	if flags.Threshold < 0 || flags.Threshold > 1 || flags.Orders < 1 {
+		fmt.Printf("invalid threshold %g or number of search orders %d\n", flags.Threshold, flags.Orders)
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	out := newOutput(false)
+	defer out.Flush()
+
+	results := openCache(out, flags.Cache)
+	if results != nil {
+		defer results.Close()
+	}
+
+	scanner := parser.CreateInputScanner(openInput(fs, flags.InputFile, flags.Input))
+	next := func() ([9][9]int, error) { return parser.ReadNextPuzzleInput(scanner) }
+	rate := func(_ context.Context, puzzle [9][9]int) (auditResult, error) {
+		r := auditResult{puzzle: format.Inline(puzzle)}
+		h, err := measureHardness(results, puzzle, flags.Orders, flags.Seed)
+		if err != nil {
+			return r, err
+		}
+		r.deadEnds = h.DeadEnds
+		result, err := logic.Solve(puzzle)
+		if err != nil {
+			return r, err
+		}
+		hardest, _ := result.Hardest()
+		r.level, r.logic = int(hardest), hardest.String()
+		if !result.Solved {
+			r.level, r.logic = math.MaxInt, "beyond the logic engine"
+		}
+		return r, nil
+	}
+
+	var rated []auditResult
+	failed := 0
+	_, err := batch.Run(context.Background(), batch.Config{Errors: batch.Continue}, next, rate, func(item batch.Item[auditResult]) bool {
+		if item.Err != nil {
+			fmt.Fprintf(out, "%s: %v\n", item.Value.puzzle, item.Err)
+			failed++
+			return true
+		}
+		rated = append(rated, item.Value)
+		return true
+	})
+	if err != nil {
+		out.fail(err)
+	}
+	if len(rated)+failed == 0 {
+		// Not a single puzzle in the input
+		out.fail(io.EOF)
+	}
+
+	logicRanks := percentiles(len(rated), func(i, j int) bool { return rated[i].level < rated[j].level })
+	searchRanks := percentiles(len(rated), func(i, j int) bool { return rated[i].deadEnds < rated[j].deadEnds })
+	flagged := 0
+	for i, r := range rated {
+		if math.Abs(logicRanks[i]-searchRanks[i]) <= flags.Threshold {
+			continue
+		}
+		flagged++
+		fmt.Fprintf(out, "%s: logic %s (%.0f%%), search %.2f dead ends (%.0f%%)\n", r.puzzle, r.logic, logicRanks[i]*100, r.deadEnds, searchRanks[i]*100)
+	}
+	fmt.Fprintf(out, "Puzzles: %d, flagged: %d, failed: %d, rank correlation: %.3f\n", len(rated), flagged, failed, correlation(logicRanks, searchRanks))
+	if failed != 0 {
+		out.Flush()
+		os.Exit(1)
+	}
+}
+
+// Returns the percentile of each of the n items in the order given by less, from 0 to 1.
+// Items that are equal share the mean of their positions, so a rating with few distinct values
+// is not spread out by the input order
+func percentiles(n int, less func(i, j int) bool) []float64 {
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return less(order[a], order[b]) })
+	ranks := make([]float64, n)
+	if n < 2 {
+		return ranks
+	}
+	for start := 0; start < n; {
+		end := start + 1
+		for end < n && !less(order[start], order[end]) {
+			end++
+		}
+		rank := float64(start+end-1) / 2 / float64(n-1)
+		for _, i := range order[start:end] {
+			ranks[i] = rank
+		}
+		start = end
+	}
+	return ranks
+}
+
+// Returns the Pearson correlation of the two series, of ranks this is the Spearman correlation
+func correlation(a, b []float64) float64 {
+	n := float64(len(a))
+	var meanA, meanB float64
+	for i := range a {
+		meanA += a[i] / n
+		meanB += b[i] / n
+	}
+	var cov, varA, varB float64
+	for i := range a {
+		cov += (a[i] - meanA) * (b[i] - meanB)
+		varA += (a[i] - meanA) * (a[i] - meanA)
+		varB += (b[i] - meanB) * (b[i] - meanB)
+	}
+	if varA == 0 || varB == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(varA*varB)
+}
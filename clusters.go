@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/AndrewSav/sudocoo/pkg/batch"
+	"github.com/AndrewSav/sudocoo/pkg/canon"
+	"github.com/AndrewSav/sudocoo/pkg/format"
+	"github.com/AndrewSav/sudocoo/pkg/parser"
+)
+
+type clustersFlags struct {
+	InputFile string // input can come from a file
+	Input     string // or form a string
+	Canonical bool   // group equivalent grids together, not just identical ones
+	MinSize   int    // smallest cluster to print
+}
+
+// A puzzle and the grid it is grouped by, grid is empty if the puzzle has no unique solution
+type gridKey struct {
+	puzzle string
+	grid   string
+}
+
+// A group of puzzles that share a solution grid
+type cluster struct {
+	grid    string
+	puzzles []string
+	lines   []int // the 1-based position of each puzzle in the input
+}
+
+// Groups the puzzles by their unique solution, so that puzzles which are different givens of the same
+// grid can be found. Puzzles without a unique solution are left out
+func runClusters(args []string) {
+	var flags clustersFlags
+
+	fs := flag.NewFlagSet("clusters", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Println("Groups puzzles by their solution grid and prints the groups with more than one puzzle")
+		fmt.Printf("Usage: %s clusters [FLAGS...]\n", filepath.Base(os.Args[0]))
+		fmt.Println("Flags:")
+		fs.PrintDefaults()
+	}
+	addInputFlags(fs, &flags.InputFile, &flags.Input)
+	fs.BoolVar(&flags.Canonical, "canonical", false, "group puzzles whose grids are equivalent (relabeled, rotated, etc), not only identical")
+	fs.IntVar(&flags.MinSize, "min", 2, "the smallest number of puzzles in a group to print it")
+	parseFlags(fs, args)
+
+	out := newOutput(false)
+	defer out.Flush()
+
+	scanner := parser.CreateInputScanner(openInput(fs, flags.InputFile, flags.Input))
+	next := func() ([9][9]int, error) { return parser.ReadNextPuzzleInput(scanner) }
+	key := func(_ context.Context, puzzle [9][9]int) (gridKey, error) {
+		k := gridKey{puzzle: format.Inline(puzzle)}
+		s, err := solvers.Get(puzzle)
+		if err != nil {
+			return k, nil
+		}
+		defer solvers.Put(s)
+		if !s.Solve() {
+			return k, nil
+		}
+		grid := s.Solution()
+		if s.Solve() {
+			return k, nil
+		}
+		if flags.Canonical {
+			grid = canon.Canonical(grid)
+		}
+		k.grid = format.Inline(grid)
+		return k, nil
+	}
+
+	var clusters []*cluster
+	byGrid := map[string]*cluster{}
+	skipped := 0
+	stats, err := batch.Run(context.Background(), batch.Config{}, next, key, func(item batch.Item[gridKey]) bool {
+		k := item.Value
+		if k.grid == "" {
+			skipped++
+			return true
+		}
+		c, ok := byGrid[k.grid]
+		if !ok {
+			c = &cluster{grid: k.grid}
+			byGrid[k.grid] = c
+			clusters = append(clusters, c)
+		}
+		c.puzzles = append(c.puzzles, k.puzzle)
+		c.lines = append(c.lines, item.Index+1)
+		return true
+	})
+	if err != nil {
+		out.fail(err)
+	}
+	if stats.Items == 0 {
+		// Not a single puzzle in the input
+		out.fail(io.EOF)
+	}
+
+	printed := 0
+	for _, c := range clusters {
+		if len(c.puzzles) < flags.MinSize {
+			continue
+		}
+		if printed != 0 {
+			fmt.Fprintln(out)
+		}
+		printed++
+		fmt.Fprintf(out, "%s: %d puzzles\n", c.grid, len(c.puzzles))
+		for i, puzzle := range c.puzzles {
+			fmt.Fprintf(out, "  %s #%d\n", puzzle, c.lines[i])
+		}
+	}
+	if printed != 0 {
+		fmt.Fprintln(out)
+	}
+	fmt.Fprintf(out, "Puzzles: %d, without a unique solution: %d, grids: %d, groups printed: %d\n", stats.Items, skipped, len(clusters), printed)
+}
@@ -1,30 +1,117 @@
 package main
 
 import (
+	"bufio"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
+	"text/template"
+	"time"
 
+	"github.com/AndrewSav/sudocoo/pkg/clipboard"
+	"github.com/AndrewSav/sudocoo/pkg/filter"
 	"github.com/AndrewSav/sudocoo/pkg/format"
+	"github.com/AndrewSav/sudocoo/pkg/packed"
+	"github.com/AndrewSav/sudocoo/pkg/rating"
+	"github.com/AndrewSav/sudocoo/pkg/recognize"
+	"github.com/AndrewSav/sudocoo/pkg/solver"
 )
 
 type Flags struct {
 	InputFile              string    // input can come from a file
 	Input                  string    // or form a string
 	All                    bool      // we want all solutions, not just the first one
-	Limit                  int       // we want that many first solutions of each puzzle
+	Limit                  int       // we want that many first solutions of each puzzle, resolved from -a, -l and MaxPerPuzzle
+	MaxPerPuzzle           int       // explicit per puzzle limit that works with or without -a
+	TotalLimit             int       // we want that many solutions in total across all the puzzles
 	CountsOnly             bool      // we want only solution counts, not soluctions themselves
 	OutputInputPuzzle      bool      // display puzzle along with its solution count
 	OutputFormat           string    // how to print out a solution
 	InputReader            io.Reader // we convert InputFile or Input to a uniform io.Reader
 	ShowStats              bool      // display stats at the end of the program run
+	StatsFormat            string    // text or json
 	NewLineAfterEachPuzzle bool      // depending on format and/or single/multiple puzzle/solution may look better with or without
 	Quiet                  bool      // just display the stats
 	DontSolve              bool      // do not solve puzzles just output them instead of solutions
+	Rate                   bool      // output puzzles with their difficulty instead of solutions
+	Uniqueness             bool      // output puzzles with whether they have no, one or several solutions instead of solutions
+	Engine                 string    // what solves the puzzles: the backtracking search or the logic engine
+	SearchTrace            bool      // print every step the search takes before the solutions
+	Size                   int       // the number of cells on a side of the grid, 9 unless it is a variant size
+	Numbers                bool      // the digits of grids larger than 9x9 are written as numbers
+	EchoInput              bool      // re-emit input lines verbatim annotated with the puzzle result
+	ColorMode              string    // auto, always or never
+	Color                  bool      // ColorMode resolved against the environment and the output destination
+	LineBuffered           bool      // flush output after each solution instead of when the buffer is full
+	Database               string    // puzzle store to record the solved puzzles in
+	Cache                  string    // file to keep the solution counts in for the next runs
+	Profile                profileFlags
+	CrossCheck             string             // reference solver command to compare the results with
+	Sample                 int                // we want that many solutions of each puzzle sampled at random instead of the first ones
+	Seed                   uint64             // seed for the sampling
+	Timeout                time.Duration      // give up on a puzzle after this long, 0 is no limit
+	Workers                int                // the number of puzzles solved at the same time, 0 is one per CPU
+	Proof                  bool               // show two differing solutions of the puzzles that have more than one
+	TemplateText           string             // per puzzle output as a Go template
+	Template               *template.Template // TemplateText parsed
+	ClipboardIn            bool               // input comes from the clipboard instead of -f or -i
+	ClipboardOut           bool               // output goes to the clipboard as well
+	Manifest               string             // file to write what the run did to, for audits
+	FilterText             string             // only puzzles whose results match this expression are output
+	Filter                 *filter.Filter     // FilterText parsed
+	Results                string             // file to write a JSON record of each puzzle to, whatever the output format
+	Box                    string             // the rows and columns of the boxes, e.g. 2x3, for sizes without usual boxes or to change them
+	Shape                  solver.Shape       // Size and Box resolved
+	VariantText            string             // the variant rules the puzzles follow on top of the classic ones
+	ConstraintText         string             // the constraints the puzzles follow on top of the classic rules
+	Variant                solver.Variant     // VariantText and ConstraintText parsed
+}
+
+// Environment variables, see https://no-color.org/ and https://bixense.com/clicolors/
+const (
+	envNoColor       = "NO_COLOR"
+	envCliColor      = "CLICOLOR"
+	envCliColorForce = "CLICOLOR_FORCE"
+)
+
+// Decides whether colored output is wanted. In the auto mode colors are only
+// used when stdout is a terminal and the environment does not say otherwise
+func resolveColor(mode string) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		if os.Getenv(envNoColor) != "" {
+			return false, nil
+		}
+		if force := os.Getenv(envCliColorForce); force != "" && force != "0" {
+			return true, nil
+		}
+		if os.Getenv(envCliColor) == "0" {
+			return false, nil
+		}
+		return isTerminal(os.Stdout), nil
+	default:
+		return false, fmt.Errorf("invalid color mode %s, want auto, always or never", mode)
+	}
+}
+
+// Reports whether the file is a terminal rather than a pipe or a regular file
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
 }
 
 // this is so we could pring available output formats in usage help
@@ -47,14 +134,89 @@ func getAvailableFormats() string {
 	return result
 }
 
-// see if user specified format is one of known formats
-func validateFormat(s string) bool {
-	for f := range format.GetKnownFormats() {
-		if s == f {
-			return true
+// Lists the numbers for the usage help
+func intsList(numbers []int) string {
+	s := make([]string, len(numbers))
+	for i, n := range numbers {
+		s[i] = strconv.Itoa(n)
+	}
+	return strings.Join(s, ", ")
+}
+
+// Registers the '-f' and '-i' flags, every command reads its puzzles the same way
+func addInputFlags(fs *flag.FlagSet, inputFile, input *string) {
+	fs.StringVar(inputFile, "f", "", "path to input file with puzzle(s). A PNG or JPEG screenshot of a grid is read as its puzzle, experimentally: clean screenshots with printed digits only, not photos. Only one of '-f' and '-i' can be specified")
+	fs.StringVar(input, "i", "", "puzzle input in inline format. You can specify a single asterisk '*' as the input to represent an empty puzzle. Only one of '-f' and '-i' can be specified")
+}
+
+// Parses the arguments, none of the commands take positional arguments
+func parseFlags(fs *flag.FlagSet, args []string) {
+	fs.Parse(args)
+
+	if fs.NArg() != 0 {
+		fmt.Println(msgf("want 0 arguments, have %d", fs.NArg()))
+		fs.Usage()
+		os.Exit(2)
+	}
+}
+
+// Converts '-f' or '-i' to a reader, exits with usage if not exactly one of them is given
+func openInput(fs *flag.FlagSet, inputFile, input string) io.Reader {
+	if inputFile == "" && input == "" {
+		fmt.Println(msg("you have to specify input with either -f or -i"))
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	if inputFile != "" && input != "" {
+		fmt.Println(msg("you have to specify either -f or -i, not both"))
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	if inputFile != "" {
+		file, err := os.Open(inputFile)
+		if err != nil {
+			fmt.Println(msgf("Error opening input file: %v", err))
+			os.Exit(2)
+		}
+		br := bufio.NewReader(file)
+		if recognize.IsImageReader(br) {
+			puzzle, err := recognize.Read(br)
+			file.Close()
+			if err != nil {
+				fmt.Println(msgf("Error reading the puzzle from the image: %v", err))
+				os.Exit(2)
+			}
+			return strings.NewReader(format.Inline(puzzle) + "\n")
 		}
+		return packed.Text(br)
 	}
-	return false
+
+	if input == "*" {
+		return strings.NewReader(".................................................................................")
+	}
+	return packed.Text(strings.NewReader(input))
+}
+
+// The number of solutions per puzzle '-a' stops at unless told otherwise
+const defaultLimit = 1000
+
+// Works out how many solutions to look for in each puzzle. Without any limit flags
+// only the first solution is searched for, '-a' alone searches up to defaultLimit
+func resolveLimit(flags *Flags, setFlags map[string]bool) error {
+	switch {
+	case setFlags["l"] && setFlags["max-solutions-per-puzzle"]:
+		return errors.New(msgf("'%s' and '%s' both set the limit, specify only one of them", "-l", "--max-solutions-per-puzzle"))
+	case setFlags["l"] && !flags.All:
+		return errors.New(msgf("'%s' only applies together with '%s', use '%s' to limit the solutions without '%s'", "-l", "-a", "--max-solutions-per-puzzle", "-a"))
+	case flags.Limit < 0 || flags.MaxPerPuzzle < 0:
+		return errors.New(msg("the solution limit cannot be negative, use 0 for no limit"))
+	case setFlags["max-solutions-per-puzzle"]:
+		flags.All = true
+		flags.Limit = flags.MaxPerPuzzle
+	}
+	return nil
 }
 
 func ParseArgs() Flags {
@@ -63,19 +225,26 @@ func ParseArgs() Flags {
 	fs := flag.NewFlagSet("sudocoo", flag.ExitOnError)
 
 	fs.Usage = func() {
-		fmt.Println("This is brute force solver for sudoku puzzles")
-		fmt.Println("Based on code by Glenn Fowler of ATT http://gsf.cococlyde.org/")
-		fmt.Println("Code archive: https://github.com/1to9only/ast-sudoku.2012-08-01")
-		fmt.Printf("Usage: %s [FLAGS...]\n", filepath.Base(os.Args[0]))
-		fmt.Println("Flags:")
+		fmt.Println(msg("This is brute force solver for sudoku puzzles"))
+		fmt.Println(msgf("Based on code by Glenn Fowler of ATT %s", "http://gsf.cococlyde.org/"))
+		fmt.Println(msgf("Code archive: %s", "https://github.com/1to9only/ast-sudoku.2012-08-01"))
+		fmt.Println(msgf("Usage: %s [FLAGS...]", filepath.Base(os.Args[0])))
+		fmt.Println(msgf("   or: %s COMMAND [FLAGS...]", filepath.Base(os.Args[0])))
+		fmt.Println(msgf("Commands: %s", getAvailableCommands()))
+		fmt.Println(msg("Flags:"))
 		fs.PrintDefaults()
+		fmt.Println(msg(exitStatusHelp))
 	}
 
-	fs.StringVar(&flags.InputFile, "f", "", "path to input file with puzzle(s). Only one of '-f' and '-i' can be specified")
-	fs.StringVar(&flags.Input, "i", "", "puzzle input in inline format. You can specify a single asterisk '*' as the input to represent an empty puzzle. Only one of '-f' and '-i' can be specified")
+	addInputFlags(fs, &flags.InputFile, &flags.Input)
 
 	fs.BoolVar(&flags.All, "a", false, "find all solution, for each puzzle but no more than specified in the -l flag")
-	fs.IntVar(&flags.Limit, "l", 1000, "the maximum number of solutions to find for each puzzle. 0 is no limit. Default: 1000. Only considered when '-a' is specified")
+	fs.IntVar(&flags.Limit, "l", defaultLimit, "the maximum number of solutions to find for each puzzle. 0 is no limit. Default: 1000. Requires '-a', cannot be combined with '--max-solutions-per-puzzle'")
+	fs.IntVar(&flags.MaxPerPuzzle, "max-solutions-per-puzzle", 0, "find up to this many solutions for each puzzle, with or without '-a'. 0 is no limit. Cannot be combined with '-l'")
+
+	fs.IntVar(&flags.TotalLimit, "total-limit", 0, "stop the whole run after this many solutions have been found across all the puzzles. 0 is no limit. Default: 0")
+
+	fs.IntVar(&flags.Workers, "j", 0, "solve this many puzzles at the same time. The results are printed in the input order whatever the number. 0 is one per CPU, except with '-i' and '-a', where the puzzles are solved one at a time and the solutions printed as they are found, unless '--filter', '--total-limit' or '--search-trace' is used. Default: 0")
 
 	fs.BoolVar(&flags.CountsOnly, "c", false, "do not print out the solutions, only solutions counts. Only considered when '-a' is specified")
 	fs.BoolVar(&flags.OutputInputPuzzle, "p", false, "print puzzle intput in inline format along with each count. Only considered when '-c' is specified")
@@ -83,54 +252,201 @@ func ParseArgs() Flags {
 	fs.StringVar(&flags.OutputFormat, "v", "visual", fmt.Sprintf("output format for solutions: %s. Default: visual", getAvailableFormats()))
 
 	fs.BoolVar(&flags.ShowStats, "s", false, "display total number of puzzles and solutions encountered and iterations taken at the end")
+	fs.StringVar(&flags.StatsFormat, "stats-format", statsText, "how '-s' prints the stats: "+statsText+", for reading, or "+statsJSON+", for other programs, a JSON object per line: one for each puzzle after its output, with type "+statsPuzzle+", index (1-based), solutions, limitReached, timedOut, iterations and seconds, and one for the run at the end, with type "+statsTotal+", puzzles, leftOut (by '--filter'), timedOut, solutions, limitReached, totalLimitReached, iterations and seconds. Combine with '-q' to print the stats only. Default: "+statsText)
 	fs.BoolVar(&flags.Quiet, "q", false, "do not print out either solutions or counts, just the stats. Only considered when '-s' is specified")
 
 	fs.BoolVar(&flags.NewLineAfterEachPuzzle, "n", false, "print newline after each solution")
 	fs.BoolVar(&flags.DontSolve, "d", false, "do not solve puzlles, output puzzles themselves instead of solutions. (useful in combionation with -v switch for format conversion)")
 
-	fs.Parse(os.Args[1:])
+	fs.BoolVar(&flags.Rate, "rate", false, "do not print the solutions, print each puzzle in the inline format with its difficulty instead: the level, "+strings.Join(rating.LevelNames(), ", ")+", from the techniques it needs (singles, then locked candidates and pairs and triples, then trials, then trials within trials), and the score, the backtracking effort of the search as the hardness command measures it. A puzzle without a unique solution is reported as such. With '-e' the difficulty is appended to the echoed lines instead. Cannot be combined with "+conflictHelp("-rate"))
 
-	if fs.NArg() != 0 {
-		fmt.Printf("want 0 arguments, have %d\n", fs.NArg())
+	fs.BoolVar(&flags.Uniqueness, "u", false, "do not print the solutions, print each puzzle in the inline format with "+uniquenessNone+", "+uniquenessUnique+" or "+uniquenessMultiple+" instead, a line each: whether it has no solution, exactly one or more than one. The search stops at the second solution, so this is quick even on puzzles with many. Cannot be combined with "+conflictHelp("-u"))
+
+	fs.StringVar(&flags.Engine, "engine", engineSearch, "what solves the puzzles: "+engineSearch+", the backtracking search, or "+engineLogic+", human techniques, see the steps command, or "+engineDLX+", dancing links, to check the search against and to compare its speed with. The logic engine never guesses, so it finds at most one solution and gets stuck on puzzles that need techniques it does not know, it prints how far it got then. '"+engineLogic+"' cannot be combined with "+conflictHelp("-engine logic")+", '"+engineDLX+"' with "+conflictHelp("-engine dlx")+". Default: "+engineSearch)
+
+	// '-trace' is the execution trace of the program, see the profiling flags below
+	fs.BoolVar(&flags.SearchTrace, "search-trace", false, "print every step the backtracking search takes on each puzzle before its solutions: the digits it places, whether they are the only candidate or a guess, the dead ends it runs into and the digits it takes out again, with the iteration and the number of cells filled so far. The traces of hard puzzles are long. Cannot be combined with "+conflictHelp("--search-trace"))
+
+	fs.IntVar(&flags.Size, "size", 9, fmt.Sprintf("the number of cells on a side of the grid: %s, or any other up to %d with '-box'. The digits are 1 to 9 and then letters, 1 to 9 and A to P for the 25x25 grid, except in the 16x16 one where they are 0 to 9 and A to F, see '-numbers'. Grids other than 9x9 only go with '-a', '-c', '-l', '-n', '-p', '-q', '-s', '-box', '-color', the input flags and the clipboard and language ones, and the %s formats. Default: 9", intsList(solver.GridSizes()), solver.MaxGridSize, strings.Join(format.GridFormats(), ", ")))
+	fs.StringVar(&flags.Box, "box", "", "the boxes of the grid as rows x columns, e.g. 3x2 for a 6x6 grid with boxes 3 cells tall, for sizes that have no usual boxes or to change them. The usual boxes are as wide as they are tall or one column wider: 2x2, 2x3, 3x3, 3x4, 4x4 and 5x5")
+	fs.BoolVar(&flags.Numbers, "numbers", false, "read and write the digits of grids larger than 9x9 as numbers from 1, separated by spaces or other characters, instead of a character each")
+
+	fs.StringVar(&flags.VariantText, "variant", "", "the puzzles follow these rules on top of the classic ones: "+strings.Join(solver.VariantNames(), ", ")+", separated by commas. x: both main diagonals have all the digits, also known as X-Sudoku. hyper: the four 3x3 windows at rows and columns 2 to 4 and 6 to 8 have all the digits, also known as Windoku. See the variant command for other constraints. Cannot be combined with "+conflictHelp("-variant"))
+
+	fs.StringVar(&flags.ConstraintText, "constraint", "", "the puzzles follow these constraints on top of the classic rules: "+strings.Join(solver.ConstraintNames(), ", ")+", separated by commas. antiknight: no two cells a knight's move apart have the same digit. nonconsecutive: no two cells side by side have digits one apart, such as 4 and 5. Combines with '-variant', and cannot be combined with the same flags")
+
+	fs.BoolVar(&flags.EchoInput, "e", false, "echo each input line unchanged, appending the result ('ok/unique', '2+ solutions', 'invalid' or 'no solution') to the lines that contain a puzzle or cells that are not one, such as a truncated puzzle, and with '-rate' the difficulty of the unique ones, e.g. 'ok/unique, hard 33.4'. Output flags are ignored")
+
+	fs.StringVar(&flags.ColorMode, "color", "auto", "highlight solved cells with colors: auto, always or never. In the auto mode colors are used only when the output is a terminal and neither NO_COLOR nor CLICOLOR=0 is set. Default: auto")
+
+	fs.BoolVar(&flags.LineBuffered, "line-buffered", false, "flush the output after each solution or count, useful when piping into a program that needs results immediately")
+
+	fs.StringVar(&flags.Database, "db", "", "record each solved puzzle with its clue count, uniqueness and, if it has a unique solution, its level in this puzzle store (an SQLite database, created if missing). Equivalent puzzles are recorded once. See the query command")
+
+	fs.StringVar(&flags.Cache, "cache", "", "keep the solution counts found with '-a -c' in this cache file (JSON Lines, created if missing) and take them from it instead of solving again, for this puzzle or any equivalent one. Not used with '--total-limit'")
+
+	fs.StringVar(&flags.CrossCheck, "cross-check", "", "instead of printing solutions, run each puzzle through this reference solver command and report where its results differ. The command gets the puzzle as an inline line on stdin and prints either the solution count or the solutions. Without '-a' solvability is compared, with '-a' the solution counts up to the limit")
+
+	fs.IntVar(&flags.Sample, "sample", 0, "print this many solutions of each puzzle sampled approximately uniformly at random, with replacement, instead of the first ones in search order. Cannot be combined with "+conflictHelp("-sample"))
+	fs.Uint64Var(&flags.Seed, "seed", 1, "seed for '-sample', the same seed gives the same samples")
+
+	fs.DurationVar(&flags.Timeout, "timeout", 0, "give up on a puzzle after this long, e.g. 5s, report it as timed out with the solutions found so far and go on with the next one. 0 is no limit. Cannot be combined with "+conflictHelp("-timeout"))
+
+	fs.BoolVar(&flags.Proof, "proof", false, "for a puzzle with more than one solution print two of them and the cells where they differ, highlighted when colors are on, instead of the first solution. Cannot be combined with "+conflictHelp("--proof"))
+
+	fs.StringVar(&flags.TemplateText, "template", "", "print a line per puzzle made from this Go text/template instead of the solutions or counts. The fields are .Index (0-based), .Label (the 1-based number of the puzzle), .Puzzle and .Solution (inline, the solution empty if there is none), .Count, .LimitReached, .Iterations, .Duration and .Rating (the search hardness score, see the hardness command, only worked out if used). E.g. '{{.Label}},{{.Count}},{{.Duration.Microseconds}}'. Cannot be combined with "+conflictHelp("--template"))
+
+	fs.StringVar(&flags.FilterText, "filter", "", "only output the puzzles whose results match this expression, e.g. 'clues < 25 && solutions == 1 && rating >= hard'. The fields are "+strings.Join(filter.Fields(), ", ")+": solutions is the number found, so it takes '-a' to tell a unique puzzle, ms is the time solving took, rating the difficulty level (easy, medium, hard or extreme, see '-rate') and hardness the search hardness score, see the hardness command. They compare with numbers and the levels with == != < <= > >=, and combine with !, && and || and parentheses. The puzzles left out do not count in the stats and the limits. Cannot be combined with "+conflictHelp("--filter"))
+
+	fs.StringVar(&flags.Results, "results", "", "write a JSON object per puzzle to this file, one per line, whatever is printed: index (1-based), puzzle, outcome (no-solution, unique, multiple, or solved when the search stopped at the first solution, as it does without '-a', or timed-out when '-timeout' cut the search short before it could tell), count and limitReached, solution (the first one), iterations, seconds and rating (the difficulty level: easy, medium, hard or extreme, see '-rate'). Cannot be combined with "+conflictHelp("--results"))
+
+	fs.Func("lang", "the language of the messages: "+strings.Join(languages(), ", ")+". The puzzles and solutions are printed the same in all of them. Default: $"+envLang+", or else the language of the locale, English if there is no translation for it", setLanguage)
+
+	fs.BoolVar(&flags.ClipboardIn, "clipboard-in", false, "read the puzzles from the clipboard instead of '-f' or '-i'")
+	fs.BoolVar(&flags.ClipboardOut, "clipboard-out", false, "copy the output to the clipboard as well, without colors")
+
+	fs.StringVar(&flags.Manifest, "manifest", "", "when the run completes, write a JSON manifest of it to this file, so that the results can be audited and reproduced: the program version, the exact arguments, the size and SHA-256 of the input, of the output and of the '--db' and '--cache' files, the totals and the time taken")
+
+	fs.StringVar(&flags.Profile.CPUProfile, "cpuprofile", "", "write a CPU profile of the run to this file, for 'go tool pprof'")
+	fs.StringVar(&flags.Profile.MemProfile, "memprofile", "", "write a heap profile as of the end of the run to this file, for 'go tool pprof'")
+	fs.StringVar(&flags.Profile.Trace, "trace", "", "write an execution trace of the run to this file, for 'go tool trace'")
+
+	parseFlags(fs, os.Args[1:])
+
+	setFlags := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
+	if err := resolveLimit(&flags, setFlags); err != nil {
+		fmt.Println(err)
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	if flags.TotalLimit < 0 {
+		fmt.Println(msgf("invalid total limit %d, want 0 or more", flags.TotalLimit))
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	if flags.StatsFormat != statsText && flags.StatsFormat != statsJSON {
+		fmt.Println(msgf("invalid stats format %s, want %s or %s", flags.StatsFormat, statsText, statsJSON))
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	if flags.Workers < 0 {
+		fmt.Println(msgf("invalid number of workers %d, want 0 or more", flags.Workers))
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	if flags.Sample < 0 {
+		fmt.Println(msgf("invalid sample size %d, want 0 or more", flags.Sample))
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	switch flags.Engine {
+	case engineSearch, engineLogic, engineDLX:
+	default:
+		fmt.Println(msgf("invalid engine %s, want one of %s", flags.Engine, strings.Join([]string{engineSearch, engineLogic, engineDLX}, ", ")))
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	if flags.Timeout < 0 {
+		fmt.Println(msgf("invalid timeout %s, want 0 or more", flags.Timeout))
 		fs.Usage()
 		os.Exit(2)
 	}
 
-	if flags.InputFile == "" && flags.Input == "" {
-		fmt.Printf("you have to specify input with either -f or -i\n")
+	if v, err := solver.ParseVariant(flags.VariantText); err != nil {
+		fmt.Println(err)
 		fs.Usage()
 		os.Exit(2)
+	} else {
+		flags.Variant = v
+	}
+	if c, err := solver.ParseConstraint(flags.ConstraintText); err != nil {
+		fmt.Println(err)
+		fs.Usage()
+		os.Exit(2)
+	} else {
+		flags.Variant |= c
 	}
 
-	if flags.InputFile != "" && flags.Input != "" {
-		fmt.Printf("you have to specify either -f or -i, not both\n")
+	if err := checkConflicts(&flags); err != nil {
+		fmt.Println(err)
 		fs.Usage()
 		os.Exit(2)
 	}
 
-	if flags.InputFile != "" {
-		file, err := os.Open(flags.InputFile)
+	if flags.ClipboardIn {
+		text, err := clipboard.Read()
+		if err != nil {
+			fmt.Println(msgf("Error reading the clipboard: %v", err))
+			os.Exit(2)
+		}
+		flags.InputReader = strings.NewReader(text)
+	} else {
+		flags.InputReader = openInput(fs, flags.InputFile, flags.Input)
+	}
+
+	if flags.TemplateText != "" {
+		t, err := template.New("template").Parse(flags.TemplateText)
 		if err != nil {
-			fmt.Printf("Error opening input file: %v\n", err)
+			fmt.Println(err)
+			fs.Usage()
 			os.Exit(2)
 		}
-		flags.InputReader = file
+		flags.Template = t
 	}
 
-	if flags.Input != "" {
+	if flags.FilterText != "" {
+		f, err := filter.Parse(flags.FilterText)
+		if err != nil {
+			fmt.Println(msgf("invalid filter: %v", err))
+			fs.Usage()
+			os.Exit(2)
+		}
+		flags.Filter = f
+	}
+
+	if err := resolveShape(&flags); err != nil {
+		fmt.Println(err)
+		fs.Usage()
+		os.Exit(2)
+	}
+	if flags.Shape != classicShape {
+		for name := range setFlags {
+			if !slices.Contains(gridFlags, name) {
+				fmt.Println(msgf("'-%s' cannot be combined with grids other than 9x9", name))
+				fs.Usage()
+				os.Exit(2)
+			}
+		}
+		if !slices.Contains(format.GridFormats(), flags.OutputFormat) {
+			fmt.Println(msgf("the %s format cannot be combined with grids other than 9x9, want one of %s", flags.OutputFormat, strings.Join(format.GridFormats(), ", ")))
+			fs.Usage()
+			os.Exit(2)
+		}
 		if flags.Input == "*" {
-			flags.InputReader = strings.NewReader(".................................................................................")
-		} else {
-			flags.InputReader = strings.NewReader(flags.Input)
+			flags.InputReader = strings.NewReader(strings.Repeat(".", flags.Size*flags.Size))
 		}
+	}
 
+	if _, err := format.Lookup(flags.OutputFormat); err != nil {
+		fmt.Println(msgf("invalid output format %s", flags.OutputFormat))
+		fs.Usage()
+		os.Exit(exitCode(err))
 	}
 
-	if !validateFormat(flags.OutputFormat) {
-		fmt.Printf("invalid output format %s\n", flags.OutputFormat)
+	color, err := resolveColor(flags.ColorMode)
+	if err != nil {
+		fmt.Println(err)
 		fs.Usage()
 		os.Exit(2)
 	}
+	flags.Color = color
 
 	return flags
 }
@@ -0,0 +1,135 @@
+//go:build cgo
+
+// C shared library build of the solver, so that other languages can call it in-process:
+//
+//	go build -buildmode=c-shared -o libsudocoo.so ./cmd/cshared
+//
+// sudocoo.h documents the exported functions. Puzzles and solutions are NUL-terminated
+// strings in the inline format, 81 characters with '.' or '0' for empty cells
+package main
+
+/*
+#include <stdlib.h>
+
+#define SUDOCOO_OK               0
+#define SUDOCOO_INVALID_PUZZLE  -1
+#define SUDOCOO_NO_SOLUTION     -2
+#define SUDOCOO_BUFFER_TOO_SMALL -3
+#define SUDOCOO_NOT_UNIQUE      -4
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/AndrewSav/sudocoo/pkg/analysis"
+	"github.com/AndrewSav/sudocoo/pkg/format"
+	"github.com/AndrewSav/sudocoo/pkg/parser"
+	"github.com/AndrewSav/sudocoo/pkg/rating"
+	"github.com/AndrewSav/sudocoo/pkg/solver"
+)
+
+// Solution strings are 81 characters plus the terminating NUL
+const solutionSize = 82
+
+func parsePuzzle(puzzle *C.char) ([9][9]int, bool) {
+	if puzzle == nil {
+		return [9][9]int{}, false
+	}
+	p, err := parser.ParsePuzzleString(C.GoString(puzzle))
+	return p, err == nil
+}
+
+func newSolver(puzzle *C.char) (*solver.Solver, bool) {
+	p, ok := parsePuzzle(puzzle)
+	if !ok {
+		return nil, false
+	}
+	s, err := solver.NewSolver(p)
+	return s, err == nil
+}
+
+// Writes the first solution of the puzzle into the caller's buffer of solution_size bytes
+//
+//export sudocoo_solve
+func sudocoo_solve(puzzle *C.char, solution *C.char, solution_size C.int) C.int {
+	if solution == nil || solution_size < solutionSize {
+		return C.SUDOCOO_BUFFER_TOO_SMALL
+	}
+	s, ok := newSolver(puzzle)
+	if !ok {
+		return C.SUDOCOO_INVALID_PUZZLE
+	}
+	if !s.Solve() {
+		return C.SUDOCOO_NO_SOLUTION
+	}
+	dst := unsafe.Slice((*byte)(unsafe.Pointer(solution)), solutionSize)
+	n := copy(dst, format.Inline(s.Solution()))
+	dst[n] = 0
+	return C.SUDOCOO_OK
+}
+
+// Returns the number of solutions, but no more than limit (0 for no limit), or a negative error code.
+// If limit_reached is not NULL it is set to 1 when there are more solutions than the limit
+//
+//export sudocoo_count
+func sudocoo_count(puzzle *C.char, limit C.int, limit_reached *C.int) C.int {
+	s, ok := newSolver(puzzle)
+	if !ok || limit < 0 {
+		return C.SUDOCOO_INVALID_PUZZLE
+	}
+	count := 0
+	reached := 0
+	for s.Solve() {
+		if limit != 0 && count == int(limit) {
+			reached = 1
+			break
+		}
+		count++
+	}
+	if limit_reached != nil {
+		*limit_reached = C.int(reached)
+	}
+	return C.int(count)
+}
+
+// Rates a puzzle with a unique solution. The level is returned in a string allocated for the caller,
+// who frees it with sudocoo_free, the score is the backtracking effort of the search
+//
+//export sudocoo_rate
+func sudocoo_rate(puzzle *C.char, level **C.char, score *C.double) C.int {
+	p, ok := parsePuzzle(puzzle)
+	if !ok {
+		return C.SUDOCOO_INVALID_PUZZLE
+	}
+	s, err := solver.NewSolver(p)
+	if err != nil {
+		return C.SUDOCOO_INVALID_PUZZLE
+	}
+	switch s.CountSolutions(1) {
+	case 0:
+		return C.SUDOCOO_NO_SOLUTION
+	case 2:
+		return C.SUDOCOO_NOT_UNIQUE
+	}
+	rated, err := rating.Rate(p, analysis.DefaultSearchOrders, 1)
+	if err != nil {
+		return C.SUDOCOO_INVALID_PUZZLE
+	}
+	if level != nil {
+		*level = C.CString(rated.Level.String())
+	}
+	if score != nil {
+		*score = C.double(rated.Score)
+	}
+	return C.SUDOCOO_OK
+}
+
+// Frees memory returned by the library
+//
+//export sudocoo_free
+func sudocoo_free(p unsafe.Pointer) {
+	C.free(p)
+}
+
+func main() {}
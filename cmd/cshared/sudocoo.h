@@ -0,0 +1,46 @@
+/*
+ * sudocoo C API, see cmd/cshared/main.go for how to build libsudocoo.
+ *
+ * Puzzles and solutions are NUL-terminated strings in the inline format:
+ * 81 characters, row by row, with '.' or '0' for empty cells.
+ * All functions are safe to call from multiple threads.
+ */
+#ifndef SUDOCOO_H
+#define SUDOCOO_H
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+#define SUDOCOO_OK                0
+#define SUDOCOO_INVALID_PUZZLE   -1 /* cannot be parsed or the givens contradict each other */
+#define SUDOCOO_NO_SOLUTION      -2
+#define SUDOCOO_BUFFER_TOO_SMALL -3
+#define SUDOCOO_NOT_UNIQUE       -4 /* the puzzle has more than one solution */
+
+/* The minimum size of the solution buffer passed to sudocoo_solve */
+#define SUDOCOO_SOLUTION_SIZE 82
+
+/* Writes the first solution of puzzle to the solution buffer of solution_size bytes.
+ * Returns SUDOCOO_OK or one of the error codes above. */
+int sudocoo_solve(const char *puzzle, char *solution, int solution_size);
+
+/* Returns the number of solutions of puzzle, but no more than limit (0 for no limit),
+ * or SUDOCOO_INVALID_PUZZLE. If limit_reached is not NULL it is set to 1 when
+ * the puzzle has more solutions than the limit, otherwise to 0. */
+int sudocoo_count(const char *puzzle, int limit, int *limit_reached);
+
+/* Rates a puzzle with a unique solution. On SUDOCOO_OK *level is set, if level is not NULL,
+ * to a newly allocated string, one of "easy", "medium", "hard" and "extreme", that the caller
+ * frees with sudocoo_free, and *score, if score is not NULL, to the backtracking effort of the
+ * search. Returns SUDOCOO_OK or one of the error codes above. */
+int sudocoo_rate(const char *puzzle, char **level, double *score);
+
+/* Frees memory returned by the functions above */
+void sudocoo_free(void *p);
+
+#ifdef __cplusplus
+}
+#endif
+
+#endif /* SUDOCOO_H */
@@ -0,0 +1,33 @@
+// AWS Lambda build of the HTTP API, for a custom runtime behind a function URL or an HTTP API:
+//
+//	GOOS=linux GOARCH=arm64 go build -o bootstrap ./cmd/lambda
+//	zip sudocoo.zip bootstrap
+//
+// SUDOCOO_MAX_LIMIT sets the maximum number of solutions a request can ask for, 10000 by default
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/AndrewSav/sudocoo/pkg/handler"
+)
+
+const envMaxLimit = "SUDOCOO_MAX_LIMIT"
+
+func main() {
+	maxLimit := 10000
+	if v := os.Getenv(envMaxLimit); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			fmt.Printf("Error: invalid %s %q, want 1 or more\n", envMaxLimit, v)
+			os.Exit(1)
+		}
+		maxLimit = limit
+	}
+	if err := handler.ServeLambda(handler.New(maxLimit, nil)); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,139 @@
+//go:build js && wasm
+
+// WebAssembly build of the solver for browsers and Node.js:
+//
+//	GOOS=js GOARCH=wasm go build -o sudocoo.wasm ./cmd/wasm
+//	cp "$(go env GOROOT)/lib/wasm/wasm_exec.js" .
+//
+// The functions are registered on globalThis.sudocoo, sudocoo.js wraps them into a promise based loader
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"syscall/js"
+
+	"github.com/AndrewSav/sudocoo/pkg/analysis"
+	"github.com/AndrewSav/sudocoo/pkg/format"
+	"github.com/AndrewSav/sudocoo/pkg/parser"
+	"github.com/AndrewSav/sudocoo/pkg/rating"
+	"github.com/AndrewSav/sudocoo/pkg/solver"
+	"github.com/AndrewSav/sudocoo/pkg/sudocoo"
+)
+
+// Parses the puzzle string and creates a solver for it
+func newSolver(puzzle string) (*solver.Solver, error) {
+	p, err := parser.ParsePuzzleString(puzzle)
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("puzzle is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return solver.NewSolver(p)
+}
+
+// Results are returned as plain objects, errors are reported in the 'error' property instead of throwing
+func errorResult(err error) any {
+	return map[string]any{"error": err.Error()}
+}
+
+// solve(puzzle: string): {solution?: string, iterations?: number, error?: string}
+func solve(this js.Value, args []js.Value) any {
+	if len(args) < 1 || args[0].Type() != js.TypeString {
+		return errorResult(fmt.Errorf("solve expects a puzzle string"))
+	}
+	s, err := newSolver(args[0].String())
+	if err != nil {
+		return errorResult(err)
+	}
+	if !s.Solve() {
+		return errorResult(fmt.Errorf("no solution"))
+	}
+	return map[string]any{"solution": format.Inline(s.Solution()), "iterations": s.Iterations()}
+}
+
+// count(puzzle: string, limit?: number): {count?: number, limitReached?: boolean, error?: string}
+// A limit of 0 or no limit counts all the solutions, which can take forever on open puzzles
+func count(this js.Value, args []js.Value) any {
+	if len(args) < 1 || args[0].Type() != js.TypeString {
+		return errorResult(fmt.Errorf("count expects a puzzle string"))
+	}
+	limit := 0
+	if len(args) > 1 && args[1].Type() == js.TypeNumber {
+		limit = args[1].Int()
+	}
+	s, err := newSolver(args[0].String())
+	if err != nil {
+		return errorResult(err)
+	}
+	solutionCount := 0
+	limitReached := false
+	for s.Solve() {
+		if limit != 0 && solutionCount == limit {
+			limitReached = true
+			break
+		}
+		solutionCount++
+	}
+	return map[string]any{"count": solutionCount, "limitReached": limitReached}
+}
+
+// generate(clues?: number, seed?: number): {puzzle?: string, error?: string}
+// A puzzle with a unique solution, see sudocoo.Generate. The same clues and seed give the same puzzle
+func generate(this js.Value, args []js.Value) any {
+	clues, seed := 0, 0
+	if len(args) > 0 && args[0].Type() == js.TypeNumber {
+		clues = args[0].Int()
+	}
+	if len(args) > 1 && args[1].Type() == js.TypeNumber {
+		seed = args[1].Int()
+	}
+	puzzle, err := sudocoo.Generate(sudocoo.WithClues(clues), sudocoo.WithSeed(uint64(seed)))
+	if err != nil {
+		return errorResult(err)
+	}
+	return map[string]any{"puzzle": format.Inline(puzzle)}
+}
+
+// rate(puzzle: string): {level?: string, score?: number, error?: string}
+// Only a puzzle with a unique solution is rated, see rating.Rate
+func rate(this js.Value, args []js.Value) any {
+	if len(args) < 1 || args[0].Type() != js.TypeString {
+		return errorResult(fmt.Errorf("rate expects a puzzle string"))
+	}
+	puzzle, err := parser.ParsePuzzleString(args[0].String())
+	if errors.Is(err, io.EOF) {
+		return errorResult(fmt.Errorf("puzzle is empty"))
+	}
+	if err != nil {
+		return errorResult(err)
+	}
+	s, err := solver.NewSolver(puzzle)
+	if err != nil {
+		return errorResult(err)
+	}
+	switch s.CountSolutions(1) {
+	case 0:
+		return errorResult(fmt.Errorf("no solution"))
+	case 2:
+		return errorResult(fmt.Errorf("the puzzle has more than one solution"))
+	}
+	rated, err := rating.Rate(puzzle, analysis.DefaultSearchOrders, 1)
+	if err != nil {
+		return errorResult(err)
+	}
+	return map[string]any{"level": rated.Level.String(), "score": rated.Score}
+}
+
+func main() {
+	js.Global().Set("sudocoo", js.ValueOf(map[string]any{
+		"solve":    js.FuncOf(solve),
+		"count":    js.FuncOf(count),
+		"generate": js.FuncOf(generate),
+		"rate":     js.FuncOf(rate),
+	}))
+	// The exported functions only work while the Go program is running
+	select {}
+}
@@ -0,0 +1,41 @@
+// Type definitions for sudocoo.js. Puzzles and solutions are strings in the inline format,
+// 81 characters with '.' or '0' for empty cells. Errors are returned, not thrown.
+
+export interface SolveResult {
+  solution?: string;
+  iterations?: number;
+  error?: string;
+}
+
+export interface CountResult {
+  count?: number;
+  // There are more solutions than the limit
+  limitReached?: boolean;
+  error?: string;
+}
+
+export interface GenerateResult {
+  // Has a unique solution
+  puzzle?: string;
+  error?: string;
+}
+
+export interface RateResult {
+  // easy, medium, hard or extreme
+  level?: string;
+  // The backtracking effort of the search
+  score?: number;
+  error?: string;
+}
+
+export interface Sudocoo {
+  solve(puzzle: string): SolveResult;
+  // A limit of 0 counts all the solutions
+  count(puzzle: string, limit?: number): CountResult;
+  // 0 clues leaves the number to the generator, the same clues and seed give the same puzzle
+  generate(clues?: number, seed?: number): GenerateResult;
+  // Only a puzzle with a unique solution is rated
+  rate(puzzle: string): RateResult;
+}
+
+export function load(url: string): Promise<Sudocoo>;
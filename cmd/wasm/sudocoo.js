@@ -0,0 +1,20 @@
+// Loader for sudocoo.wasm. Requires wasm_exec.js from the Go distribution to be loaded first,
+// it defines the global Go class:
+//
+//   import { load } from "./sudocoo.js";
+//   const sudocoo = await load("sudocoo.wasm");
+//   const { solution, error } = sudocoo.solve("4...3.......6..8...");
+
+export async function load(url) {
+  const go = new Go();
+  const { instance } = await WebAssembly.instantiateStreaming(fetch(url), go.importObject);
+  // The Go program never exits, it keeps the exported functions alive
+  go.run(instance);
+  const api = globalThis.sudocoo;
+  return {
+    solve: (puzzle) => api.solve(puzzle),
+    count: (puzzle, limit = 0) => api.count(puzzle, limit),
+    generate: (clues = 0, seed = 0) => api.generate(clues, seed),
+    rate: (puzzle) => api.rate(puzzle),
+  };
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/AndrewSav/sudocoo/pkg/cnf"
+	"github.com/AndrewSav/sudocoo/pkg/format"
+	"github.com/AndrewSav/sudocoo/pkg/parser"
+)
+
+type exportCNFFlags struct {
+	InputFile  string // input can come from a file
+	Input      string // or form a string
+	OutputFile string // template of the file name of each puzzle
+}
+
+// What the file name template of a puzzle is made from
+type cnfData struct {
+	Index  int    // 0-based position of the puzzle in the input
+	Label  string // 1-based number of the puzzle
+	Puzzle string // inline format
+}
+
+// Writes each puzzle as a DIMACS CNF formula, see package cnf, for SAT solvers to solve. The formulas
+// go to a file each, or one after another to the standard output
+func runExportCNF(args []string) {
+	var flags exportCNFFlags
+
+	fs := flag.NewFlagSet("export-cnf", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Println("Writes puzzles as DIMACS CNF formulas for SAT solvers. Variable (row*9 + column)*9 + digit, rows and columns from 0, is true when the digit is in the cell")
+		fmt.Printf("Usage: %s export-cnf [FLAGS...]\n", filepath.Base(os.Args[0]))
+		fmt.Println("Flags:")
+		fs.PrintDefaults()
+	}
+	addInputFlags(fs, &flags.InputFile, &flags.Input)
+	fs.StringVar(&flags.OutputFile, "o", "", "Go text/template of the file to write each formula to, e.g. 'puzzle-{{.Label}}.cnf'. The fields are .Index (0-based), .Label (the 1-based number of the puzzle) and .Puzzle (inline). Default: the formulas are written to the standard output one after another")
+	parseFlags(fs, args)
+
+	path, err := template.New("o").Parse(flags.OutputFile)
+	if err != nil {
+		fmt.Println(err)
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	out := newOutput(false)
+	defer out.Flush()
+
+	scanner := parser.CreateInputScanner(openInput(fs, flags.InputFile, flags.Input))
+	index := 0
+	for ; ; index++ {
+		puzzle, err := parser.ReadNextPuzzleInput(scanner)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			out.fail(err)
+		}
+		data := cnfData{Index: index, Label: strconv.Itoa(index + 1), Puzzle: format.Inline(puzzle)}
+		comment := fmt.Sprintf("puzzle %s: %s", data.Label, data.Puzzle)
+		if flags.OutputFile == "" {
+			if err := cnf.Write(out, puzzle, comment); err != nil {
+				out.fail(err)
+			}
+			out.endRecord()
+			continue
+		}
+		var name strings.Builder
+		if err := path.Execute(&name, data); err != nil {
+			out.fail(err)
+		}
+		if err := writeFile(name.String(), func(w io.Writer) error { return cnf.Write(w, puzzle, comment) }); err != nil {
+			out.fail(err)
+		}
+		fmt.Fprintln(out, name.String())
+		out.endRecord()
+	}
+	if index == 0 {
+		// Not a single puzzle in the input
+		out.fail(io.EOF)
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/AndrewSav/sudocoo/pkg/batch"
+	"github.com/AndrewSav/sudocoo/pkg/canon"
+	"github.com/AndrewSav/sudocoo/pkg/format"
+	"github.com/AndrewSav/sudocoo/pkg/packed"
+	"github.com/AndrewSav/sudocoo/pkg/parser"
+)
+
+type compareFlags struct {
+	Left       string // the first collection
+	Right      string // the second collection
+	Show       string // which of the sets to print
+	CountsOnly bool   // print the sizes of the sets only
+}
+
+// A puzzle of a collection, the first one of those equivalent to it
+type collectionPuzzle struct {
+	puzzle string
+	line   int // the 1-based position of the puzzle in the collection
+}
+
+// The puzzles of a collection by their fingerprints, in the order they first appear
+type collection struct {
+	total        int // puzzles read, equivalent ones included
+	fingerprints []string
+	puzzles      map[string]collectionPuzzle
+}
+
+// Compares two puzzle collections, telling equivalent puzzles (relabeled, rotated, etc) for the same.
+// Prints the puzzles the collections have in common, taken from the left one, and the ones only
+// one of them has, with the count of each. Within a collection only the first of equivalent puzzles counts
+func runCompare(args []string) {
+	var flags compareFlags
+
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Println("Compares two puzzle collections, prints the puzzles they have in common and the ones only one of them has")
+		fmt.Printf("Usage: %s compare -left FILE -right FILE [FLAGS...]\n", filepath.Base(os.Args[0]))
+		fmt.Println("Flags:")
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&flags.Left, "left", "", "path to the first collection, text or packed")
+	fs.StringVar(&flags.Right, "right", "", "path to the second collection, text or packed")
+	fs.StringVar(&flags.Show, "show", "all", "what to print: 'all' the sets with their counts, or only the puzzles of one of them, one per line: 'both' for the common ones, 'left' or 'right' for the ones only that collection has")
+	fs.BoolVar(&flags.CountsOnly, "c", false, "print the counts only, not the puzzles")
+	parseFlags(fs, args)
+
+	if flags.Left == "" || flags.Right == "" {
+		fmt.Println("you have to specify both -left and -right")
+		fs.Usage()
+		os.Exit(2)
+	}
+	switch flags.Show {
+	case "all", "both", "left", "right":
+	default:
+		fmt.Printf("invalid -show %s, want all, both, left or right\n", flags.Show)
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	out := newOutput(false)
+	defer out.Flush()
+
+	left, err := readCollection(flags.Left)
+	if err != nil {
+		out.fail(err)
+	}
+	right, err := readCollection(flags.Right)
+	if err != nil {
+		out.fail(err)
+	}
+
+	var both, onlyLeft, onlyRight []collectionPuzzle
+	for _, fp := range left.fingerprints {
+		if _, ok := right.puzzles[fp]; ok {
+			both = append(both, left.puzzles[fp])
+		} else {
+			onlyLeft = append(onlyLeft, left.puzzles[fp])
+		}
+	}
+	for _, fp := range right.fingerprints {
+		if _, ok := left.puzzles[fp]; !ok {
+			onlyRight = append(onlyRight, right.puzzles[fp])
+		}
+	}
+
+	if flags.Show != "all" {
+		puzzles := map[string][]collectionPuzzle{"both": both, "left": onlyLeft, "right": onlyRight}[flags.Show]
+		if flags.CountsOnly {
+			fmt.Fprintln(out, len(puzzles))
+			return
+		}
+		for _, p := range puzzles {
+			fmt.Fprintln(out, p.puzzle)
+		}
+		return
+	}
+	fmt.Fprintf(out, "%s: %d puzzles, %d distinct\n", flags.Left, left.total, len(left.fingerprints))
+	fmt.Fprintf(out, "%s: %d puzzles, %d distinct\n", flags.Right, right.total, len(right.fingerprints))
+	writeCompareSet(out, "In both", both, flags.CountsOnly)
+	writeCompareSet(out, "Only in "+flags.Left, onlyLeft, flags.CountsOnly)
+	writeCompareSet(out, "Only in "+flags.Right, onlyRight, flags.CountsOnly)
+}
+
+// Prints a set of puzzles with its count and the positions the puzzles have in their collection
+func writeCompareSet(out io.Writer, title string, puzzles []collectionPuzzle, countsOnly bool) {
+	fmt.Fprintf(out, "%s: %d\n", title, len(puzzles))
+	if countsOnly {
+		return
+	}
+	for _, p := range puzzles {
+		fmt.Fprintf(out, "  %s #%d\n", p.puzzle, p.line)
+	}
+}
+
+// A puzzle as it is read, with its fingerprint
+type fingerprinted struct {
+	puzzle      string
+	fingerprint string
+}
+
+// Reads the puzzles of a collection file and works out their fingerprints
+func readCollection(path string) (*collection, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := parser.CreateInputScanner(packed.Text(file))
+	next := func() ([9][9]int, error) { return parser.ReadNextPuzzleInput(scanner) }
+	fingerprint := func(_ context.Context, puzzle [9][9]int) (fingerprinted, error) {
+		return fingerprinted{puzzle: format.Inline(puzzle), fingerprint: canon.Fingerprint(puzzle)}, nil
+	}
+	c := &collection{puzzles: map[string]collectionPuzzle{}}
+	stats, err := batch.Run(context.Background(), batch.Config{}, next, fingerprint, func(item batch.Item[fingerprinted]) bool {
+		if _, ok := c.puzzles[item.Value.fingerprint]; !ok {
+			c.fingerprints = append(c.fingerprints, item.Value.fingerprint)
+			c.puzzles[item.Value.fingerprint] = collectionPuzzle{puzzle: item.Value.puzzle, line: item.Index + 1}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	c.total = stats.Items
+	return c, nil
+}
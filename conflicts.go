@@ -0,0 +1,91 @@
+package main
+
+import "errors"
+
+// Whether a run uses a flag, by the name the messages and the help show it with. Flags that only
+// conflict for some of their values, such as '-engine', have an entry for each of those values
+var flagInUse = map[string]func(f *Flags) bool{
+	"-a":             func(f *Flags) bool { return f.All },
+	"-d":             func(f *Flags) bool { return f.DontSolve },
+	"-e":             func(f *Flags) bool { return f.EchoInput },
+	"-f":             func(f *Flags) bool { return f.InputFile != "" },
+	"-i":             func(f *Flags) bool { return f.Input != "" },
+	"-u":             func(f *Flags) bool { return f.Uniqueness },
+	"-rate":          func(f *Flags) bool { return f.Rate },
+	"-sample":        func(f *Flags) bool { return f.Sample != 0 },
+	"-timeout":       func(f *Flags) bool { return f.Timeout != 0 },
+	"-variant":       func(f *Flags) bool { return f.VariantText != "" },
+	"-constraint":    func(f *Flags) bool { return f.ConstraintText != "" },
+	"-engine logic":  func(f *Flags) bool { return f.Engine == engineLogic },
+	"-engine dlx":    func(f *Flags) bool { return f.Engine == engineDLX },
+	"--proof":        func(f *Flags) bool { return f.Proof },
+	"--search-trace": func(f *Flags) bool { return f.SearchTrace },
+	"--template":     func(f *Flags) bool { return f.TemplateText != "" },
+	"--filter":       func(f *Flags) bool { return f.FilterText != "" },
+	"--results":      func(f *Flags) bool { return f.Results != "" },
+	"--db":           func(f *Flags) bool { return f.Database != "" },
+	"--cache":        func(f *Flags) bool { return f.Cache != "" },
+	"--cross-check":  func(f *Flags) bool { return f.CrossCheck != "" },
+	"--clipboard-in": func(f *Flags) bool { return f.ClipboardIn },
+}
+
+// Flags that cannot be used together with others. names are a flag, or flags that share their
+// conflicts, and with the flags they conflict with, all of them keys of flagInUse
+type flagConflict struct {
+	names []string
+	with  []string
+}
+
+// Checked in this order, the first conflict found is the one reported. The help of the flags is made
+// from this too, see conflictHelp
+var flagConflicts = []flagConflict{
+	{[]string{"--clipboard-in"}, []string{"-f", "-i"}},
+	{[]string{"-sample"}, []string{"-a", "-d", "-e", "--cross-check"}},
+	{[]string{"--proof"}, []string{"-a", "-d", "-e", "-sample", "--cross-check"}},
+	{[]string{"-rate"}, []string{"-a", "-d", "-sample", "--proof", "--template", "--results", "--cross-check"}},
+	{[]string{"-engine logic"}, []string{"-a", "-sample", "--proof", "-rate", "--cross-check"}},
+	{[]string{"-engine dlx"}, []string{"-sample", "--proof", "-rate", "--cross-check"}},
+	{[]string{"-timeout"}, []string{"-d", "-e", "-sample", "--proof", "-rate", "-engine logic", "-engine dlx", "--cross-check"}},
+	{[]string{"-u"}, []string{"-a", "-d", "-e", "-sample", "--proof", "-rate", "-timeout", "--search-trace", "--template", "--results", "-engine logic", "-engine dlx", "--cross-check"}},
+	{[]string{"--search-trace"}, []string{"-d", "-e", "-sample", "--proof", "-rate", "-engine logic", "-engine dlx", "--cross-check"}},
+	{[]string{"-variant", "-constraint"}, []string{"-e", "-sample", "--proof", "-rate", "-engine logic", "-engine dlx", "--db", "--cache", "--filter", "--results", "--template", "--cross-check"}},
+	{[]string{"--template"}, []string{"-d", "-e", "-sample", "--proof", "--cross-check"}},
+	{[]string{"--filter"}, []string{"-d", "-e", "--cross-check"}},
+	{[]string{"--results"}, []string{"-d", "-e", "--cross-check"}},
+}
+
+func anyInUse(f *Flags, names []string) bool {
+	for _, name := range names {
+		if flagInUse[name](f) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns an error naming the first of flagConflicts the flags run into, if any
+func checkConflicts(f *Flags) error {
+	for _, c := range flagConflicts {
+		if !anyInUse(f, c.names) || !anyInUse(f, c.with) {
+			continue
+		}
+		if len(c.names) == 2 {
+			return errors.New(msgf("'%s' and '%s' cannot be combined with %s", c.names[0], c.names[1], flagList(c.with...)))
+		}
+		return errors.New(msgf("'%s' cannot be combined with %s", c.names[0], flagList(c.with...)))
+	}
+	return nil
+}
+
+// Returns the flags the flag cannot be combined with for its help, such as "'-a' or '-d'".
+// The help is in English whatever the language
+func conflictHelp(name string) string {
+	for _, c := range flagConflicts {
+		for _, n := range c.names {
+			if n == name {
+				return joinFlags("%s or %s", c.with)
+			}
+		}
+	}
+	panic("no conflicts for " + name)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/AndrewSav/sudocoo/pkg/analysis"
+	"github.com/AndrewSav/sudocoo/pkg/batch"
+	"github.com/AndrewSav/sudocoo/pkg/format"
+	"github.com/AndrewSav/sudocoo/pkg/parser"
+)
+
+// What the reference solver said about a puzzle, and where it disagrees with us
+type crossCheckResult struct {
+	puzzle        string
+	discrepancies []string
+}
+
+// Runs each puzzle through the reference solver command and compares the results with ours.
+// The command gets the puzzle as a single inline line on its standard input. Its output is either
+// a solution count, or solutions in any format we can read. Without '-a' only whether the puzzle is
+// solvable is compared, with '-a' the number of solutions up to the limit is. Every solution the
+// reference reports has to be a solution of the puzzle.
+// Prints a line per discrepancy and a summary, exits with 1 if there are any discrepancies
+func runCrossCheck(flags *Flags, next puzzleSource, out *output) {
+	limit := 1
+	if flags.All {
+		limit = flags.Limit
+	}
+	check := func(ctx context.Context, puzzle [9][9]int) (crossCheckResult, error) {
+		return crossCheck(ctx, flags.CrossCheck, puzzle, limit), nil
+	}
+	discrepancies := 0
+	stats, err := batch.Run(context.Background(), batch.Config{}, next, check, func(item batch.Item[crossCheckResult]) bool {
+		for _, d := range item.Value.discrepancies {
+			fmt.Fprintf(out, "%s: %s\n", item.Value.puzzle, d)
+			out.endRecord()
+		}
+		if len(item.Value.discrepancies) != 0 {
+			discrepancies++
+		}
+		return true
+	})
+	if err != nil {
+		out.fail(err)
+	}
+	if stats.Items == 0 {
+		// Not a single puzzle in the input
+		out.fail(io.EOF)
+	}
+	fmt.Fprintf(out, "Checked %d puzzles against %q, %d with discrepancies\n", stats.Items, flags.CrossCheck, discrepancies)
+	if discrepancies != 0 {
+		out.fail(fmt.Errorf("the reference solver disagrees on %d puzzles", discrepancies))
+	}
+}
+
+// Compares the reference solver's results for a single puzzle with ours, counting up to limit
+// solutions, 0 is no limit
+func crossCheck(ctx context.Context, command string, puzzle [9][9]int, limit int) crossCheckResult {
+	r := crossCheckResult{puzzle: format.Inline(puzzle)}
+
+	countLimit := limit
+	if countLimit == 0 {
+		countLimit = math.MaxInt
+	}
+	ours, err := countSolutions(puzzle, countLimit)
+	if err != nil {
+		// An inconsistent puzzle has no solutions
+		ours = 0
+	}
+
+	output, err := runReference(ctx, command, r.puzzle)
+	if err != nil {
+		r.discrepancies = append(r.discrepancies, fmt.Sprintf("reference solver failed: %v", err))
+		return r
+	}
+	theirs, solutions, err := parseReferenceOutput(output)
+	if err != nil {
+		r.discrepancies = append(r.discrepancies, fmt.Sprintf("cannot read the reference solver output: %v", err))
+		return r
+	}
+	for _, solution := range solutions {
+		if !analysis.IsSolutionOf(solution, puzzle) {
+			r.discrepancies = append(r.discrepancies, fmt.Sprintf("reference solution %s is not a solution of the puzzle", format.Inline(solution)))
+		}
+	}
+	if limit != 0 {
+		theirs = min(theirs, limit)
+	}
+	if ours != theirs {
+		r.discrepancies = append(r.discrepancies, fmt.Sprintf("%s here, %s from the reference", countText(ours, limit), countText(theirs, limit)))
+	}
+	return r
+}
+
+// Describes a solution count, a count at the limit means that there may be more
+func countText(count, limit int) string {
+	if limit != 0 && count == limit {
+		return fmt.Sprintf("%d+ solutions", count)
+	}
+	return fmt.Sprintf("%d solutions", count)
+}
+
+// Runs the command through the shell with the puzzle on its standard input
+func runReference(ctx context.Context, command, puzzle string) ([]byte, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Stdin = strings.NewReader(puzzle + "\n")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%v: %s", err, msg)
+		}
+		return nil, err
+	}
+	return output, nil
+}
+
+// The output is either a single number, the solution count, or the solutions themselves.
+// Returns the count and the solutions if there are any
+func parseReferenceOutput(output []byte) (int, [][9][9]int, error) {
+	if count, err := strconv.Atoi(strings.TrimSpace(string(output))); err == nil {
+		if count < 0 {
+			return 0, nil, fmt.Errorf("negative solution count %d", count)
+		}
+		return count, nil, nil
+	}
+	var solutions [][9][9]int
+	reader := parser.NewBytesReader(output)
+	for {
+		solution, err := reader.Next()
+		if err == io.EOF {
+			return len(solutions), solutions, nil
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+		solutions = append(solutions, solution)
+	}
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/AndrewSav/sudocoo/pkg/analysis"
+	"github.com/AndrewSav/sudocoo/pkg/format"
+	"github.com/AndrewSav/sudocoo/pkg/parser"
+	"github.com/AndrewSav/sudocoo/pkg/solver"
+)
+
+type designFlags struct {
+	InputFile string // input can come from a file
+	Input     string // or form a string
+	Limit     int    // solutions are counted up to that many
+	Top       int    // the number of additions to suggest
+}
+
+const designHelp = `Commands:
+  show               print the puzzle as a grid with its status
+  print              print the puzzle inline
+  add r1c2 5         add a clue, also: add r1c2=5
+  remove r1c2        remove a clue
+  undo               take back the last change
+  suggest            for a unique puzzle, the clues that can be removed keeping it unique,
+                     otherwise the additions that leave the fewest solutions
+  minimize           remove clues in order while the puzzle stays unique
+  help               print this help
+  quit               leave, also: exit`
+
+// A design session: the puzzle being worked on and the ones before each change, for undo
+type designSession struct {
+	puzzle  [9][9]int
+	history [][9][9]int
+	limit   int
+	top     int
+	out     io.Writer
+}
+
+// Lets a setter work on a puzzle interactively, starting from a solution grid or a partial puzzle.
+// Reads commands from stdin, prints the solution count after every change and suggests which clues
+// can go and which additions narrow the solutions down the most
+func runDesign(args []string) {
+	var flags designFlags
+
+	fs := flag.NewFlagSet("design", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Println("Works on a puzzle interactively: add and remove clues with live solution counts and suggestions")
+		fmt.Printf("Usage: %s design [FLAGS...]\n", filepath.Base(os.Args[0]))
+		fmt.Println("Flags:")
+		fs.PrintDefaults()
+		fmt.Println(designHelp)
+	}
+	addInputFlags(fs, &flags.InputFile, &flags.Input)
+	fs.IntVar(&flags.Limit, "limit", defaultLimit, "count the solutions up to this many")
+	fs.IntVar(&flags.Top, "top", 10, "the number of additions to suggest")
+	parseFlags(fs, args)
+
+	if flags.Limit < 1 {
+		fmt.Printf("invalid limit %d, want 1 or more\n", flags.Limit)
+		fs.Usage()
+		os.Exit(2)
+	}
+	if flags.Top < 1 {
+		fmt.Printf("invalid number of suggestions %d, want 1 or more\n", flags.Top)
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	out := newOutput(true)
+	defer out.Flush()
+
+	puzzle, err := parser.ReadNextPuzzleInput(parser.CreateInputScanner(openInput(fs, flags.InputFile, flags.Input)))
+	if err != nil {
+		out.fail(err)
+	}
+	if _, err := solver.NewSolver(puzzle); err != nil {
+		out.fail(err)
+	}
+	d := &designSession{puzzle: puzzle, limit: flags.Limit, top: flags.Top, out: out}
+	d.show()
+
+	prompt := isTerminal(os.Stdin)
+	commands := bufio.NewScanner(os.Stdin)
+	for {
+		if prompt {
+			fmt.Fprint(out, "> ")
+			out.Flush()
+		}
+		if !commands.Scan() {
+			break
+		}
+		fields := strings.Fields(strings.ReplaceAll(commands.Text(), "=", " "))
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == "quit" || fields[0] == "exit" {
+			break
+		}
+		if err := d.run(fields[0], fields[1:]); err != nil {
+			fmt.Fprintf(out, "Error: %v\n", err)
+		}
+		out.endRecord()
+	}
+	if err := commands.Err(); err != nil {
+		out.fail(err)
+	}
+}
+
+// Runs a single command of the session
+func (d *designSession) run(command string, args []string) error {
+	switch command {
+	case "show":
+		d.show()
+	case "print":
+		fmt.Fprintln(d.out, format.Inline(d.puzzle))
+	case "add":
+		if len(args) != 2 {
+			return fmt.Errorf("want: add r1c2 5")
+		}
+		y, x, err := parseDesignCell(args[0])
+		if err != nil {
+			return err
+		}
+		digit, err := strconv.Atoi(args[1])
+		if err != nil || digit < 1 || digit > 9 {
+			return fmt.Errorf("invalid digit %s", args[1])
+		}
+		if d.puzzle[y][x] != 0 {
+			return fmt.Errorf("r%dc%d has a clue already", y+1, x+1)
+		}
+		next := d.puzzle
+		next[y][x] = digit
+		if _, err := solver.NewSolver(next); err != nil {
+			return fmt.Errorf("%d clashes with the clues in the row, column or box of r%dc%d", digit, y+1, x+1)
+		}
+		d.change(next)
+	case "remove":
+		if len(args) != 1 {
+			return fmt.Errorf("want: remove r1c2")
+		}
+		y, x, err := parseDesignCell(args[0])
+		if err != nil {
+			return err
+		}
+		if d.puzzle[y][x] == 0 {
+			return fmt.Errorf("r%dc%d has no clue", y+1, x+1)
+		}
+		next := d.puzzle
+		next[y][x] = 0
+		d.change(next)
+	case "undo":
+		if len(d.history) == 0 {
+			return fmt.Errorf("nothing to undo")
+		}
+		d.puzzle = d.history[len(d.history)-1]
+		d.history = d.history[:len(d.history)-1]
+		d.status()
+	case "suggest":
+		return d.suggest()
+	case "minimize":
+		return d.minimize()
+	case "help":
+		fmt.Fprintln(d.out, designHelp)
+	default:
+		return fmt.Errorf("unknown command %s, see help", command)
+	}
+	return nil
+}
+
+// Moves on to the next version of the puzzle, keeping the current one for undo
+func (d *designSession) change(next [9][9]int) {
+	d.history = append(d.history, d.puzzle)
+	d.puzzle = next
+	d.status()
+}
+
+func (d *designSession) show() {
+	// The visual format is built in, only format names from users can be unknown
+	text, _ := format.Format(d.puzzle, "visual")
+	fmt.Fprintln(d.out, text)
+	d.status()
+}
+
+// Prints the clue count and how many solutions the puzzle has
+func (d *designSession) status() {
+	count, _ := countSolutions(d.puzzle, d.limit+1)
+	solutions := strconv.Itoa(count)
+	switch {
+	case count > d.limit:
+		solutions = fmt.Sprintf("%d+", d.limit)
+	case count == 1:
+		solutions = "1 (unique)"
+	}
+	fmt.Fprintf(d.out, "Clues: %d, solutions: %s\n", analysis.ClueCount(d.puzzle), solutions)
+}
+
+// A clue that could be added with what it does to the puzzle
+type designAddition struct {
+	y, x, digit int
+	result      solver.WhatIfResult
+	forced      int
+}
+
+// Suggests the next changes: with a unique solution, the clues that can go without losing it,
+// otherwise the additions that leave the fewest solutions, and then force the most cells
+func (d *designSession) suggest() error {
+	count, _ := countSolutions(d.puzzle, 2)
+	switch count {
+	case 0:
+		return fmt.Errorf("the puzzle has no solution, remove a clue or undo")
+	case 1:
+		var removable []string
+		for y := range 9 {
+			for x := range 9 {
+				if d.puzzle[y][x] != 0 && d.uniqueWithout(d.puzzle, y, x) {
+					removable = append(removable, fmt.Sprintf("r%dc%d", y+1, x+1))
+				}
+			}
+		}
+		if len(removable) == 0 {
+			fmt.Fprintln(d.out, "The puzzle is minimal, every clue is needed")
+			return nil
+		}
+		fmt.Fprintf(d.out, "Can be removed keeping the solution unique: %s\n", strings.Join(removable, " "))
+		return nil
+	}
+
+	candidates, err := analysis.FindSolutionCandidates(d.puzzle)
+	if err != nil {
+		return err
+	}
+	s, err := solver.NewSolver(d.puzzle)
+	if err != nil {
+		return err
+	}
+	var additions []designAddition
+	for y := range 9 {
+		for x := range 9 {
+			if d.puzzle[y][x] != 0 || len(candidates.Digits(y, x)) == 1 {
+				// A cell that is determined already narrows nothing down
+				continue
+			}
+			for _, digit := range candidates.Digits(y, x) {
+				r, err := s.WhatIf(y, x, digit, d.limit)
+				if err != nil {
+					return err
+				}
+				a := designAddition{y: y, x: x, digit: digit, result: r}
+				for _, row := range r.Forced {
+					for _, v := range row {
+						if v != 0 {
+							a.forced++
+						}
+					}
+				}
+				additions = append(additions, a)
+			}
+		}
+	}
+	slices.SortStableFunc(additions, func(a, b designAddition) int {
+		if a.result.LimitReached != b.result.LimitReached {
+			if a.result.LimitReached {
+				return 1
+			}
+			return -1
+		}
+		if a.result.Solutions != b.result.Solutions {
+			return a.result.Solutions - b.result.Solutions
+		}
+		return b.forced - a.forced
+	})
+	for _, a := range additions[:min(d.top, len(additions))] {
+		solutions := strconv.Itoa(a.result.Solutions)
+		if a.result.LimitReached {
+			solutions += "+"
+		}
+		fmt.Fprintf(d.out, "add r%dc%d %d: %s solutions, %d cells forced\n", a.y+1, a.x+1, a.digit, solutions, a.forced)
+	}
+	return nil
+}
+
+// Removes the clues one at a time, in row major order, as long as the solution stays unique,
+// which leaves a minimal puzzle: none of the clues left can go
+func (d *designSession) minimize() error {
+	if count, _ := countSolutions(d.puzzle, 2); count != 1 {
+		return fmt.Errorf("only a puzzle with a unique solution can be minimized")
+	}
+	next := d.puzzle
+	removed := 0
+	for y := range 9 {
+		for x := range 9 {
+			if next[y][x] != 0 && d.uniqueWithout(next, y, x) {
+				next[y][x] = 0
+				removed++
+			}
+		}
+	}
+	fmt.Fprintf(d.out, "Removed %d clues\n", removed)
+	if removed != 0 {
+		d.change(next)
+	}
+	return nil
+}
+
+// Reports whether the puzzle still has a unique solution without the clue in the cell
+func (d *designSession) uniqueWithout(puzzle [9][9]int, y, x int) bool {
+	puzzle[y][x] = 0
+	count, _ := countSolutions(puzzle, 2)
+	return count == 1
+}
+
+// Parses a cell as r1c2, 1-based, and returns it 0-based
+func parseDesignCell(s string) (int, int, error) {
+	var row, column int
+	if n, err := fmt.Sscanf(strings.ToLower(s), "r%1dc%1d", &row, &column); err != nil || n != 2 || len(s) != 4 ||
+		row < 1 || row > 9 || column < 1 || column > 9 {
+		return 0, 0, fmt.Errorf("invalid cell %s, want r1c1 to r9c9", s)
+	}
+	return row - 1, column - 1, nil
+}
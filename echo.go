@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/AndrewSav/sudocoo/pkg/analysis"
+	"github.com/AndrewSav/sudocoo/pkg/parser"
+	"github.com/AndrewSav/sudocoo/pkg/rating"
+)
+
+// Results a puzzle line can be annotated with
+const (
+	echoUnique     = "ok/unique"
+	echoMultiple   = "2+ solutions"
+	echoInvalid    = "invalid"
+	echoNoSolution = "no solution"
+)
+
+// Solves the puzzle far enough to tell whether it has none, one or more solutions
+func classifyPuzzle(puzzle [9][9]int) string {
+	count, err := countSolutions(puzzle, 2)
+	if err != nil {
+		return echoInvalid
+	}
+	switch count {
+	case 0:
+		return echoNoSolution
+	case 1:
+		return echoUnique
+	default:
+		return echoMultiple
+	}
+}
+
+// Re-emits every input line verbatim. Lines that contain a puzzle get the result
+// appended after a '#', and so do lines with cells that are not a puzzle, such as a
+// truncated one, as invalid. Blank lines, comments and headers without cells are passed
+// through untouched, so the output can replace the original collection file. With rate
+// set unique puzzles get their difficulty as well, the way '-rate' prints it
+func echoInput(out *output, r io.Reader, rate bool) {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			// Keep the original line ending, the annotation goes in front of it
+			content := strings.TrimRight(line, "\r\n")
+			ending := line[len(content):]
+			puzzle, perr := parser.ParsePuzzleString(content)
+			switch {
+			case strings.HasPrefix(strings.TrimSpace(content), "#") || errors.Is(perr, io.EOF):
+				// A comment, or no cells at all
+				fmt.Fprint(out, line)
+			case perr != nil:
+				fmt.Fprintf(out, "%s # %s%s", content, echoInvalid, ending)
+				out.endRecord()
+			default:
+				result := classifyPuzzle(puzzle)
+				if rate && result == echoUnique {
+					rated, err := rating.Rate(puzzle, analysis.DefaultSearchOrders, 1)
+					if err != nil {
+						out.fail(err)
+					}
+					result += ", " + rated.String()
+				}
+				fmt.Fprintf(out, "%s # %s%s", content, result, ending)
+				out.endRecord()
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return
+		}
+		if err != nil {
+			out.fail(err)
+		}
+	}
+}
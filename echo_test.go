@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestEchoInput(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{"unique", workerPuzzle + "\n", workerPuzzle + " # ok/unique\n"},
+		{"multiple", "." + workerPuzzle[1:] + "\n", "." + workerPuzzle[1:] + " # 2+ solutions\n"},
+		{"contradicting givens", "44" + workerPuzzle[2:] + "\r\n", "44" + workerPuzzle[2:] + " # invalid\r\n"},
+		{"too few cells", "123456789\n", "123456789 # invalid\n"},
+		{"truncated", workerPuzzle[:80], workerPuzzle[:80] + " # invalid"},
+		{"blank", "\n", "\n"},
+		{"comment", "# " + workerPuzzle[:40] + "\n", "# " + workerPuzzle[:40] + "\n"},
+		{"header", "Hard puzzles\n", "Hard puzzles\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got strings.Builder
+			out := &output{Writer: bufio.NewWriter(&got)}
+			echoInput(out, strings.NewReader(test.line), false)
+			out.Flush()
+			if got.String() != test.want {
+				t.Errorf("got %q, want %q", got.String(), test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/AndrewSav/sudocoo/pkg/format"
+	"github.com/AndrewSav/sudocoo/pkg/logic"
+	"github.com/AndrewSav/sudocoo/pkg/solver"
+)
+
+// What '-engine' picks from
+const (
+	engineSearch = "search"
+	engineLogic  = "logic"
+	engineDLX    = "dlx"
+)
+
+// Solves the puzzle with the logic engine for '-engine logic'. A solved puzzle is printed like a solution
+// the search found, one the engine gets stuck on as far as the engine got, with a line saying so
+func solveLogic(flags *Flags, r *puzzleResult) error {
+	result, err := logic.Solve(r.puzzle)
+	if err != nil {
+		// The givens are consistent, the solver pool checked them, so the engine ran into a contradiction
+		return nil
+	}
+	print := !(flags.ShowStats && flags.Quiet) && flags.Template == nil
+	if !result.Solved {
+		r.stuck = true
+		if print {
+			text, err := formatSolution(*flags, solver.NewSolution(result.Grid, r.puzzle))
+			if err != nil {
+				return err
+			}
+			r.records = append(r.records, formatRecord(flags, text+"\n"+fmt.Sprintf("Stuck after %d steps, %d cells left", len(result.Steps), emptyCells(result.Grid))))
+		}
+		return nil
+	}
+	r.count = 1
+	// The logic engine does not search, there are no iterations to account for
+	r.addSolution(flags, 0)
+	if print {
+		text, err := formatSolution(*flags, solver.NewSolution(result.Grid, r.puzzle))
+		if err != nil {
+			return err
+		}
+		r.records = append(r.records, formatRecord(flags, text))
+	}
+	if flags.Template != nil || flags.Results != "" {
+		r.solution = format.Inline(result.Grid)
+	}
+	return nil
+}
+
+func emptyCells(grid [9][9]int) int {
+	empty := 0
+	for _, row := range grid {
+		for _, digit := range row {
+			if digit == 0 {
+				empty++
+			}
+		}
+	}
+	return empty
+}
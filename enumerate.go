@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/AndrewSav/sudocoo/pkg/batch"
+	"github.com/AndrewSav/sudocoo/pkg/format"
+	"github.com/AndrewSav/sudocoo/pkg/parser"
+	"github.com/AndrewSav/sudocoo/pkg/solver"
+)
+
+type enumerateFlags struct {
+	InputFile  string // input can come from a file
+	Input      string // or form a string
+	Shards     int    // the number of shards the search is split into
+	Shard      int    // the shard to run, 1-based
+	Depth      int    // the number of empty cells filled in to split the search, -1 is automatic
+	Checkpoint string // file to save the progress to and to resume from
+	Sum        string // file with shard results to add up instead of enumerating
+}
+
+// With the automatic split depth each shard gets at least that many subtrees, so that
+// the shards take about the same time and the checkpoints come often enough
+const subtreesPerShard = 64
+
+// How often the progress is saved to the checkpoint file
+const checkpointInterval = 10 * time.Second
+
+// The progress of a shard, as saved to the checkpoint file
+type enumerateCheckpoint struct {
+	Puzzle string `json:"puzzle"`
+	Shards int    `json:"shards"`
+	Shard  int    `json:"shard"`
+	Depth  int    `json:"depth"`
+	Done   int    `json:"done"`  // subtrees of the shard fully counted
+	Count  string `json:"count"` // solutions in those subtrees, in decimal since it may not fit in 64 bits
+}
+
+// A line of the shard output, which is what -sum reads
+var shardLine = regexp.MustCompile(`^(\S+) shard (\d+)/(\d+) depth (\d+): (\d+)$`)
+
+// Counts all the completions of a puzzle, or a shard of them, with the shard counts adding up
+// to the total. Several machines can run a shard each and add the results up with -sum
+func runEnumerate(args []string) {
+	var flags enumerateFlags
+
+	fs := flag.NewFlagSet("enumerate", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Println("Counts all the solutions of a puzzle, splitting the search into shards that can run on different machines")
+		fmt.Printf("Usage: %s enumerate [FLAGS...]\n", filepath.Base(os.Args[0]))
+		fmt.Println("Flags:")
+		fs.PrintDefaults()
+	}
+	addInputFlags(fs, &flags.InputFile, &flags.Input)
+	fs.IntVar(&flags.Shards, "shards", 1, "the number of shards to split the search into")
+	fs.IntVar(&flags.Shard, "shard", 1, "the shard to run, from 1 to the number of shards")
+	fs.IntVar(&flags.Depth, "depth", -1, fmt.Sprintf("the number of empty cells to fill in to split the search. Default: the fewest that give each shard %d subtrees", subtreesPerShard))
+	fs.StringVar(&flags.Checkpoint, "checkpoint", "", "file to save the progress of the shard to, a run with the same file resumes from it")
+	fs.StringVar(&flags.Sum, "sum", "", "add up the shard results in this file instead of enumerating, '-' is the standard input")
+	parseFlags(fs, args)
+
+	out := newOutput(false)
+	defer out.Flush()
+
+	if flags.Sum != "" {
+		if flags.InputFile != "" || flags.Input != "" {
+			fmt.Println("-sum cannot be combined with -f or -i")
+			fs.Usage()
+			os.Exit(2)
+		}
+		sumShards(flags.Sum, out)
+		return
+	}
+	if flags.Shards < 1 || flags.Shard < 1 || flags.Shard > flags.Shards {
+		fmt.Printf("invalid shard %d of %d\n", flags.Shard, flags.Shards)
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	scanner := parser.CreateInputScanner(openInput(fs, flags.InputFile, flags.Input))
+	puzzle, err := parser.ReadNextPuzzleInput(scanner)
+	if err != nil {
+		out.fail(err)
+	}
+	if _, err := parser.ReadNextPuzzleInput(scanner); !errors.Is(err, io.EOF) {
+		out.fail(fmt.Errorf("enumerate takes a single puzzle"))
+	}
+
+	depth := flags.Depth
+	if depth < 0 {
+		if depth, err = solver.SplitDepth(puzzle, flags.Shards*subtreesPerShard); err != nil {
+			out.fail(err)
+		}
+	}
+	subtrees, err := solver.ShardSubtrees(puzzle, depth, flags.Shard-1, flags.Shards)
+	if err != nil {
+		out.fail(err)
+	}
+
+	progress := enumerateCheckpoint{
+		Puzzle: format.Inline(puzzle),
+		Shards: flags.Shards,
+		Shard:  flags.Shard,
+		Depth:  depth,
+		Count:  "0",
+	}
+	if flags.Checkpoint != "" {
+		if err := loadCheckpoint(flags.Checkpoint, &progress); err != nil {
+			out.fail(err)
+		}
+	}
+	total, _ := new(big.Int).SetString(progress.Count, 10)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if progress.Done > len(subtrees) {
+		out.fail(fmt.Errorf("checkpoint %s is corrupt", flags.Checkpoint))
+	}
+	pending := subtrees[progress.Done:]
+	next := func() (solver.Puzzle, error) {
+		if len(pending) == 0 {
+			return solver.Puzzle{}, io.EOF
+		}
+		subtree := pending[0]
+		pending = pending[1:]
+		return subtree, nil
+	}
+	count := func(_ context.Context, subtree solver.Puzzle) (*big.Int, error) {
+		return countCompletions(subtree)
+	}
+	saved := time.Now()
+	_, err = batch.Run(ctx, batch.Config{}, next, count, func(item batch.Item[*big.Int]) bool {
+		total.Add(total, item.Value)
+		progress.Done++
+		if flags.Checkpoint != "" && time.Since(saved) >= checkpointInterval {
+			progress.Count = total.String()
+			if err := saveCheckpoint(flags.Checkpoint, progress); err != nil {
+				out.fail(err)
+			}
+			saved = time.Now()
+		}
+		return true
+	})
+	progress.Count = total.String()
+	if flags.Checkpoint != "" {
+		if err := saveCheckpoint(flags.Checkpoint, progress); err != nil {
+			out.fail(err)
+		}
+	}
+	if ctx.Err() != nil {
+		out.fail(fmt.Errorf("interrupted after %d of %d subtrees", progress.Done, len(subtrees)))
+	}
+	if err != nil {
+		out.fail(err)
+	}
+	fmt.Fprintf(out, "%s shard %d/%d depth %d: %s\n", progress.Puzzle, progress.Shard, progress.Shards, progress.Depth, progress.Count)
+}
+
+// Counts the solutions of a subtree. A 64 bit counter is spilled into a big one before it can overflow
+func countCompletions(puzzle solver.Puzzle) (*big.Int, error) {
+	s, err := solvers.Get(puzzle)
+	if err != nil {
+		return nil, err
+	}
+	defer solvers.Put(s)
+	total := new(big.Int)
+	var count uint64
+	for s.Solve() {
+		count++
+		if count == 1<<62 {
+			total.Add(total, new(big.Int).SetUint64(count))
+			count = 0
+		}
+	}
+	return total.Add(total, new(big.Int).SetUint64(count)), nil
+}
+
+// Resumes from the checkpoint file if there is one. It has to be for the same puzzle, shard and depth
+func loadCheckpoint(path string, progress *enumerateCheckpoint) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var saved enumerateCheckpoint
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("reading checkpoint %s: %w", path, err)
+	}
+	if saved.Puzzle != progress.Puzzle || saved.Shards != progress.Shards || saved.Shard != progress.Shard || saved.Depth != progress.Depth {
+		return fmt.Errorf("checkpoint %s is for shard %d/%d depth %d of %s", path, saved.Shard, saved.Shards, saved.Depth, saved.Puzzle)
+	}
+	if _, ok := new(big.Int).SetString(saved.Count, 10); !ok || saved.Done < 0 {
+		return fmt.Errorf("checkpoint %s is corrupt", path)
+	}
+	*progress = saved
+	return nil
+}
+
+// Replaces the checkpoint file, through a temporary file so that a crash never leaves half of it
+func saveCheckpoint(path string, progress any) error {
+	data, _ := json.Marshal(progress)
+	temp := path + ".tmp"
+	if err := os.WriteFile(temp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(temp, path)
+}
+
+// Adds up the shard results, which all have to be for the same puzzle, number of shards and depth.
+// Every shard has to be there exactly once
+func sumShards(path string, out *output) {
+	var input io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			out.fail(err)
+		}
+		defer file.Close()
+		input = file
+	}
+
+	var puzzle string
+	shards, depth := 0, 0
+	seen := map[int]bool{}
+	total := new(big.Int)
+	scanner := bufio.NewScanner(input)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		m := shardLine.FindStringSubmatch(text)
+		if m == nil {
+			out.fail(fmt.Errorf("line %d is not a shard result", line))
+		}
+		shard, _ := strconv.Atoi(m[2])
+		n, _ := strconv.Atoi(m[3])
+		d, _ := strconv.Atoi(m[4])
+		count, _ := new(big.Int).SetString(m[5], 10)
+		if puzzle == "" {
+			puzzle, shards, depth = m[1], n, d
+		}
+		if m[1] != puzzle || n != shards || d != depth {
+			out.fail(fmt.Errorf("line %d is for shard %d/%d depth %d of %s, not of the same split as the lines before it", line, shard, n, d, m[1]))
+		}
+		if shard < 1 || shard > n {
+			out.fail(fmt.Errorf("line %d has shard %d of %d", line, shard, n))
+		}
+		if seen[shard] {
+			out.fail(fmt.Errorf("shard %d appears more than once", shard))
+		}
+		seen[shard] = true
+		total.Add(total, count)
+	}
+	if err := scanner.Err(); err != nil {
+		out.fail(err)
+	}
+	if puzzle == "" {
+		out.fail(fmt.Errorf("no shard results in %s", path))
+	}
+	var missing []string
+	for shard := 1; shard <= shards; shard++ {
+		if !seen[shard] {
+			missing = append(missing, strconv.Itoa(shard))
+		}
+	}
+	if len(missing) != 0 {
+		out.fail(fmt.Errorf("missing shards %s of %d", strings.Join(missing, ", "), shards))
+	}
+	fmt.Fprintf(out, "%s total of %d shards: %s\n", puzzle, shards, total)
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/AndrewSav/sudocoo/pkg/parser"
+	"github.com/AndrewSav/sudocoo/pkg/rating"
+	"github.com/AndrewSav/sudocoo/pkg/store"
+)
+
+type importFlags struct {
+	InputFile string // input can come from a file
+	Input     string // or form a string
+	Database  string // puzzle store to import into
+	Format    string // format of the input
+	Source    string // source to record for puzzles that do not have one
+}
+
+type exportFlags struct {
+	Database   string // puzzle store to export from
+	Format     string // format of the output
+	OutputFile string // write here instead of stdout
+}
+
+// see if user specified exchange format is one of known formats
+func validateExchangeFormat(f string) bool {
+	for _, known := range store.ExchangeFormats {
+		if f == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Adds puzzles from a file to the store, skipping the ones that are already there
+// or equivalent to them. Metadata present in the input is kept
+func runImport(args []string) {
+	var flags importFlags
+
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Println("Adds puzzles to a puzzle store, equivalent puzzles are only stored once. Puzzles with a unique solution are rated unless the input has their rating")
+		fmt.Printf("Usage: %s import -db FILE [FLAGS...]\n", filepath.Base(os.Args[0]))
+		fmt.Println("Flags:")
+		fs.PrintDefaults()
+	}
+	addInputFlags(fs, &flags.InputFile, &flags.Input)
+	fs.StringVar(&flags.Database, "db", "", "puzzle store to import into, created if missing")
+	fs.StringVar(&flags.Format, "format", store.FormatText, fmt.Sprintf("input format: %s. Default: text", strings.Join(store.ExchangeFormats, ", ")))
+	fs.StringVar(&flags.Source, "source", "", "source to record for the puzzles that do not have one. Default: the input file name")
+	parseFlags(fs, args)
+
+	if flags.Database == "" {
+		fmt.Println("you have to specify the store with -db")
+		fs.Usage()
+		os.Exit(2)
+	}
+	if !validateExchangeFormat(flags.Format) {
+		fmt.Printf("invalid format %s\n", flags.Format)
+		fs.Usage()
+		os.Exit(2)
+	}
+	input := openInput(fs, flags.InputFile, flags.Input)
+	if flags.Source == "" {
+		flags.Source = flags.InputFile
+	}
+
+	out := newOutput(false)
+	defer out.Flush()
+
+	records, err := store.ReadRecords(input, flags.Format)
+	if err != nil {
+		out.fail(err)
+	}
+	db, err := store.Open(flags.Database)
+	if err != nil {
+		out.fail(err)
+	}
+	defer db.Close()
+
+	added := 0
+	for i, r := range records {
+		puzzle, err := parser.ParsePuzzleString(r.Puzzle)
+		if err != nil {
+			out.fail(fmt.Errorf("record %d: %v", i+1, err))
+		}
+		count, err := countSolutions(puzzle, store.MultipleSolutions)
+		if err != nil {
+			out.fail(fmt.Errorf("record %d: %v", i+1, err))
+		}
+		// The derived fields are always recomputed, the metadata comes from the input
+		record := store.NewRecord(puzzle, count, flags.Source)
+		record.Rating, record.Label = r.Rating, r.Label
+		if record.Rating == "" && count == 1 {
+			if level, err := rating.LevelOf(puzzle); err == nil {
+				record.Rating = level.String()
+			}
+		}
+		if r.Source != "" {
+			record.Source = r.Source
+		}
+		if !r.Added.IsZero() {
+			record.Added = r.Added
+		}
+		ok, err := db.Add(record)
+		if err != nil {
+			out.fail(err)
+		}
+		if ok {
+			added++
+		}
+	}
+	fmt.Fprintf(out, "Read: %d\n", len(records))
+	fmt.Fprintf(out, "Added: %d\n", added)
+	fmt.Fprintf(out, "Duplicates: %d\n", len(records)-added)
+}
+
+// Writes all the puzzles of the store with their metadata in an external format
+func runExport(args []string) {
+	var flags exportFlags
+
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Println("Writes out the puzzles of a puzzle store")
+		fmt.Printf("Usage: %s export -db FILE [FLAGS...]\n", filepath.Base(os.Args[0]))
+		fmt.Println("Flags:")
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&flags.Database, "db", "", "puzzle store to export from")
+	fs.StringVar(&flags.Format, "format", store.FormatJSONL, fmt.Sprintf("output format: %s. Default: jsonl", strings.Join(store.ExchangeFormats, ", ")))
+	fs.StringVar(&flags.OutputFile, "o", "", "write to this file instead of the standard output")
+	parseFlags(fs, args)
+
+	if flags.Database == "" {
+		fmt.Println("you have to specify the store with -db")
+		fs.Usage()
+		os.Exit(2)
+	}
+	if !validateExchangeFormat(flags.Format) {
+		fmt.Printf("invalid format %s\n", flags.Format)
+		fs.Usage()
+		os.Exit(2)
+	}
+	if _, err := os.Stat(flags.Database); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	out := newOutput(false)
+	defer out.Flush()
+
+	db, err := store.Open(flags.Database)
+	if err != nil {
+		out.fail(err)
+	}
+	defer db.Close()
+
+	var w io.Writer = out
+	if flags.OutputFile != "" {
+		file, err := os.Create(flags.OutputFile)
+		if err != nil {
+			out.fail(err)
+		}
+		defer file.Close()
+		w = file
+	}
+	records, err := db.Query(store.Query{Solutions: store.AnySolutions})
+	if err != nil {
+		out.fail(err)
+	}
+	if err := store.WriteRecords(w, flags.Format, records); err != nil {
+		out.fail(err)
+	}
+}
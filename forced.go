@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/AndrewSav/sudocoo/pkg/analysis"
+	"github.com/AndrewSav/sudocoo/pkg/batch"
+	"github.com/AndrewSav/sudocoo/pkg/format"
+	"github.com/AndrewSav/sudocoo/pkg/parser"
+)
+
+type forcedFlags struct {
+	InputFile string // input can come from a file
+	Input     string // or form a string
+}
+
+// A puzzle with the digits its cells take across the solutions
+type forcedResult struct {
+	puzzle     [9][9]int
+	candidates analysis.SolutionCandidates
+}
+
+// Shows what the solutions of each puzzle have in common, so that a setter can see which cells are
+// determined already before adding more clues. Prints the determined cells as a grid and the digits
+// each of the other cells can take, exits with 1 if any of the puzzles is inconsistent or has no solution
+func runForced(args []string) {
+	var flags forcedFlags
+
+	fs := flag.NewFlagSet("forced", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Println("Prints the cells that have the same digit in all the solutions of puzzles and the candidates of the other cells")
+		fmt.Printf("Usage: %s forced [FLAGS...]\n", filepath.Base(os.Args[0]))
+		fmt.Println("Flags:")
+		fs.PrintDefaults()
+	}
+	addInputFlags(fs, &flags.InputFile, &flags.Input)
+	parseFlags(fs, args)
+
+	out := newOutput(false)
+	defer out.Flush()
+
+	scanner := parser.CreateInputScanner(openInput(fs, flags.InputFile, flags.Input))
+	next := func() ([9][9]int, error) { return parser.ReadNextPuzzleInput(scanner) }
+	find := func(_ context.Context, puzzle [9][9]int) (forcedResult, error) {
+		c, err := analysis.FindSolutionCandidates(puzzle)
+		return forcedResult{puzzle: puzzle, candidates: c}, err
+	}
+
+	failed := false
+	stats, err := batch.Run(context.Background(), batch.Config{Errors: batch.Continue}, next, find, func(item batch.Item[forcedResult]) bool {
+		if item.Index != 0 {
+			fmt.Fprintln(out)
+		}
+		fmt.Fprintln(out, format.Inline(item.Value.puzzle))
+		if item.Err != nil {
+			fmt.Fprintf(out, "Error: %v\n", item.Err)
+			failed = true
+			return true
+		}
+		writeForced(out, item.Value)
+		return true
+	})
+	if err != nil {
+		out.fail(err)
+	}
+	if stats.Items == 0 {
+		// Not a single puzzle in the input
+		out.fail(io.EOF)
+	}
+	if failed {
+		out.Flush()
+		os.Exit(1)
+	}
+}
+
+func writeForced(w io.Writer, r forcedResult) {
+	determined := r.candidates.Determined()
+	givens, forced, open := 0, 0, 0
+	for y := range 9 {
+		for x := range 9 {
+			switch {
+			case r.puzzle[y][x] != 0:
+				givens++
+			case determined[y][x] != 0:
+				forced++
+			default:
+				open++
+			}
+		}
+	}
+	fmt.Fprintf(w, "Determined: %s (givens: %d, forced: %d, open: %d)\n", format.Inline(determined), givens, forced, open)
+	for y := range 9 {
+		for x := range 9 {
+			if determined[y][x] != 0 {
+				continue
+			}
+			var digits strings.Builder
+			for _, digit := range r.candidates.Digits(y, x) {
+				fmt.Fprint(&digits, digit)
+			}
+			fmt.Fprintf(w, "r%dc%d: %s\n", y+1, x+1, digits.String())
+		}
+	}
+}
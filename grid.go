@@ -0,0 +1,128 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AndrewSav/sudocoo/pkg/format"
+	"github.com/AndrewSav/sudocoo/pkg/parser"
+	"github.com/AndrewSav/sudocoo/pkg/solver"
+)
+
+// The flags that work with grids other than 9x9, the others are for the classic grid only, as
+// solver.GridSolver only solves
+var gridFlags = []string{"a", "box", "c", "clipboard-in", "clipboard-out", "color", "f", "i", "l", "lang", "line-buffered", "n", "numbers", "p", "q", "s", "size", "v"}
+
+// The classic grid, the one the whole program works with, the others only get solved
+var classicShape, _ = solver.ShapeOf(9)
+
+// Works out the shape of the grid from '-size' and '-box'
+func resolveShape(flags *Flags) error {
+	if flags.Box == "" {
+		sh, ok := solver.ShapeOf(flags.Size)
+		if !ok {
+			return errors.New(msgf("invalid size %d, want one of %s", flags.Size, intsList(solver.GridSizes())))
+		}
+		flags.Shape = sh
+		return nil
+	}
+	rows, columns, _ := strings.Cut(flags.Box, "x")
+	sh := solver.Shape{Size: flags.Size}
+	var errRows, errColumns error
+	sh.BoxRows, errRows = strconv.Atoi(rows)
+	sh.BoxColumns, errColumns = strconv.Atoi(columns)
+	if errRows != nil || errColumns != nil || !sh.Valid() {
+		return errors.New(msgf("invalid box %s for size %d, want rows x columns, e.g. 2x3", flags.Box, flags.Size))
+	}
+	flags.Shape = sh
+	return nil
+}
+
+// Solves the puzzles of a grid other than 9x9 with 3x3 boxes, see '-size' and '-box'. It is the same
+// as solving classic puzzles, with fewer features: the puzzles are solved one after another, and
+// printed in one of the formats that fit any grid
+func solveGrids(flags *Flags, out *output) {
+	sh := flags.Shape
+	r := parser.NewGridReader(flags.InputReader, flags.Size, flags.Numbers)
+	print := !(flags.ShowStats && flags.Quiet) && !(flags.All && flags.CountsOnly)
+	var (
+		totalSolutions = 0
+		globalLimit    = false
+		puzzleCount    = 0
+		iterations     = 0
+		start          = time.Now()
+	)
+	for {
+		puzzle, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			out.fail(err)
+		}
+		puzzleCount++
+		s, err := solver.NewGridSolver(sh, puzzle)
+		if err != nil {
+			out.fail(err)
+		}
+		count, limitHit := 0, false
+		for s.Solve() {
+			if flags.All && flags.Limit != 0 && count == flags.Limit {
+				limitHit = true
+				break
+			}
+			count++
+			if print {
+				text, err := format.FormatGrid(s.Solution(), puzzle, sh, flags.OutputFormat, flags.Numbers, flags.Color)
+				if err != nil {
+					out.fail(err)
+				}
+				fmt.Fprint(out, formatRecord(flags, text))
+				out.endRecord()
+			}
+			if !flags.All {
+				break
+			}
+		}
+		iterations += s.Iterations()
+		totalSolutions += count
+		globalLimit = globalLimit || limitHit
+		if !flags.All && count == 0 && !(flags.ShowStats && flags.Quiet) {
+			fmt.Fprintln(out, msg("No solution"))
+			out.endRecord()
+		}
+		if flags.All && flags.CountsOnly && !(flags.ShowStats && flags.Quiet) {
+			countText := fmt.Sprintf("%d", count)
+			if limitHit {
+				countText = msgf("%d+ (limit reached)", count)
+			}
+			if flags.OutputInputPuzzle {
+				text, err := format.FormatGrid(puzzle, nil, sh, "inline", flags.Numbers, false)
+				if err != nil {
+					out.fail(err)
+				}
+				fmt.Fprintf(out, "%s: %s\n", text, countText)
+			} else {
+				fmt.Fprintf(out, "%s\n", countText)
+			}
+			out.endRecord()
+		}
+	}
+	if puzzleCount == 0 {
+		out.fail(io.EOF)
+	}
+	if flags.ShowStats {
+		limit := ""
+		if globalLimit {
+			limit = msg(" (limit reached for some puzzles, actual number is higher)")
+		}
+		fmt.Fprintln(out, msgf("Total puzzles: %d", puzzleCount))
+		fmt.Fprintln(out, msgf("Total solutions: %d%s", totalSolutions, limit))
+		fmt.Fprintln(out, msgf("Total iterations: %d", iterations))
+		fmt.Fprint(out, msgf("Time taken: %s", time.Since(start)))
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/AndrewSav/sudocoo/pkg/analysis"
+	"github.com/AndrewSav/sudocoo/pkg/batch"
+	"github.com/AndrewSav/sudocoo/pkg/cache"
+	"github.com/AndrewSav/sudocoo/pkg/format"
+	"github.com/AndrewSav/sudocoo/pkg/logic"
+	"github.com/AndrewSav/sudocoo/pkg/parser"
+)
+
+type hardnessFlags struct {
+	InputFile string // input can come from a file
+	Input     string // or form a string
+	Orders    int    // random search orders to average over
+	Seed      uint64 // seed for the search orders
+	Sort      bool   // print the puzzles from the easiest to the hardest
+	Cache     string // file to keep the measures in for the next runs
+	Depth     bool   // find the trial depth of each puzzle as well
+	MaxDepth  int    // the deepest trial depth to look for
+}
+
+// A puzzle with its measured hardness
+type hardnessResult struct {
+	puzzle   string
+	hardness analysis.SearchHardness
+	depth    *trialDepth // only with -trial-depth
+}
+
+// The trial depth of a puzzle as it is kept in the cache
+type trialDepth struct {
+	Depth int  `json:"depth"`
+	Found bool `json:"found"` // false if the puzzle needs a deeper trial than the limit, or has several solutions
+}
+
+// Measures how hard each puzzle is for the backtracking search, see analysis.SearchHardness.
+// Prints one line per puzzle and exits with 1 if any of the puzzles is inconsistent
+func runHardness(args []string) {
+	var flags hardnessFlags
+
+	fs := flag.NewFlagSet("hardness", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Println("Estimates how hard puzzles are from the backtracking statistics over several random search orders")
+		fmt.Printf("Usage: %s hardness [FLAGS...]\n", filepath.Base(os.Args[0]))
+		fmt.Println("Flags:")
+		fs.PrintDefaults()
+	}
+	addInputFlags(fs, &flags.InputFile, &flags.Input)
+	fs.IntVar(&flags.Orders, "orders", analysis.DefaultSearchOrders, "the number of random search orders to average over")
+	fs.Uint64Var(&flags.Seed, "seed", 1, "seed for the random search orders, the same seed gives the same results")
+	fs.BoolVar(&flags.Sort, "sort", false, "print the puzzles from the easiest to the hardest instead of in the input order")
+	fs.BoolVar(&flags.Depth, "trial-depth", false, "find the trial depth of each puzzle as well: how deeply nested the assumptions that lead to contradictions have to be to solve it with singles")
+	fs.IntVar(&flags.MaxDepth, "max-trial-depth", logic.DefaultMaxTrialDepth, "the deepest trial depth to look for, each level multiplies the time by the number of candidates. Requires '-trial-depth'")
+	addCacheFlag(fs, &flags.Cache)
+	parseFlags(fs, args)
+
+	if flags.Orders < 1 {
+		fmt.Printf("invalid number of search orders %d\n", flags.Orders)
+		fs.Usage()
+		os.Exit(2)
+	}
+	if flags.MaxDepth < 0 {
+		fmt.Printf("invalid trial depth %d, want 0 or more\n", flags.MaxDepth)
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	out := newOutput(false)
+	defer out.Flush()
+
+	results := openCache(out, flags.Cache)
+	if results != nil {
+		defer results.Close()
+	}
+
+	scanner := parser.CreateInputScanner(openInput(fs, flags.InputFile, flags.Input))
+	next := func() ([9][9]int, error) { return parser.ReadNextPuzzleInput(scanner) }
+	measure := func(_ context.Context, puzzle [9][9]int) (hardnessResult, error) {
+		r := hardnessResult{puzzle: format.Inline(puzzle)}
+		var err error
+		if r.hardness, err = measureHardness(results, puzzle, flags.Orders, flags.Seed); err != nil || !flags.Depth {
+			return r, err
+		}
+		r.depth, err = measureTrialDepth(results, puzzle, flags.MaxDepth)
+		return r, err
+	}
+
+	var measured []hardnessResult
+	inconsistent := 0
+	stats, err := batch.Run(context.Background(), batch.Config{Errors: batch.Continue}, next, measure, func(item batch.Item[hardnessResult]) bool {
+		if item.Err != nil {
+			fmt.Fprintf(out, "%s: %v\n", item.Value.puzzle, item.Err)
+			inconsistent++
+			return true
+		}
+		if flags.Sort {
+			measured = append(measured, item.Value)
+		} else {
+			writeHardness(out, item.Value, flags.MaxDepth)
+		}
+		return true
+	})
+	if err != nil {
+		out.fail(err)
+	}
+	if stats.Items == 0 {
+		// Not a single puzzle in the input
+		out.fail(io.EOF)
+	}
+	sort.SliceStable(measured, func(i, j int) bool { return measured[i].hardness.DeadEnds < measured[j].hardness.DeadEnds })
+	for _, r := range measured {
+		writeHardness(out, r, flags.MaxDepth)
+	}
+	if inconsistent != 0 {
+		out.Flush()
+		os.Exit(1)
+	}
+}
+
+func writeHardness(w io.Writer, r hardnessResult, maxDepth int) {
+	fmt.Fprintf(w, "%s: %.2f dead ends, %.2f guesses, %.2f iterations", r.puzzle, r.hardness.DeadEnds, r.hardness.Guesses, r.hardness.Iterations)
+	switch {
+	case r.depth == nil:
+	case r.depth.Found:
+		fmt.Fprintf(w, ", trial depth %d", r.depth.Depth)
+	default:
+		fmt.Fprintf(w, ", trial depth >%d", maxDepth)
+	}
+	fmt.Fprintln(w)
+}
+
+// Measures the search hardness, taking it from the cache if there is one and it has the measure of
+// the puzzle or an equivalent one, with the same orders and seed. The orders are random equivalents
+// of the puzzle anyway, so the measure of an equivalent puzzle is just as good
+func measureHardness(results *cache.Cache, puzzle [9][9]int, orders int, seed uint64) (analysis.SearchHardness, error) {
+	if results == nil {
+		return analysis.MeasureSearchHardness(puzzle, orders, seed)
+	}
+	key := cache.Key(fmt.Sprintf("hardness orders=%d seed=%d", orders, seed), puzzle)
+	var h analysis.SearchHardness
+	if ok, err := results.Get(key, &h); err != nil || ok {
+		return h, err
+	}
+	h, err := analysis.MeasureSearchHardness(puzzle, orders, seed)
+	if err != nil {
+		return h, err
+	}
+	return h, results.Put(key, h)
+}
+
+// Finds the trial depth of the puzzle up to the limit, see logic.TrialDepth, taking it from the cache
+// if there is one and it has the depth of the puzzle or an equivalent one, which is the same
+func measureTrialDepth(results *cache.Cache, puzzle [9][9]int, maxDepth int) (*trialDepth, error) {
+	key := cache.Key(fmt.Sprintf("trial-depth max=%d", maxDepth), puzzle)
+	d := &trialDepth{}
+	if results != nil {
+		if ok, err := results.Get(key, d); err != nil || ok {
+			return d, err
+		}
+	}
+	var err error
+	if d.Depth, d.Found, err = logic.TrialDepth(puzzle, maxDepth); err != nil {
+		return nil, err
+	}
+	if results != nil {
+		return d, results.Put(key, d)
+	}
+	return d, nil
+}
+
+// Adds the flag for the file of the cache of results
+func addCacheFlag(fs *flag.FlagSet, path *string) {
+	fs.StringVar(path, "cache", "", "keep the results in this cache file (JSON Lines, created if missing) and take them from it instead of working them out again, for the same puzzle or any equivalent one")
+}
+
+// Opens the cache of results, or returns nil if there is no path
+func openCache(out *output, path string) *cache.Cache {
+	if path == "" {
+		return nil
+	}
+	results, err := cache.Open(path)
+	if err != nil {
+		out.fail(err)
+	}
+	return results
+}
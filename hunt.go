@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/AndrewSav/sudocoo/pkg/batch"
+	"github.com/AndrewSav/sudocoo/pkg/format"
+	"github.com/AndrewSav/sudocoo/pkg/hunt"
+	"github.com/AndrewSav/sudocoo/pkg/parser"
+)
+
+type huntFlags struct {
+	InputFile  string // input can come from a file
+	Input      string // or form a string
+	Clues      int    // the number of clues the puzzles should have
+	Checkpoint string // file to save the progress to and to resume from
+	ShowStats  bool   // display totals at the end
+}
+
+// The progress of a hunt, as saved to the checkpoint file
+type huntCheckpoint struct {
+	Grid  string `json:"grid"`
+	Clues int    `json:"clues"`
+	Units int    `json:"units"`
+	Done  int    `json:"done"`  // units fully searched
+	Found int    `json:"found"` // puzzles printed so far
+}
+
+// Searches a solution grid for puzzles with a number of clues and prints them as they are found.
+// The search goes unit by unit, so that it can be checkpointed and resumed. A resumed search
+// starts with the units that were in progress, so the puzzles they found before are printed again
+func runHunt(args []string) {
+	var flags huntFlags
+
+	fs := flag.NewFlagSet("hunt", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Println("Searches a solution grid for puzzles with the given number of clues, using unavoidable sets to prune the search")
+		fmt.Printf("Usage: %s hunt [FLAGS...]\n", filepath.Base(os.Args[0]))
+		fmt.Println("Flags:")
+		fs.PrintDefaults()
+	}
+	addInputFlags(fs, &flags.InputFile, &flags.Input)
+	fs.IntVar(&flags.Clues, "clues", 17, "the number of clues the puzzles should have. Unique puzzles with fewer clues that come up are printed too")
+	fs.StringVar(&flags.Checkpoint, "checkpoint", "", "file to save the progress to, a run with the same file resumes from it")
+	fs.BoolVar(&flags.ShowStats, "s", false, "display the number of unavoidable sets, units searched and puzzles found at the end")
+	parseFlags(fs, args)
+
+	out := newOutput(true)
+	defer out.Flush()
+
+	scanner := parser.CreateInputScanner(openInput(fs, flags.InputFile, flags.Input))
+	grid, err := parser.ReadNextPuzzleInput(scanner)
+	if err != nil {
+		out.fail(err)
+	}
+	if _, err := parser.ReadNextPuzzleInput(scanner); !errors.Is(err, io.EOF) {
+		out.fail(fmt.Errorf("hunt takes a single solution grid"))
+	}
+	hunter, err := hunt.New(grid, flags.Clues)
+	if err != nil {
+		out.fail(err)
+	}
+
+	progress := huntCheckpoint{Grid: format.Inline(grid), Clues: flags.Clues, Units: hunter.Units()}
+	if flags.Checkpoint != "" {
+		if err := loadHuntCheckpoint(flags.Checkpoint, &progress); err != nil {
+			out.fail(err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	unit := progress.Done
+	next := func() (int, error) {
+		if unit == hunter.Units() {
+			return 0, io.EOF
+		}
+		unit++
+		return unit - 1, nil
+	}
+	// Puzzles are printed as soon as they are found, a unit can find a lot of them
+	var mu sync.Mutex
+	print := func(puzzle [9][9]int) {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintln(out, format.Inline(puzzle))
+		out.endRecord()
+	}
+	search := func(_ context.Context, unit int) (int, error) {
+		return hunter.RunUnit(unit, print), nil
+	}
+	saved := time.Now()
+	_, err = batch.Run(ctx, batch.Config{}, next, search, func(item batch.Item[int]) bool {
+		progress.Found += item.Value
+		progress.Done++
+		if flags.Checkpoint != "" && time.Since(saved) >= checkpointInterval {
+			if err := saveCheckpoint(flags.Checkpoint, progress); err != nil {
+				out.fail(err)
+			}
+			saved = time.Now()
+		}
+		return true
+	})
+	if flags.Checkpoint != "" {
+		if err := saveCheckpoint(flags.Checkpoint, progress); err != nil {
+			out.fail(err)
+		}
+	}
+	mu.Lock()
+	if ctx.Err() != nil {
+		out.fail(fmt.Errorf("interrupted after %d of %d units", progress.Done, progress.Units))
+	}
+	if err != nil {
+		out.fail(err)
+	}
+	if flags.ShowStats {
+		fmt.Fprintf(out, "Unavoidable sets: %d\n", hunter.Sets())
+		fmt.Fprintf(out, "Units searched: %d\n", progress.Units)
+		fmt.Fprintf(out, "Puzzles found: %d\n", progress.Found)
+	}
+}
+
+// Resumes from the checkpoint file if there is one. It has to be for the same grid and number of clues
+func loadHuntCheckpoint(path string, progress *huntCheckpoint) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var saved huntCheckpoint
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("reading checkpoint %s: %w", path, err)
+	}
+	if saved.Grid != progress.Grid || saved.Clues != progress.Clues {
+		return fmt.Errorf("checkpoint %s is for %d clue puzzles of %s", path, saved.Clues, saved.Grid)
+	}
+	if saved.Units != progress.Units || saved.Done < 0 || saved.Done > saved.Units {
+		return fmt.Errorf("checkpoint %s is corrupt", path)
+	}
+	*progress = saved
+	return nil
+}
@@ -0,0 +1,72 @@
+// Package fakeserver plays canned byte streams to network clients in tests, such as the Redis
+// and NATS clients of pkg/queue and the worker that uses them
+package fakeserver
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// What the server does with a connection: reads what the client sends from r and replies on w
+type Script func(t *testing.T, r *bufio.Reader, w net.Conn)
+
+// Takes a connection for each script and plays the script on it. The connection is closed when
+// the script ends, a script that needs it open longer reads until the client closes it.
+// Returns the address to dial
+func Serve(t *testing.T, scripts ...Script) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for _, script := range scripts {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer conn.Close()
+				script(t, bufio.NewReader(conn), conn)
+			}()
+		}
+	}()
+	// Cleanups run after the deferred calls of the test, which close the clients
+	t.Cleanup(func() {
+		l.Close()
+		wg.Wait()
+	})
+	return l.Addr().String()
+}
+
+// Reads what the client is expected to send next
+func Expect(t *testing.T, r *bufio.Reader, want string) {
+	t.Helper()
+	got := make([]byte, len(want))
+	if _, err := io.ReadFull(r, got); err != nil {
+		t.Errorf("reading %q: %v", want, err)
+		return
+	}
+	if string(got) != want {
+		t.Errorf("client sent %q, want %q", got, want)
+	}
+}
+
+// Writes the reply a byte at a time, so that the client gets it in pieces
+func WriteSlowly(w io.Writer, reply string) {
+	for i := range len(reply) {
+		if _, err := w.Write([]byte{reply[i]}); err != nil {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/AndrewSav/sudocoo/pkg/analysis"
+	"github.com/AndrewSav/sudocoo/pkg/batch"
+	"github.com/AndrewSav/sudocoo/pkg/format"
+	"github.com/AndrewSav/sudocoo/pkg/layout"
+	"github.com/AndrewSav/sudocoo/pkg/parser"
+)
+
+type layoutFlags struct {
+	InputFile  string // input can come from a file
+	Input      string // or form a string
+	Format     string // text, html or pdf
+	Rows       int    // grids down a page
+	Columns    int    // grids across a page
+	Caption    string // template of the caption under each grid
+	Title      string // printed at the top of every page
+	Solutions  bool   // add pages with the solutions after the puzzles
+	OutputFile string // file to write to instead of stdout
+}
+
+// What the caption template of a puzzle is made from
+type captionData struct {
+	Index  int    // 0-based position of the puzzle in the input
+	Label  string // 1-based number of the puzzle
+	Puzzle string // inline format
+	Clues  int
+}
+
+// A puzzle of the booklet with its solution, if there is a unique one
+type layoutPuzzle struct {
+	puzzle   [9][9]int
+	solution [9][9]int
+	solved   bool
+}
+
+// Arranges puzzles on pages, a number of rows and columns of them per page with captions,
+// for printing a booklet. The solutions can follow on pages of their own, captioned the same
+func runLayout(args []string) {
+	var flags layoutFlags
+
+	fs := flag.NewFlagSet("layout", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Println("Lays out puzzles on pages with captions, as text, HTML or PDF, for printing")
+		fmt.Printf("Usage: %s layout [FLAGS...]\n", filepath.Base(os.Args[0]))
+		fmt.Println("Flags:")
+		fs.PrintDefaults()
+	}
+	addInputFlags(fs, &flags.InputFile, &flags.Input)
+	fs.StringVar(&flags.Format, "format", layout.FormatText, fmt.Sprintf("output format: %s", strings.Join(layout.Formats(), ", ")))
+	fs.IntVar(&flags.Rows, "rows", 3, "grids down a page")
+	fs.IntVar(&flags.Columns, "columns", 2, "grids across a page")
+	fs.StringVar(&flags.Caption, "caption", "Puzzle {{.Label}}", "Go text/template of the caption under each grid. The fields are .Index (0-based), .Label (the 1-based number of the puzzle), .Puzzle (inline) and .Clues")
+	fs.StringVar(&flags.Title, "title", "", "title at the top of every page")
+	fs.BoolVar(&flags.Solutions, "solutions", false, "add the solutions after the puzzles, starting on a new page. Puzzles without a unique solution get an empty grid")
+	fs.StringVar(&flags.OutputFile, "o", "", "write to this file instead of the standard output")
+	parseFlags(fs, args)
+
+	if !slices.Contains(layout.Formats(), flags.Format) {
+		fmt.Printf("invalid format %s, want one of %s\n", flags.Format, strings.Join(layout.Formats(), ", "))
+		fs.Usage()
+		os.Exit(2)
+	}
+	if flags.Rows < 1 || flags.Columns < 1 {
+		fmt.Printf("invalid layout %dx%d, want at least a row and a column\n", flags.Rows, flags.Columns)
+		fs.Usage()
+		os.Exit(2)
+	}
+	caption, err := template.New("caption").Parse(flags.Caption)
+	if err != nil {
+		fmt.Println(err)
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	out := newOutput(false)
+	defer out.Flush()
+
+	scanner := parser.CreateInputScanner(openInput(fs, flags.InputFile, flags.Input))
+	next := func() ([9][9]int, error) { return parser.ReadNextPuzzleInput(scanner) }
+	solve := func(_ context.Context, puzzle [9][9]int) (layoutPuzzle, error) {
+		p := layoutPuzzle{puzzle: puzzle}
+		if !flags.Solutions {
+			return p, nil
+		}
+		s, err := solvers.Get(puzzle)
+		if err != nil {
+			return p, nil
+		}
+		defer solvers.Put(s)
+		if s.Solve() {
+			p.solution = s.Solution()
+			p.solved = !s.Solve()
+		}
+		return p, nil
+	}
+
+	var puzzles, solutions []layout.Item
+	stats, err := batch.Run(context.Background(), batch.Config{}, next, solve, func(item batch.Item[layoutPuzzle]) bool {
+		var sb strings.Builder
+		data := captionData{
+			Index:  item.Index,
+			Label:  strconv.Itoa(item.Index + 1),
+			Puzzle: format.Inline(item.Value.puzzle),
+			Clues:  analysis.ClueCount(item.Value.puzzle),
+		}
+		if err := caption.Execute(&sb, data); err != nil {
+			out.fail(err)
+		}
+		puzzles = append(puzzles, layout.Item{Grid: item.Value.puzzle, Caption: sb.String()})
+		solution := layout.Item{Caption: sb.String()}
+		if item.Value.solved {
+			solution.Grid = item.Value.solution
+		}
+		solutions = append(solutions, solution)
+		return true
+	})
+	if err != nil {
+		out.fail(err)
+	}
+	if stats.Items == 0 {
+		// Not a single puzzle in the input
+		out.fail(io.EOF)
+	}
+	sections := [][]layout.Item{puzzles}
+	if flags.Solutions {
+		sections = append(sections, solutions)
+	}
+
+	var w io.Writer = out
+	if flags.OutputFile != "" {
+		file, err := os.Create(flags.OutputFile)
+		if err != nil {
+			out.fail(err)
+		}
+		defer file.Close()
+		w = file
+	}
+	if err := layout.Write(w, sections, layout.Options{Rows: flags.Rows, Columns: flags.Columns, Title: flags.Title}, flags.Format); err != nil {
+		out.fail(err)
+	}
+}
@@ -1,114 +1,394 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
+	"github.com/AndrewSav/sudocoo/pkg/batch"
+	"github.com/AndrewSav/sudocoo/pkg/clipboard"
 	"github.com/AndrewSav/sudocoo/pkg/format"
-	"github.com/AndrewSav/sudocoo/pkg/parser"
+	"github.com/AndrewSav/sudocoo/pkg/plugins"
+	"github.com/AndrewSav/sudocoo/pkg/rating"
 	"github.com/AndrewSav/sudocoo/pkg/solver"
+	"github.com/AndrewSav/sudocoo/pkg/store"
 )
 
+// Commands are given as the first argument, without a command the puzzles are solved
+var commands = map[string]func(args []string){
+	"animate":     runAnimate,
+	"audit":       runAudit,
+	"clusters":    runClusters,
+	"compare":     runCompare,
+	"design":      runDesign,
+	"enumerate":   runEnumerate,
+	"export":      runExport,
+	"export-cnf":  runExportCNF,
+	"forced":      runForced,
+	"hardness":    runHardness,
+	"hunt":        runHunt,
+	"import":      runImport,
+	"layout":      runLayout,
+	"mcp":         runMCP,
+	"pack":        runPack,
+	"pencilmarks": runPencilmarks,
+	"qr":          runQR,
+	"query":       runQuery,
+	"report":      runReport,
+	"samurai":     runSamurai,
+	"selfcheck":   runSelfcheck,
+	"serve":       runServe,
+	"steps":       runSteps,
+	"validate":    runValidate,
+	"variant":     runVariant,
+	"watch":       runWatch,
+	"worker":      runWorker,
+}
+
+// Lists the commands for the usage help
+func getAvailableCommands() string {
+	names := []string{}
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// Loads the plugins of plugins.Dir, warning about the ones that fail. Only the first call does anything
+var loadPlugins = sync.OnceFunc(func() {
+	if _, err := plugins.Load(plugins.Dir()); err != nil {
+		fmt.Fprintln(os.Stderr, msgf("Warning: %v", err))
+	}
+})
+
+// Solvers are reused between puzzles to keep allocations down on large inputs
+var solvers solver.Pool
+
 func main() {
+	setLanguageFromEnvironment()
+	// Opening the plugins costs every command, most of which do not need them: only the variant
+	// command loads them on its own, the rest when the directory is given explicitly
+	if _, ok := os.LookupEnv(plugins.EnvDir); ok {
+		loadPlugins()
+	}
+
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			command(os.Args[2:])
+			return
+		}
+	}
 
 	flags := ParseArgs()
 
-	scanner := parser.CreateInputScanner(flags.InputReader)
+	out := newOutput(flags.LineBuffered)
+	defer out.Flush()
+
+	if flags.ClipboardOut {
+		var copied strings.Builder
+		out.tee(&copied)
+		// Deferred after the flush above, so it runs first and has to flush itself
+		defer func() {
+			out.Flush()
+			if err := clipboard.Write(format.StripColors(copied.String())); err != nil {
+				out.fail(err)
+			}
+		}()
+	}
+
+	if flags.Shape != classicShape {
+		solveGrids(&flags, out)
+		return
+	}
+
+	var run *manifest
+	if flags.Manifest != "" {
+		run = newManifest(&flags, out)
+		// Deferred before the store and the cache are opened, so it runs after they are closed
+		defer func() {
+			out.Flush()
+			if err := run.save(flags.Manifest, flags.Database, flags.Cache, flags.Results); err != nil {
+				out.fail(err)
+			}
+		}()
+	}
+
+	var resultsFile *resultsFile
+	if flags.Results != "" {
+		var err error
+		if resultsFile, err = createResults(flags.Results, flags.LineBuffered); err != nil {
+			out.fail(err)
+		}
+		defer func() {
+			if err := resultsFile.Close(); err != nil {
+				out.fail(err)
+			}
+		}()
+	}
+
+	stopProfiling, err := startProfiling(flags.Profile)
+	if err != nil {
+		out.fail(err)
+	}
+	// Profiles are written even when the run stops on a bad puzzle
+	out.beforeExit = stopProfiling
+	defer stopProfiling()
+
+	if flags.EchoInput {
+		echoInput(out, flags.InputReader, flags.Rate)
+		return
+	}
+
+	next, closeInput := openPuzzles(&flags)
+	defer closeInput()
+
+	var db *store.Store
+	if flags.Database != "" {
+		var err error
+		if db, err = store.Open(flags.Database); err != nil {
+			out.fail(err)
+		}
+		defer db.Close()
+	}
+
+	results := openCache(out, flags.Cache)
+	if results != nil {
+		defer results.Close()
+	}
+
+	if flags.CrossCheck != "" {
+		runCrossCheck(&flags, next, out)
+		return
+	}
 
 	// Statistics block
 	var (
 		totalSolutions = 0
 		globalLimit    = false
+		totalLimitHit  = false // --total-limit reached, the run stops early
 		puzzleCount    = 0
+		skipped        = 0 // puzzles left out by --filter
+		timedOut       = 0 // puzzles given up on at -timeout
 		iterations     = 0
 		start          = time.Now()
 	)
 
-	for ; ; puzzleCount++ {
-		puzzleInput, err := parser.ReadNextPuzzleInput(scanner)
-		// If this is the first puzzle and there is no puzzle,
-		// then it's a error, otherwise we processed all puzzles
-		if errors.Is(err, io.EOF) && puzzleCount != 0 {
-			break
-		}
-		// We exit on these errors because the format is realy loose
-		// and it is unlikely we can recover once something went wrong
-		if err != nil {
-			fmt.Printf("Error: %v\n", err)
-			os.Exit(1)
-		}
-		s, err := solver.NewSolver(puzzleInput)
-		if err != nil {
-			fmt.Printf("Error: %v\n", err)
-			os.Exit(1)
-		}
-		if flags.DontSolve {
-			fmt.Printf("%s\n", format.Format(puzzleInput, flags.OutputFormat))
-			if flags.NewLineAfterEachPuzzle {
-				fmt.Println()
+	// Puzzles are solved and formatted on all CPUs, printing and everything that depends
+	// on the order of the puzzles happens here. A single puzzle, or the puzzles of '-a', which can
+	// have more solutions than fit in memory, are solved here one at a time instead, and their
+	// solutions printed as they are found. That cannot be done when something has to be printed
+	// before them, or when the filter or the total limit may leave them out
+	inline := flags.Workers == 0 && (flags.Input != "" || flags.All) && !flags.SearchTrace && flags.Filter == nil && flags.TotalLimit == 0
+	var emit func(record string)
+	if inline {
+		emit = func(record string) {
+			fmt.Fprint(out, record)
+			out.endRecord()
+		}
+	}
+	solve := func(ctx context.Context, puzzle [9][9]int) (*puzzleResult, error) {
+		r, err := solvePuzzle(ctx, &flags, db != nil, results, puzzle, emit)
+		// Rating and filtering here keeps the rating, which can take longer than the solving, off the writer
+		if err == nil && flags.Results != "" && !flags.Rate {
+			// Like --filter, the results have a level for puzzles that cannot be rated
+			if r.level, err = rating.LevelOf(puzzle); err != nil {
+				r.level, err = rating.Extreme, nil
 			}
-		} else {
-			if flags.All {
-				solutionCount := 0
-				for s.Solve() {
-					iterations += s.Iterations()
-					solutionCount++
-					if solutionCount > flags.Limit && flags.Limit != 0 {
-						solutionCount--
-						globalLimit = true
-						break
-					}
-					if !flags.CountsOnly && !(flags.ShowStats && flags.Quiet) {
-						fmt.Printf("%s\n", format.Format(s.Solution(), flags.OutputFormat))
-						if flags.NewLineAfterEachPuzzle {
-							fmt.Println()
-						}
-					}
-				}
-				// After all solutions of the current puzzle found
-				totalSolutions += solutionCount
-				if flags.CountsOnly && !(flags.ShowStats && flags.Quiet) {
-					var count string
-					if solutionCount > flags.Limit && flags.Limit != 0 {
-						// Indicate that we hit the limit, and hence the acutal number is higher
-						count = fmt.Sprintf("%d (limit)", flags.Limit)
-					} else {
-						count = fmt.Sprintf("%d", solutionCount)
-					}
-					if flags.OutputInputPuzzle {
-						fmt.Printf("%s: %s\n", format.Format(puzzleInput, "inline"), count)
-					} else {
-						fmt.Printf("%s\n", count)
-					}
+		}
+		// The store only rates puzzles with a unique solution
+		if err == nil && db != nil && r.stored == 1 && r.level == 0 {
+			if r.level, err = rating.LevelOf(puzzle); err != nil {
+				r.level, err = 0, nil
+			}
+		}
+		if err == nil && flags.Filter != nil {
+			r.skipped = !flags.Filter.Match(r.filterRecord())
+		}
+		return r, err
+	}
+	// Interrupting the run still prints what has been solved so far
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	_, err = batch.Run(ctx, batch.Config{Workers: flags.Workers, Inline: inline}, next, solve, func(item batch.Item[*puzzleResult]) bool {
+		r := item.Value
+		if r.skipped {
+			skipped++
+			return true
+		}
+		puzzleCount++
+		if r.timedOut {
+			timedOut++
+		}
+		jsonStats := flags.ShowStats && flags.StatsFormat == statsJSON
+		if flags.DontSolve || flags.Rate || flags.Uniqueness {
+			for _, record := range r.records {
+				fmt.Fprint(out, record)
+				out.endRecord()
+			}
+			if jsonStats {
+				writePuzzleStats(out, item.Index, r, r.count, r.limitHit, r.solved)
+			}
+			return true
+		}
+
+		if r.trace != "" && !(flags.ShowStats && flags.Quiet) {
+			fmt.Fprint(out, r.trace)
+			out.endRecord()
+		}
+
+		solutionCount := r.count
+		limitHit := r.limitHit
+		solved := r.solved
+		if flags.TotalLimit != 0 && solutionCount >= flags.TotalLimit-totalSolutions {
+			// Only print what is left of the run-wide limit
+			solutionCount = flags.TotalLimit - totalSolutions
+			solved = solutionCount
+			limitHit = false
+			totalLimitHit = true
+		}
+		iterations += r.iterationsAfter(solved)
+		if limitHit {
+			globalLimit = true
+		}
+		totalSolutions += solutionCount
+		records := r.records[:min(len(r.records), solutionCount)]
+		if r.stuck {
+			// How far the logic engine got is not a solution, but it is printed instead of one
+			records = r.records
+		}
+		for _, record := range records {
+			fmt.Fprint(out, record)
+			out.endRecord()
+		}
+
+		if flags.Template != nil {
+			if !(flags.ShowStats && flags.Quiet) {
+				err := flags.Template.Execute(out, newTemplateRecord(item.Index, r, solutionCount, limitHit, solved))
+				if err != nil {
+					out.fail(err)
 				}
+				fmt.Fprintln(out)
+				out.endRecord()
+			}
+		} else if r.timedOut && !(flags.All && flags.CountsOnly) && !(flags.ShowStats && flags.Quiet) {
+			fmt.Fprintln(out, msg("Timed out"))
+			out.endRecord()
+		} else if !flags.All && solutionCount == 0 && !r.stuck {
+			fmt.Fprintln(out, msg("No solution"))
+			out.endRecord()
+		}
+		if flags.All && flags.CountsOnly && !(flags.ShowStats && flags.Quiet) && flags.Template == nil {
+			var count string
+			if limitHit {
+				// Indicate that we hit the limit, and hence the acutal number is higher
+				count = msgf("%d+ (limit reached)", solutionCount)
+			} else if r.timedOut {
+				// The search gave up before it was over, there may be more
+				count = msgf("%d+ (timed out)", solutionCount)
+			} else if totalLimitHit {
+				// The enumeration of this puzzle was cut short by the run-wide limit
+				count = msgf("%d (total limit)", solutionCount)
 			} else {
-				if s.Solve() {
-					iterations += s.Iterations()
-					totalSolutions++
-					if !(flags.ShowStats && flags.Quiet) {
-						fmt.Printf("%s\n", format.Format(s.Solution(), flags.OutputFormat))
-						if flags.NewLineAfterEachPuzzle {
-							fmt.Println()
-						}
-					}
-				} else {
-					fmt.Printf("No solution\n")
-				}
+				count = fmt.Sprintf("%d", solutionCount)
 			}
+			if flags.OutputInputPuzzle {
+				fmt.Fprintf(out, "%s: %s\n", format.Inline(r.puzzle), count)
+			} else {
+				fmt.Fprintf(out, "%s\n", count)
+			}
+			out.endRecord()
 		}
+
+		if jsonStats {
+			writePuzzleStats(out, item.Index, r, solutionCount, limitHit, solved)
+		}
+
+		if resultsFile != nil {
+			if err := resultsFile.write(&flags, item.Index, r, solutionCount, limitHit, totalLimitHit, solved); err != nil {
+				out.fail(err)
+			}
+		}
+
+		if db != nil {
+			record := store.NewRecord(r.puzzle, r.stored, flags.InputFile)
+			if r.stored == 1 && r.level != 0 {
+				record.Rating = r.level.String()
+			}
+			if _, err := db.Add(record); err != nil {
+				out.fail(err)
+			}
+		}
+		return !totalLimitHit
+	})
+	if errors.Is(err, context.Canceled) {
+		err = solver.ErrCancelled
+	}
+	if err != nil {
+		out.fail(err)
+	}
+	if puzzleCount+skipped == 0 {
+		// Not a single puzzle in the input
+		out.fail(io.EOF)
 	}
-	if flags.ShowStats {
+	if run != nil {
+		run.finish(puzzleCount, totalSolutions, iterations, globalLimit, totalLimitHit)
+	}
+	if flags.ShowStats && flags.StatsFormat == statsJSON {
+		writeRunStats(out, runStats{
+			Puzzles:           puzzleCount,
+			LeftOut:           skipped,
+			TimedOut:          timedOut,
+			Solutions:         totalSolutions,
+			LimitReached:      globalLimit,
+			TotalLimitReached: totalLimitHit,
+			Iterations:        iterations,
+		}, start)
+	} else if flags.ShowStats {
 		limit := ""
 		if globalLimit {
 			// Indicate that we hit the limit, and hence the acutal number is higher
-			limit = " (limit)"
+			limit = msg(" (limit reached for some puzzles, actual number is higher)")
+		}
+		if totalLimitHit {
+			limit += msg(" (total limit)")
+		}
+		fmt.Fprintln(out, msgf("Total puzzles: %d", puzzleCount))
+		if flags.Filter != nil {
+			fmt.Fprintln(out, msgf("Left out by the filter: %d", skipped))
 		}
-		fmt.Printf("Total puzzles: %d\n", puzzleCount)
-		fmt.Printf("Total solutions: %d%s\n", totalSolutions, limit)
-		fmt.Printf("Total iterations: %d\n", iterations)
-		fmt.Printf("Time taken: %s", time.Since(start))
+		if flags.Timeout != 0 {
+			fmt.Fprintln(out, msgf("Timed out: %d", timedOut))
+		}
+		fmt.Fprintln(out, msgf("Total solutions: %d%s", totalSolutions, limit))
+		fmt.Fprintln(out, msgf("Total iterations: %d", iterations))
+		fmt.Fprint(out, msgf("Time taken: %s", time.Since(start)))
+	}
+}
+
+// Formats a solution for output, highlighting the solved cells when colors are enabled
+func formatSolution(flags Flags, solution solver.Solution) (string, error) {
+	return format.FormatSolution(solution, flags.OutputFormat, flags.Color)
+}
+
+// Counts the solutions of the puzzle, but stops at max
+func countSolutions(puzzle [9][9]int, max int) (int, error) {
+	s, err := solvers.Get(puzzle)
+	if err != nil {
+		return 0, err
+	}
+	defer solvers.Put(s)
+	count := 0
+	for count < max && s.Solve() {
+		count++
 	}
+	return count, nil
 }
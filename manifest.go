@@ -0,0 +1,117 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+// What a run did, written to the --manifest file at the end, so that the results can be audited
+// and the run reproduced: the exact arguments, checksums of what it read and wrote, and the totals
+type manifest struct {
+	Program           string         `json:"program"`
+	Version           string         `json:"version"`   // of the module, "(devel)" for a local build
+	GoVersion         string         `json:"goVersion"` // the program was built with
+	Args              []string       `json:"args"`      // all of them, the program name left out
+	Input             manifestFile   `json:"input"`
+	Outputs           []manifestFile `json:"outputs"` // the standard output first, then the files written
+	Puzzles           int            `json:"puzzles"`
+	Solutions         int            `json:"solutions"`
+	Iterations        int            `json:"iterations"`
+	LimitReached      bool           `json:"limitReached"`      // for some puzzles, their counts are higher
+	TotalLimitReached bool           `json:"totalLimitReached"` // the run stopped at --total-limit
+	Started           time.Time      `json:"started"`
+	Seconds           float64        `json:"seconds"` // the run took
+
+	stdout hash.Hash // of everything printed so far
+	bytes  int64     // printed so far
+}
+
+// A file the run read or wrote. Names other than paths say where the data came from or went: "-i"
+// is the input given on the command line, "clipboard" the clipboard and "stdout" the standard output
+type manifestFile struct {
+	Name   string `json:"name"`
+	Bytes  int64  `json:"bytes"`
+	SHA256 string `json:"sha256"`
+}
+
+// Starts a manifest of the run. The output is checksummed from here on
+func newManifest(flags *Flags, out *output) *manifest {
+	m := &manifest{
+		Program:   "sudocoo",
+		Version:   "unknown",
+		GoVersion: runtime.Version(),
+		Args:      os.Args[1:],
+		Started:   time.Now(),
+		stdout:    sha256.New(),
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		m.Version = info.Main.Version
+	}
+	switch {
+	case flags.ClipboardIn:
+		m.Input = manifestFile{Name: "clipboard"}
+	case flags.InputFile != "":
+		m.Input = fileChecksum(flags.InputFile)
+	default:
+		sum := sha256.Sum256([]byte(flags.Input))
+		m.Input = manifestFile{Name: "-i", Bytes: int64(len(flags.Input)), SHA256: hex.EncodeToString(sum[:])}
+	}
+	out.tee(m)
+	return m
+}
+
+// Counts and checksums the output
+func (m *manifest) Write(p []byte) (int, error) {
+	m.bytes += int64(len(p))
+	return m.stdout.Write(p)
+}
+
+// Adds the totals of the run
+func (m *manifest) finish(puzzles, solutions, iterations int, limitReached, totalLimitReached bool) {
+	m.Puzzles = puzzles
+	m.Solutions = solutions
+	m.Iterations = iterations
+	m.LimitReached = limitReached
+	m.TotalLimitReached = totalLimitReached
+}
+
+// Writes the manifest to the file. The files of the run are checksummed now, so call it after they are closed
+func (m *manifest) save(path string, files ...string) error {
+	m.Seconds = time.Since(m.Started).Seconds()
+	m.Outputs = []manifestFile{{Name: "stdout", Bytes: m.bytes, SHA256: hex.EncodeToString(m.stdout.Sum(nil))}}
+	for _, f := range files {
+		if f != "" {
+			m.Outputs = append(m.Outputs, fileChecksum(f))
+		}
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Returns the size and checksum of the file, or just the name if it cannot be read
+func fileChecksum(path string) manifestFile {
+	result := manifestFile{Name: path}
+	file, err := os.Open(path)
+	if err != nil {
+		return result
+	}
+	defer file.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, file)
+	if err != nil {
+		return result
+	}
+	result.Bytes = n
+	result.SHA256 = hex.EncodeToString(h.Sum(nil))
+	return result
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/bits"
+	"os"
+	"path/filepath"
+
+	"github.com/AndrewSav/sudocoo/pkg/analysis"
+	"github.com/AndrewSav/sudocoo/pkg/format"
+	"github.com/AndrewSav/sudocoo/pkg/parser"
+	"github.com/AndrewSav/sudocoo/pkg/rating"
+	"github.com/AndrewSav/sudocoo/pkg/solver"
+)
+
+// Model Context Protocol (https://modelcontextprotocol.io) revision this server implements
+const mcpProtocolVersion = "2024-11-05"
+
+// JSON-RPC 2.0 error codes
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+)
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"` // absent for notifications, which get no response
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type mcpTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+	run         func(puzzle [9][9]int) (any, error)
+}
+
+type mcpContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type mcpToolResult struct {
+	Content []mcpContent `json:"content"`
+	IsError bool         `json:"isError,omitempty"`
+}
+
+// All the tools take just a puzzle
+var puzzleSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"puzzle": map[string]any{
+			"type":        "string",
+			"description": "81 characters row by row, digits for givens and '.' or '0' for empty cells",
+		},
+	},
+	"required": []string{"puzzle"},
+}
+
+var mcpTools = []mcpTool{
+	{
+		Name:        "solve",
+		Description: "Solves a sudoku puzzle and tells whether the solution is unique",
+		InputSchema: puzzleSchema,
+		run:         mcpSolve,
+	},
+	{
+		Name:        "validate",
+		Description: "Checks a sudoku puzzle: clue count, whether the givens contradict each other, and whether it has no, one or several solutions",
+		InputSchema: puzzleSchema,
+		run:         mcpValidate,
+	},
+	{
+		Name:        "hint",
+		Description: "Returns the next cell to fill in a sudoku puzzle, with the technique that finds it where possible",
+		InputSchema: puzzleSchema,
+		run:         mcpHint,
+	},
+	{
+		Name:        "rate",
+		Description: "Rates the difficulty of a sudoku puzzle with a unique solution: the level (easy, medium, hard or extreme) from the techniques it needs, and the score, the backtracking effort of a search",
+		InputSchema: puzzleSchema,
+		run:         mcpRate,
+	},
+}
+
+// Serves the solver as MCP tools over stdin and stdout, so that AI assistants can use it
+func runMCP(args []string) {
+	fs := flag.NewFlagSet("mcp", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Println("Runs a Model Context Protocol server on stdin/stdout with the tools: solve, validate, hint, rate")
+		fmt.Printf("Usage: %s mcp\n", filepath.Base(os.Args[0]))
+	}
+	parseFlags(fs, args)
+
+	out := newOutput(true)
+	defer out.Flush()
+	encoder := json.NewEncoder(out)
+
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			if resp := handleMCPMessage(line); resp != nil {
+				encoder.Encode(resp)
+				out.endRecord()
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return
+		}
+		if err != nil {
+			out.fail(err)
+		}
+	}
+}
+
+// Returns the response to a message, nil for notifications
+func handleMCPMessage(line []byte) *rpcResponse {
+	var req rpcRequest
+	if err := json.Unmarshal(line, &req); err != nil {
+		return &rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{rpcParseError, err.Error()}}
+	}
+	if req.ID == nil {
+		return nil
+	}
+	resp := &rpcResponse{JSONRPC: "2.0", ID: req.ID}
+	if req.JSONRPC != "2.0" {
+		resp.Error = &rpcError{rpcInvalidRequest, "jsonrpc must be 2.0"}
+		return resp
+	}
+	switch req.Method {
+	case "initialize":
+		resp.Result = map[string]any{
+			"protocolVersion": mcpProtocolVersion,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]any{"name": "sudocoo", "version": "1"},
+		}
+	case "ping":
+		resp.Result = map[string]any{}
+	case "tools/list":
+		resp.Result = map[string]any{"tools": mcpTools}
+	case "tools/call":
+		var params struct {
+			Name      string `json:"name"`
+			Arguments struct {
+				Puzzle string `json:"puzzle"`
+			} `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &rpcError{rpcInvalidParams, err.Error()}
+			return resp
+		}
+		for _, tool := range mcpTools {
+			if tool.Name == params.Name {
+				resp.Result = callMCPTool(tool, params.Arguments.Puzzle)
+				return resp
+			}
+		}
+		resp.Error = &rpcError{rpcInvalidParams, fmt.Sprintf("unknown tool %q", params.Name)}
+	default:
+		resp.Error = &rpcError{rpcMethodNotFound, fmt.Sprintf("method %q not found", req.Method)}
+	}
+	return resp
+}
+
+// Tool failures are results with isError set, so that the assistant sees the message
+func callMCPTool(tool mcpTool, input string) mcpToolResult {
+	result, err := func() (any, error) {
+		puzzle, err := parser.ParsePuzzleString(input)
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("puzzle is empty")
+		}
+		if err != nil {
+			return nil, err
+		}
+		return tool.run(puzzle)
+	}()
+	if err != nil {
+		return mcpToolResult{Content: []mcpContent{{Type: "text", Text: "Error: " + err.Error()}}, IsError: true}
+	}
+	text, _ := json.Marshal(result)
+	return mcpToolResult{Content: []mcpContent{{Type: "text", Text: string(text)}}}
+}
+
+func mcpSolve(puzzle [9][9]int) (any, error) {
+	s, err := solver.NewSolver(puzzle)
+	if err != nil {
+		return nil, err
+	}
+	if !s.Solve() {
+		return nil, solver.ErrNoSolution
+	}
+	solution := format.Inline(s.Solution())
+	return map[string]any{"solution": solution, "unique": !s.Solve()}, nil
+}
+
+func mcpValidate(puzzle [9][9]int) (any, error) {
+	result := map[string]any{
+		"clues":      analysis.ClueCount(puzzle),
+		"symmetric":  analysis.IsSymmetric(puzzle),
+		"symmetry":   analysis.Symmetries(puzzle).String(),
+		"consistent": true,
+	}
+	switch classifyPuzzle(puzzle) {
+	case echoInvalid:
+		result["consistent"] = false
+		result["solutions"] = "0"
+	case echoNoSolution:
+		result["solutions"] = "0"
+	case echoUnique:
+		result["solutions"] = "1"
+	default:
+		result["solutions"] = "2+"
+	}
+	return result, nil
+}
+
+func mcpHint(puzzle [9][9]int) (any, error) {
+	s, err := solver.NewSolver(puzzle)
+	if err != nil {
+		return nil, err
+	}
+	if !s.Solve() {
+		return nil, solver.ErrNoSolution
+	}
+	solution := s.Solution()
+	hint := func(y, x, digit int, technique string) map[string]any {
+		return map[string]any{
+			"cell":      fmt.Sprintf("r%dc%d", y+1, x+1),
+			"row":       y + 1,
+			"column":    x + 1,
+			"digit":     digit,
+			"technique": technique,
+		}
+	}
+	if single, ok := analysis.FindSingle(puzzle); ok {
+		return hint(single.Row, single.Column, single.Digit, single.Technique), nil
+	}
+	// No single, take the digit from the solution for the cell with the fewest candidates
+	if s.Solve() {
+		return nil, fmt.Errorf("the puzzle has more than one solution and no single to place")
+	}
+	candidates := analysis.Candidates(puzzle)
+	best, by, bx := 10, -1, -1
+	for y := 0; y < 9; y++ {
+		for x := 0; x < 9; x++ {
+			if n := bits.OnesCount16(candidates[y][x]); puzzle[y][x] == 0 && n < best {
+				best, by, bx = n, y, x
+			}
+		}
+	}
+	if by < 0 {
+		return nil, fmt.Errorf("the puzzle is already solved")
+	}
+	return hint(by, bx, solution[by][bx], "from the solution, no single is available"), nil
+}
+
+func mcpRate(puzzle [9][9]int) (any, error) {
+	s, err := solver.NewSolver(puzzle)
+	if err != nil {
+		return nil, err
+	}
+	switch s.CountSolutions(1) {
+	case 0:
+		return nil, solver.ErrNoSolution
+	case 2:
+		return nil, fmt.Errorf("the puzzle has more than one solution, only unique puzzles are rated")
+	}
+	rated, err := rating.Rate(puzzle, analysis.DefaultSearchOrders, 1)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"level": rated.Level.String(), "score": rated.Score}, nil
+}
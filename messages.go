@@ -0,0 +1,190 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Messages for the user are looked up by their English text in the catalog of the language picked,
+// so that the tool can be shipped to puzzle communities that do not speak English. Messages missing
+// from a catalog stay in English, and so do the flag descriptions. The output formats, puzzles and
+// solutions, are never translated: other programs read them
+var catalogs = map[string]map[string]string{
+	"de": {
+		"This is brute force solver for sudoku puzzles":      "Ein Brute-Force-Löser für Sudoku-Rätsel",
+		"Based on code by Glenn Fowler of ATT %s":            "Basiert auf dem Code von Glenn Fowler (AT&T) %s",
+		"Code archive: %s":                                   "Code-Archiv: %s",
+		"Usage: %s [FLAGS...]":                               "Aufruf: %s [OPTIONEN...]",
+		"   or: %s COMMAND [FLAGS...]":                       "  oder: %s BEFEHL [OPTIONEN...]",
+		"Commands: %s":                                       "Befehle: %s",
+		"Flags:":                                             "Optionen:",
+		"want 0 arguments, have %d":                          "es werden keine Argumente erwartet, angegeben sind %d",
+		"you have to specify input with either -f or -i":     "die Eingabe muss mit -f oder -i angegeben werden",
+		"you have to specify either -f or -i, not both":      "entweder -f oder -i angeben, nicht beides",
+		"Error opening input file: %v":                       "Fehler beim Öffnen der Eingabedatei: %v",
+		"Error reading the puzzle from the image: %v":        "Fehler beim Lesen des Rätsels aus dem Bild: %v",
+		"Error reading the clipboard: %v":                    "Fehler beim Lesen der Zwischenablage: %v",
+		"%s or %s":                                           "%s oder %s",
+		"'%s' cannot be combined with %s":                    "'%s' kann nicht mit %s kombiniert werden",
+		"'%s' and '%s' cannot be combined with %s":           "'%s' und '%s' können nicht mit %s kombiniert werden",
+		"'-%s' cannot be combined with grids other than 9x9": "'-%s' kann nur mit 9x9-Gittern verwendet werden",
+		"the %s format cannot be combined with grids other than 9x9, want one of %s":         "das Format %s kann nur mit 9x9-Gittern verwendet werden, erwartet wird eines von %s",
+		"'%s' and '%s' both set the limit, specify only one of them":                         "'%s' und '%s' legen beide das Limit fest, nur eine davon angeben",
+		"'%s' only applies together with '%s', use '%s' to limit the solutions without '%s'": "'%s' gilt nur zusammen mit '%s', '%s' begrenzt die Lösungen auch ohne '%s'",
+		"the solution limit cannot be negative, use 0 for no limit":                          "das Lösungslimit kann nicht negativ sein, 0 bedeutet kein Limit",
+		"invalid total limit %d, want 0 or more":                                             "ungültiges Gesamtlimit %d, erwartet wird 0 oder mehr",
+		"invalid sample size %d, want 0 or more":                                             "ungültige Stichprobengröße %d, erwartet wird 0 oder mehr",
+		"invalid number of workers %d, want 0 or more":                                       "ungültige Anzahl von Workern %d, erwartet wird 0 oder mehr",
+		"invalid timeout %s, want 0 or more":                                                 "ungültiges Zeitlimit %s, erwartet wird 0 oder mehr",
+		"invalid output format %s":                                                           "ungültiges Ausgabeformat %s",
+		"invalid stats format %s, want %s or %s":                                             "ungültiges Statistikformat %s, erwartet wird %s oder %s",
+		"invalid engine %s, want one of %s":                                                  "ungültiger Löser %s, erwartet wird einer von %s",
+		"invalid size %d, want one of %s":                                                    "ungültige Größe %d, erwartet wird eine von %s",
+		"invalid box %s for size %d, want rows x columns, e.g. 2x3":                          "ungültiger Block %s für Größe %d, erwartet wird Zeilen x Spalten, z. B. 2x3",
+		"invalid filter: %v":                                                                 "ungültiger Filter: %v",
+		"unknown language %s, want one of %s":                                                "unbekannte Sprache %s, erwartet wird eine von %s",
+		"Error: %v":                                                                          "Fehler: %v",
+		"Warning: %v":                                                                        "Warnung: %v",
+		"No solution":                                                                        "Keine Lösung",
+		"Timed out":                                                                          "Zeitlimit überschritten",
+		"%d+ (limit reached)":                                                                "%d+ (Limit erreicht)",
+		"%d+ (timed out)":                                                                    "%d+ (Zeitlimit überschritten)",
+		"%d (total limit)":                                                                   "%d (Gesamtlimit)",
+		" (limit reached for some puzzles, actual number is higher)":                         " (Limit bei einigen Rätseln erreicht, die tatsächliche Zahl ist höher)",
+		" (total limit)":                                                                     " (Gesamtlimit)",
+		"Total puzzles: %d":                                                                  "Rätsel insgesamt: %d",
+		"Left out by the filter: %d":                                                         "Vom Filter ausgelassen: %d",
+		"Timed out: %d":                                                                      "Zeitlimit überschritten: %d",
+		"Total solutions: %d%s":                                                              "Lösungen insgesamt: %d%s",
+		"Total iterations: %d":                                                               "Iterationen insgesamt: %d",
+		"Time taken: %s":                                                                     "Benötigte Zeit: %s",
+		exitStatusHelp:                                                                       "Exit-Status: 0 bei Erfolg, 1 bei Fehlern, 2 bei ungültigen Optionen, 3 bei einem widersprüchlichen oder unvollständigen Rätsel, 4 wenn ein Rätsel keine Lösung hat, obwohl eine nötig ist, 5 wenn die Suche an ihrem Iterationslimit aufgibt und 130 bei einem Abbruch",
+	},
+	"ru": {
+		"This is brute force solver for sudoku puzzles":      "Решатель судоку полным перебором",
+		"Based on code by Glenn Fowler of ATT %s":            "Основан на коде Гленна Фаулера (AT&T) %s",
+		"Code archive: %s":                                   "Архив кода: %s",
+		"Usage: %s [FLAGS...]":                               "Использование: %s [ФЛАГИ...]",
+		"   or: %s COMMAND [FLAGS...]":                       "          или: %s КОМАНДА [ФЛАГИ...]",
+		"Commands: %s":                                       "Команды: %s",
+		"Flags:":                                             "Флаги:",
+		"want 0 arguments, have %d":                          "аргументы не ожидаются, передано %d",
+		"you have to specify input with either -f or -i":     "укажите входные данные с помощью -f или -i",
+		"you have to specify either -f or -i, not both":      "укажите либо -f, либо -i, но не оба сразу",
+		"Error opening input file: %v":                       "Ошибка при открытии входного файла: %v",
+		"Error reading the puzzle from the image: %v":        "Ошибка при чтении головоломки из изображения: %v",
+		"Error reading the clipboard: %v":                    "Ошибка при чтении буфера обмена: %v",
+		"%s or %s":                                           "%s или %s",
+		"'%s' cannot be combined with %s":                    "'%s' нельзя использовать вместе с %s",
+		"'%s' and '%s' cannot be combined with %s":           "'%s' и '%s' нельзя использовать вместе с %s",
+		"'-%s' cannot be combined with grids other than 9x9": "'-%s' можно использовать только с сеткой 9x9",
+		"the %s format cannot be combined with grids other than 9x9, want one of %s":         "формат %s можно использовать только с сеткой 9x9, ожидается один из %s",
+		"'%s' and '%s' both set the limit, specify only one of them":                         "'%s' и '%s' оба задают предел, укажите только один из них",
+		"'%s' only applies together with '%s', use '%s' to limit the solutions without '%s'": "'%s' действует только вместе с '%s', '%s' ограничивает число решений и без '%s'",
+		"the solution limit cannot be negative, use 0 for no limit":                          "предел числа решений не может быть отрицательным, 0 означает без предела",
+		"invalid total limit %d, want 0 or more":                                             "недопустимый общий предел %d, ожидается 0 или больше",
+		"invalid sample size %d, want 0 or more":                                             "недопустимый размер выборки %d, ожидается 0 или больше",
+		"invalid number of workers %d, want 0 or more":                                       "недопустимое число исполнителей %d, ожидается 0 или больше",
+		"invalid timeout %s, want 0 or more":                                                 "недопустимый тайм-аут %s, ожидается 0 или больше",
+		"invalid output format %s":                                                           "недопустимый формат вывода %s",
+		"invalid stats format %s, want %s or %s":                                             "недопустимый формат статистики %s, ожидается %s или %s",
+		"invalid engine %s, want one of %s":                                                  "недопустимый решатель %s, ожидается один из %s",
+		"invalid size %d, want one of %s":                                                    "недопустимый размер %d, ожидается один из %s",
+		"invalid box %s for size %d, want rows x columns, e.g. 2x3":                          "недопустимый блок %s для размера %d, ожидается строки x столбцы, например 2x3",
+		"invalid filter: %v":                                                                 "недопустимый фильтр: %v",
+		"unknown language %s, want one of %s":                                                "неизвестный язык %s, ожидается один из %s",
+		"Error: %v":                                                                          "Ошибка: %v",
+		"Warning: %v":                                                                        "Предупреждение: %v",
+		"No solution":                                                                        "Нет решения",
+		"Timed out":                                                                          "Время истекло",
+		"%d+ (limit reached)":                                                                "%d+ (достигнут предел)",
+		"%d+ (timed out)":                                                                    "%d+ (время истекло)",
+		"%d (total limit)":                                                                   "%d (общий предел)",
+		" (limit reached for some puzzles, actual number is higher)":                         " (для некоторых головоломок достигнут предел, на самом деле решений больше)",
+		" (total limit)":                                                                     " (общий предел)",
+		"Total puzzles: %d":                                                                  "Всего головоломок: %d",
+		"Left out by the filter: %d":                                                         "Отброшено фильтром: %d",
+		"Timed out: %d":                                                                      "Время истекло: %d",
+		"Total solutions: %d%s":                                                              "Всего решений: %d%s",
+		"Total iterations: %d":                                                               "Всего итераций: %d",
+		"Time taken: %s":                                                                     "Затраченное время: %s",
+		exitStatusHelp:                                                                       "Код возврата: 0 при успехе, 1 при ошибках, 2 при неверных флагах, 3 при противоречивой или неполной головоломке, 4 если у головоломки нет решения, а оно требуется, 5 если поиск прекращён по пределу итераций и 130 при прерывании",
+	},
+}
+
+// Where the language is taken from if there is no flag, before the usual locale variables
+const envLang = "SUDOCOO_LANG"
+
+// The catalog in use, nil for English
+var messages map[string]string
+
+// Returns the message in the language picked
+func msg(s string) string {
+	if translated, ok := messages[s]; ok {
+		return translated
+	}
+	return s
+}
+
+// Formats the message in the language picked
+func msgf(format string, args ...any) string {
+	return fmt.Sprintf(msg(format), args...)
+}
+
+// Lists flags for a message, quoted and joined with 'or' in the language picked: '-a', '-d' or '-e'
+func flagList(names ...string) string {
+	return joinFlags(msg("%s or %s"), names)
+}
+
+// Lists the flags quoted, the last two joined with or, a format with the rest and the last one
+func joinFlags(or string, names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = "'" + name + "'"
+	}
+	if len(quoted) == 1 {
+		return quoted[0]
+	}
+	return fmt.Sprintf(or, strings.Join(quoted[:len(quoted)-1], ", "), quoted[len(quoted)-1])
+}
+
+// Returns the languages there are catalogs for, English included
+func languages() []string {
+	result := []string{"en"}
+	for lang := range catalogs {
+		result = append(result, lang)
+	}
+	sort.Strings(result[1:])
+	return result
+}
+
+// Picks the language by its code, such as de, or a locale, such as de_DE.UTF-8
+func setLanguage(lang string) error {
+	code, _, _ := strings.Cut(lang, ".")
+	code, _, _ = strings.Cut(code, "_")
+	code = strings.ToLower(code)
+	if code == "en" || code == "c" || code == "posix" {
+		messages = nil
+		return nil
+	}
+	catalog, ok := catalogs[code]
+	if !ok {
+		return errors.New(msgf("unknown language %s, want one of %s", lang, strings.Join(languages(), ", ")))
+	}
+	messages = catalog
+	return nil
+}
+
+// Picks the language of the environment: SUDOCOO_LANG, or else the first of the locale variables
+// that is set. A language without a catalog is English
+func setLanguageFromEnvironment() {
+	for _, name := range []string{envLang, "LC_ALL", "LC_MESSAGES", "LANG"} {
+		if lang := os.Getenv(name); lang != "" {
+			setLanguage(lang)
+			return
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/AndrewSav/sudocoo/pkg/format"
+	"github.com/AndrewSav/sudocoo/pkg/parser"
+	"github.com/AndrewSav/sudocoo/pkg/solver"
+)
+
+// All the program output goes through this buffered writer. Printing every
+// solution straight to stdout dominates the run time on enumeration-heavy runs
+type output struct {
+	*bufio.Writer
+	lineBuffered bool   // flush after each record, for pipelines that need results immediately
+	beforeExit   func() // called when the program exits on an error, if set
+	copies       []io.Writer
+}
+
+func newOutput(lineBuffered bool) *output {
+	return &output{Writer: bufio.NewWriterSize(os.Stdout, 64*1024), lineBuffered: lineBuffered}
+}
+
+// Sends everything written from now on to w as well as to stdout
+func (o *output) tee(w io.Writer) {
+	o.Flush()
+	o.copies = append(o.copies, w)
+	o.Writer.Reset(io.MultiWriter(append([]io.Writer{os.Stdout}, o.copies...)...))
+}
+
+// Called after each complete record (a solution, a count, an annotated line)
+func (o *output) endRecord() {
+	if o.lineBuffered {
+		o.Flush()
+	}
+}
+
+// Exit statuses, so that scripts can tell what went wrong without matching the messages. 2 is
+// for bad flags, as the flag package has it
+const (
+	exitError        = 1   // anything not listed here
+	exitUsage        = 2   // bad flags, as the flag package has it
+	exitInvalidInput = 3   // an inconsistent puzzle or one cut short
+	exitNoSolution   = 4   // a puzzle that had to have a solution has none
+	exitLimitReached = 5   // the search gave up at its iteration limit
+	exitCancelled    = 130 // interrupted, as shells report SIGINT
+)
+
+// The exit statuses for the usage
+const exitStatusHelp = "Exit status: 0 on success, 1 on errors, 2 on bad flags, 3 on an inconsistent or incomplete puzzle, 4 when a puzzle has no solution where one is needed, 5 when the search gives up at its iteration limit and 130 when interrupted"
+
+// Returns the exit status for the error
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, solver.ErrInvalidPuzzle), errors.Is(err, parser.ErrNotEnoughCells):
+		return exitInvalidInput
+	case errors.Is(err, solver.ErrNoSolution):
+		return exitNoSolution
+	case errors.Is(err, solver.ErrLimitReached):
+		return exitLimitReached
+	case errors.Is(err, solver.ErrCancelled):
+		return exitCancelled
+	case errors.Is(err, format.ErrUnknownFormat):
+		return exitUsage
+	}
+	return exitError
+}
+
+// Prints the error and exits with the status for it, making sure that everything printed before it
+// is not lost
+func (o *output) fail(err error) {
+	fmt.Fprintln(o, msgf("Error: %v", err))
+	o.Flush()
+	if o.beforeExit != nil {
+		o.beforeExit()
+	}
+	os.Exit(exitCode(err))
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/AndrewSav/sudocoo/pkg/packed"
+	"github.com/AndrewSav/sudocoo/pkg/parser"
+)
+
+type packFlags struct {
+	InputFile  string // input can come from a file
+	Input      string // or form a string
+	OutputFile string // write here instead of stdout
+	Counts     bool   // store the solution count with each puzzle
+}
+
+// Converts puzzles to the packed binary format. Packed files can be given to -f anywhere,
+// they are recognized by their header, so '-d -v FORMAT' converts them back
+func runPack(args []string) {
+	var flags packFlags
+
+	fs := flag.NewFlagSet("pack", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Printf("Writes puzzles in a compact binary format, %d bytes per puzzle\n", packed.PuzzleSize)
+		fmt.Printf("Usage: %s pack [FLAGS...]\n", filepath.Base(os.Args[0]))
+		fmt.Printf("Packed files are accepted by -f everywhere, '%s -f FILE -d -v inline' unpacks one\n", filepath.Base(os.Args[0]))
+		fmt.Println("Flags:")
+		fs.PrintDefaults()
+	}
+	addInputFlags(fs, &flags.InputFile, &flags.Input)
+	fs.StringVar(&flags.OutputFile, "o", "", "write to this file instead of the standard output")
+	fs.BoolVar(&flags.Counts, "counts", false, "store the solution count with each puzzle: 0, 1 or 2 for two or more")
+	parseFlags(fs, args)
+
+	scanner := parser.CreateInputScanner(openInput(fs, flags.InputFile, flags.Input))
+
+	out := newOutput(false)
+	defer out.Flush()
+
+	var w io.Writer = out
+	if flags.OutputFile != "" {
+		file, err := os.Create(flags.OutputFile)
+		if err != nil {
+			out.fail(err)
+		}
+		defer file.Close()
+		w = file
+	}
+	pw, err := packed.NewWriter(w, flags.Counts)
+	if err != nil {
+		out.fail(err)
+	}
+	for puzzleCount := 0; ; puzzleCount++ {
+		puzzle, err := parser.ReadNextPuzzleInput(scanner)
+		if errors.Is(err, io.EOF) && puzzleCount != 0 {
+			break
+		}
+		if err != nil {
+			out.fail(err)
+		}
+		count := packed.NoCount
+		if flags.Counts {
+			// An inconsistent puzzle has no solutions
+			count, _ = countSolutions(puzzle, 2)
+		}
+		if err := pw.Write(puzzle, count); err != nil {
+			out.fail(err)
+		}
+	}
+	if err := pw.Flush(); err != nil {
+		out.fail(err)
+	}
+}
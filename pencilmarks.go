@@ -0,0 +1,113 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/AndrewSav/sudocoo/pkg/format"
+	"github.com/AndrewSav/sudocoo/pkg/parser"
+	"github.com/AndrewSav/sudocoo/pkg/solver"
+)
+
+type pencilmarksFlags struct {
+	InputFile    string // input can come from a file
+	Input        string // or form a string
+	All          bool   // we want all solutions, not just the first one
+	Limit        int    // the maximum number of solutions to find
+	CountsOnly   bool   // we want only the solution count, not the solutions themselves
+	OutputFormat string // how to print out a solution
+	DontSolve    bool   // print the placed digits instead of the solutions
+}
+
+// Solves positions in the middle of a solve, given as pencil marks, see package parser for the formats.
+// The candidates eliminated from the pencil marks are never tried, so the solutions are those of the
+// position exactly as the solver has it, which is fewer than those of its placed digits if an elimination
+// was wrong
+func runPencilmarks(args []string) {
+	var flags pencilmarksFlags
+
+	fs := flag.NewFlagSet("pencilmarks", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Println("Solves puzzles given as pencil marks, honoring the candidates eliminated from them")
+		fmt.Printf("Usage: %s pencilmarks [FLAGS...]\n", filepath.Base(os.Args[0]))
+		fmt.Println("Flags:")
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&flags.InputFile, "f", "", "path to input file with pencil marks, 729 characters or 81 .sdx tokens per puzzle. Only one of '-f' and '-i' can be specified")
+	fs.StringVar(&flags.Input, "i", "", "pencil marks, 729 characters or 81 .sdx tokens. Only one of '-f' and '-i' can be specified")
+	fs.BoolVar(&flags.All, "a", false, "find all solutions, but no more than specified in the -l flag")
+	fs.IntVar(&flags.Limit, "l", defaultLimit, "the maximum number of solutions to find. 0 is no limit. Requires '-a'")
+	fs.BoolVar(&flags.CountsOnly, "c", false, "do not print out the solutions, only the solution count. Only considered when '-a' is specified")
+	fs.StringVar(&flags.OutputFormat, "v", "visual", fmt.Sprintf("output format for solutions: %s", getAvailableFormats()))
+	fs.BoolVar(&flags.DontSolve, "d", false, "do not solve the puzzles, output their placed digits instead, e.g. to convert them with '-v'")
+	parseFlags(fs, args)
+
+	if _, err := format.Lookup(flags.OutputFormat); err != nil {
+		fmt.Printf("invalid output format %s\n", flags.OutputFormat)
+		fs.Usage()
+		os.Exit(exitCode(err))
+	}
+	if flags.Limit < 0 {
+		fmt.Printf("invalid limit %d, want 0 or more\n", flags.Limit)
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	out := newOutput(false)
+	defer out.Flush()
+
+	limit := 1
+	if flags.All {
+		limit = flags.Limit
+	}
+	countsOnly := flags.All && flags.CountsOnly
+	reader := parser.NewPencilmarksReader(openInput(fs, flags.InputFile, flags.Input))
+	for n := 0; ; n++ {
+		puzzle, candidates, err := reader.Next()
+		if err == io.EOF && n != 0 {
+			return
+		}
+		if err != nil {
+			out.fail(err)
+		}
+		if flags.DontSolve {
+			text, err := format.Format(puzzle, flags.OutputFormat)
+			if err != nil {
+				out.fail(err)
+			}
+			fmt.Fprintln(out, text)
+			continue
+		}
+		s, err := solver.NewRestrictedSolver(puzzle, candidates)
+		if err != nil {
+			out.fail(err)
+		}
+		count, more := 0, false
+		// Only a count needs one more solution, to tell whether the limit was reached
+		for (limit == 0 || count < limit || countsOnly) && s.Solve() {
+			if limit != 0 && count == limit {
+				more = true
+				break
+			}
+			count++
+			if !countsOnly {
+				text, err := format.Format(s.Solution(), flags.OutputFormat)
+				if err != nil {
+					out.fail(err)
+				}
+				fmt.Fprintln(out, text)
+			}
+		}
+		switch {
+		case countsOnly && more:
+			fmt.Fprintf(out, "%d+ (limit reached)\n", count)
+		case countsOnly:
+			fmt.Fprintf(out, "%d\n", count)
+		case count == 0:
+			fmt.Fprintln(out, "No solution")
+		}
+	}
+}
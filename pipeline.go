@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/AndrewSav/sudocoo/pkg/cache"
+	"github.com/AndrewSav/sudocoo/pkg/filter"
+	"github.com/AndrewSav/sudocoo/pkg/format"
+	"github.com/AndrewSav/sudocoo/pkg/packed"
+	"github.com/AndrewSav/sudocoo/pkg/parser"
+	"github.com/AndrewSav/sudocoo/pkg/rating"
+	"github.com/AndrewSav/sudocoo/pkg/recognize"
+	"github.com/AndrewSav/sudocoo/pkg/solver"
+	"github.com/AndrewSav/sudocoo/pkg/store"
+)
+
+// Everything the writer needs to print a puzzle and to account for it in the stats
+type puzzleResult struct {
+	puzzle       [9][9]int
+	records      []string      // formatted output records, a solution each, ready to print, unless they were printed as they were found
+	count        int           // the number of solutions found
+	limitHit     bool          // there are more solutions than the per puzzle limit
+	solved       int           // the solutions the search found, there may be one over the count when it goes past the limit
+	iterations   int           // running total of iterations after the last solution found
+	iterationsAt []int         // running total of iterations after each solution found, only with --total-limit
+	stored       int           // the solution count to record in the store, only with --db
+	solution     string        // the first solution in the inline format, only with --template and --results
+	level        rating.Level  // only with --results
+	duration     time.Duration // how long solving and formatting the puzzle took
+	skipped      bool          // does not match --filter, nothing is output for it
+	stuck        bool          // the logic engine could not solve it, see '-engine'
+	trace        string        // the steps of the search, only with --search-trace
+	timedOut     bool          // the search gave up at -timeout, there may be more solutions
+}
+
+// Returns the next puzzle, io.EOF when there are no more
+type puzzleSource func() ([9][9]int, error)
+
+// Finds the fastest way to read the puzzles. Text files are memory mapped and parsed in place,
+// anything else (-i, packed, b64 and plugin formats, images, pipes) goes through the rune scanner. The returned function
+// releases the input
+func openPuzzles(flags *Flags) (puzzleSource, func()) {
+	if flags.InputFile != "" {
+		data, unmap, err := parser.MapFile(flags.InputFile)
+		if err == nil && !packed.Encoded(data) && !recognize.IsImage(data) {
+			return parser.NewBytesReader(data).Next, func() { unmap() }
+		}
+		if err == nil {
+			unmap()
+		}
+	}
+	scanner := parser.CreateInputScanner(flags.InputReader)
+	return func() ([9][9]int, error) { return parser.ReadNextPuzzleInput(scanner) }, func() {}
+}
+
+// Solves a single puzzle and formats its solutions. Without '-a' only the first solution is looked for.
+// Since no puzzle can print more than --total-limit solutions the search stops there, it is up
+// to the writer to cut it down further to what is left of the total limit. With -timeout the search
+// gives up on the puzzle when it is out of time, keeping the solutions found so far. When emit is
+// not nil the solutions are handed to it as they are found instead of being kept in the result
+func solvePuzzle(ctx context.Context, flags *Flags, withStore bool, results *cache.Cache, puzzle [9][9]int, emit func(record string)) (*puzzleResult, error) {
+	start := time.Now()
+	r := &puzzleResult{puzzle: puzzle}
+	if emit == nil {
+		emit = func(record string) { r.records = append(r.records, record) }
+	}
+	defer func() { r.duration = time.Since(start) }()
+	s, err := solvers.GetVariant(puzzle, flags.Variant)
+	if err != nil {
+		return nil, err
+	}
+	defer solvers.Put(s)
+
+	if flags.DontSolve {
+		text, err := format.Format(puzzle, flags.OutputFormat)
+		if err != nil {
+			return nil, err
+		}
+		r.records = append(r.records, formatRecord(flags, text))
+		return r, nil
+	}
+
+	if flags.Proof {
+		if err := solveProof(flags, s, r); err != nil {
+			return nil, err
+		}
+		return r, nil
+	}
+
+	if flags.Engine == engineLogic {
+		if err := solveLogic(flags, r); err != nil {
+			return nil, err
+		}
+		return r, nil
+	}
+
+	if flags.Uniqueness {
+		solveUniqueness(flags, s, r)
+		return r, nil
+	}
+
+	if flags.Rate {
+		if err := solveRating(flags, s, r); err != nil {
+			return nil, err
+		}
+		return r, nil
+	}
+
+	print := !(flags.ShowStats && flags.Quiet) && !(flags.All && flags.CountsOnly) && flags.Template == nil
+	if flags.Sample != 0 {
+		// A puzzle without solutions gets no samples and is reported as having none
+		samples, _ := solver.Sample(puzzle, flags.Sample, rand.New(rand.NewPCG(flags.Seed, flags.Seed)))
+		for _, sample := range samples {
+			// Sampling is not a search, there are no iterations to account for
+			r.addSolution(flags, 0)
+			r.count++
+			if print {
+				text, err := formatSolution(*flags, solver.NewSolution(sample, puzzle))
+				if err != nil {
+					return nil, err
+				}
+				emit(formatRecord(flags, text))
+			}
+		}
+	}
+	// Only a count of all the solutions up to the limit is worth keeping, the total limit cuts counts short.
+	// A count taken from the cache adds no iterations to the stats
+	var key string
+	cached := false
+	if results != nil && flags.All && flags.CountsOnly && flags.TotalLimit == 0 && flags.Sample == 0 {
+		key = cache.Key(fmt.Sprintf("count limit=%d", flags.Limit), puzzle)
+		var c cachedCount
+		if cached, err = results.Get(key, &c); err != nil {
+			return nil, err
+		}
+		r.count, r.limitHit = c.Count, c.LimitReached
+	}
+	var trace *strings.Builder
+	if flags.SearchTrace && !cached {
+		trace = traceSearch(s, puzzle)
+	}
+	var e solver.Engine = s
+	if flags.Engine == engineDLX {
+		if e, err = solver.NewDLXSolver(puzzle); err != nil {
+			return nil, err
+		}
+	}
+	solve := e.Solve
+	if flags.Engine != engineDLX {
+		// The search stops when the run is interrupted, and with -timeout when the puzzle is out of time
+		if flags.Timeout != 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, flags.Timeout)
+			defer cancel()
+		}
+		solve = func() bool {
+			found := s.SolveContext(ctx)
+			// An interrupted run is cancelled rather than out of time
+			r.timedOut = !found && errors.Is(ctx.Err(), context.DeadlineExceeded)
+			return found
+		}
+	}
+	iterations := 0
+	for flags.Sample == 0 && !cached && solve() {
+		iterations += e.Iterations()
+		r.addSolution(flags, iterations)
+		if flags.All && flags.Limit != 0 && r.count == flags.Limit {
+			// This solution is over the limit, so we know there are more than the limit
+			r.limitHit = true
+			break
+		}
+		r.count++
+		if print {
+			text, err := formatSolution(*flags, solver.NewSolution(e.Solution(), puzzle))
+			if err != nil {
+				return nil, err
+			}
+			emit(formatRecord(flags, text))
+		}
+		if (flags.Template != nil || flags.Results != "") && r.count == 1 {
+			r.solution = format.Inline(e.Solution())
+		}
+		if !flags.All || flags.TotalLimit != 0 && r.count >= flags.TotalLimit {
+			break
+		}
+	}
+	// The search of an interrupted run is not over, there is nothing to report for the puzzle
+	if err := ctx.Err(); errors.Is(err, context.Canceled) {
+		return nil, err
+	}
+
+	if trace != nil {
+		r.trace = trace.String()
+	}
+
+	if key != "" && !cached {
+		if err := results.Put(key, cachedCount{Count: r.count, LimitReached: r.limitHit}); err != nil {
+			return nil, err
+		}
+	}
+
+	if withStore {
+		if r.stored, err = countSolutions(puzzle, store.MultipleSolutions); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Makes the solver write its steps for '--search-trace', a line each, to the returned builder
+func traceSearch(s *solver.Solver, puzzle [9][9]int) *strings.Builder {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Search trace of %s\n", format.Inline(puzzle))
+	s.Trace(func(e solver.TraceEvent) {
+		fmt.Fprintf(&sb, "%8d depth %2d: %s\n", e.Iteration, e.Depth, e)
+	})
+	return &sb
+}
+
+// Returns what --filter expressions are evaluated against
+func (r *puzzleResult) filterRecord() filter.Record {
+	return filter.Record{Puzzle: r.puzzle, Solutions: r.count, LimitReached: r.limitHit, Duration: r.duration, Level: r.level, Iterations: r.iterations}
+}
+
+// Accounts for a solution found when the search had taken that many iterations in all. The total after
+// each of the solutions is only kept when --total-limit may leave some of them out
+func (r *puzzleResult) addSolution(flags *Flags, iterations int) {
+	r.solved++
+	r.iterations = iterations
+	if flags.TotalLimit != 0 {
+		r.iterationsAt = append(r.iterationsAt, iterations)
+	}
+}
+
+// Returns the running total of iterations after the first n solutions found, 0 for none
+func (r *puzzleResult) iterationsAfter(n int) int {
+	if n == 0 {
+		return 0
+	}
+	if n < r.solved {
+		return r.iterationsAt[n-1]
+	}
+	return r.iterations
+}
+
+// A solution count as it is kept in the cache
+type cachedCount struct {
+	Count        int  `json:"count"`
+	LimitReached bool `json:"limit_reached"`
+}
+
+// Terminates a formatted solution or puzzle the way it is printed
+func formatRecord(flags *Flags, s string) string {
+	if flags.NewLineAfterEachPuzzle {
+		return s + "\n\n"
+	}
+	return s + "\n"
+}
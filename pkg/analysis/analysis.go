@@ -0,0 +1,243 @@
+package analysis
+
+import (
+	"fmt"
+	"math/bits"
+	"slices"
+	"strings"
+)
+
+const sudokuSize = 9
+
+// Returns the number of givens (non-empty cells) in the puzzle
+func ClueCount(puzzle [sudokuSize][sudokuSize]int) int {
+	count := 0
+	for y := 0; y < sudokuSize; y++ {
+		for x := 0; x < sudokuSize; x++ {
+			if puzzle[y][x] != 0 {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// Checks whether the clue pattern stays the same when the grid is rotated by 180 degrees,
+// which is the symmetry most published puzzles have. Only cell positions are compared,
+// not the digits in them
+func IsSymmetric(puzzle [sudokuSize][sudokuSize]int) bool {
+	return Symmetries(puzzle)&Rotational180 != 0
+}
+
+// A set of symmetries of a clue pattern, as bits
+type Symmetry uint8
+
+const (
+	Rotational180    Symmetry = 1 << iota // the pattern is the same rotated by 180 degrees
+	Rotational90                          // the same rotated by 90 degrees, which implies 180 too
+	MirrorHorizontal                      // the same flipped top to bottom
+	MirrorVertical                        // the same flipped left to right
+	Diagonal                              // the same flipped around the main diagonal
+	AntiDiagonal                          // the same flipped around the anti-diagonal
+)
+
+// No symmetry at all
+const NoSymmetry Symmetry = 0
+
+var symmetryNames = []struct {
+	symmetry Symmetry
+	name     string
+}{
+	{Rotational180, "rotational-180"},
+	{Rotational90, "rotational-90"},
+	{MirrorHorizontal, "mirror-horizontal"},
+	{MirrorVertical, "mirror-vertical"},
+	{Diagonal, "diagonal"},
+	{AntiDiagonal, "anti-diagonal"},
+}
+
+// Returns the names of the symmetries joined with '+', or "none"
+func (s Symmetry) String() string {
+	var names []string
+	for _, n := range symmetryNames {
+		if s&n.symmetry != 0 {
+			names = append(names, n.name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, "+")
+}
+
+// Returns the names ParseSymmetry accepts
+func SymmetryNames() []string {
+	names := []string{"none"}
+	for _, n := range symmetryNames {
+		names = append(names, n.name)
+	}
+	return names
+}
+
+// Returns the symmetry with the name, see SymmetryNames
+func ParseSymmetry(name string) (Symmetry, error) {
+	if name == "none" {
+		return NoSymmetry, nil
+	}
+	for _, n := range symmetryNames {
+		if n.name == name {
+			return n.symmetry, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown symmetry %s, want one of %s", name, strings.Join(SymmetryNames(), ", "))
+}
+
+// Where each symmetry moves a cell to
+var symmetryTransforms = func() []struct {
+	symmetry Symmetry
+	cell     func(y, x int) (int, int)
+} {
+	const last = sudokuSize - 1
+	return []struct {
+		symmetry Symmetry
+		cell     func(y, x int) (int, int)
+	}{
+		{Rotational180, func(y, x int) (int, int) { return last - y, last - x }},
+		{Rotational90, func(y, x int) (int, int) { return x, last - y }},
+		{MirrorHorizontal, func(y, x int) (int, int) { return last - y, x }},
+		{MirrorVertical, func(y, x int) (int, int) { return y, last - x }},
+		{Diagonal, func(y, x int) (int, int) { return x, y }},
+		{AntiDiagonal, func(y, x int) (int, int) { return last - x, last - y }},
+	}
+}()
+
+// Returns the cells that have to be all clues or all empty for a clue pattern to have the symmetries:
+// the cell and every cell the symmetries, applied any number of times, move it to. Cells are row
+// and column pairs
+func (s Symmetry) Orbit(y, x int) [][2]int {
+	orbit := [][2]int{{y, x}}
+	for i := 0; i < len(orbit); i++ {
+		for _, t := range symmetryTransforms {
+			if s&t.symmetry == 0 {
+				continue
+			}
+			ty, tx := t.cell(orbit[i][0], orbit[i][1])
+			if !slices.Contains(orbit, [2]int{ty, tx}) {
+				orbit = append(orbit, [2]int{ty, tx})
+			}
+		}
+	}
+	return orbit
+}
+
+// Classifies the clue pattern by the transformations that keep it the same.
+// Only cell positions are compared, not the digits in them
+func Symmetries(puzzle [sudokuSize][sudokuSize]int) Symmetry {
+	result := NoSymmetry
+	for _, t := range symmetryTransforms {
+		same := true
+		for y := 0; y < sudokuSize && same; y++ {
+			for x := 0; x < sudokuSize; x++ {
+				ty, tx := t.cell(y, x)
+				if (puzzle[y][x] == 0) != (puzzle[ty][tx] == 0) {
+					same = false
+					break
+				}
+			}
+		}
+		if same {
+			result |= t.symmetry
+		}
+	}
+	return result
+}
+
+// Checks that the grid is complete, follows the rules and keeps all the givens of the puzzle
+func IsSolutionOf(solution, puzzle [sudokuSize][sudokuSize]int) bool {
+	var rows, columns, boxes [sudokuSize]uint16
+	for y := 0; y < sudokuSize; y++ {
+		for x := 0; x < sudokuSize; x++ {
+			digit := solution[y][x]
+			if digit < 1 || digit > sudokuSize {
+				return false
+			}
+			if puzzle[y][x] != 0 && puzzle[y][x] != digit {
+				return false
+			}
+			bit := uint16(1) << digit
+			box := y/3*3 + x/3
+			if rows[y]&bit != 0 || columns[x]&bit != 0 || boxes[box]&bit != 0 {
+				return false
+			}
+			rows[y] |= bit
+			columns[x] |= bit
+			boxes[box] |= bit
+		}
+	}
+	return true
+}
+
+// Returns the digits each empty cell can still take given the other cells, as bit masks
+// with bit d set for digit d. Filled cells get 0
+func Candidates(puzzle [sudokuSize][sudokuSize]int) [sudokuSize][sudokuSize]uint16 {
+	var candidates [sudokuSize][sudokuSize]uint16
+	for y := 0; y < sudokuSize; y++ {
+		for x := 0; x < sudokuSize; x++ {
+			if puzzle[y][x] != 0 {
+				continue
+			}
+			var used uint16
+			by, bx := y/3*3, x/3*3
+			for i := 0; i < sudokuSize; i++ {
+				used |= 1<<puzzle[y][i] | 1<<puzzle[i][x] | 1<<puzzle[by+i/3][bx+i%3]
+			}
+			candidates[y][x] = ^used & 0x3fe
+		}
+	}
+	return candidates
+}
+
+// A cell that can be filled in by the easiest techniques
+type Single struct {
+	Row, Column int // 0-based
+	Digit       int
+	Technique   string // 'naked single' or 'hidden single in a row|column|box'
+}
+
+// Looks for a naked single (a cell with only one candidate) and then for a hidden single
+// (a digit with only one place in a row, column or box). Returns false if there is neither
+func FindSingle(puzzle [sudokuSize][sudokuSize]int) (Single, bool) {
+	candidates := Candidates(puzzle)
+	for y := 0; y < sudokuSize; y++ {
+		for x := 0; x < sudokuSize; x++ {
+			if c := candidates[y][x]; c != 0 && c&(c-1) == 0 {
+				return Single{Row: y, Column: x, Digit: bits.TrailingZeros16(c), Technique: "naked single"}, true
+			}
+		}
+	}
+	units := []struct {
+		name string
+		cell func(unit, i int) (int, int)
+	}{
+		{"row", func(unit, i int) (int, int) { return unit, i }},
+		{"column", func(unit, i int) (int, int) { return i, unit }},
+		{"box", func(unit, i int) (int, int) { return unit/3*3 + i/3, unit%3*3 + i%3 }},
+	}
+	for _, u := range units {
+		for unit := 0; unit < sudokuSize; unit++ {
+			for d := 1; d <= sudokuSize; d++ {
+				places, py, px := 0, 0, 0
+				for i := 0; i < sudokuSize; i++ {
+					y, x := u.cell(unit, i)
+					if candidates[y][x]&(1<<d) != 0 {
+						places, py, px = places+1, y, x
+					}
+				}
+				if places == 1 {
+					return Single{Row: py, Column: px, Digit: d, Technique: "hidden single in a " + u.name}, true
+				}
+			}
+		}
+	}
+	return Single{}, false
+}
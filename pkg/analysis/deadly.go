@@ -0,0 +1,45 @@
+package analysis
+
+import "github.com/AndrewSav/sudocoo/pkg/solver"
+
+// Given two different solutions of a puzzle, finds a solution of it that differs from the first one in as few
+// cells as can be had by shrinking the difference with the second one. The result differs from the first
+// solution in a deadly pattern: a set of cells that can swap their digits around without breaking the
+// rules, and that no smaller set inside it can. At least one of these cells must be a given for the
+// puzzle to be unique, which is what makes it a more useful proof of non-uniqueness than two arbitrary
+// solutions, those can differ all over the grid.
+// The search only ever fills in the cells the solutions differ in, so it takes a few quick solves
+func DeadlyPattern(first, second [sudokuSize][sudokuSize]int) [sudokuSize][sudokuSize]int {
+	for shrunk := true; shrunk; {
+		shrunk = false
+		for y := range sudokuSize {
+			for x := range sudokuSize {
+				if first[y][x] == second[y][x] {
+					continue
+				}
+				// Keep every cell the solutions agree in and this one as they are in the first solution,
+				// and look for a solution other than the first among the rest
+				var puzzle [sudokuSize][sudokuSize]int
+				for i := range sudokuSize {
+					for j := range sudokuSize {
+						if first[i][j] == second[i][j] || i == y && j == x {
+							puzzle[i][j] = first[i][j]
+						}
+					}
+				}
+				s, err := solver.NewSolver(puzzle)
+				if err != nil {
+					continue
+				}
+				for s.Solve() {
+					if solution := s.Solution(); solution != first {
+						second = solution
+						shrunk = true
+						break
+					}
+				}
+			}
+		}
+	}
+	return second
+}
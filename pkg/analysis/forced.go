@@ -0,0 +1,88 @@
+package analysis
+
+import (
+	"math/bits"
+
+	"github.com/AndrewSav/sudocoo/pkg/solver"
+)
+
+// The digits each cell takes across all the solutions of a puzzle, as masks with bit d-1 set for digit d.
+// A cell with a single digit is determined already: it has that digit in every solution
+type SolutionCandidates [sudokuSize][sudokuSize]uint16
+
+// How many more solutions are listed before trying the digits one by one
+const solutionsToList = 64
+
+// Finds the digits each cell can take in a solution of the puzzle. Puzzles with few solutions are done
+// by listing them all, others can have far too many for that. Then each digit not seen in a solution yet is
+// tried in its cell, and every solution found on the way marks all its digits as seen, so it takes at most
+// a solve per candidate.
+// Returns error when the puzzle is inconsistent or has no solution
+func FindSolutionCandidates(puzzle [sudokuSize][sudokuSize]int) (SolutionCandidates, error) {
+	var c SolutionCandidates
+	s, err := solver.NewSolver(puzzle)
+	if err != nil {
+		return c, err
+	}
+	if !s.Solve() {
+		return c, solver.ErrNoSolution
+	}
+	c.mark(s.Solution())
+	// A puzzle with a few solutions is done by listing them, a unique one most of all
+	for range solutionsToList {
+		if !s.Solve() {
+			return c, nil
+		}
+		c.mark(s.Solution())
+	}
+	for y := range sudokuSize {
+		for x := range sudokuSize {
+			if puzzle[y][x] != 0 {
+				continue
+			}
+			for digit := 1; digit <= sudokuSize; digit++ {
+				if c[y][x]&(1<<(digit-1)) != 0 {
+					continue
+				}
+				tried := puzzle
+				tried[y][x] = digit
+				// A digit that clashes with the givens makes the puzzle inconsistent, it is not a candidate either
+				if s, err := solver.NewSolver(tried); err == nil && s.Solve() {
+					c.mark(s.Solution())
+				}
+			}
+		}
+	}
+	return c, nil
+}
+
+func (c *SolutionCandidates) mark(solution [sudokuSize][sudokuSize]int) {
+	for y := range sudokuSize {
+		for x := range sudokuSize {
+			c[y][x] |= 1 << (solution[y][x] - 1)
+		}
+	}
+}
+
+// Returns the digits the cell takes across the solutions in increasing order
+func (c *SolutionCandidates) Digits(y, x int) []int {
+	var digits []int
+	for digit := 1; digit <= sudokuSize; digit++ {
+		if c[y][x]&(1<<(digit-1)) != 0 {
+			digits = append(digits, digit)
+		}
+	}
+	return digits
+}
+
+// Returns the grid with the cells that have the same digit in every solution filled in, givens included
+func (c *SolutionCandidates) Determined() (grid [sudokuSize][sudokuSize]int) {
+	for y := range sudokuSize {
+		for x := range sudokuSize {
+			if bits.OnesCount16(c[y][x]) == 1 {
+				grid[y][x] = bits.TrailingZeros16(c[y][x]) + 1
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,82 @@
+package analysis
+
+import (
+	"math/rand/v2"
+
+	"github.com/AndrewSav/sudocoo/pkg/solver"
+)
+
+// A rough measure of how hard a puzzle is, taken from the backtracking search alone.
+// How much the search has to guess depends a lot on the order it tries cells and digits in,
+// so the puzzle is searched several times, each time shuffled by a random transformation that
+// keeps it the same puzzle (relabeling digits, permuting bands, stacks, rows and columns within them
+// and transposing), and the statistics are averaged. It is much cheaper than rating by techniques and
+// correlates with it well enough to sort large collections roughly
+type SearchHardness struct {
+	Orders     int     // the number of search orders averaged over
+	DeadEnds   float64 // mean times the search had to backtrack, this is the score
+	Guesses    float64 // mean times the search picked one of several candidates
+	Iterations float64 // mean search iterations
+}
+
+// The number of search orders used when none is given
+const DefaultSearchOrders = 8
+
+// Measures the hardness of a puzzle over the number of random search orders, the same seed gives
+// the same orders. The search goes on until it finds a second solution or proves that there is none,
+// the way a uniqueness check would
+func MeasureSearchHardness(puzzle [sudokuSize][sudokuSize]int, orders int, seed uint64) (SearchHardness, error) {
+	if orders <= 0 {
+		orders = DefaultSearchOrders
+	}
+	random := rand.New(rand.NewPCG(seed, seed))
+	h := SearchHardness{Orders: orders}
+	for range orders {
+		s, err := solver.NewSolver(shuffle(puzzle, random))
+		if err != nil {
+			return SearchHardness{}, err
+		}
+		for found := 0; found < 2 && s.Solve(); found++ {
+		}
+		h.DeadEnds += float64(s.DeadEnds())
+		h.Guesses += float64(s.Guesses())
+		h.Iterations += float64(s.Iterations())
+	}
+	h.DeadEnds /= float64(orders)
+	h.Guesses /= float64(orders)
+	h.Iterations /= float64(orders)
+	return h, nil
+}
+
+// Returns a random equivalent of the puzzle
+func shuffle(puzzle [sudokuSize][sudokuSize]int, random *rand.Rand) (result [sudokuSize][sudokuSize]int) {
+	rows := shuffledLines(random)
+	columns := shuffledLines(random)
+	digits := random.Perm(sudokuSize)
+	transpose := random.IntN(2) == 1
+	for y := range sudokuSize {
+		for x := range sudokuSize {
+			digit := puzzle[rows[y]][columns[x]]
+			if digit != 0 {
+				digit = digits[digit-1] + 1
+			}
+			if transpose {
+				result[x][y] = digit
+			} else {
+				result[y][x] = digit
+			}
+		}
+	}
+	return
+}
+
+// Returns a random order of rows (or columns) that keeps each band (or stack) together
+func shuffledLines(random *rand.Rand) (lines [sudokuSize]int) {
+	bands := random.Perm(3)
+	for i, band := range bands {
+		for j, line := range random.Perm(3) {
+			lines[i*3+j] = band*3 + line
+		}
+	}
+	return
+}
@@ -0,0 +1,57 @@
+// Package animation records how a puzzle gets solved, a frame of the grid at each step, and writes
+// the frames as an asciinema cast or an animated GIF, for teaching materials and demos
+package animation
+
+const sudokuSize = 9
+
+// The grid at a point of the solve
+type Frame struct {
+	Grid    [sudokuSize][sudokuSize]int
+	Caption string // what happened, shown under the grid in casts
+}
+
+// Keeps at most a number of frames, spread evenly over a solve of any length: once there are more,
+// every other frame is dropped, and from then on only half as many of the new ones are kept
+type Recorder struct {
+	max    int
+	stride int // every stride-th frame added is kept
+	added  int
+	frames []Frame
+}
+
+// Returns a recorder that keeps at most limit frames, at least 2
+func NewRecorder(limit int) *Recorder {
+	return &Recorder{max: max(limit, 2), stride: 1}
+}
+
+func (r *Recorder) Add(f Frame) {
+	if r.added%r.stride == 0 {
+		r.frames = append(r.frames, f)
+		if len(r.frames) > r.max {
+			kept := r.frames[:0]
+			for i, f := range r.frames {
+				if i%2 == 0 {
+					kept = append(kept, f)
+				}
+			}
+			r.frames = kept
+			r.stride *= 2
+		}
+	}
+	r.added++
+}
+
+// Returns the frames kept
+func (r *Recorder) Frames() []Frame {
+	return r.frames
+}
+
+// Returns the cells that are different in the two grids
+func changed(previous, current [sudokuSize][sudokuSize]int) (result [sudokuSize][sudokuSize]bool) {
+	for y := range sudokuSize {
+		for x := range sudokuSize {
+			result[y][x] = previous[y][x] != current[y][x]
+		}
+	}
+	return
+}
@@ -0,0 +1,94 @@
+package animation
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Casts are asciicast version 2, see https://docs.asciinema.org/manual/asciicast/v2/: a JSON header
+// line, then a JSON line per output event with its time in seconds. Each frame clears the screen and
+// draws the grid with the caption under it
+
+const (
+	castWidth  = 60
+	castHeight = 15
+
+	clearScreen = "\x1b[H\x1b[2J"
+	colorFilled = "\x1b[36m"   // digits that are not givens
+	colorNew    = "\x1b[1;33m" // digits that changed since the frame before
+	colorReset  = "\x1b[0m"
+)
+
+// How long the last frame stays before the cast ends
+const hold = 2 * time.Second
+
+type castHeader struct {
+	Version int    `json:"version"`
+	Width   int    `json:"width"`
+	Height  int    `json:"height"`
+	Title   string `json:"title,omitempty"`
+}
+
+// Writes the frames as a cast, a frame every delay. The puzzle tells the givens apart
+func WriteCast(w io.Writer, puzzle [sudokuSize][sudokuSize]int, frames []Frame, delay time.Duration, title string) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	if err := enc.Encode(castHeader{Version: 2, Width: castWidth, Height: castHeight, Title: title}); err != nil {
+		return err
+	}
+	previous := puzzle
+	var at time.Duration
+	for i, f := range frames {
+		at = time.Duration(i) * delay
+		event := []any{at.Seconds(), "o", castFrame(puzzle, previous, f)}
+		if err := enc.Encode(event); err != nil {
+			return err
+		}
+		previous = f.Grid
+	}
+	// An empty event keeps the last frame on the screen for a while
+	if err := enc.Encode([]any{(at + hold).Seconds(), "o", ""}); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// Draws a frame for a terminal, lines end with CR LF as the terminal gets them
+func castFrame(puzzle, previous [sudokuSize][sudokuSize]int, f Frame) string {
+	changes := changed(previous, f.Grid)
+	var sb strings.Builder
+	sb.WriteString(clearScreen)
+	for y := range sudokuSize {
+		if y != 0 && y%3 == 0 {
+			sb.WriteString("------+-------+------\r\n")
+		}
+		for x := range sudokuSize {
+			if x != 0 {
+				sb.WriteByte(' ')
+				if x%3 == 0 {
+					sb.WriteString("| ")
+				}
+			}
+			digit := f.Grid[y][x]
+			switch {
+			case digit == 0:
+				sb.WriteByte('.')
+			case changes[y][x]:
+				fmt.Fprintf(&sb, "%s%d%s", colorNew, digit, colorReset)
+			case puzzle[y][x] == 0:
+				fmt.Fprintf(&sb, "%s%d%s", colorFilled, digit, colorReset)
+			default:
+				fmt.Fprintf(&sb, "%d", digit)
+			}
+		}
+		sb.WriteString("\r\n")
+	}
+	if f.Caption != "" {
+		sb.WriteString("\r\n" + f.Caption + "\r\n")
+	}
+	return sb.String()
+}
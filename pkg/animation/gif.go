@@ -0,0 +1,151 @@
+package animation
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+	"time"
+)
+
+// GIFs draw the grid only, the captions are left out. After the first frame, each frame is only the
+// part of the picture that changed, which keeps long solves small
+
+const (
+	cellPixels = 26
+	thinLine   = 1
+	thickLine  = 2
+	digitScale = 2 // pixels per dot of the digit font
+	gridPixels = sudokuSize*cellPixels + thickLine
+	lastDelay  = 200 // hundredths of a second the last frame stays
+)
+
+// Indexes of the palette
+const (
+	white uint8 = iota
+	black
+	gray
+	blue   // digits that are not givens
+	orange // digits that changed since the frame before
+)
+
+var palette = color.Palette{
+	white:  color.White,
+	black:  color.Black,
+	gray:   color.Gray{Y: 160},
+	blue:   color.RGBA{0x1f, 0x5f, 0xbf, 0xff},
+	orange: color.RGBA{0xe0, 0x60, 0x00, 0xff},
+}
+
+// Digits in 5x7 dots
+var font = [sudokuSize + 1][7]string{
+	1: {"..#..", ".##..", "..#..", "..#..", "..#..", "..#..", ".###."},
+	2: {".###.", "#...#", "....#", "...#.", "..#..", ".#...", "#####"},
+	3: {"#####", "...#.", "..#..", "...#.", "....#", "#...#", ".###."},
+	4: {"...#.", "..##.", ".#.#.", "#..#.", "#####", "...#.", "...#."},
+	5: {"#####", "#....", "####.", "....#", "....#", "#...#", ".###."},
+	6: {"..##.", ".#...", "#....", "####.", "#...#", "#...#", ".###."},
+	7: {"#####", "....#", "...#.", "..#..", ".#...", ".#...", ".#..."},
+	8: {".###.", "#...#", "#...#", ".###.", "#...#", "#...#", ".###."},
+	9: {".###.", "#...#", "#...#", ".####", "....#", "...#.", ".##.."},
+}
+
+// Writes the frames as an animated GIF that loops, a frame every delay. The puzzle tells the givens apart
+func WriteGIF(w io.Writer, puzzle [sudokuSize][sudokuSize]int, frames []Frame, delay time.Duration) error {
+	anim := &gif.GIF{}
+	hundredths := max(1, int(delay/(10*time.Millisecond)))
+	previous := puzzle
+	var last *image.Paletted
+	for _, f := range frames {
+		img := drawGrid(puzzle, previous, f.Grid)
+		previous = f.Grid
+		if last == nil {
+			anim.Image = append(anim.Image, img)
+			anim.Delay = append(anim.Delay, hundredths)
+			anim.Disposal = append(anim.Disposal, gif.DisposalNone)
+			last = img
+			continue
+		}
+		r := difference(last, img)
+		last = img
+		if r.Empty() {
+			// Nothing to draw, the frame before stays on longer
+			anim.Delay[len(anim.Delay)-1] += hundredths
+			continue
+		}
+		anim.Image = append(anim.Image, img.SubImage(r).(*image.Paletted))
+		anim.Delay = append(anim.Delay, hundredths)
+		anim.Disposal = append(anim.Disposal, gif.DisposalNone)
+	}
+	if len(anim.Delay) != 0 {
+		anim.Delay[len(anim.Delay)-1] += lastDelay
+	}
+	return gif.EncodeAll(w, anim)
+}
+
+// Draws the grid with the digits of the frame
+func drawGrid(puzzle, previous, grid [sudokuSize][sudokuSize]int) *image.Paletted {
+	img := image.NewPaletted(image.Rect(0, 0, gridPixels, gridPixels), palette)
+	fill := func(r image.Rectangle, c uint8) {
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				img.SetColorIndex(x, y, c)
+			}
+		}
+	}
+	for i := 0; i <= sudokuSize; i++ {
+		p := i * cellPixels
+		width, c := thinLine, gray
+		if i%3 == 0 {
+			width, c = thickLine, black
+		}
+		fill(image.Rect(p, 0, p+width, gridPixels), c)
+		fill(image.Rect(0, p, gridPixels, p+width), c)
+	}
+	// The thick lines are drawn again so that the thin ones do not cross them
+	for i := 0; i <= sudokuSize; i += 3 {
+		p := i * cellPixels
+		fill(image.Rect(p, 0, p+thickLine, gridPixels), black)
+		fill(image.Rect(0, p, gridPixels, p+thickLine), black)
+	}
+	changes := changed(previous, grid)
+	for y := range sudokuSize {
+		for x := range sudokuSize {
+			digit := grid[y][x]
+			if digit == 0 {
+				continue
+			}
+			c := black
+			if changes[y][x] {
+				c = orange
+			} else if puzzle[y][x] == 0 {
+				c = blue
+			}
+			// Centered in the cell
+			left := x*cellPixels + (cellPixels+thickLine-5*digitScale)/2
+			top := y*cellPixels + (cellPixels+thickLine-7*digitScale)/2
+			for dy, row := range font[digit] {
+				for dx, dot := range row {
+					if dot == '#' {
+						fill(image.Rect(left+dx*digitScale, top+dy*digitScale, left+(dx+1)*digitScale, top+(dy+1)*digitScale), c)
+					}
+				}
+			}
+		}
+	}
+	return img
+}
+
+// Returns the bounds of the pixels that differ in the two images of the same size
+func difference(a, b *image.Paletted) image.Rectangle {
+	var r image.Rectangle
+	bounds := a.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if a.ColorIndexAt(x, y) != b.ColorIndexAt(x, y) {
+				r = r.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return r
+}
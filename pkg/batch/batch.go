@@ -0,0 +1,253 @@
+// Package batch runs a stream of work items on a pool of workers and delivers the results,
+// in the input order unless told otherwise. The command line solver, the batch jobs of the
+// server and the queue worker all go through it, so that they spread the work, report the
+// results and react to errors the same way
+package batch
+
+import (
+	"context"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// What to do when the work on an item fails
+type ErrorPolicy int
+
+const (
+	// Stop at the first failed item, once all the items before it are delivered.
+	// The failed item is not delivered, Run returns its error
+	Stop ErrorPolicy = iota
+	// Deliver the failed item with its error and go on with the rest
+	Continue
+)
+
+// How many results may wait for their turn per worker by default
+const defaultWindow = 4
+
+// The zero value runs a worker per CPU, delivers in order and stops at the first error
+type Config struct {
+	Workers   int         // the number of items worked on at the same time, 0 is one per CPU
+	Window    int         // items each worker may be ahead of the delivery, 0 is 4. Bounds the memory held by results waiting for a slow item
+	Unordered bool        // deliver the results as soon as they are ready instead of in the input order
+	Errors    ErrorPolicy // what a failed item does to the run
+	Inline    bool        // work on the items one at a time on the calling goroutine, each between the delivery of the one before and its own. Workers, Window and Unordered are ignored
+}
+
+// A result as it is delivered
+type Item[T any] struct {
+	Index int   // 0-based position of the item in the input
+	Value T     // whatever the work returned
+	Err   error // the work failed, only delivered with the Continue policy
+}
+
+// Results that know how many solutions and iterations they account for
+type Tallier interface {
+	Tally() (solutions, iterations int)
+}
+
+// Totals over the delivered items
+type Stats struct {
+	Items      int // items delivered
+	Failed     int // items that failed, with the Stop policy this is at most one
+	Solutions  int // solutions reported by the results that are a Tallier
+	Iterations int // iterations reported by the results that are a Tallier
+}
+
+// The error that stopped the run, with the position of the item it came from
+type Error struct {
+	Index int
+	Err   error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Internal result, err marks source errors apart from the work ones
+type result[T any] struct {
+	item      Item[T]
+	sourceErr error
+}
+
+// Reads items with next until it returns io.EOF, works on them with work on the worker pool and
+// hands the results to deliver, which is only ever called from the calling goroutine.
+//
+// An error from next ends the input, the items read before it are still worked on and delivered,
+// then Run returns it as an *Error. A failed item is handled according to the error policy.
+// When deliver returns false or ctx is canceled, Run returns without waiting for the work in progress
+func Run[In, Out any](ctx context.Context, config Config, next func() (In, error), work func(context.Context, In) (Out, error), deliver func(Item[Out]) bool) (Stats, error) {
+	if config.Inline {
+		return runInline(ctx, config, next, work, deliver)
+	}
+	workers := config.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	window := config.Window
+	if window <= 0 {
+		window = defaultWindow
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type task struct {
+		index int
+		value In
+		err   error
+	}
+	tasks := make(chan task, workers)
+	results := make(chan result[Out], workers)
+	tokens := make(chan struct{}, workers*window)
+
+	go func() {
+		defer close(tasks)
+		for index := 0; ; index++ {
+			select {
+			case tokens <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			value, err := next()
+			// Only io.EOF itself ends the input, an error wrapping it is a failure
+			if err == io.EOF {
+				return
+			}
+			select {
+			case tasks <- task{index: index, value: value, err: err}:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range tasks {
+				r := result[Out]{item: Item[Out]{Index: t.index}, sourceErr: t.err}
+				if t.err == nil {
+					r.item.Value, r.item.Err = work(ctx, t.value)
+				}
+				select {
+				case results <- r:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var (
+		stats     Stats
+		sourceErr error
+	)
+	// Returns false when the run has to stop, with the error if any
+	handle := func(r result[Out]) (bool, error) {
+		<-tokens
+		if r.sourceErr != nil {
+			// Nothing comes after it, but the items before it may still be in the works
+			sourceErr = &Error{Index: r.item.Index, Err: r.sourceErr}
+			return true, nil
+		}
+		if r.item.Err != nil {
+			stats.Failed++
+			if config.Errors == Stop {
+				return false, &Error{Index: r.item.Index, Err: r.item.Err}
+			}
+		}
+		stats.add(r.item.Value)
+		return deliver(r.item), nil
+	}
+
+	// Results come in the order they are finished, in order mode keep them until their turn
+	pending := map[int]result[Out]{}
+	turn := 0
+	for {
+		var r result[Out]
+		select {
+		case received, ok := <-results:
+			if !ok {
+				if sourceErr != nil {
+					return stats, sourceErr
+				}
+				return stats, ctx.Err()
+			}
+			r = received
+		case <-ctx.Done():
+			return stats, ctx.Err()
+		}
+		if config.Unordered {
+			if ok, err := handle(r); !ok {
+				return stats, err
+			}
+			continue
+		}
+		pending[r.item.Index] = r
+		for {
+			r, ok := pending[turn]
+			if !ok {
+				break
+			}
+			delete(pending, turn)
+			turn++
+			if ok, err := handle(r); !ok {
+				return stats, err
+			}
+		}
+	}
+}
+
+// Accounts for a delivered item
+func (s *Stats) add(value any) {
+	s.Items++
+	if t, ok := value.(Tallier); ok {
+		solutions, iterations := t.Tally()
+		s.Solutions += solutions
+		s.Iterations += iterations
+	}
+}
+
+// Same as Run, without the workers: nothing is read or worked on ahead, so the work may write
+// output of its own, it comes in the order of the items. The context is checked between the items
+func runInline[In, Out any](ctx context.Context, config Config, next func() (In, error), work func(context.Context, In) (Out, error), deliver func(Item[Out]) bool) (Stats, error) {
+	var stats Stats
+	for index := 0; ; index++ {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		value, err := next()
+		if err == io.EOF {
+			return stats, nil
+		}
+		if err != nil {
+			return stats, &Error{Index: index, Err: err}
+		}
+		item := Item[Out]{Index: index}
+		item.Value, item.Err = work(ctx, value)
+		if item.Err != nil {
+			stats.Failed++
+			if config.Errors == Stop {
+				return stats, &Error{Index: index, Err: item.Err}
+			}
+		}
+		stats.add(item.Value)
+		if !deliver(item) {
+			return stats, nil
+		}
+	}
+}
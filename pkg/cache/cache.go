@@ -0,0 +1,115 @@
+// Package cache keeps the results of expensive analyses, like solution counts and hardness measures,
+// in a file, so that running an analysis again over overlapping collections does not redo the work.
+// Results are keyed by the canonical fingerprint of the puzzle, so a result found for a puzzle is reused
+// for all the puzzles equivalent to it, which have the same solution count, rating and so on.
+//
+// The file is in the JSON Lines format, an entry per line, and entries are only ever appended.
+// A later entry for the same key wins, so the file can be concatenated with another cache file
+// or trimmed with ordinary text tools.
+package cache
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/AndrewSav/sudocoo/pkg/canon"
+)
+
+const sudokuSize = 9
+
+// A line of the cache file
+type entry struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+// A cache open for reading and adding results. It is safe for concurrent use
+type Cache struct {
+	mu      sync.Mutex
+	file    *os.File // opened for appending
+	entries map[string]json.RawMessage
+}
+
+// Opens the cache, creating the file if it does not exist
+func Open(path string) (*Cache, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	c := &Cache{file: file, entries: map[string]json.RawMessage{}}
+	reader := bufio.NewReader(file)
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if len(data) > 0 {
+			var e entry
+			if jerr := json.Unmarshal(data, &e); jerr != nil {
+				file.Close()
+				return nil, fmt.Errorf("%s line %d: %v", path, line, jerr)
+			}
+			c.entries[e.Key] = e.Value
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (c *Cache) Close() error {
+	return c.file.Close()
+}
+
+// Returns the key of a result of the puzzle. The kind names the analysis along with whatever
+// parameters change its result, e.g. "count limit=1000", results of different kinds never mix
+func Key(kind string, puzzle [sudokuSize][sudokuSize]int) string {
+	return kind + " " + canon.Fingerprint(puzzle)
+}
+
+// Looks the key up and decodes the result into value, reports whether it was there
+func (c *Cache) Get(key string, value any) (bool, error) {
+	c.mu.Lock()
+	data, ok := c.entries[key]
+	c.mu.Unlock()
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal(data, value); err != nil {
+		return false, fmt.Errorf("cached %s: %v", key, err)
+	}
+	return true, nil
+}
+
+// Stores the result under the key, replacing the one there was
+func (c *Cache) Put(key string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	line, err := json.Marshal(entry{Key: key, Value: data})
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	c.entries[key] = data
+	return nil
+}
+
+// Number of results in the cache
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
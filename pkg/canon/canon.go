@@ -0,0 +1,160 @@
+package canon
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Two puzzles are equivalent if one can be turned into the other by the transformations
+// that keep a sudoku valid: relabeling the digits, transposing, permuting bands and stacks,
+// and permuting rows within a band and columns within a stack. There are 2*6^8 geometric
+// transformations, and for each of them the digits are relabeled in the order of their
+// first appearance. The canonical form is the lexicographically smallest result
+// (empty cells are zeroes), so all equivalent puzzles have the same canonical form.
+
+// Implementation note: for each of the 2*1296 column arrangements the rows are chosen
+// one by one with a depth-first search, comparing each finished row with the best grid
+// found so far. A row that compares greater cuts off the whole subtree, so in practice
+// only a small fraction of the 3,359,232 transformations is looked at.
+
+const sudokuSize = 9
+
+// All permutations of three elements, used for bands, stacks, rows in a band and columns in a stack
+var perms3 = [6][3]int{{0, 1, 2}, {0, 2, 1}, {1, 0, 2}, {1, 2, 0}, {2, 0, 1}, {2, 1, 0}}
+
+type canonizer struct {
+	grid       [sudokuSize][sudokuSize]int // the puzzle, possibly transposed
+	colMap     [sudokuSize]int             // output column -> grid column
+	current    [sudokuSize][sudokuSize]int // rows chosen so far, relabeled
+	best       [sudokuSize][sudokuSize]int
+	generation int // incremented each time best changes
+}
+
+// Relabeling state: digit in the puzzle -> digit in the output
+type relabeling struct {
+	digits [sudokuSize + 1]int
+	next   int
+}
+
+// Returns the canonical form of the puzzle, see above
+func Canonical(puzzle [sudokuSize][sudokuSize]int) [sudokuSize][sudokuSize]int {
+	c := canonizer{}
+	for y := range c.best {
+		for x := range c.best[y] {
+			c.best[y][x] = sudokuSize + 1 // greater than anything, so the first complete grid wins
+		}
+	}
+	for transpose := 0; transpose < 2; transpose++ {
+		for y := 0; y < sudokuSize; y++ {
+			for x := 0; x < sudokuSize; x++ {
+				if transpose == 0 {
+					c.grid[y][x] = puzzle[y][x]
+				} else {
+					c.grid[y][x] = puzzle[x][y]
+				}
+			}
+		}
+		for _, stacks := range perms3 {
+			for _, c0 := range perms3 {
+				for _, c1 := range perms3 {
+					for _, c2 := range perms3 {
+						columns := [3][3]int{c0, c1, c2}
+						for x := 0; x < sudokuSize; x++ {
+							c.colMap[x] = 3*stacks[x/3] + columns[x/3][x%3]
+						}
+						c.searchRows(0, [3]bool{}, -1, [3]bool{}, relabeling{next: 1}, true)
+					}
+				}
+			}
+		}
+	}
+	return c.best
+}
+
+// Picks the grid row for output row 'row'. The first row of each output band can be any row
+// of a band not used yet, the other two come from the same band. 'equal' tells whether the rows
+// chosen so far are the same as in c.best, only then the new row has to be compared
+func (c *canonizer) searchRows(row int, usedBands [3]bool, band int, usedRows [3]bool, r relabeling, equal bool) {
+	if row == sudokuSize {
+		if !equal {
+			c.best = c.current
+			c.generation++
+		}
+		return
+	}
+	for b := 0; b < 3; b++ {
+		if row%3 == 0 {
+			if usedBands[b] {
+				continue
+			}
+		} else if b != band {
+			continue
+		}
+		for i := 0; i < 3; i++ {
+			if row%3 != 0 && usedRows[i] {
+				continue
+			}
+			nextUsedBands, nextUsedRows := usedBands, usedRows
+			if row%3 == 0 {
+				nextUsedBands[b] = true
+				nextUsedRows = [3]bool{}
+			}
+			nextUsedRows[i] = true
+
+			// Lay out and relabel the row
+			nr := r
+			for x := 0; x < sudokuSize; x++ {
+				digit := c.grid[3*b+i][c.colMap[x]]
+				if digit != 0 {
+					if nr.digits[digit] == 0 {
+						nr.digits[digit] = nr.next
+						nr.next++
+					}
+					digit = nr.digits[digit]
+				}
+				c.current[row][x] = digit
+			}
+
+			stillEqual := equal
+			if equal {
+				cmp := compareRows(c.current[row], c.best[row])
+				if cmp > 0 {
+					continue
+				}
+				stillEqual = cmp == 0
+			}
+			generation := c.generation
+			c.searchRows(row+1, nextUsedBands, b, nextUsedRows, nr, stillEqual)
+			if c.generation != generation {
+				// c.best now comes from this subtree, so the rows above are equal to it
+				equal = true
+			}
+		}
+	}
+}
+
+func compareRows(a, b [sudokuSize]int) int {
+	for x := 0; x < sudokuSize; x++ {
+		if a[x] != b[x] {
+			if a[x] < b[x] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// Returns a short stable identifier of the puzzle that is the same for all equivalent puzzles
+func Fingerprint(puzzle [sudokuSize][sudokuSize]int) string {
+	// The digits one after another with zeroes for empty cells, as the zeroes format has them, but
+	// written out here so that a format registered under that name cannot change fingerprints
+	var text [sudokuSize * sudokuSize]byte
+	for y, row := range Canonical(puzzle) {
+		for x, digit := range row {
+			text[y*sudokuSize+x] = byte('0' + digit)
+		}
+	}
+	sum := sha256.Sum256(text[:])
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,226 @@
+// Package client calls the HTTP API of 'sudocoo serve', see api/openapi.yaml
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error codes the server returns, see the Error schema in api/openapi.yaml
+const (
+	CodeInvalidRequest   = "invalid_request"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeInvalidPuzzle    = "invalid_puzzle"
+	CodeLimitOutOfRange  = "limit_out_of_range"
+	CodeNoSolution       = "no_solution"
+	CodeNotUnique        = "not_unique"
+	CodeOverloaded       = "overloaded"
+	CodeCanceled         = "canceled"
+	CodeInternal         = "internal"
+)
+
+// An error response of the server
+type Error struct {
+	StatusCode int
+	Code       string `json:"code"`
+	Message    string `json:"error"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (%s)", e.Message, e.Code)
+}
+
+// Requests are retried on network errors and on 502, 503 and 504 responses, all the
+// endpoints are safe to repeat. The fields can be changed before the first request
+type Client struct {
+	BaseURL    string       // e.g. http://localhost:8080
+	HTTPClient *http.Client // http.DefaultClient if nil
+	MaxRetries int          // retries after the first attempt
+	RetryDelay time.Duration
+}
+
+// Returns a client with 3 retries starting half a second apart
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), MaxRetries: 3, RetryDelay: 500 * time.Millisecond}
+}
+
+type CountResult struct {
+	Count        int  `json:"count"`
+	LimitReached bool `json:"limitReached"` // there are more solutions than the limit
+}
+
+type RateResult struct {
+	Level string  `json:"level"` // easy, medium, hard or extreme
+	Score float64 `json:"score"` // the backtracking effort of the search
+}
+
+type EnumerateResult struct {
+	Count        int  `json:"count"`
+	LimitReached bool `json:"limitReached"`
+	Iterations   int  `json:"iterations"`
+}
+
+type puzzleRequest struct {
+	Puzzle string `json:"puzzle"`
+	Limit  int    `json:"limit,omitempty"`
+}
+
+// Returns the first solution of the puzzle, in the inline format
+func (c *Client) Solve(ctx context.Context, puzzle string) (string, error) {
+	var resp struct {
+		Solution string `json:"solution"`
+	}
+	err := c.post(ctx, "/solve", puzzleRequest{Puzzle: puzzle}, &resp)
+	return resp.Solution, err
+}
+
+// Counts the solutions of the puzzle up to the limit, 0 means the server maximum
+func (c *Client) Count(ctx context.Context, puzzle string, limit int) (CountResult, error) {
+	var resp CountResult
+	err := c.post(ctx, "/count", puzzleRequest{Puzzle: puzzle, Limit: limit}, &resp)
+	return resp, err
+}
+
+// Rates a puzzle with a unique solution. A puzzle with several gets an *Error with CodeNotUnique
+func (c *Client) Rate(ctx context.Context, puzzle string) (RateResult, error) {
+	var resp RateResult
+	err := c.post(ctx, "/rate", puzzleRequest{Puzzle: puzzle}, &resp)
+	return resp, err
+}
+
+// Returns a new puzzle with a unique solution in the inline format. clues is the number of clues
+// to aim for, 0 leaves it to the server, the same clues and seed give the same puzzle
+func (c *Client) Generate(ctx context.Context, clues int, seed uint64) (string, error) {
+	req := struct {
+		Clues int    `json:"clues,omitempty"`
+		Seed  uint64 `json:"seed,omitempty"`
+	}{clues, seed}
+	var resp struct {
+		Puzzle string `json:"puzzle"`
+	}
+	err := c.post(ctx, "/generate", req, &resp)
+	return resp.Puzzle, err
+}
+
+// Calls fn with each solution of the puzzle as the server finds it, up to the limit.
+// Returning an error from fn stops the enumeration and Enumerate returns that error
+func (c *Client) Enumerate(ctx context.Context, puzzle string, limit int, fn func(index int, solution string) error) (EnumerateResult, error) {
+	query := url.Values{"puzzle": {puzzle}}
+	if limit != 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	resp, err := c.do(ctx, http.MethodGet, "/enumerate?"+query.Encode(), nil)
+	if err != nil {
+		return EnumerateResult{}, err
+	}
+	defer resp.Body.Close()
+
+	// Server-sent events: 'event:' and 'data:' lines, events separated by blank lines
+	var event string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data := []byte(strings.TrimPrefix(line, "data: "))
+			switch event {
+			case "solution":
+				var s struct {
+					Index    int    `json:"index"`
+					Solution string `json:"solution"`
+				}
+				if err := json.Unmarshal(data, &s); err != nil {
+					return EnumerateResult{}, err
+				}
+				if err := fn(s.Index, s.Solution); err != nil {
+					return EnumerateResult{}, err
+				}
+			case "done":
+				var result EnumerateResult
+				err := json.Unmarshal(data, &result)
+				return result, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return EnumerateResult{}, err
+	}
+	return EnumerateResult{}, fmt.Errorf("the stream ended without a done event")
+}
+
+func (c *Client) post(ctx context.Context, path string, req, resp any) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	r, err := c.do(ctx, http.MethodPost, path, body)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+	return json.NewDecoder(r.Body).Decode(resp)
+}
+
+func retryable(status int) bool {
+	return status == http.StatusBadGateway || status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout
+}
+
+// Sends the request, retrying as described on Client. Returns the response if it is
+// successful and an *Error if the server answered with an error
+func (c *Client) do(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	delay := c.RetryDelay
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		resp, err := httpClient.Do(req)
+		if err == nil && resp.StatusCode < 300 {
+			return resp, nil
+		}
+		if err == nil {
+			apiErr := &Error{StatusCode: resp.StatusCode}
+			data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+			resp.Body.Close()
+			if json.Unmarshal(data, apiErr) != nil || apiErr.Code == "" {
+				apiErr.Code, apiErr.Message = CodeInternal, strings.TrimSpace(string(data))
+			}
+			if !retryable(resp.StatusCode) {
+				return nil, apiErr
+			}
+			err = apiErr
+			// The server knows better how long to wait
+			if seconds, perr := strconv.Atoi(resp.Header.Get("Retry-After")); perr == nil {
+				delay = time.Duration(seconds) * time.Second
+			}
+		}
+		if attempt >= c.MaxRetries || ctx.Err() != nil {
+			return nil, err
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, errors.Join(err, ctx.Err())
+		}
+		delay *= 2
+	}
+}
@@ -0,0 +1,94 @@
+// Package clipboard copies text to and from the system clipboard. It runs the tools each system
+// comes with or commonly has installed: pbcopy and pbpaste on macOS, PowerShell and clip on Windows,
+// and wl-clipboard, xclip or xsel elsewhere, whichever is found first. The Wayland tools come first
+// in a Wayland session
+package clipboard
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// A command line that reads or writes the clipboard
+type tool []string
+
+// Returns the tools that can read the clipboard, in the order they are tried
+func readTools() []tool {
+	switch runtime.GOOS {
+	case "darwin":
+		return []tool{{"pbpaste"}}
+	case "windows":
+		return []tool{{"powershell", "-NoProfile", "-Command", "Get-Clipboard -Raw"}}
+	}
+	x := []tool{{"xclip", "-selection", "clipboard", "-out"}, {"xsel", "--clipboard", "--output"}}
+	wayland := tool{"wl-paste", "--no-newline"}
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		return append([]tool{wayland}, x...)
+	}
+	return append(x, wayland)
+}
+
+// Returns the tools that can write the clipboard, in the order they are tried
+func writeTools() []tool {
+	switch runtime.GOOS {
+	case "darwin":
+		return []tool{{"pbcopy"}}
+	case "windows":
+		return []tool{{"clip"}}
+	}
+	x := []tool{{"xclip", "-selection", "clipboard", "-in"}, {"xsel", "--clipboard", "--input"}}
+	wayland := tool{"wl-copy"}
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		return append([]tool{wayland}, x...)
+	}
+	return append(x, wayland)
+}
+
+// Returns the command of the first tool that is installed
+func find(tools []tool) (*exec.Cmd, error) {
+	var names []string
+	for _, t := range tools {
+		if path, err := exec.LookPath(t[0]); err == nil {
+			return exec.Command(path, t[1:]...), nil
+		}
+		names = append(names, t[0])
+	}
+	return nil, fmt.Errorf("no clipboard tool found, install one of %s", strings.Join(names, ", "))
+}
+
+// Returns the text on the clipboard
+func Read() (string, error) {
+	cmd, err := find(readTools())
+	if err != nil {
+		return "", err
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %v: %s", cmd.Args[0], err, msg)
+		}
+		return "", fmt.Errorf("%s: %v", cmd.Args[0], err)
+	}
+	return string(output), nil
+}
+
+// Puts the text on the clipboard
+func Write(text string) error {
+	cmd, err := find(writeTools())
+	if err != nil {
+		return err
+	}
+	// The X tools stay in the background to serve the clipboard, with their output left
+	// open. Waiting for it to be closed would wait for the next copy, so it is not read
+	cmd.Stdin = strings.NewReader(text)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %v", cmd.Args[0], err)
+	}
+	return nil
+}
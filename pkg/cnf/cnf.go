@@ -0,0 +1,112 @@
+// Package cnf encodes puzzles as boolean formulas in the DIMACS CNF format that SAT solvers read.
+//
+// There is a variable for each digit in each cell, 729 of them, see Variable: it is true when the
+// digit is in the cell. The clauses say that every cell has at least one digit and at most one, that
+// every row, column and box has every digit at least once and at most once, and, one clause each,
+// that the givens are in their cells. The "at most" clauses are all the pairs, so the encoding has
+// some redundancy, which SAT solvers do better with. A model of the formula is a solution of the
+// puzzle, the variables that are true in it are its digits
+package cnf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+const sudokuSize = 9
+
+// The number of variables of the encoding
+const Variables = sudokuSize * sudokuSize * sudokuSize
+
+// Returns the variable of the digit in the cell, the row and the column 0-based, the digit 1 to 9.
+// The variables are numbered from 1, row by row, and by digit within a cell
+func Variable(row, column, digit int) int {
+	return (row*sudokuSize+column)*sudokuSize + digit
+}
+
+// Returns the cell, the row and the column 0-based, and the digit of the variable, Variable the other way round
+func Cell(variable int) (row, column, digit int) {
+	v := variable - 1
+	return v / (sudokuSize * sudokuSize), v / sudokuSize % sudokuSize, v%sudokuSize + 1
+}
+
+// Returns the clauses of the puzzle, each one a list of literals: a variable, or its negation
+// for the literals that are negative
+func Clauses(puzzle [sudokuSize][sudokuSize]int) [][]int {
+	var clauses [][]int
+	// The cells of every unit that has to have each digit once: the rows, the columns and the boxes
+	var units [][][2]int
+	for i := range sudokuSize {
+		var row, column, box [][2]int
+		for j := range sudokuSize {
+			row = append(row, [2]int{i, j})
+			column = append(column, [2]int{j, i})
+			box = append(box, [2]int{i/3*3 + j/3, i%3*3 + j%3})
+		}
+		units = append(units, row, column, box)
+	}
+	// Exactly one of the literals is true
+	exactlyOne := func(literals []int) {
+		clauses = append(clauses, literals)
+		for i := range literals {
+			for _, other := range literals[i+1:] {
+				clauses = append(clauses, []int{-literals[i], -other})
+			}
+		}
+	}
+	for y := range sudokuSize {
+		for x := range sudokuSize {
+			var digits []int
+			for d := 1; d <= sudokuSize; d++ {
+				digits = append(digits, Variable(y, x, d))
+			}
+			exactlyOne(digits)
+		}
+	}
+	for _, unit := range units {
+		for d := 1; d <= sudokuSize; d++ {
+			var cells []int
+			for _, cell := range unit {
+				cells = append(cells, Variable(cell[0], cell[1], d))
+			}
+			exactlyOne(cells)
+		}
+	}
+	for y, row := range puzzle {
+		for x, digit := range row {
+			if digit != 0 {
+				clauses = append(clauses, []int{Variable(y, x, digit)})
+			}
+		}
+	}
+	return clauses
+}
+
+// Writes the puzzle in the DIMACS CNF format: the comments, a line each, then the problem line and
+// the clauses. The comments can say where the puzzle comes from, the ones that say what the
+// variables are are always written. Returns error when the writer fails, or when a digit of the
+// puzzle is not 0 to 9
+func Write(w io.Writer, puzzle [sudokuSize][sudokuSize]int, comments ...string) error {
+	for _, row := range puzzle {
+		for _, digit := range row {
+			if digit < 0 || digit > sudokuSize {
+				return fmt.Errorf("invalid digit %d", digit)
+			}
+		}
+	}
+	clauses := Clauses(puzzle)
+	bw := bufio.NewWriter(w)
+	for _, c := range comments {
+		fmt.Fprintf(bw, "c %s\n", c)
+	}
+	fmt.Fprintln(bw, "c variable (row*9 + column)*9 + digit is true when the digit is in the cell, rows and columns from 0")
+	fmt.Fprintf(bw, "p cnf %d %d\n", Variables, len(clauses))
+	for _, clause := range clauses {
+		for _, literal := range clause {
+			fmt.Fprintf(bw, "%d ", literal)
+		}
+		fmt.Fprintln(bw, "0")
+	}
+	return bw.Flush()
+}
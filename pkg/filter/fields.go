@@ -0,0 +1,65 @@
+package filter
+
+import (
+	"github.com/AndrewSav/sudocoo/pkg/analysis"
+	"github.com/AndrewSav/sudocoo/pkg/rating"
+)
+
+// Returns the level of the puzzle, see rating.LevelOf. A puzzle that cannot be rated, e.g. one
+// without a solution, is extreme
+func levelOf(puzzle [sudokuSize][sudokuSize]int) rating.Level {
+	level, err := rating.LevelOf(puzzle)
+	if err != nil {
+		return rating.Extreme
+	}
+	return level
+}
+
+// A record with the fields worked out on demand
+type record struct {
+	Record
+	level    rating.Level
+	hardness *float64
+}
+
+func (r *record) levelValue() float64 {
+	if r.level == 0 {
+		r.level = levelOf(r.Puzzle)
+	}
+	return float64(r.level)
+}
+
+// Returns the search hardness score, see the hardness command, 0 for a puzzle that cannot be measured
+func (r *record) searchHardness() float64 {
+	if r.hardness == nil {
+		h, err := analysis.MeasureSearchHardness(r.Puzzle, analysis.DefaultSearchOrders, 1)
+		r.hardness = &h.DeadEnds
+		if err != nil {
+			*r.hardness = 0
+		}
+	}
+	return *r.hardness
+}
+
+func (r *record) clues() float64 {
+	clues := 0
+	for y := range sudokuSize {
+		for x := range sudokuSize {
+			if r.Puzzle[y][x] != 0 {
+				clues++
+			}
+		}
+	}
+	return float64(clues)
+}
+
+// The fields expressions can use, the names are what the usage help lists
+var fields = map[string]value{
+	"clues":         {num: (*record).clues},
+	"solutions":     {num: func(r *record) float64 { return float64(r.Solutions) }},
+	"limit_reached": {cond: func(r *record) bool { return r.LimitReached }},
+	"iterations":    {num: func(r *record) float64 { return float64(r.Iterations) }},
+	"ms":            {num: func(r *record) float64 { return float64(r.Duration) / 1e6 }},
+	"rating":        {num: (*record).levelValue},
+	"hardness":      {num: (*record).searchHardness},
+}
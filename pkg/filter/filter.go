@@ -0,0 +1,304 @@
+// Package filter is a small expression language for selecting puzzles by their results, e.g.
+//
+//	clues < 25 && solutions == 1 && rating >= hard
+//
+// An expression is made of the fields of a Record, numbers, the levels of the rating package,
+// true and false, the comparisons == != < <= > >=, and !, && and || on the conditions, with
+// parentheses for grouping. && binds tighter than ||, as in Go. The expression has to be a condition
+package filter
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/AndrewSav/sudocoo/pkg/rating"
+)
+
+const sudokuSize = 9
+
+// A puzzle with the results of solving it, what expressions are evaluated against
+type Record struct {
+	Puzzle       [sudokuSize][sudokuSize]int
+	Solutions    int  // solutions found, up to the limit
+	LimitReached bool // there are more solutions than the limit
+	Iterations   int
+	Duration     time.Duration
+	Level        rating.Level // the rating, if it is known already, 0 to have it worked out when needed
+}
+
+// A parsed expression
+type Filter struct {
+	text  string
+	match func(*record) bool
+}
+
+// Parses the expression. Returns an error with the column of the problem for an expression that
+// does not parse, has an unknown name, or compares a condition with a number
+func Parse(s string) (*Filter, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	v, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != end {
+		return nil, p.unexpected(t)
+	}
+	if v.cond == nil {
+		return nil, fmt.Errorf("%s is a number, not a condition", s)
+	}
+	return &Filter{text: s, match: v.cond}, nil
+}
+
+// Reports whether the record matches. Fields that take extra work, such as the rating, are only
+// worked out for the records that get to them
+func (f *Filter) Match(r Record) bool {
+	return f.match(&record{Record: r, level: r.Level})
+}
+
+func (f *Filter) String() string {
+	return f.text
+}
+
+// Returns the names of the fields, for the usage help
+func Fields() []string {
+	var names []string
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// An expression compiled to a function of the record, one of the two is set
+type value struct {
+	num  func(*record) float64
+	cond func(*record) bool
+}
+
+type tokenKind int
+
+const (
+	end tokenKind = iota
+	name
+	number
+	operator
+)
+
+type token struct {
+	kind   tokenKind
+	text   string
+	column int // 1-based, in runes
+}
+
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")"}
+
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		start := i
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case r == '_' || unicode.IsLetter(r):
+			for i < len(runes) && (runes[i] == '_' || unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i])) {
+				i++
+			}
+			tokens = append(tokens, token{name, string(runes[start:i]), start + 1})
+			continue
+		case unicode.IsDigit(r) || r == '.':
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{number, string(runes[start:i]), start + 1})
+			continue
+		}
+		op := ""
+		for _, o := range operators {
+			if strings.HasPrefix(string(runes[i:]), o) {
+				op = o
+				break
+			}
+		}
+		if op == "" {
+			return nil, fmt.Errorf("unexpected %q at column %d", r, start+1)
+		}
+		tokens = append(tokens, token{operator, op, start + 1})
+		i += len(op)
+	}
+	return append(tokens, token{end, "", len(runes) + 1}), nil
+}
+
+// A recursive descent parser, a method per precedence level from the lowest
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+// Consumes the next token if it is the operator
+func (p *parser) accept(op string) bool {
+	if t := p.peek(); t.kind == operator && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) unexpected(t token) error {
+	if t.kind == end {
+		return fmt.Errorf("unexpected end of the expression")
+	}
+	return fmt.Errorf("unexpected %s at column %d", t.text, t.column)
+}
+
+// Returns the condition of the operand of a logical operator
+func (p *parser) condition(v value, op token) (func(*record) bool, error) {
+	if v.cond == nil {
+		return nil, fmt.Errorf("%s at column %d takes conditions, not numbers", op.text, op.column)
+	}
+	return v.cond, nil
+}
+
+func (p *parser) or() (value, error) {
+	return p.logical("||", p.and, func(a, b func(*record) bool) func(*record) bool {
+		return func(r *record) bool { return a(r) || b(r) }
+	})
+}
+
+func (p *parser) and() (value, error) {
+	return p.logical("&&", p.not, func(a, b func(*record) bool) func(*record) bool {
+		return func(r *record) bool { return a(r) && b(r) }
+	})
+}
+
+// Parses operands of the next level joined by the operator
+func (p *parser) logical(op string, operand func() (value, error), join func(a, b func(*record) bool) func(*record) bool) (value, error) {
+	left, err := operand()
+	if err != nil {
+		return value{}, err
+	}
+	for {
+		t := p.peek()
+		if !p.accept(op) {
+			return left, nil
+		}
+		right, err := operand()
+		if err != nil {
+			return value{}, err
+		}
+		a, err := p.condition(left, t)
+		if err != nil {
+			return value{}, err
+		}
+		b, err := p.condition(right, t)
+		if err != nil {
+			return value{}, err
+		}
+		left = value{cond: join(a, b)}
+	}
+}
+
+func (p *parser) not() (value, error) {
+	t := p.peek()
+	if !p.accept("!") {
+		return p.comparison()
+	}
+	v, err := p.not()
+	if err != nil {
+		return value{}, err
+	}
+	cond, err := p.condition(v, t)
+	if err != nil {
+		return value{}, err
+	}
+	return value{cond: func(r *record) bool { return !cond(r) }}, nil
+}
+
+var comparisons = map[string]func(a, b float64) bool{
+	"==": func(a, b float64) bool { return a == b },
+	"!=": func(a, b float64) bool { return a != b },
+	"<":  func(a, b float64) bool { return a < b },
+	"<=": func(a, b float64) bool { return a <= b },
+	">":  func(a, b float64) bool { return a > b },
+	">=": func(a, b float64) bool { return a >= b },
+}
+
+func (p *parser) comparison() (value, error) {
+	left, err := p.primary()
+	if err != nil {
+		return value{}, err
+	}
+	t := p.peek()
+	compare, ok := comparisons[t.text]
+	if t.kind != operator || !ok {
+		return left, nil
+	}
+	p.pos++
+	right, err := p.primary()
+	if err != nil {
+		return value{}, err
+	}
+	if left.cond != nil && right.cond != nil && (t.text == "==" || t.text == "!=") {
+		a, b, equal := left.cond, right.cond, t.text == "=="
+		return value{cond: func(r *record) bool { return (a(r) == b(r)) == equal }}, nil
+	}
+	if left.num == nil || right.num == nil {
+		return value{}, fmt.Errorf("%s at column %d compares a condition with a number", t.text, t.column)
+	}
+	a, b := left.num, right.num
+	return value{cond: func(r *record) bool { return compare(a(r), b(r)) }}, nil
+}
+
+func (p *parser) primary() (value, error) {
+	t := p.peek()
+	switch t.kind {
+	case number:
+		n, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return value{}, fmt.Errorf("invalid number %s at column %d", t.text, t.column)
+		}
+		p.pos++
+		return value{num: func(*record) float64 { return n }}, nil
+	case name:
+		p.pos++
+		if v, ok := fields[t.text]; ok {
+			return v, nil
+		}
+		if level, ok := rating.ParseLevel(t.text); ok {
+			return value{num: func(*record) float64 { return float64(level) }}, nil
+		}
+		switch t.text {
+		case "true":
+			return value{cond: func(*record) bool { return true }}, nil
+		case "false":
+			return value{cond: func(*record) bool { return false }}, nil
+		}
+		return value{}, fmt.Errorf("unknown name %s at column %d, want one of %s or a level: %s", t.text, t.column, strings.Join(Fields(), ", "), strings.Join(rating.LevelNames(), ", "))
+	case operator:
+		if p.accept("(") {
+			v, err := p.or()
+			if err != nil {
+				return value{}, err
+			}
+			if !p.accept(")") {
+				return value{}, p.unexpected(p.peek())
+			}
+			return v, nil
+		}
+	}
+	return value{}, p.unexpected(t)
+}
@@ -0,0 +1,154 @@
+package filter
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	// The expression parser of this package is called parser
+	puzzleparser "github.com/AndrewSav/sudocoo/pkg/parser"
+	"github.com/AndrewSav/sudocoo/pkg/rating"
+	"github.com/AndrewSav/sudocoo/pkg/solver"
+)
+
+func TestMatch(t *testing.T) {
+	// A hard 18 clue puzzle, the first of data/input1.txt
+	data, err := os.ReadFile("../../data/input1.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	line, _, _ := strings.Cut(string(data), "\n")
+	hardPuzzle, err := puzzleparser.ParsePuzzleString(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Its solution with four cells emptied, singles solve it
+	s, err := solver.NewSolver(hardPuzzle)
+	if err != nil || !s.Solve() {
+		t.Fatalf("solving %s: %v", line, err)
+	}
+	easyPuzzle := s.Solution()
+	clear(easyPuzzle[0][:4])
+
+	hard := Record{Puzzle: hardPuzzle, Solutions: 1, Iterations: 2500, Duration: 1500 * time.Microsecond}
+	easy := Record{Puzzle: easyPuzzle, Solutions: 2, LimitReached: true, Iterations: 4}
+	tests := []struct {
+		expr   string
+		record Record
+		want   bool
+	}{
+		// The fields
+		{"clues == 18", hard, true},
+		{"clues == 77", easy, true},
+		{"solutions == 1", hard, true},
+		{"solutions > 1", easy, true},
+		{"limit_reached", easy, true},
+		{"limit_reached", hard, false},
+		{"iterations >= 2500", hard, true},
+		{"iterations < 5", easy, true},
+		{"ms == 1.5", hard, true},
+		{"ms > 0", easy, false},
+		{"rating == easy", easy, true},
+		{"rating >= hard", hard, true},
+		// Looking for a second solution backtracks once even when singles solve the puzzle
+		{"hardness == 1", easy, true},
+		{"hardness > 100", hard, true},
+		// A known level is taken as it is, without rating the puzzle
+		{"rating == extreme", Record{Puzzle: easyPuzzle, Level: rating.Extreme}, true},
+		// The comparisons
+		{"clues != 18", hard, false},
+		{"clues <= 18", hard, true},
+		{"clues < 18", hard, false},
+		{"clues > 17", hard, true},
+		{"18 == clues", hard, true},
+		{"iterations > 1000.5", hard, true},
+		{"easy < medium", hard, true},
+		{"limit_reached == true", easy, true},
+		{"limit_reached != false", hard, false},
+		{"true", hard, true},
+		{"false", hard, false},
+		// Precedence: ! binds tighter than &&, which binds tighter than ||
+		{"true || false && false", hard, true},
+		{"(true || false) && false", hard, false},
+		{"false && false || true", hard, true},
+		{"false && (false || true)", hard, false},
+		{"!false && false", hard, false},
+		{"!(false && false)", hard, true},
+		{"!!true", hard, true},
+		{"!limit_reached && solutions == 1 || clues > 80", hard, true},
+		{"!limit_reached && solutions == 1 || clues > 80", easy, false},
+		{"clues < 25 && solutions == 1 && rating >= hard", hard, true},
+		{"clues < 25 && solutions == 1 && rating >= hard", easy, false},
+		{" ( ( clues<25 ) ) ", hard, true},
+	}
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			f, err := Parse(test.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := f.Match(test.record); got != test.want {
+				t.Errorf("Match = %v, want %v", got, test.want)
+			}
+			if f.String() != test.expr {
+				t.Errorf("String = %q, want the expression", f.String())
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string // the error
+	}{
+		{"", "unexpected end of the expression"},
+		{"clues <", "unexpected end of the expression"},
+		{"clues < 25 &&", "unexpected end of the expression"},
+		{"(true", "unexpected end of the expression"},
+		{"true)", "unexpected ) at column 5"},
+		{"clues 25", "unexpected 25 at column 7"},
+		{"clues < < 25", "unexpected < at column 9"},
+		{"clues @ 25", "unexpected '@' at column 7"},
+		{"clues = 25", "unexpected '=' at column 7"},
+		{"clues & true", "unexpected '&' at column 7"},
+		{"1.2.3 < clues", "invalid number 1.2.3 at column 1"},
+		{"clues", "clues is a number, not a condition"},
+		{"(clues)", "(clues) is a number, not a condition"},
+		{"clues && true", "&& at column 7 takes conditions, not numbers"},
+		{"true || 1", "|| at column 6 takes conditions, not numbers"},
+		{"!clues", "! at column 1 takes conditions, not numbers"},
+		{"clues == true", "== at column 7 compares a condition with a number"},
+		{"true < false", "< at column 6 compares a condition with a number"},
+		{"clue == 1", "unknown name clue at column 1"},
+		{"rating == impossible", "unknown name impossible at column 11"},
+	}
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			f, err := Parse(test.expr)
+			if err == nil {
+				t.Fatalf("parsed as %s, want an error", f)
+			}
+			if !strings.HasPrefix(err.Error(), test.want) {
+				t.Errorf("got error %q, want %q", err, test.want)
+			}
+		})
+	}
+}
+
+// The usage help lists the fields the expressions know
+func TestFields(t *testing.T) {
+	names := Fields()
+	if len(names) != len(fields) {
+		t.Fatalf("Fields returns %d names, there are %d fields", len(names), len(fields))
+	}
+	for i, name := range names {
+		if _, err := Parse(name + " == " + name); err != nil {
+			t.Errorf("field %s: %v", name, err)
+		}
+		if i > 0 && names[i-1] >= name {
+			t.Errorf("Fields is not sorted: %s before %s", names[i-1], name)
+		}
+	}
+}
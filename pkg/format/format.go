@@ -1,8 +1,14 @@
 package format
 
 import (
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"strconv"
 	"strings"
+
+	"github.com/AndrewSav/sudocoo/pkg/solver"
 )
 
 const sudokuSize = 9
@@ -19,8 +25,22 @@ type FormatTemplate struct {
 	Empty                  string // Empty cell character
 	ColumnPrefix           string
 	ColumnSuffix           string
+	RowLabel               string                 // printed before each row with the 1-based row number for its %d
+	Digits                 [sudokuSize + 1]string // how the digits are written, indexed by the digit, as themselves if not set
+	SolvedPrefix           string                 // written before each digit filled in by the solver
+	SolvedSuffix           string                 // and after it
+	Encode                 Encoder                // writes the grid instead of the fields above, if set
 }
 
+// Writes a grid in a format that is not made of the digits, such as an encoding
+type Encoder func(puzzle [sudokuSize][sudokuSize]int) string
+
+// Braille digits are the letters a to j, the number sign before a run of them is left out
+var brailleDigits = [sudokuSize + 1]string{1: "⠁", 2: "⠃", 3: "⠉", 4: "⠙", 5: "⠑", 6: "⠋", 7: "⠛", 8: "⠓", 9: "⠊"}
+
+// Keycap emoji, a digit with the emoji variation selector and the combining keycap
+var emojiDigits = [sudokuSize + 1]string{1: "1\ufe0f\u20e3", 2: "2\ufe0f\u20e3", 3: "3\ufe0f\u20e3", 4: "4\ufe0f\u20e3", 5: "5\ufe0f\u20e3", 6: "6\ufe0f\u20e3", 7: "7\ufe0f\u20e3", 8: "8\ufe0f\u20e3", 9: "9\ufe0f\u20e3"}
+
 // These formats come from here: https://github.com/1to9only/ast-sudoku.2012-08-01/blob/master/src/cmd/sudoku/sudocoo.rt
 var formats = map[string]FormatTemplate{
 	"inline": {
@@ -114,57 +134,188 @@ var formats = map[string]FormatTemplate{
 		ColumnPrefix:           "",
 		ColumnSuffix:           "",
 	},
+	"spoken": {
+		Name:                   "spoken",
+		Description:            "For screen readers, a line per row with the cells separated by commas, empty cells are 'blank'",
+		Header:                 "",
+		ColumnSeparator:        ", ",
+		RowSeparator:           "\n",
+		VerticalBoxSeparator:   "",
+		HorizontalBoxSeparator: "",
+		Footer:                 "",
+		Empty:                  "blank",
+		ColumnPrefix:           "",
+		ColumnSuffix:           "",
+		RowLabel:               "Row %d: ",
+	},
+	"braille": {
+		Name:                   "braille",
+		Description:            "For Braille displays, a line of Unicode Braille cells per row, boxes apart. Empty cells are dots 3-6",
+		Header:                 "",
+		ColumnSeparator:        "",
+		RowSeparator:           "\n",
+		VerticalBoxSeparator:   "⠀",
+		HorizontalBoxSeparator: "\n",
+		Footer:                 "",
+		Empty:                  "⠤",
+		ColumnPrefix:           "",
+		ColumnSuffix:           "",
+		Digits:                 brailleDigits,
+	},
+	"emoji": {
+		Name:                   "emoji",
+		Description:            "For chat and social media posts, keycap emoji digits, empty cells are white squares",
+		Header:                 "",
+		ColumnSeparator:        "",
+		RowSeparator:           "\n",
+		VerticalBoxSeparator:   " ",
+		HorizontalBoxSeparator: "\n",
+		Footer:                 "",
+		Empty:                  "⬜",
+		ColumnPrefix:           "",
+		ColumnSuffix:           "",
+		Digits:                 emojiDigits,
+	},
+	"emoji-spoiler": {
+		Name:                   "emoji-spoiler",
+		Description:            "Same as emoji, with the digits filled in by the solver hidden behind ||spoiler|| markers, as Discord has them",
+		Header:                 "",
+		ColumnSeparator:        "",
+		RowSeparator:           "\n",
+		VerticalBoxSeparator:   " ",
+		HorizontalBoxSeparator: "\n",
+		Footer:                 "",
+		Empty:                  "⬜",
+		ColumnPrefix:           "",
+		ColumnSuffix:           "",
+		Digits:                 emojiDigits,
+		SolvedPrefix:           "||",
+		SolvedSuffix:           "||",
+	},
+}
+
+// ANSI escape sequences used to highlight the digits filled in by the solver
+const (
+	colorSolved = "\x1b[36m"
+	colorReset  = "\x1b[0m"
+)
+
+// Returns the text without the color sequences that highlight solved digits
+func StripColors(s string) string {
+	return strings.NewReplacer(colorSolved, "", colorReset, "").Replace(s)
+}
+
+// Adds a format another package implements, e.g. an encoding that is not made of the digits. Call it from init
+func Register(format FormatTemplate) {
+	formats[format.Name] = format
 }
 
 func FormatFromTemplate(puzzle [sudokuSize][sudokuSize]int, format FormatTemplate) string {
-	var sb strings.Builder
-	if format.Header != "" {
-		fmt.Fprintf(&sb, "%s", format.Header)
+	text := formatFromTemplate(solver.NewSolution(puzzle, puzzle), format, false)
+	logFormatted(format, text)
+	return text
+}
+
+// If colored is set, the digits that are not givens, i.e. filled in by the solver,
+// are wrapped in ANSI color sequences
+func formatFromTemplate(cells solver.Solution, format FormatTemplate, colored bool) string {
+	if format.Encode != nil {
+		var puzzle [sudokuSize][sudokuSize]int
+		for y, row := range cells {
+			for x, cell := range row {
+				puzzle[y][x] = cell.Digit
+			}
+		}
+		return format.Encode(puzzle)
 	}
+	// Every solution printed goes through here, so only the row label, which is a format of its own, goes through fmt
+	var sb strings.Builder
+	sb.Grow(256)
+	sb.WriteString(format.Header)
 	for y := 0; y < sudokuSize; y++ {
-		if format.ColumnPrefix != "" {
-			fmt.Fprintf(&sb, "%s", format.ColumnPrefix)
+		if format.RowLabel != "" {
+			fmt.Fprintf(&sb, format.RowLabel, y+1)
 		}
+		sb.WriteString(format.ColumnPrefix)
 		for x := 0; x < sudokuSize; x++ {
-			digit := fmt.Sprintf("%d", puzzle[y][x])
-			if digit == "0" {
-				fmt.Fprintf(&sb, format.Empty)
-			} else {
-				fmt.Fprintf(&sb, "%s", digit)
+			cell := cells[y][x]
+			digit := format.Digits[cell.Digit]
+			if digit == "" {
+				digit = strconv.Itoa(cell.Digit)
 			}
-			if format.ColumnSeparator != "" && x != sudokuSize-1 {
-				fmt.Fprintf(&sb, "%s", format.ColumnSeparator)
+			switch {
+			case cell.Digit == 0:
+				sb.WriteString(format.Empty)
+			case !cell.Given:
+				sb.WriteString(format.SolvedPrefix)
+				if colored {
+					sb.WriteString(colorSolved)
+				}
+				sb.WriteString(digit)
+				if colored {
+					sb.WriteString(colorReset)
+				}
+				sb.WriteString(format.SolvedSuffix)
+			default:
+				sb.WriteString(digit)
+			}
+			if x != sudokuSize-1 {
+				sb.WriteString(format.ColumnSeparator)
 			}
 			if format.VerticalBoxSeparator != "" && x != sudokuSize-1 && x != 0 && x%3 == 2 {
-				fmt.Fprintf(&sb, "%s", format.VerticalBoxSeparator)
-				if format.ColumnSeparator != "" {
-					fmt.Fprintf(&sb, "%s", format.ColumnSeparator)
-				}
+				sb.WriteString(format.VerticalBoxSeparator)
+				sb.WriteString(format.ColumnSeparator)
 			}
 		}
-		if format.ColumnSuffix != "" {
-			fmt.Fprintf(&sb, "%s", format.ColumnSuffix)
-		}
-		if format.RowSeparator != "" && y != sudokuSize-1 {
-			fmt.Fprintf(&sb, "%s", format.RowSeparator)
+		sb.WriteString(format.ColumnSuffix)
+		if y != sudokuSize-1 {
+			sb.WriteString(format.RowSeparator)
 		}
 		if format.HorizontalBoxSeparator != "" && y != sudokuSize-1 && y != 0 && y%3 == 2 {
-			fmt.Fprintf(&sb, "%s", format.HorizontalBoxSeparator)
+			sb.WriteString(format.HorizontalBoxSeparator)
 		}
 	}
-	if format.Footer != "" {
-		fmt.Fprintf(&sb, "%s", format.Footer)
-	}
+	sb.WriteString(format.Footer)
 	return sb.String()
 }
 
-func Format(puzzle [sudokuSize][sudokuSize]int, formatName string) string {
+// Returned, wrapped, for a format name that is not registered
+var ErrUnknownFormat = errors.New("unknown format")
+
+// Returns the format with the name, or an error naming the known formats if there is none
+func Lookup(formatName string) (FormatTemplate, error) {
 	format, ok := formats[formatName]
 	if !ok {
-		panic(fmt.Sprintf("Unknown format '%s'", formatName))
-	} else {
-		return FormatFromTemplate(puzzle, format)
+		names := slices.Sorted(maps.Keys(formats))
+		return FormatTemplate{}, fmt.Errorf("%w %s, want one of %s", ErrUnknownFormat, formatName, strings.Join(names, ", "))
+	}
+	return format, nil
+}
+
+// Formats the puzzle in the format with the name, or returns ErrUnknownFormat if there is no such format
+func Format(puzzle [sudokuSize][sudokuSize]int, formatName string) (string, error) {
+	format, err := Lookup(formatName)
+	if err != nil {
+		return "", err
+	}
+	return FormatFromTemplate(puzzle, format), nil
+}
+
+// Formats the puzzle in the inline format. That one is always registered, so this cannot fail
+func Inline(puzzle [sudokuSize][sudokuSize]int) string {
+	return FormatFromTemplate(puzzle, formats["inline"])
+}
+
+// Same as Format, but for a solution that knows its givens. With colored set the digits
+// filled in by the solver are highlighted with ANSI colors, so that the solved cells stand out
+func FormatSolution(solution solver.Solution, formatName string, colored bool) (string, error) {
+	format, err := Lookup(formatName)
+	if err != nil {
+		return "", err
 	}
+	text := formatFromTemplate(solution, format, colored)
+	logFormatted(format, text)
+	return text, nil
 }
 
 func GetKnownFormats() map[string]FormatTemplate {
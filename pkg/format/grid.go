@@ -0,0 +1,82 @@
+package format
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/AndrewSav/sudocoo/pkg/parser"
+	"github.com/AndrewSav/sudocoo/pkg/solver"
+)
+
+// The formats FormatGrid writes, the others are made for the 9x9 grid
+var gridFormats = []string{"inline", "vbforums", "visual"}
+
+// Returns the names of the formats FormatGrid writes
+func GridFormats() []string {
+	return append([]string(nil), gridFormats...)
+}
+
+// Formats a grid of any size, see solver.GridSolver, in one of GridFormats. The box separators of
+// the visual format follow the boxes of the shape. The digits are written with parser.Symbols, or as
+// numbers with numbers set, separated by spaces then. With colored set the digits filled in by the
+// solver, the ones that are not in the givens, are highlighted, nil givens have none of them
+func FormatGrid(grid, givens solver.Grid, sh solver.Shape, formatName string, numbers, colored bool) (string, error) {
+	var columnSeparator, boxSeparator string
+	rowSeparator := "\n"
+	width := 1
+	switch formatName {
+	case "inline":
+		rowSeparator = ""
+		if numbers {
+			columnSeparator, rowSeparator = " ", " "
+		}
+	case "vbforums":
+		if numbers {
+			columnSeparator = " "
+			width = len(strconv.Itoa(sh.Size))
+		}
+	case "visual":
+		columnSeparator, boxSeparator = " ", "|"
+		if numbers {
+			width = len(strconv.Itoa(sh.Size))
+		}
+	default:
+		return "", fmt.Errorf("the %s format is only for 9x9 grids, want one of %s", formatName, strings.Join(gridFormats, ", "))
+	}
+	symbols := parser.Symbols(sh.Size)
+	var sb strings.Builder
+	for y, row := range grid {
+		var line strings.Builder
+		for x, digit := range row {
+			if x != 0 {
+				line.WriteString(columnSeparator)
+				if boxSeparator != "" && x%sh.BoxColumns == 0 {
+					line.WriteString(boxSeparator + columnSeparator)
+				}
+			}
+			text := "."
+			if digit != 0 && numbers {
+				text = strconv.Itoa(digit)
+			} else if digit != 0 {
+				text = symbols[digit-1 : digit]
+			}
+			if len(text) < width {
+				text = strings.Repeat(" ", width-len(text)) + text
+			}
+			if colored && digit != 0 && givens != nil && givens[y][x] == 0 {
+				text = colorSolved + text + colorReset
+			}
+			line.WriteString(text)
+		}
+		sb.WriteString(line.String())
+		if y == len(grid)-1 {
+			break
+		}
+		sb.WriteString(rowSeparator)
+		if boxSeparator != "" && (y+1)%sh.BoxRows == 0 {
+			sb.WriteString(strings.Repeat("-", len(StripColors(line.String()))) + "\n")
+		}
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,22 @@
+package format
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+)
+
+var logger atomic.Pointer[slog.Logger]
+
+// Makes the package log every puzzle or solution it formats, at the debug level. Logging is off by
+// default, nil turns it off again. It is safe to call while puzzles are being formatted
+func SetLogger(l *slog.Logger) {
+	logger.Store(l)
+}
+
+// Logs the text a format was written as, if there is a logger
+func logFormatted(format FormatTemplate, text string) {
+	if l := logger.Load(); l != nil {
+		l.LogAttrs(context.Background(), slog.LevelDebug, "format written", slog.String("format", format.Name), slog.Int("bytes", len(text)))
+	}
+}
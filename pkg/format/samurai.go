@@ -0,0 +1,35 @@
+package format
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/AndrewSav/sudocoo/pkg/solver"
+)
+
+// Formats a samurai puzzle or solution the way parser.ReadNextSamurai reads it: the 21x21 square
+// with spaces outside of the grids and '.' for the empty cells, a line for each row. With colored set
+// the digits filled in by the solver, the ones that are not in the givens, are highlighted
+func FormatSamurai(grid, givens solver.Samurai, colored bool) string {
+	var sb strings.Builder
+	for y, row := range grid {
+		var line strings.Builder
+		for x, digit := range row {
+			switch {
+			case !solver.InSamurai(y, x):
+				line.WriteString(" ")
+			case digit == 0:
+				line.WriteString(".")
+			case colored && givens[y][x] == 0:
+				line.WriteString(colorSolved + strconv.Itoa(digit) + colorReset)
+			default:
+				line.WriteString(strconv.Itoa(digit))
+			}
+		}
+		sb.WriteString(strings.TrimRight(line.String(), " "))
+		if y != len(grid)-1 {
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,520 @@
+// Package handler serves the solver over HTTP. Handler is a standard http.Handler, so it can be
+// mounted on any server or passed as is to platforms that take one, such as Google Cloud Functions;
+// see lambda.go for AWS Lambda. The endpoints are described in api/openapi.yaml
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AndrewSav/sudocoo/pkg/analysis"
+	"github.com/AndrewSav/sudocoo/pkg/format"
+	"github.com/AndrewSav/sudocoo/pkg/metrics"
+	"github.com/AndrewSav/sudocoo/pkg/parser"
+	"github.com/AndrewSav/sudocoo/pkg/rating"
+	"github.com/AndrewSav/sudocoo/pkg/solver"
+	"github.com/AndrewSav/sudocoo/pkg/sudocoo"
+)
+
+// How often a progress event is sent while enumerating
+const progressInterval = 500 * time.Millisecond
+
+// The search of a request comes back after that many iterations without a solution, to see whether
+// the request is still wanted and for /enumerate to send progress. A few milliseconds of search
+const solveBudget = 1 << 16
+
+// Request bodies larger than that are rejected, a puzzle is less than a hundred bytes
+const maxRequestSize = 64 * 1024
+
+// Request body of the /solve, /count and /rate endpoints, /enumerate takes the same as query parameters
+type PuzzleRequest struct {
+	Puzzle string `json:"puzzle"`          // in the inline format
+	Limit  int    `json:"limit,omitempty"` // the maximum number of solutions to look for, 0 means the server maximum
+}
+
+type SolveResponse struct {
+	Solution   string `json:"solution"`
+	Iterations int    `json:"-"`
+}
+
+type CountResponse struct {
+	Count        int  `json:"count"`
+	LimitReached bool `json:"limitReached"` // there are more solutions than the limit
+	Iterations   int  `json:"-"`
+}
+
+// Request body of the /generate endpoint
+type GenerateRequest struct {
+	Clues int    `json:"clues,omitempty"` // the number of clues to aim for, 0 leaves it to the generator
+	Seed  uint64 `json:"seed,omitempty"`  // the same seed and clues give the same puzzle
+}
+
+type GenerateResponse struct {
+	Puzzle string `json:"puzzle"`
+}
+
+type RateResponse struct {
+	Level      string  `json:"level"` // easy, medium, hard or extreme, see the rating package
+	Score      float64 `json:"score"` // the backtracking effort of the search
+	Iterations int     `json:"-"`
+}
+
+// Error body of all the endpoints, 'code' is one of the error codes below
+type ErrorResponse struct {
+	Code  string `json:"code"`
+	Error string `json:"error"`
+}
+
+// Error codes, clients should branch on these rather than on the messages
+const (
+	CodeInvalidRequest   = "invalid_request"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeInvalidPuzzle    = "invalid_puzzle"
+	CodeLimitOutOfRange  = "limit_out_of_range"
+	CodeNoSolution       = "no_solution"
+	CodeNotUnique        = "not_unique"
+	CodeOverloaded       = "overloaded"
+	CodeCanceled         = "canceled"
+	CodeInternal         = "internal"
+)
+
+// An error that knows its HTTP status and error code
+type Error struct {
+	Status int
+	Code   string
+	err    error
+}
+
+func (e *Error) Error() string {
+	return e.err.Error()
+}
+
+func newError(status int, code string, f string, args ...any) *Error {
+	return &Error{Status: status, Code: code, err: fmt.Errorf(f, args...)}
+}
+
+// Events of the /enumerate stream
+type solutionEvent struct {
+	Index    int    `json:"index"`
+	Solution string `json:"solution"`
+}
+
+type progressEvent struct {
+	Solutions  int `json:"solutions"`
+	Iterations int `json:"iterations"`
+}
+
+type doneEvent struct {
+	Count        int  `json:"count"`
+	LimitReached bool `json:"limitReached"`
+	Iterations   int  `json:"iterations"`
+}
+
+// Serves /solve, /count, /rate, /generate and /enumerate
+type Handler struct {
+	maxLimit int           // the most solutions a single request is allowed to ask for
+	limits   parser.Limits // what the puzzles sent can take up, MaxSize and MaxPuzzles only apply to jobs
+	metrics  *metrics.Metrics
+	mux      *http.ServeMux
+	solvers  solver.Pool
+}
+
+// Returns a handler that allows up to maxLimit solutions per request and records
+// request metrics to m, which can be nil
+func New(maxLimit int, m *metrics.Metrics) *Handler {
+	h := &Handler{maxLimit: maxLimit, limits: parser.DefaultLimits, metrics: m, mux: http.NewServeMux()}
+	h.mux.HandleFunc("/solve", h.instrument("solve", h.handleSolve))
+	h.mux.HandleFunc("/count", h.instrument("count", h.handleCount))
+	h.mux.HandleFunc("/rate", h.instrument("rate", h.handleRate))
+	h.mux.HandleFunc("/generate", h.instrument("generate", h.handleGenerate))
+	h.mux.HandleFunc("/enumerate", h.instrument("enumerate", h.handleEnumerate))
+	return h
+}
+
+// Sets the line length and the puzzle size the puzzles of requests and jobs can have, the input
+// size and the number of puzzles of jobs are set by their JobConfig. The default is parser.DefaultLimits
+func (h *Handler) SetInputLimits(limits parser.Limits) {
+	h.limits = limits
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// A handler that reports the number of solutions found and iterations taken, and whether it failed
+type instrumentedHandler func(w http.ResponseWriter, r *http.Request) (solutions, iterations int, failed bool)
+
+// Records the metrics of each request to the handler
+func (h *Handler) instrument(name string, ih instrumentedHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		done := h.metrics.Start(name)
+		done(ih(w, r))
+	}
+}
+
+// Caps the number of requests in progress across the handlers it wraps
+type Limiter struct {
+	slots chan struct{}
+}
+
+func NewLimiter(n int) *Limiter {
+	return &Limiter{slots: make(chan struct{}, n)}
+}
+
+// Rejects requests with 503 and the overloaded error code while the limit is reached,
+// so that a busy server sheds load instead of slowing every request down
+func (l *Limiter) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case l.slots <- struct{}{}:
+			defer func() { <-l.slots }()
+			next.ServeHTTP(w, r)
+		default:
+			w.Header().Set("Retry-After", "1")
+			writeError(w, newError(http.StatusServiceUnavailable, CodeOverloaded, "the server is busy, %d requests are in progress", cap(l.slots)))
+		}
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// Converts any error to the error body, errors other than *Error are internal
+func ToErrorResponse(err error) (int, ErrorResponse) {
+	var he *Error
+	if errors.As(err, &he) {
+		return he.Status, ErrorResponse{Code: he.Code, Error: he.Error()}
+	}
+	return http.StatusInternalServerError, ErrorResponse{Code: CodeInternal, Error: err.Error()}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status, resp := ToErrorResponse(err)
+	writeJSON(w, status, resp)
+}
+
+// Parses the puzzle of the request
+func (h *Handler) parsePuzzle(req PuzzleRequest) ([9][9]int, error) {
+	puzzle, err := parser.NewLimitedReader(strings.NewReader(req.Puzzle), h.limits).Next()
+	if errors.Is(err, io.EOF) {
+		return puzzle, newError(http.StatusBadRequest, CodeInvalidPuzzle, "puzzle is empty")
+	}
+	if err != nil {
+		return puzzle, newError(http.StatusBadRequest, CodeInvalidPuzzle, "%v", err)
+	}
+	return puzzle, nil
+}
+
+// Parses the puzzle and checks the requested limit against the server maximum.
+// A zero limit is replaced with the maximum. The solver comes from the pool, put it back when done
+func (h *Handler) newSolver(req PuzzleRequest) (*solver.Solver, int, error) {
+	if req.Limit < 0 || req.Limit > h.maxLimit {
+		return nil, 0, newError(http.StatusBadRequest, CodeLimitOutOfRange, "limit %d is out of range, want 0 to %d", req.Limit, h.maxLimit)
+	}
+	puzzle, err := h.parsePuzzle(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	s, err := h.solvers.Get(puzzle)
+	if err != nil {
+		return nil, 0, newError(http.StatusBadRequest, CodeInvalidPuzzle, "%v", err)
+	}
+	s.SetSolveBudget(solveBudget)
+	limit := req.Limit
+	if limit == 0 {
+		limit = h.maxLimit
+	}
+	return s, limit, nil
+}
+
+// Finds the next solution of a solver with solveBudget set. Returns an error once ctx is done: the client
+// went away, the job was canceled or the platform ran out of time. idle, which can be nil, is called
+// every solveBudget iterations without a solution
+func nextSolution(ctx context.Context, s *solver.Solver, idle func()) (bool, error) {
+	for {
+		if s.SolveContext(ctx) {
+			return true, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return false, newError(http.StatusServiceUnavailable, CodeCanceled, "the search was canceled: %v", err)
+		}
+		if !s.BudgetExceeded() {
+			return false, nil
+		}
+		if idle != nil {
+			idle()
+		}
+	}
+}
+
+// Returns the first solution of the puzzle. The limit of the request does not apply
+func (h *Handler) Solve(ctx context.Context, req PuzzleRequest) (SolveResponse, error) {
+	if req.Limit != 0 {
+		return SolveResponse{}, newError(http.StatusBadRequest, CodeInvalidRequest, "limit does not apply to solve")
+	}
+	s, _, err := h.newSolver(req)
+	if err != nil {
+		return SolveResponse{}, err
+	}
+	defer h.solvers.Put(s)
+	found, err := nextSolution(ctx, s, nil)
+	if err != nil {
+		return SolveResponse{}, err
+	}
+	if !found {
+		return SolveResponse{Iterations: s.Iterations()}, newError(http.StatusUnprocessableEntity, CodeNoSolution, "no solution")
+	}
+	return SolveResponse{Solution: format.Inline(s.Solution()), Iterations: s.Iterations()}, nil
+}
+
+// Returns the number of solutions of the puzzle, up to the limit of the request
+func (h *Handler) Count(ctx context.Context, req PuzzleRequest) (CountResponse, error) {
+	s, limit, err := h.newSolver(req)
+	if err != nil {
+		return CountResponse{}, err
+	}
+	defer h.solvers.Put(s)
+	var resp CountResponse
+	for {
+		found, err := nextSolution(ctx, s, nil)
+		if err != nil {
+			return CountResponse{}, err
+		}
+		if !found {
+			break
+		}
+		if resp.Count == limit {
+			resp.LimitReached = true
+			break
+		}
+		resp.Count++
+	}
+	resp.Iterations = s.Iterations()
+	return resp, nil
+}
+
+// Rates a puzzle with a unique solution, see rating.Rate. The limit of the request does not apply
+func (h *Handler) Rate(ctx context.Context, req PuzzleRequest) (RateResponse, error) {
+	if req.Limit != 0 {
+		return RateResponse{}, newError(http.StatusBadRequest, CodeInvalidRequest, "limit does not apply to rate")
+	}
+	puzzle, err := h.parsePuzzle(req)
+	if err != nil {
+		return RateResponse{}, err
+	}
+	s, err := h.solvers.Get(puzzle)
+	if err != nil {
+		return RateResponse{}, newError(http.StatusBadRequest, CodeInvalidPuzzle, "%v", err)
+	}
+	defer h.solvers.Put(s)
+	s.SetSolveBudget(solveBudget)
+	count := 0
+	for count < 2 {
+		found, err := nextSolution(ctx, s, nil)
+		if err != nil {
+			return RateResponse{}, err
+		}
+		if !found {
+			break
+		}
+		count++
+	}
+	switch count {
+	case 0:
+		return RateResponse{Iterations: s.Iterations()}, newError(http.StatusUnprocessableEntity, CodeNoSolution, "no solution")
+	case 2:
+		return RateResponse{Iterations: s.Iterations()}, newError(http.StatusUnprocessableEntity, CodeNotUnique, "the puzzle has more than one solution")
+	}
+	rated, err := rating.Rate(puzzle, analysis.DefaultSearchOrders, 1)
+	if err != nil {
+		return RateResponse{}, err
+	}
+	return RateResponse{Level: rated.Level.String(), Score: rated.Score, Iterations: s.Iterations()}, nil
+}
+
+// Makes a puzzle with a unique solution, see sudocoo.Generate
+func (h *Handler) Generate(req GenerateRequest) (GenerateResponse, error) {
+	puzzle, err := sudocoo.Generate(sudocoo.WithClues(req.Clues), sudocoo.WithSeed(req.Seed))
+	if err != nil {
+		return GenerateResponse{}, newError(http.StatusBadRequest, CodeInvalidRequest, "%v", err)
+	}
+	return GenerateResponse{Puzzle: format.Inline(puzzle)}, nil
+}
+
+func checkMethod(w http.ResponseWriter, r *http.Request, method string) error {
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		return newError(http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method %s is not allowed, use %s", r.Method, method)
+	}
+	return nil
+}
+
+// Decodes a JSON puzzle request from a POST body. Unknown fields are rejected,
+// so that misspelled parameters do not go unnoticed
+func decodePuzzleRequest(w http.ResponseWriter, r *http.Request) (req PuzzleRequest, err error) {
+	if err = checkMethod(w, r, http.MethodPost); err != nil {
+		return
+	}
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(&req); err != nil {
+		err = newError(http.StatusBadRequest, CodeInvalidRequest, "invalid request body: %v", err)
+	}
+	return
+}
+
+func (h *Handler) handleSolve(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	req, err := decodePuzzleRequest(w, r)
+	if err != nil {
+		writeError(w, err)
+		return 0, 0, true
+	}
+	resp, err := h.Solve(r.Context(), req)
+	var he *Error
+	if errors.As(err, &he) && he.Code == CodeNoSolution {
+		writeError(w, err)
+		return 0, resp.Iterations, false
+	}
+	if err != nil {
+		writeError(w, err)
+		return 0, 0, true
+	}
+	writeJSON(w, http.StatusOK, resp)
+	return 1, resp.Iterations, false
+}
+
+func (h *Handler) handleRate(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	req, err := decodePuzzleRequest(w, r)
+	if err != nil {
+		writeError(w, err)
+		return 0, 0, true
+	}
+	resp, err := h.Rate(r.Context(), req)
+	var he *Error
+	if errors.As(err, &he) && (he.Code == CodeNoSolution || he.Code == CodeNotUnique) {
+		writeError(w, err)
+		return 0, resp.Iterations, false
+	}
+	if err != nil {
+		writeError(w, err)
+		return 0, 0, true
+	}
+	writeJSON(w, http.StatusOK, resp)
+	return 1, resp.Iterations, false
+}
+
+func (h *Handler) handleGenerate(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	if err := checkMethod(w, r, http.MethodPost); err != nil {
+		writeError(w, err)
+		return 0, 0, true
+	}
+	var req GenerateRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeError(w, newError(http.StatusBadRequest, CodeInvalidRequest, "invalid request body: %v", err))
+		return 0, 0, true
+	}
+	resp, err := h.Generate(req)
+	if err != nil {
+		writeError(w, err)
+		return 0, 0, true
+	}
+	writeJSON(w, http.StatusOK, resp)
+	return 1, 0, false
+}
+
+func (h *Handler) handleCount(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	req, err := decodePuzzleRequest(w, r)
+	if err != nil {
+		writeError(w, err)
+		return 0, 0, true
+	}
+	resp, err := h.Count(r.Context(), req)
+	if err != nil {
+		writeError(w, err)
+		return 0, 0, true
+	}
+	writeJSON(w, http.StatusOK, resp)
+	return resp.Count, resp.Iterations, false
+}
+
+// Streams solutions as server-sent events (https://html.spec.whatwg.org/multipage/server-sent-events.html)
+// so that a browser can show them as they are found. The stream consists of 'solution' events,
+// periodic 'progress' events and a final 'done' event. Invalid requests get an ordinary
+// JSON error response instead of a stream
+func (h *Handler) handleEnumerate(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	if err := checkMethod(w, r, http.MethodGet); err != nil {
+		writeError(w, err)
+		return 0, 0, true
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, fmt.Errorf("streaming is not supported"))
+		return 0, 0, true
+	}
+	req := PuzzleRequest{Puzzle: r.URL.Query().Get("puzzle")}
+	if l := r.URL.Query().Get("limit"); l != "" {
+		limit, err := strconv.Atoi(l)
+		if err != nil {
+			writeError(w, newError(http.StatusBadRequest, CodeInvalidRequest, "invalid limit %q", l))
+			return 0, 0, true
+		}
+		req.Limit = limit
+	}
+	s, limit, err := h.newSolver(req)
+	if err != nil {
+		writeError(w, err)
+		return 0, 0, true
+	}
+	defer h.solvers.Put(s)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(event string, v any) {
+		data, _ := json.Marshal(v)
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+		flusher.Flush()
+	}
+
+	done := doneEvent{}
+	lastProgress := time.Now()
+	progress := func() {
+		if time.Since(lastProgress) >= progressInterval {
+			send("progress", progressEvent{Solutions: done.Count, Iterations: s.Iterations()})
+			lastProgress = time.Now()
+		}
+	}
+	for {
+		found, err := nextSolution(r.Context(), s, progress)
+		if err != nil {
+			// The client went away, nobody is listening
+			return done.Count, s.Iterations(), false
+		}
+		if !found {
+			break
+		}
+		if done.Count == limit {
+			done.LimitReached = true
+			break
+		}
+		done.Count++
+		send("solution", solutionEvent{Index: done.Count, Solution: format.Inline(s.Solution())})
+		progress()
+	}
+	done.Iterations = s.Iterations()
+	send("done", done)
+	return done.Count, done.Iterations, false
+}
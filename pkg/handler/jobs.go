@@ -0,0 +1,574 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AndrewSav/sudocoo/pkg/batch"
+	"github.com/AndrewSav/sudocoo/pkg/format"
+	"github.com/AndrewSav/sudocoo/pkg/parser"
+	"github.com/AndrewSav/sudocoo/pkg/webhook"
+)
+
+// Job states, a job goes from queued to running to one of the final states
+const (
+	JobQueued   = "queued"
+	JobRunning  = "running"
+	JobDone     = "done"
+	JobFailed   = "failed"
+	JobCanceled = "canceled"
+)
+
+// Error codes of the job endpoints, in addition to the ones above
+const (
+	CodeJobNotFound    = "job_not_found"
+	CodeJobNotFinished = "job_not_finished"
+	CodeBatchTooLarge  = "batch_too_large"
+	CodeTooManyJobs    = "too_many_jobs"
+	CodeShuttingDown   = "shutting_down"
+)
+
+// Finished jobs and their results are forgotten after that
+const jobTTL = time.Hour
+
+// The most jobs kept at the same time, including the finished ones
+const maxJobs = 1000
+
+type JobConfig struct {
+	MaxPuzzles       int             // the most puzzles a job can have
+	MaxSize          int64           // the most bytes of input a job can have
+	Workers          int             // jobs running at the same time, the rest wait in the queue
+	AllowURLs        bool            // jobs can ask the server to fetch their input from a URL
+	AllowPrivateURLs bool            // the input URLs and the webhooks may point to private addresses, see webhook.ValidateURL
+	Webhooks         *webhook.Sender // notifies jobs' webhooks when they finish, nil disables webhooks
+}
+
+// How long fetching the input of a job from its URL may take, the body included
+const fetchTimeout = 30 * time.Second
+
+// Body of a JSON job submission, the input can also be uploaded as is
+// with the other fields in the query string
+type jobRequest struct {
+	URL     string `json:"url"`               // where to fetch the puzzles from
+	Limit   int    `json:"limit,omitempty"`   // for each puzzle, 0 means the server maximum
+	Webhook string `json:"webhook,omitempty"` // URL to post the JobSummary to when the job finishes
+}
+
+type JobStatus struct {
+	ID        string     `json:"id"`
+	State     string     `json:"state"`
+	Total     int        `json:"total"` // the number of puzzles, 0 until the input is read
+	Processed int        `json:"processed"`
+	Error     string     `json:"error,omitempty"` // why the job failed
+	Created   time.Time  `json:"created"`
+	Finished  *time.Time `json:"finished,omitempty"`
+}
+
+// A line of the job results
+type JobResult struct {
+	Index        int    `json:"index"` // 1-based number of the puzzle in the input
+	Puzzle       string `json:"puzzle"`
+	Solution     string `json:"solution,omitempty"` // the first one
+	Count        int    `json:"count"`
+	LimitReached bool   `json:"limitReached"`
+	Error        string `json:"error,omitempty"`
+}
+
+// What the webhook of a job receives when it finishes
+type JobSummary struct {
+	JobStatus
+	Solved     int    `json:"solved"`     // puzzles with at least one solution
+	Unsolvable int    `json:"unsolvable"` // puzzles without solutions
+	Failures   int    `json:"failures"`   // puzzles that could not be solved, such as ones with contradicting givens
+	Results    string `json:"results"`    // URL of the results
+}
+
+type job struct {
+	status     JobStatus
+	cancel     context.CancelFunc
+	results    bytes.Buffer // JSON lines
+	summary    JobSummary   // the counts so far
+	webhook    string
+	resultsURL string
+}
+
+func (j *job) finished() bool {
+	return j.status.State != JobQueued && j.status.State != JobRunning
+}
+
+// Serves the asynchronous batch endpoints under /jobs. Jobs and their results live in memory
+type Jobs struct {
+	handler *Handler
+	config  JobConfig
+	client  *http.Client   // fetches the input from the URLs of the jobs
+	workers chan struct{}  // a slot for each running job
+	running sync.WaitGroup // the jobs past their queue, and the webhooks being sent
+	mu      sync.Mutex
+	jobs    map[string]*job
+	closing bool // Shutdown was called, no jobs are taken and the running ones stop after their current puzzle
+}
+
+// Ends the input of a running job when the server is shutting down
+var errShuttingDown = errors.New("the server is shutting down")
+
+// Returns the batch endpoints, using the limits of h for each puzzle
+func (h *Handler) NewJobs(config JobConfig) *Jobs {
+	client := webhook.NewClient(fetchTimeout, config.AllowPrivateURLs)
+	return &Jobs{handler: h, config: config, client: client, workers: make(chan struct{}, config.Workers), jobs: map[string]*job{}}
+}
+
+// POST   /jobs              submits a job, returns its status
+// GET    /jobs/{id}         returns the status
+// GET    /jobs/{id}/results returns the results once the job is finished
+// DELETE /jobs/{id}         cancels the job, or forgets it if it is finished
+func (js *Jobs) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/jobs")
+	var err error
+	switch {
+	case path == "" || path == "/":
+		if err = checkMethod(w, r, http.MethodPost); err == nil {
+			err = js.handleSubmit(w, r)
+		}
+	case strings.HasSuffix(path, "/results"):
+		if err = checkMethod(w, r, http.MethodGet); err == nil {
+			err = js.handleResults(w, strings.TrimSuffix(path[1:], "/results"))
+		}
+	default:
+		id := path[1:]
+		switch r.Method {
+		case http.MethodGet:
+			err = js.handleStatus(w, id)
+		case http.MethodDelete:
+			err = js.handleCancel(w, id)
+		default:
+			w.Header().Set("Allow", "GET, DELETE")
+			err = newError(http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method %s is not allowed, use GET or DELETE", r.Method)
+		}
+	}
+	if err != nil {
+		writeError(w, err)
+	}
+}
+
+func newJobID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Reads the input of a job from the request body, a 'file' field of a multipart form, or,
+// if the body is JSON, from the URL it names. The other parameters come from the JSON or the query string,
+// they are checked before the input is read, let alone fetched
+func (js *Jobs) readInput(w http.ResponseWriter, r *http.Request) (input []byte, params jobRequest, err error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if params.Limit, err = strconv.Atoi(l); err != nil {
+			return nil, params, newError(http.StatusBadRequest, CodeInvalidRequest, "invalid limit %q", l)
+		}
+	}
+	params.Webhook = r.URL.Query().Get("webhook")
+	if mediaType == "application/json" {
+		decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&params); err != nil {
+			return nil, params, newError(http.StatusBadRequest, CodeInvalidRequest, "invalid request body: %v", err)
+		}
+	}
+	if err := js.checkParams(r.Context(), params, mediaType == "application/json"); err != nil {
+		return nil, params, err
+	}
+	var body io.Reader
+	switch mediaType {
+	case "application/json":
+		fetched, err := js.fetch(r.Context(), params.URL)
+		if err != nil {
+			return nil, params, err
+		}
+		defer fetched.Close()
+		body = fetched
+	case "multipart/form-data":
+		r.Body = http.MaxBytesReader(w, r.Body, js.config.MaxSize+maxRequestSize)
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			return nil, params, newError(http.StatusBadRequest, CodeInvalidRequest, "invalid upload: %v", err)
+		}
+		defer file.Close()
+		body = file
+	default:
+		body = r.Body
+	}
+	// Read one byte more than allowed to tell whether there is more
+	input, err = io.ReadAll(io.LimitReader(body, js.config.MaxSize+1))
+	if err != nil {
+		return nil, params, newError(http.StatusBadRequest, CodeInvalidRequest, "could not read the input: %v", err)
+	}
+	if int64(len(input)) > js.config.MaxSize {
+		return nil, params, newError(http.StatusRequestEntityTooLarge, CodeBatchTooLarge, "the input is larger than %d bytes", js.config.MaxSize)
+	}
+	return input, params, nil
+}
+
+// Checks the parameters of a job, fromURL when its input is to be fetched from params.URL
+func (js *Jobs) checkParams(ctx context.Context, params jobRequest, fromURL bool) error {
+	if params.Limit < 0 || params.Limit > js.handler.maxLimit {
+		return newError(http.StatusBadRequest, CodeLimitOutOfRange, "limit %d is out of range, want 0 to %d", params.Limit, js.handler.maxLimit)
+	}
+	if params.Webhook != "" {
+		if js.config.Webhooks == nil {
+			return newError(http.StatusBadRequest, CodeInvalidRequest, "webhooks are disabled on this server")
+		}
+		if err := webhook.ValidateURL(ctx, params.Webhook, js.config.AllowPrivateURLs); err != nil {
+			return newError(http.StatusBadRequest, CodeInvalidRequest, "webhook: %v", err)
+		}
+	}
+	if fromURL {
+		if !js.config.AllowURLs {
+			return newError(http.StatusBadRequest, CodeInvalidRequest, "fetching input from a URL is disabled on this server, upload it instead")
+		}
+		if err := webhook.ValidateURL(ctx, params.URL, js.config.AllowPrivateURLs); err != nil {
+			return newError(http.StatusBadRequest, CodeInvalidRequest, "url: %v", err)
+		}
+	}
+	return nil
+}
+
+// Fetches the input of a job from its URL, with the same checks of the addresses as the webhooks have.
+// The caller reads no more than the size limit of the body and closes it
+func (js *Jobs) fetch(ctx context.Context, url string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, newError(http.StatusBadRequest, CodeInvalidRequest, "invalid url: %v", err)
+	}
+	resp, err := js.client.Do(req)
+	if err != nil {
+		return nil, newError(http.StatusBadRequest, CodeInvalidRequest, "could not fetch the input: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, newError(http.StatusBadRequest, CodeInvalidRequest, "could not fetch the input: %s", resp.Status)
+	}
+	if resp.ContentLength > js.config.MaxSize {
+		resp.Body.Close()
+		return nil, newError(http.StatusRequestEntityTooLarge, CodeBatchTooLarge, "the input is larger than %d bytes", js.config.MaxSize)
+	}
+	return resp.Body, nil
+}
+
+func (js *Jobs) handleSubmit(w http.ResponseWriter, r *http.Request) error {
+	input, params, err := js.readInput(w, r)
+	if err != nil {
+		return err
+	}
+
+	js.mu.Lock()
+	if js.closing {
+		js.mu.Unlock()
+		return newError(http.StatusServiceUnavailable, CodeShuttingDown, "the server is shutting down")
+	}
+	for id, j := range js.jobs {
+		if j.finished() && time.Since(*j.status.Finished) > jobTTL {
+			delete(js.jobs, id)
+		}
+	}
+	if len(js.jobs) >= maxJobs {
+		js.mu.Unlock()
+		return newError(http.StatusServiceUnavailable, CodeTooManyJobs, "there are too many jobs, try again later")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &job{status: JobStatus{ID: newJobID(), State: JobQueued, Created: time.Now()}, cancel: cancel, webhook: params.Webhook}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	j.resultsURL = fmt.Sprintf("%s://%s/jobs/%s/results", scheme, r.Host, j.status.ID)
+	js.jobs[j.status.ID] = j
+	status := j.status
+	js.mu.Unlock()
+
+	go js.run(ctx, j, input, params.Limit)
+
+	w.Header().Set("Location", "/jobs/"+status.ID)
+	writeJSON(w, http.StatusAccepted, status)
+	return nil
+}
+
+// Sets the final state of the job unless it was canceled already
+func (js *Jobs) finish(j *job, state string, err error) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	js.finishLocked(j, state, err)
+}
+
+// Same as finish, with js.mu held. Calls the webhook of the job if it has one
+func (js *Jobs) finishLocked(j *job, state string, err error) {
+	if j.finished() {
+		return
+	}
+	now := time.Now()
+	j.status.State, j.status.Finished = state, &now
+	j.cancel()
+	if err != nil {
+		j.status.Error = err.Error()
+	}
+	if j.webhook != "" {
+		summary := j.summary
+		summary.JobStatus, summary.Results = j.status, j.resultsURL
+		js.running.Add(1)
+		go func() {
+			defer js.running.Done()
+			if err := js.config.Webhooks.Send(context.Background(), j.webhook, summary); err != nil {
+				log.Printf("job %s: %v", j.status.ID, err)
+			}
+		}()
+	}
+}
+
+func (js *Jobs) run(ctx context.Context, j *job, input []byte, limit int) {
+	select {
+	case js.workers <- struct{}{}:
+		defer func() { <-js.workers }()
+	case <-ctx.Done():
+		return
+	}
+	done := js.handler.metrics.StartBatch("job")
+	solved, solutions, iterations, failed := 0, 0, 0, false
+	defer func() { done(solved, solutions, iterations, failed) }()
+
+	js.mu.Lock()
+	if j.finished() {
+		js.mu.Unlock()
+		return
+	}
+	j.status.State = JobRunning
+	js.running.Add(1)
+	js.mu.Unlock()
+	defer js.running.Done()
+
+	var puzzles []string
+	limits := js.handler.limits
+	limits.MaxSize, limits.MaxPuzzles = js.config.MaxSize, js.config.MaxPuzzles
+	reader := parser.NewLimitedReader(bytes.NewReader(input), limits)
+	for {
+		puzzle, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			failed = true
+			js.finish(j, JobFailed, fmt.Errorf("puzzle %d: %v", len(puzzles)+1, err))
+			return
+		}
+		puzzles = append(puzzles, format.Inline(puzzle))
+	}
+	js.mu.Lock()
+	j.status.Total = len(puzzles)
+	js.mu.Unlock()
+
+	// Puzzles of a job are solved one at a time, the number of jobs running at once
+	// is what spreads the work over the CPUs
+	next := 0
+	source := func() (string, error) {
+		js.mu.Lock()
+		closing := js.closing
+		js.mu.Unlock()
+		if closing {
+			return "", errShuttingDown
+		}
+		if next == len(puzzles) {
+			return "", io.EOF
+		}
+		next++
+		return puzzles[next-1], nil
+	}
+	solve := func(ctx context.Context, puzzle string) (jobOutcome, error) {
+		outcome := js.solve(ctx, puzzle, limit)
+		if outcome.result.Error != "" {
+			return outcome, errors.New(outcome.result.Error)
+		}
+		return outcome, nil
+	}
+	stats, err := batch.Run(ctx, batch.Config{Inline: true, Errors: batch.Continue}, source, solve, func(item batch.Item[jobOutcome]) bool {
+		if ctx.Err() != nil {
+			// Canceled in the middle of the puzzle, its result is not complete
+			return false
+		}
+		result := item.Value.result
+		result.Index = item.Index + 1
+		line, _ := json.Marshal(result)
+		js.mu.Lock()
+		defer js.mu.Unlock()
+		switch {
+		case item.Err != nil:
+			j.summary.Failures++
+		case result.Count == 0:
+			j.summary.Unsolvable++
+		default:
+			j.summary.Solved++
+		}
+		j.results.Write(line)
+		j.results.WriteByte('\n')
+		j.status.Processed++
+		return true
+	})
+	solutions, iterations = stats.Solutions, stats.Iterations
+	js.mu.Lock()
+	solved = j.summary.Solved
+	js.mu.Unlock()
+	if errors.Is(err, errShuttingDown) {
+		js.finish(j, JobCanceled, err)
+		return
+	}
+	if err != nil {
+		// Canceled, the state is set already
+		return
+	}
+	js.finish(j, JobDone, nil)
+}
+
+// A puzzle of a job as it is solved, along with the iterations for the metrics
+type jobOutcome struct {
+	result     JobResult
+	iterations int
+}
+
+func (o jobOutcome) Tally() (solutions, iterations int) {
+	return o.result.Count, o.iterations
+}
+
+// Solves a single puzzle of a job, see Handler.SolvePuzzle
+func (js *Jobs) solve(ctx context.Context, puzzle string, limit int) jobOutcome {
+	result, iterations := js.handler.SolvePuzzle(ctx, puzzle, limit)
+	return jobOutcome{result: result, iterations: iterations}
+}
+
+// Solves a puzzle the way batch jobs do, looking for up to limit solutions and keeping the first one.
+// Returns the iterations taken as well. The search stops when ctx is done, with the solutions found so far
+func (h *Handler) SolvePuzzle(ctx context.Context, puzzle string, limit int) (JobResult, int) {
+	result := JobResult{Puzzle: puzzle}
+	s, limit, err := h.newSolver(PuzzleRequest{Puzzle: puzzle, Limit: limit})
+	if err != nil {
+		result.Error = err.Error()
+		return result, 0
+	}
+	defer h.solvers.Put(s)
+	for {
+		found, err := nextSolution(ctx, s, nil)
+		if err != nil || !found {
+			// The search is over, or it was canceled
+			break
+		}
+		if result.Count == limit {
+			result.LimitReached = true
+			break
+		}
+		if result.Count == 0 {
+			result.Solution = format.Inline(s.Solution())
+		}
+		result.Count++
+	}
+	return result, s.Iterations()
+}
+
+// For when the server is shutting down: stops taking jobs, cancels the queued ones and lets the
+// running ones finish the puzzle they are on, with its result, and send their webhooks. The jobs
+// still running when ctx is done are canceled in the middle of their puzzle
+func (js *Jobs) Shutdown(ctx context.Context) {
+	js.mu.Lock()
+	js.closing = true
+	for _, j := range js.jobs {
+		if j.status.State == JobQueued {
+			js.finishLocked(j, JobCanceled, nil)
+		}
+	}
+	js.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		js.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	for _, j := range js.jobs {
+		js.finishLocked(j, JobCanceled, nil)
+	}
+}
+
+// Returns the job or a not found error
+func (js *Jobs) get(id string) (*job, error) {
+	j, ok := js.jobs[id]
+	if !ok {
+		return nil, newError(http.StatusNotFound, CodeJobNotFound, "job %q not found", id)
+	}
+	return j, nil
+}
+
+func (js *Jobs) handleStatus(w http.ResponseWriter, id string) error {
+	js.mu.Lock()
+	j, err := js.get(id)
+	if err != nil {
+		js.mu.Unlock()
+		return err
+	}
+	status := j.status
+	js.mu.Unlock()
+	writeJSON(w, http.StatusOK, status)
+	return nil
+}
+
+// Results are JSON lines, one per puzzle processed; for canceled and failed jobs
+// that is the puzzles processed before it stopped
+func (js *Jobs) handleResults(w http.ResponseWriter, id string) error {
+	js.mu.Lock()
+	j, err := js.get(id)
+	if err != nil {
+		js.mu.Unlock()
+		return err
+	}
+	if !j.finished() {
+		js.mu.Unlock()
+		return newError(http.StatusConflict, CodeJobNotFinished, "job %q is %s", id, j.status.State)
+	}
+	// A slow client must not hold up the other requests, the results are sent without the lock
+	results := bytes.Clone(j.results.Bytes())
+	js.mu.Unlock()
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Write(results)
+	return nil
+}
+
+func (js *Jobs) handleCancel(w http.ResponseWriter, id string) error {
+	js.mu.Lock()
+	j, err := js.get(id)
+	if err != nil {
+		js.mu.Unlock()
+		return err
+	}
+	if j.finished() {
+		delete(js.jobs, id)
+	} else {
+		js.finishLocked(j, JobCanceled, nil)
+	}
+	status := j.status
+	js.mu.Unlock()
+	writeJSON(w, http.StatusOK, status)
+	return nil
+}
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The first puzzle of data/input1.txt, a hard one with a single solution
+const jobPuzzle = "4...3.......6..8..........1....5..9..8....6...7.2........1.27..5.3....4.9........"
+
+// Submits a job with the input and returns its ID
+func submit(t *testing.T, js *Jobs, input string) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	js.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(input)))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("submitting a job: %d %s", w.Code, w.Body)
+	}
+	var status JobStatus
+	if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+		t.Fatal(err)
+	}
+	return status.ID
+}
+
+// Returns the status of the job and its results
+func jobState(js *Jobs, id string) (JobStatus, string) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	j := js.jobs[id]
+	return j.status, j.results.String()
+}
+
+// A running job finishes the puzzle it is on, a queued one is canceled and no more jobs are taken
+func TestShutdown(t *testing.T) {
+	js := New(10, nil).NewJobs(JobConfig{MaxPuzzles: 10000, MaxSize: 1 << 20, Workers: 1})
+	running := submit(t, js, strings.Repeat(jobPuzzle+"\n", 5000))
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+		if status, _ := jobState(js, running); status.Processed > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the job did not start")
+		}
+	}
+	// The only worker is taken
+	queued := submit(t, js, jobPuzzle+"\n")
+
+	js.Shutdown(context.Background())
+	status, results := jobState(js, running)
+	if status.State != JobCanceled || status.Error != errShuttingDown.Error() || status.Processed == status.Total {
+		t.Errorf("got %+v for the running job, want it stopped by the shutdown", status)
+	}
+	lines := strings.Split(strings.TrimSuffix(results, "\n"), "\n")
+	if len(lines) != status.Processed {
+		t.Errorf("got %d results for %d puzzles processed", len(lines), status.Processed)
+	}
+	for _, line := range lines {
+		var result JobResult
+		if err := json.Unmarshal([]byte(line), &result); err != nil || result.Count != 1 {
+			t.Errorf("got result %s, want the puzzle solved", line)
+		}
+	}
+	if status, _ := jobState(js, queued); status.State != JobCanceled || status.Processed != 0 {
+		t.Errorf("got %+v for the queued job, want it canceled", status)
+	}
+
+	w := httptest.NewRecorder()
+	js.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(jobPuzzle)))
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), CodeShuttingDown) {
+		t.Errorf("got %d %s submitting a job after the shutdown, want %s", w.Code, w.Body, CodeShuttingDown)
+	}
+}
+
+// Jobs still running when the time is up are canceled
+func TestShutdownTimeout(t *testing.T) {
+	js := New(10, nil).NewJobs(JobConfig{MaxPuzzles: 10000, MaxSize: 1 << 20, Workers: 1})
+	id := submit(t, js, strings.Repeat(jobPuzzle+"\n", 5000))
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+		if status, _ := jobState(js, id); status.State == JobRunning {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the job did not start")
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	js.Shutdown(ctx)
+	if status, _ := jobState(js, id); status.State != JobCanceled || status.Error != "" {
+		t.Errorf("got %+v, want the job canceled", status)
+	}
+}
+
+// A client that does not read the results of a job does not hold up the requests of the others
+func TestResultsDoNotBlock(t *testing.T) {
+	js := New(10, nil).NewJobs(JobConfig{Workers: 1})
+	finished := time.Now()
+	big := &job{status: JobStatus{ID: "big", State: JobDone, Finished: &finished}, cancel: func() {}}
+	// Far more than the socket buffers take, so that writing it blocks
+	line := bytes.Repeat([]byte{'x'}, 1023)
+	for range 64 * 1024 {
+		big.results.Write(line)
+		big.results.WriteByte('\n')
+	}
+	js.jobs[big.status.ID] = big
+	server := httptest.NewServer(js)
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	fmt.Fprintf(conn, "GET /jobs/big/results HTTP/1.1\r\nHost: %s\r\n\r\n", server.Listener.Addr())
+	// Let the handler fill the buffers
+	time.Sleep(100 * time.Millisecond)
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(server.URL + "/jobs/big")
+	if err != nil {
+		t.Fatalf("getting the status while the results are not read: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("got status %s, want 200", resp.Status)
+	}
+}
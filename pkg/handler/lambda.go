@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The Lambda runtime sets this to the address of its runtime API
+// (https://docs.aws.amazon.com/lambda/latest/dg/runtimes-api.html)
+const envLambdaRuntimeAPI = "AWS_LAMBDA_RUNTIME_API"
+
+// An event in the payload format 2.0 of API Gateway HTTP APIs and Lambda function URLs
+type lambdaRequest struct {
+	RawPath         string            `json:"rawPath"`
+	RawQueryString  string            `json:"rawQueryString"`
+	Headers         map[string]string `json:"headers"`
+	Body            string            `json:"body"`
+	IsBase64Encoded bool              `json:"isBase64Encoded"`
+	RequestContext  struct {
+		HTTP struct {
+			Method string `json:"method"`
+		} `json:"http"`
+	} `json:"requestContext"`
+}
+
+type lambdaResponse struct {
+	StatusCode      int               `json:"statusCode"`
+	Headers         map[string]string `json:"headers"`
+	Body            string            `json:"body"`
+	IsBase64Encoded bool              `json:"isBase64Encoded"`
+}
+
+// Collects the whole response, Lambda does not stream. Flush is a no-op,
+// so /enumerate still works and returns all the events at once
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	return b.body.Write(p)
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+func (b *bufferedResponse) Flush() {}
+
+// Runs a single Lambda event through h and returns the response event
+func InvokeLambda(ctx context.Context, h http.Handler, event []byte) ([]byte, error) {
+	var req lambdaRequest
+	if err := json.Unmarshal(event, &req); err != nil {
+		return nil, fmt.Errorf("invalid event: %v", err)
+	}
+	body := []byte(req.Body)
+	if req.IsBase64Encoded {
+		var err error
+		if body, err = base64.StdEncoding.DecodeString(req.Body); err != nil {
+			return nil, fmt.Errorf("invalid event body: %v", err)
+		}
+	}
+	url := req.RawPath
+	if req.RawQueryString != "" {
+		url += "?" + req.RawQueryString
+	}
+	r, err := http.NewRequestWithContext(ctx, req.RequestContext.HTTP.Method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range req.Headers {
+		r.Header.Set(name, value)
+	}
+
+	w := &bufferedResponse{header: http.Header{}}
+	h.ServeHTTP(w, r)
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	resp := lambdaResponse{StatusCode: w.status, Headers: map[string]string{}, Body: w.body.String()}
+	for name, values := range w.header {
+		resp.Headers[name] = strings.Join(values, ", ")
+	}
+	return json.Marshal(resp)
+}
+
+// Handles Lambda invocations with h, this is the main loop of a custom runtime
+// (the provided.al2023 one, with the binary named 'bootstrap'). Only returns on errors
+// talking to the runtime API
+func ServeLambda(h http.Handler) error {
+	api := os.Getenv(envLambdaRuntimeAPI)
+	if api == "" {
+		return fmt.Errorf("%s is not set, not running in AWS Lambda", envLambdaRuntimeAPI)
+	}
+	base := "http://" + api + "/2018-06-01/runtime/invocation/"
+	for {
+		resp, err := http.Get(base + "next")
+		if err != nil {
+			return err
+		}
+		event, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("runtime API returned %s for the next invocation", resp.Status)
+		}
+		id := resp.Header.Get("Lambda-Runtime-Aws-Request-Id")
+
+		ctx, cancel := context.Background(), context.CancelFunc(func() {})
+		if ms, err := strconv.ParseInt(resp.Header.Get("Lambda-Runtime-Deadline-Ms"), 10, 64); err == nil {
+			ctx, cancel = context.WithDeadline(ctx, time.UnixMilli(ms))
+		}
+		out, err := InvokeLambda(ctx, h, event)
+		cancel()
+
+		if err != nil {
+			report, _ := json.Marshal(map[string]string{"errorMessage": err.Error(), "errorType": "InvalidEvent"})
+			resp, err = http.Post(base+id+"/error", "application/json", bytes.NewReader(report))
+		} else {
+			resp, err = http.Post(base+id+"/response", "application/json", bytes.NewReader(out))
+		}
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+	}
+}
@@ -0,0 +1,341 @@
+// Package hunt searches a solution grid for puzzles with a given number of clues, the way
+// minimum clue research does it. Every puzzle with a unique solution has to have a clue in each
+// unavoidable set of its grid: a set of cells whose digits can be rearranged into another valid grid
+// without touching the rest. So instead of trying all the clue subsets, the search only picks clue sets
+// that hit every known unavoidable set, and then checks them with the solver. A clue set that turns out
+// to have another solution gives a new unavoidable set, which prunes the rest of the search
+package hunt
+
+import (
+	"fmt"
+	"math/bits"
+	"slices"
+	"sync"
+
+	"github.com/AndrewSav/sudocoo/pkg/analysis"
+	"github.com/AndrewSav/sudocoo/pkg/solver"
+)
+
+const sudokuSize = 9
+
+// A set of cells as a bit mask, cell y*9+x is bit y*9+x
+type cellSet [2]uint64
+
+func (s cellSet) has(cell int) bool {
+	return s[cell/64]&(1<<(cell%64)) != 0
+}
+
+func (s cellSet) with(cell int) cellSet {
+	s[cell/64] |= 1 << (cell % 64)
+	return s
+}
+
+func (s cellSet) and(o cellSet) cellSet {
+	return cellSet{s[0] & o[0], s[1] & o[1]}
+}
+
+func (s cellSet) andNot(o cellSet) cellSet {
+	return cellSet{s[0] &^ o[0], s[1] &^ o[1]}
+}
+
+func (s cellSet) empty() bool {
+	return s[0] == 0 && s[1] == 0
+}
+
+func (s cellSet) count() int {
+	return bits.OnesCount64(s[0]) + bits.OnesCount64(s[1])
+}
+
+// Returns the cells in increasing order
+func (s cellSet) cells() []int {
+	var cells []int
+	for i, word := range s {
+		for word != 0 {
+			bit := bits.TrailingZeros64(word)
+			cells = append(cells, i*64+bit)
+			word &^= 1 << bit
+		}
+	}
+	return cells
+}
+
+// A point of the search: the cells chosen as clues and the cells ruled out as clues
+type state struct {
+	clues cellSet
+	dead  cellSet
+}
+
+// The most unavoidable sets kept from the initial scan, the smallest ones prune the most
+const maxInitialSets = 2000
+
+// The most sets learnt by the units that are passed on to the later units. They are only found
+// where the known sets do not prune enough, so they are worth keeping, but every set slows down each step
+const maxLearntSets = 20000
+
+// How many alternative grids to take from each digit combination in the initial scan
+const alternativesPerScan = 500
+
+// The search is split a clue deeper at a time until there are at least that many units,
+// so that the units are short enough to checkpoint often
+const (
+	minUnits      = 100000
+	maxSplitDepth = 10
+)
+
+// Searches a single grid for puzzles with a number of clues. The search is split into units
+// that can be run separately and in any order, each of them finds its own puzzles
+type Hunter struct {
+	grid  [sudokuSize][sudokuSize]int
+	clues int
+	sets  []cellSet // the unavoidable sets known before the search, smallest first
+	units []state
+
+	mu     sync.Mutex
+	learnt []cellSet // sets the units found, the later units start with them
+}
+
+// Prepares the search of the grid, which has to be a complete valid grid, for puzzles with
+// the number of clues. Finds the unavoidable sets and splits the search into units
+func New(grid [sudokuSize][sudokuSize]int, clues int) (*Hunter, error) {
+	if !analysis.IsSolutionOf(grid, grid) {
+		return nil, fmt.Errorf("the grid is not a complete valid grid")
+	}
+	if clues < 1 || clues > sudokuSize*sudokuSize {
+		return nil, fmt.Errorf("invalid number of clues %d", clues)
+	}
+	h := &Hunter{grid: grid, clues: clues, sets: unavoidableSets(grid)}
+	for depth := 1; depth <= maxSplitDepth && len(h.units) < minUnits; depth++ {
+		h.units = h.split(state{}, depth)
+	}
+	return h, nil
+}
+
+// Returns the number of unavoidable sets found before the search
+func (h *Hunter) Sets() int {
+	return len(h.sets)
+}
+
+// Returns the number of units the search is split into
+func (h *Hunter) Units() int {
+	return len(h.units)
+}
+
+// Runs a unit of the search and calls found with each puzzle as it finds it, returns how many it found.
+// A unique clue set with fewer clues than asked for is found as well, but not the puzzles it is part of.
+// Units can run concurrently, then found is called concurrently too
+func (h *Hunter) RunUnit(unit int, found func([sudokuSize][sudokuSize]int)) int {
+	h.mu.Lock()
+	r := run{hunter: h, sets: append(slices.Clone(h.sets), h.learnt...), found: found}
+	h.mu.Unlock()
+	start := len(r.sets)
+	r.search(h.units[unit], nil, 0)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	room := maxLearntSets - len(h.learnt)
+	h.learnt = append(h.learnt, r.sets[start:start+min(room, len(r.sets)-start)]...)
+	return r.count
+}
+
+// Splits the search into the states a few clues down, using the initial unavoidable sets only,
+// so that the units do not depend on what the search learns
+func (h *Hunter) split(s state, depth int) []state {
+	if depth == 0 || s.clues.count() == h.clues {
+		return []state{s}
+	}
+	set, ok := smallestUnhit(unhit(h.sets, s.clues), s)
+	if !ok {
+		return []state{s}
+	}
+	var states []state
+	for _, child := range branches(s, set) {
+		states = append(states, h.split(child, depth-1)...)
+	}
+	return states
+}
+
+// The search of a unit, with the unavoidable sets it has learnt on the way
+type run struct {
+	hunter *Hunter
+	sets   []cellSet
+	found  func([sudokuSize][sudokuSize]int)
+	count  int
+}
+
+// Searches on from the state. open holds the sets with no clue in them out of the first known
+// sets, the sets learnt since are checked here, so that each level only looks at what is left
+func (r *run) search(s state, open []cellSet, known int) {
+	open = append(open, unhit(r.sets[known:], s.clues)...)
+	known = len(r.sets)
+	remaining := r.hunter.clues - s.clues.count()
+	if lowerBound(open, s, remaining) > remaining {
+		return
+	}
+	set, ok := smallestUnhit(open, s)
+	if !ok {
+		// Every known set is hit, either the clues make a puzzle or the other solution shows a new set
+		puzzle := r.puzzle(s.clues)
+		other, unique := r.otherSolution(puzzle)
+		if unique {
+			r.found(puzzle)
+			r.count++
+			return
+		}
+		set = difference(r.hunter.grid, other)
+		r.sets = append(r.sets, set)
+		open = append(open, set)
+		known = len(r.sets)
+		set = set.andNot(s.dead)
+	}
+	if remaining == 0 {
+		return
+	}
+	for _, child := range branches(s, set) {
+		r.search(child, unhit(open, child.clues), known)
+	}
+}
+
+// Returns the grid with only the clue cells filled in
+func (r *run) puzzle(clues cellSet) (puzzle [sudokuSize][sudokuSize]int) {
+	for _, cell := range clues.cells() {
+		puzzle[cell/sudokuSize][cell%sudokuSize] = r.hunter.grid[cell/sudokuSize][cell%sudokuSize]
+	}
+	return
+}
+
+// Returns a solution of the puzzle other than the grid, or true if there is none
+func (r *run) otherSolution(puzzle [sudokuSize][sudokuSize]int) ([sudokuSize][sudokuSize]int, bool) {
+	s, err := solvers.Get(puzzle)
+	if err != nil {
+		// The clues come from a valid grid, so they cannot be inconsistent
+		panic(err)
+	}
+	defer solvers.Put(s)
+	for s.Solve() {
+		if solution := s.Solution(); solution != r.hunter.grid {
+			return solution, false
+		}
+	}
+	return [sudokuSize][sudokuSize]int{}, true
+}
+
+var solvers solver.Pool
+
+// Each cell of the set in turn becomes a clue, with the cells before it ruled out, so that
+// every clue set that hits the set is in exactly one of the branches
+func branches(s state, set cellSet) []state {
+	var children []state
+	dead := s.dead
+	for _, cell := range set.andNot(s.dead).cells() {
+		children = append(children, state{clues: s.clues.with(cell), dead: dead})
+		dead = dead.with(cell)
+	}
+	return children
+}
+
+// Returns the sets with none of the clues in them
+func unhit(sets []cellSet, clues cellSet) []cellSet {
+	var result []cellSet
+	for _, set := range sets {
+		if set.and(clues).empty() {
+			result = append(result, set)
+		}
+	}
+	return result
+}
+
+// Returns the set among the unhit ones with the fewest cells that can still be clues, only those cells.
+// A set with none of them left comes first, it has no branches and so ends the search there
+func smallestUnhit(sets []cellSet, s state) (cellSet, bool) {
+	best, bestCount := cellSet{}, -1
+	for _, set := range sets {
+		open := set.andNot(s.dead)
+		if c := open.count(); bestCount == -1 || c < bestCount {
+			best, bestCount = open, c
+			if c == 0 {
+				break
+			}
+		}
+	}
+	return best, bestCount != -1
+}
+
+// Returns how many more clues are needed at least: the number of unhit sets that do not share
+// any cell that can still be a clue, picked greedily. Stops counting once it goes over the limit
+func lowerBound(sets []cellSet, s state, limit int) int {
+	var used cellSet
+	count := 0
+	for _, set := range sets {
+		open := set.andNot(s.dead)
+		if open.empty() {
+			return limit + 1
+		}
+		if open.and(used).empty() {
+			used = cellSet{used[0] | open[0], used[1] | open[1]}
+			count++
+			if count > limit {
+				break
+			}
+		}
+	}
+	return count
+}
+
+// Returns the cells where the grids differ
+func difference(a, b [sudokuSize][sudokuSize]int) cellSet {
+	var set cellSet
+	for y := range sudokuSize {
+		for x := range sudokuSize {
+			if a[y][x] != b[y][x] {
+				set = set.with(y*sudokuSize + x)
+			}
+		}
+	}
+	return set
+}
+
+// Finds unavoidable sets by removing all the cells of two, three and four digits from the grid
+// and comparing the other solutions of what is left with the grid. Only the minimal sets are kept,
+// smallest first, a set with another set inside it never prunes anything the smaller one does not
+func unavoidableSets(grid [sudokuSize][sudokuSize]int) []cellSet {
+	var found []cellSet
+	for mask := 1; mask < 1<<sudokuSize; mask++ {
+		if n := bits.OnesCount(uint(mask)); n < 2 || n > 4 {
+			continue
+		}
+		puzzle := grid
+		for y := range sudokuSize {
+			for x := range sudokuSize {
+				if mask&(1<<(grid[y][x]-1)) != 0 {
+					puzzle[y][x] = 0
+				}
+			}
+		}
+		s, _ := solvers.Get(puzzle)
+		for i := 0; i < alternativesPerScan && s.Solve(); i++ {
+			if solution := s.Solution(); solution != grid {
+				found = append(found, difference(grid, solution))
+			}
+		}
+		solvers.Put(s)
+	}
+	slices.SortStableFunc(found, func(a, b cellSet) int { return a.count() - b.count() })
+
+	var minimal []cellSet
+	for _, set := range found {
+		redundant := false
+		for _, kept := range minimal {
+			if kept.andNot(set).empty() {
+				redundant = true
+				break
+			}
+		}
+		if !redundant {
+			minimal = append(minimal, set)
+			if len(minimal) == maxInitialSets {
+				break
+			}
+		}
+	}
+	return minimal
+}
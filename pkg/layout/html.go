@@ -0,0 +1,81 @@
+package layout
+
+import (
+	"html/template"
+	"io"
+)
+
+// A grid the way the template gets it
+type htmlGrid struct {
+	Cells   [sudokuSize][sudokuSize]string
+	Caption string
+}
+
+// The document is self contained, its print style puts each page on a sheet of its own
+var htmlTemplate = template.Must(template.New("layout").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{if .Title}}{{.Title}}{{else}}Sudoku{{end}}</title>
+<style>
+body { font-family: sans-serif; margin: 0; }
+.page { padding: 1cm; page-break-after: always; break-after: page; }
+.page:last-child { page-break-after: auto; break-after: auto; }
+h1 { font-size: 14pt; text-align: center; margin: 0 0 0.5cm; }
+.grids { display: grid; grid-template-columns: repeat({{.Columns}}, 1fr); gap: 0.8cm; }
+figure { margin: 0; text-align: center; }
+table { border-collapse: collapse; margin: 0 auto; border: 2px solid #000; }
+td { width: 1.8em; height: 1.8em; border: 1px solid #888; text-align: center; font-size: 13pt; padding: 0; }
+td:nth-child(3n) { border-right: 2px solid #000; }
+tr:nth-child(3n) td { border-bottom: 2px solid #000; }
+figcaption { margin-top: 0.2cm; font-size: 10pt; }
+</style>
+</head>
+<body>
+{{- range .Pages}}
+<div class="page">
+{{- if $.Title}}
+<h1>{{$.Title}}</h1>
+{{- end}}
+<div class="grids">
+{{- range .}}
+<figure>
+<table>
+{{- range .Cells}}
+<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{- end}}
+</table>
+<figcaption>{{.Caption}}</figcaption>
+</figure>
+{{- end}}
+</div>
+</div>
+{{- end}}
+</body>
+</html>
+`))
+
+func writeHTML(w io.Writer, pages [][]Item, o Options) error {
+	var data struct {
+		Title   string
+		Columns int
+		Pages   [][]htmlGrid
+	}
+	data.Title, data.Columns = o.Title, o.Columns
+	for _, page := range pages {
+		var p []htmlGrid
+		for _, item := range page {
+			g := htmlGrid{Caption: item.Caption}
+			for y := range sudokuSize {
+				for x := range sudokuSize {
+					if digit := item.Grid[y][x]; digit != 0 {
+						g.Cells[y][x] = string(rune('0' + digit))
+					}
+				}
+			}
+			p = append(p, g)
+		}
+		data.Pages = append(data.Pages, p)
+	}
+	return htmlTemplate.Execute(w, data)
+}
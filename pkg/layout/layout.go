@@ -0,0 +1,67 @@
+// Package layout arranges puzzles on pages, a number of rows and columns of grids per page
+// with a caption under each, to go from a collection to a printable booklet. Pages can be
+// written as text, as an HTML document that prints a page per sheet, or as a PDF
+package layout
+
+import (
+	"fmt"
+	"io"
+)
+
+const sudokuSize = 9
+
+// A grid to place on a page
+type Item struct {
+	Grid    [sudokuSize][sudokuSize]int
+	Caption string
+}
+
+// How the grids are arranged
+type Options struct {
+	Rows    int    // grids down a page
+	Columns int    // grids across a page
+	Title   string // printed at the top of every page, if not empty
+}
+
+// The formats pages can be written in
+const (
+	FormatText = "text"
+	FormatHTML = "html"
+	FormatPDF  = "pdf"
+)
+
+// Lists the formats Write takes
+func Formats() []string {
+	return []string{FormatText, FormatHTML, FormatPDF}
+}
+
+// Writes the sections of items to w in the format, as many pages as it takes. Each section starts
+// on a new page, e.g. the solutions after the puzzles
+func Write(w io.Writer, sections [][]Item, o Options, f string) error {
+	if o.Rows < 1 || o.Columns < 1 {
+		return fmt.Errorf("invalid layout %dx%d, want at least a row and a column", o.Rows, o.Columns)
+	}
+	switch f {
+	case FormatText:
+		return writeText(w, pages(sections, o), o)
+	case FormatHTML:
+		return writeHTML(w, pages(sections, o), o)
+	case FormatPDF:
+		return writePDF(w, pages(sections, o), o)
+	}
+	return fmt.Errorf("unknown layout format %s", f)
+}
+
+// Splits the sections into pages
+func pages(sections [][]Item, o Options) [][]Item {
+	perPage := o.Rows * o.Columns
+	var result [][]Item
+	for _, items := range sections {
+		for len(items) > 0 {
+			n := min(perPage, len(items))
+			result = append(result, items[:n])
+			items = items[n:]
+		}
+	}
+	return result
+}
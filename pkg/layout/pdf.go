@@ -0,0 +1,151 @@
+package layout
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// PDF pages are A4 and drawn with lines and the Helvetica font every PDF reader has, so nothing has to be
+// embedded and the file stays small. The font is in the WinAnsi encoding, characters of captions and titles
+// outside of ASCII are printed as '?'
+
+// Page geometry in points
+const (
+	pageWidth     = 595
+	pageHeight    = 842
+	pageMargin    = 40
+	gridGap       = 24 // between the slots of the grids
+	titleHeight   = 32 // taken from the top of the page when there is a title
+	captionHeight = 18 // under each grid
+	titleSize     = 16 // font sizes
+	captionSize   = 10
+)
+
+// Widths of the ASCII characters from space to '~' in Helvetica, in thousandths of the font size
+var helveticaWidths = [...]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278, // space to /
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556, // 0 to ?
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778, // @ to O
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556, // P to _
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556, // ` to o
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584, // p to ~
+}
+
+// Helvetica cap height, in thousandths of the font size, to center digits in the cells
+const helveticaCapHeight = 718
+
+func writePDF(w io.Writer, pages [][]Item, o Options) error {
+	var contents []string
+	for _, page := range pages {
+		contents = append(contents, pdfPage(page, o))
+	}
+	if len(contents) == 0 {
+		// A PDF needs a page
+		contents = append(contents, "")
+	}
+
+	var b bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, b.Len())
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+	b.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	// Objects 1 to 3 are the catalog, the page tree and the font, then a page and its contents for each page
+	var kids []string
+	for i := range contents {
+		kids = append(kids, fmt.Sprintf("%d 0 R", 4+2*i))
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(contents)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	for i, content := range contents {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, 5+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// Returns the content stream that draws a page
+func pdfPage(page []Item, o Options) string {
+	var sb strings.Builder
+	top := float64(pageHeight - pageMargin)
+	if o.Title != "" {
+		pdfText(&sb, o.Title, titleSize, pageWidth/2, top-titleSize)
+		top -= titleHeight
+	}
+	slotWidth := (float64(pageWidth-2*pageMargin) - float64(gridGap*(o.Columns-1))) / float64(o.Columns)
+	slotHeight := (top - pageMargin - float64(gridGap*(o.Rows-1))) / float64(o.Rows)
+	size := min(slotWidth, slotHeight-captionHeight)
+	for i, item := range page {
+		row, column := i/o.Columns, i%o.Columns
+		left := pageMargin + float64(column)*(slotWidth+gridGap) + (slotWidth-size)/2
+		bottom := top - float64(row)*(slotHeight+gridGap) - size
+		pdfGrid(&sb, item.Grid, left, bottom, size)
+		pdfText(&sb, item.Caption, captionSize, left+size/2, bottom-captionSize-4)
+	}
+	return sb.String()
+}
+
+// Draws a grid with its lower left corner at left, bottom
+func pdfGrid(sb *strings.Builder, grid [sudokuSize][sudokuSize]int, left, bottom, size float64) {
+	cell := size / sudokuSize
+	// Thin lines between the cells, then thick ones around the boxes
+	for _, thick := range []bool{false, true} {
+		if thick {
+			sb.WriteString("1.8 w 0 G\n")
+		} else {
+			sb.WriteString("0.5 w 0.5 G\n")
+		}
+		for i := 0; i <= sudokuSize; i++ {
+			if (i%3 == 0) != thick {
+				continue
+			}
+			at := float64(i) * cell
+			fmt.Fprintf(sb, "%.2f %.2f m %.2f %.2f l S\n", left+at, bottom, left+at, bottom+size)
+			fmt.Fprintf(sb, "%.2f %.2f m %.2f %.2f l S\n", left, bottom+at, left+size, bottom+at)
+		}
+	}
+	fontSize := cell * 0.6
+	for y := range sudokuSize {
+		for x := range sudokuSize {
+			digit := grid[y][x]
+			if digit == 0 {
+				continue
+			}
+			baseline := bottom + float64(sudokuSize-1-y)*cell + (cell-fontSize*helveticaCapHeight/1000)/2
+			pdfText(sb, string(rune('0'+digit)), fontSize, left+(float64(x)+0.5)*cell, baseline)
+		}
+	}
+}
+
+// Writes the text with its baseline at y, centered on x
+func pdfText(sb *strings.Builder, text string, size, x, y float64) {
+	if text == "" {
+		return
+	}
+	var escaped strings.Builder
+	width := 0
+	for _, r := range text {
+		if r < ' ' || r > '~' {
+			r = '?'
+		}
+		width += helveticaWidths[r-' ']
+		if r == '(' || r == ')' || r == '\\' {
+			escaped.WriteByte('\\')
+		}
+		escaped.WriteRune(r)
+	}
+	x -= float64(width) * size / 1000 / 2
+	fmt.Fprintf(sb, "BT /F1 %.2f Tf %.2f %.2f Td (%s) Tj ET\n", size, x, y, escaped.String())
+}
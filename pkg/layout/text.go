@@ -0,0 +1,82 @@
+package layout
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// Text pages draw each grid in 13 lines of box drawing with ASCII characters, with the caption under it.
+// Grids of a row are side by side, separated by gutterWidth spaces, and pages are separated by a form
+// feed, which printers and pagers take as the start of a new page
+
+const (
+	gutterWidth = 4
+	textWidth   = 25 // characters in a line of a grid
+)
+
+func writeText(w io.Writer, pages [][]Item, o Options) error {
+	bw := bufio.NewWriter(w)
+	for n, page := range pages {
+		if n != 0 {
+			bw.WriteString("\f\n")
+		}
+		if o.Title != "" {
+			bw.WriteString(o.Title + "\n\n")
+		}
+		for row := 0; row*o.Columns < len(page); row++ {
+			if row != 0 {
+				bw.WriteString("\n")
+			}
+			line := page[row*o.Columns : min((row+1)*o.Columns, len(page))]
+			blocks := make([][]string, len(line))
+			for i, item := range line {
+				blocks[i] = textGrid(item)
+			}
+			for y := range blocks[0] {
+				var sb strings.Builder
+				for i, block := range blocks {
+					if i != 0 {
+						sb.WriteString(strings.Repeat(" ", gutterWidth))
+					}
+					sb.WriteString(block[y])
+				}
+				bw.WriteString(strings.TrimRight(sb.String(), " ") + "\n")
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// Returns the lines of a grid with its caption, all of them textWidth wide
+func textGrid(item Item) []string {
+	border := "+-------+-------+-------+"
+	lines := []string{border}
+	for y := range sudokuSize {
+		var sb strings.Builder
+		for x := range sudokuSize {
+			if x%3 == 0 {
+				sb.WriteString("| ")
+			}
+			if digit := item.Grid[y][x]; digit != 0 {
+				sb.WriteByte(byte('0' + digit))
+			} else {
+				sb.WriteByte('.')
+			}
+			sb.WriteByte(' ')
+		}
+		sb.WriteByte('|')
+		lines = append(lines, sb.String())
+		if y%3 == 2 {
+			lines = append(lines, border)
+		}
+	}
+	caption := []rune(item.Caption)
+	if len(caption) > textWidth {
+		caption = caption[:textWidth]
+	}
+	// Centered under the grid
+	pad := (textWidth - len(caption)) / 2
+	lines = append(lines, strings.Repeat(" ", pad)+string(caption)+strings.Repeat(" ", textWidth-len(caption)-pad))
+	return lines
+}
@@ -0,0 +1,123 @@
+package logic
+
+import (
+	"math/bits"
+
+	"github.com/AndrewSav/sudocoo/pkg/solver"
+)
+
+// Trial depth measures how deeply nested the assumptions have to be to solve a puzzle with singles and
+// contradictions alone, a hardness signal that does not depend on which named techniques a solver knows.
+// At depth 0 only singles are placed. At depth d, on top of the singles, a candidate is assumed and the
+// puzzle reasoned about at depth d-1, and if that runs into a contradiction the candidate is eliminated.
+// Most puzzles are at depth 0 or 1, the hardest known ones at 2 and rarely deeper. The cost grows by a factor
+// of the number of candidates with every level, so the depth is only looked for up to a limit
+
+// The limit TrialDepth is usually called with
+const DefaultMaxTrialDepth = 3
+
+// How reasoning about a grid ended
+type outcome int
+
+const (
+	stuck outcome = iota
+	solved
+	contradiction
+)
+
+// Returns the smallest trial depth that solves the puzzle, or false if none up to maxDepth does.
+// Returns an error for inconsistent givens and for puzzles that turn out to have no solution
+func TrialDepth(puzzle [sudokuSize][sudokuSize]int, maxDepth int) (int, bool, error) {
+	g, err := newGrid(puzzle)
+	if err != nil {
+		return 0, false, err
+	}
+	for depth := 0; depth <= maxDepth; depth++ {
+		trial := *g
+		switch trial.reason(depth) {
+		case solved:
+			return depth, true, nil
+		case contradiction:
+			return 0, false, solver.ErrNoSolution
+		}
+	}
+	return 0, false, nil
+}
+
+// Places singles and eliminates the candidates whose trials at a lower depth run into contradictions,
+// until the grid is solved, broken, or there is nothing left to do
+func (g *grid) reason(depth int) outcome {
+	for {
+		if !g.propagate() {
+			return contradiction
+		}
+		if g.empty == 0 {
+			return solved
+		}
+		if depth == 0 {
+			return stuck
+		}
+		progress := false
+		for y := range sudokuSize {
+			for x := range sudokuSize {
+				for _, digit := range digitsOf(g.candidates[y][x]) {
+					trial := *g
+					trial.place(Cell{y, x}, digit)
+					if trial.reason(depth-1) == contradiction {
+						g.candidates[y][x] &^= 1 << digit
+						progress = true
+					}
+				}
+			}
+		}
+		if !progress {
+			return stuck
+		}
+	}
+}
+
+// Places naked and hidden singles until there are none, returns false if the grid breaks on the way
+func (g *grid) propagate() bool {
+	for progress := true; progress; {
+		progress = false
+		for y := range sudokuSize {
+			for x := range sudokuSize {
+				if g.values[y][x] != 0 {
+					continue
+				}
+				c := g.candidates[y][x]
+				if c == 0 {
+					return false
+				}
+				if c&(c-1) == 0 {
+					g.place(Cell{y, x}, bits.TrailingZeros16(c))
+					progress = true
+				}
+			}
+		}
+		for kind := range unitCells {
+			for _, cells := range unitCells[kind] {
+				var placed, once, twice uint16
+				for _, c := range cells {
+					placed |= 1 << g.values[c.Row][c.Column]
+					twice |= once & g.candidates[c.Row][c.Column]
+					once |= g.candidates[c.Row][c.Column]
+				}
+				if (placed|once)&0x3fe != 0x3fe {
+					return false
+				}
+				hidden := once &^ twice &^ placed
+				for _, digit := range digitsOf(hidden) {
+					// A digit whose only cell was just taken by another one is caught on the next pass
+					for _, c := range cells {
+						if g.candidates[c.Row][c.Column]&(1<<digit) != 0 {
+							g.place(c, digit)
+							progress = true
+						}
+					}
+				}
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,227 @@
+// Package logic solves puzzles the way a person would, one deduction at a time, and records
+// each deduction as a step. Unlike the backtracking solver it never guesses, so it can get stuck
+// on puzzles that need techniques it does not know
+package logic
+
+import (
+	"math/bits"
+
+	"github.com/AndrewSav/sudocoo/pkg/solver"
+)
+
+const sudokuSize = 9
+
+// A cell position, 0-based
+type Cell struct {
+	Row, Column int
+}
+
+// Index of the box the cell is in, 0-based from the top left
+func (c Cell) Box() int {
+	return c.Row/3*3 + c.Column/3
+}
+
+// A digit in a cell, either placed or eliminated
+type Candidate struct {
+	Cell
+	Digit int
+}
+
+type UnitKind int
+
+const (
+	Row UnitKind = iota
+	Column
+	Box
+)
+
+// A row, column or box
+type Unit struct {
+	Kind  UnitKind
+	Index int // 0-based
+}
+
+// Returns the cells of the unit in order
+func (u Unit) Cells() [sudokuSize]Cell {
+	return unitCells[u.Kind][u.Index]
+}
+
+// Techniques in the order they are tried, from the easiest
+type Technique int
+
+const (
+	FullHouse      Technique = iota // the last empty cell of a unit
+	NakedSingle                     // a cell with a single candidate
+	HiddenSingle                    // a digit with a single place in a unit
+	LockedPointing                  // the candidates for a digit in a box are all in one row or column
+	LockedClaiming                  // the candidates for a digit in a row or column are all in one box
+	NakedPair                       // two cells of a unit with the same two candidates
+	HiddenPair                      // two digits with the same two places in a unit
+	NakedTriple                     // three cells of a unit with three candidates between them
+	HiddenTriple                    // three digits with three places between them in a unit
+)
+
+// A single deduction
+type Step struct {
+	Technique    Technique
+	Digits       []int       // the digits the pattern is made of
+	Cells        []Cell      // the cells the pattern is made of
+	Unit         Unit        // the unit the pattern is in, for locked candidates the unit the digit is locked in
+	Placements   []Candidate // digits placed by the step
+	Eliminations []Candidate // candidates removed by the step
+}
+
+// What the engine made of a puzzle
+type Result struct {
+	Steps  []Step
+	Grid   [sudokuSize][sudokuSize]int // the grid as far as the steps got
+	Solved bool                        // the steps got all the way, otherwise the techniques ran out
+}
+
+// Returns the hardest technique the steps use, false if there are no steps
+func (r Result) Hardest() (Technique, bool) {
+	if len(r.Steps) == 0 {
+		return 0, false
+	}
+	hardest := r.Steps[0].Technique
+	for _, step := range r.Steps[1:] {
+		hardest = max(hardest, step.Technique)
+	}
+	return hardest, true
+}
+
+// The cells of each unit, by kind and index
+var unitCells [3][sudokuSize][sudokuSize]Cell
+
+func init() {
+	for unit := range sudokuSize {
+		for i := range sudokuSize {
+			unitCells[Row][unit][i] = Cell{unit, i}
+			unitCells[Column][unit][i] = Cell{i, unit}
+			unitCells[Box][unit][i] = Cell{unit/3*3 + i/3, unit%3*3 + i%3}
+		}
+	}
+}
+
+// The puzzle as it is being solved: the placed digits and the candidates of the empty cells,
+// as bit masks with bit d set for digit d
+type grid struct {
+	values     [sudokuSize][sudokuSize]int
+	candidates [sudokuSize][sudokuSize]uint16
+	empty      int
+}
+
+func newGrid(puzzle [sudokuSize][sudokuSize]int) (*grid, error) {
+	g := &grid{}
+	for y := range sudokuSize {
+		for x := range sudokuSize {
+			g.candidates[y][x] = 0x3fe
+		}
+	}
+	g.empty = sudokuSize * sudokuSize
+	for y := range sudokuSize {
+		for x := range sudokuSize {
+			digit := puzzle[y][x]
+			if digit == 0 {
+				continue
+			}
+			if digit > sudokuSize || g.candidates[y][x]&(1<<digit) == 0 {
+				return nil, solver.ErrInvalidPuzzle
+			}
+			g.place(Cell{y, x}, digit)
+		}
+	}
+	return g, nil
+}
+
+// Puts the digit in the cell and removes it from the candidates of the cell's peers
+func (g *grid) place(cell Cell, digit int) {
+	g.values[cell.Row][cell.Column] = digit
+	g.candidates[cell.Row][cell.Column] = 0
+	g.empty--
+	for _, kind := range []UnitKind{Row, Column, Box} {
+		index := [...]int{cell.Row, cell.Column, cell.Box()}[kind]
+		for _, peer := range unitCells[kind][index] {
+			g.candidates[peer.Row][peer.Column] &^= 1 << digit
+		}
+	}
+}
+
+// Applies the placements and eliminations of a step
+func (g *grid) apply(step Step) {
+	for _, p := range step.Placements {
+		g.place(p.Cell, p.Digit)
+	}
+	for _, e := range step.Eliminations {
+		g.candidates[e.Row][e.Column] &^= 1 << e.Digit
+	}
+}
+
+// Checks whether an empty cell has no candidates left or a unit has no place left for a digit
+// it is missing, which means the givens contradict each other
+func (g *grid) broken() bool {
+	for y := range sudokuSize {
+		for x := range sudokuSize {
+			if g.values[y][x] == 0 && g.candidates[y][x] == 0 {
+				return true
+			}
+		}
+	}
+	for kind := range unitCells {
+		for _, cells := range unitCells[kind] {
+			var covered uint16
+			for _, c := range cells {
+				covered |= g.candidates[c.Row][c.Column] | 1<<g.values[c.Row][c.Column]
+			}
+			if covered&0x3fe != 0x3fe {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Returns the digits of a candidate mask in increasing order
+func digitsOf(mask uint16) []int {
+	var digits []int
+	for mask != 0 {
+		digit := bits.TrailingZeros16(mask)
+		digits = append(digits, digit)
+		mask &^= 1 << digit
+	}
+	return digits
+}
+
+// Applies the easiest technique that makes progress until the puzzle is solved or none does.
+// Returns an error for inconsistent givens and for puzzles that turn out to have no solution
+func Solve(puzzle [sudokuSize][sudokuSize]int) (Result, error) {
+	g, err := newGrid(puzzle)
+	if err != nil {
+		return Result{}, err
+	}
+	var result Result
+	for g.empty != 0 {
+		if g.broken() {
+			return Result{}, solver.ErrNoSolution
+		}
+		step, ok := nextStep(g)
+		if !ok {
+			break
+		}
+		g.apply(step)
+		result.Steps = append(result.Steps, step)
+	}
+	result.Grid = g.values
+	result.Solved = g.empty == 0
+	return result, nil
+}
+
+// Finds the next step with the easiest technique that has one
+func nextStep(g *grid) (Step, bool) {
+	for _, find := range techniques {
+		if step, ok := find(g); ok {
+			return step, true
+		}
+	}
+	return Step{}, false
+}
@@ -0,0 +1,119 @@
+package logic
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// The names HoDoKu and SudokuWiki use for the techniques
+var techniqueNames = map[Technique]string{
+	FullHouse:      "Full House",
+	NakedSingle:    "Naked Single",
+	HiddenSingle:   "Hidden Single",
+	LockedPointing: "Locked Candidates Type 1 (Pointing)",
+	LockedClaiming: "Locked Candidates Type 2 (Claiming)",
+	NakedPair:      "Naked Pair",
+	HiddenPair:     "Hidden Pair",
+	NakedTriple:    "Naked Triple",
+	HiddenTriple:   "Hidden Triple",
+}
+
+func (t Technique) String() string {
+	if name, ok := techniqueNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("Technique(%d)", int(t))
+}
+
+// Returns the unit in HoDoKu notation, e.g. r1, c9 or b5
+func (u Unit) String() string {
+	return fmt.Sprintf("%c%d", "rcb"[u.Kind], u.Index+1)
+}
+
+// Returns the cell in HoDoKu notation, e.g. r1c9
+func (c Cell) String() string {
+	return fmt.Sprintf("r%dc%d", c.Row+1, c.Column+1)
+}
+
+// Returns the step in HoDoKu notation, which SudokuWiki understands as well, e.g.
+// "Hidden Single: r3c4=7", "Locked Candidates Type 1 (Pointing): 5 in b1 => r1c78<>5" or
+// "Naked Pair: 1,6 in r4c29 => r4c57<>16"
+func (s Step) String() string {
+	switch s.Technique {
+	case FullHouse, NakedSingle, HiddenSingle:
+		p := s.Placements[0]
+		return fmt.Sprintf("%s: %s=%d", s.Technique, p.Cell, p.Digit)
+	case NakedPair, HiddenPair, NakedTriple, HiddenTriple:
+		return fmt.Sprintf("%s: %s in %s => %s", s.Technique, joinDigits(s.Digits, ","), formatCells(s.Cells), formatEliminations(s.Eliminations))
+	default:
+		return fmt.Sprintf("%s: %s in %s => %s", s.Technique, joinDigits(s.Digits, ","), s.Unit, formatEliminations(s.Eliminations))
+	}
+}
+
+// Groups the eliminations by cells that lose the same digits, e.g. "r1c5<>15, r2c46<>1"
+func formatEliminations(eliminations []Candidate) string {
+	var cells []Cell
+	digits := map[Cell][]int{}
+	for _, e := range eliminations {
+		if _, ok := digits[e.Cell]; !ok {
+			cells = append(cells, e.Cell)
+		}
+		digits[e.Cell] = append(digits[e.Cell], e.Digit)
+	}
+	var groups []string
+	byDigits := map[string][]Cell{}
+	for _, c := range cells {
+		key := joinDigits(digits[c], "")
+		if _, ok := byDigits[key]; !ok {
+			groups = append(groups, key)
+		}
+		byDigits[key] = append(byDigits[key], c)
+	}
+	parts := make([]string, len(groups))
+	for i, key := range groups {
+		parts[i] = formatCells(byDigits[key]) + "<>" + key
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Writes a set of cells the compact HoDoKu way: the columns of each row together and rows
+// with the same columns together, e.g. "r1c78" or "r12c3,r5c1"
+func formatCells(cells []Cell) string {
+	columns := map[int][]int{}
+	var rows []int
+	for _, c := range cells {
+		if _, ok := columns[c.Row]; !ok {
+			rows = append(rows, c.Row)
+		}
+		columns[c.Row] = append(columns[c.Row], c.Column+1)
+	}
+	slices.Sort(rows)
+	var parts []string
+	for i := 0; i < len(rows); {
+		key := columns[rows[i]]
+		slices.Sort(key)
+		same := []int{rows[i] + 1}
+		j := i + 1
+		for ; j < len(rows); j++ {
+			other := columns[rows[j]]
+			slices.Sort(other)
+			if !slices.Equal(key, other) {
+				break
+			}
+			same = append(same, rows[j]+1)
+		}
+		parts = append(parts, "r"+joinDigits(same, "")+"c"+joinDigits(key, ""))
+		i = j
+	}
+	return strings.Join(parts, ",")
+}
+
+func joinDigits(digits []int, separator string) string {
+	parts := make([]string, len(digits))
+	for i, d := range digits {
+		parts[i] = strconv.Itoa(d)
+	}
+	return strings.Join(parts, separator)
+}
@@ -0,0 +1,284 @@
+package logic
+
+import (
+	"math/bits"
+	"slices"
+)
+
+// Each technique returns the first step it finds, scanning units and cells in order so that
+// the same puzzle always gives the same steps
+var techniques = []func(g *grid) (Step, bool){
+	findFullHouse,
+	findNakedSingle,
+	findHiddenSingle,
+	findLockedPointing,
+	findLockedClaiming,
+	findNakedPair,
+	findHiddenPair,
+	findNakedTriple,
+	findHiddenTriple,
+}
+
+// The only empty cell of a unit
+func findFullHouse(g *grid) (Step, bool) {
+	for _, kind := range []UnitKind{Row, Column, Box} {
+		for index, cells := range unitCells[kind] {
+			var last Cell
+			empty := 0
+			for _, c := range cells {
+				if g.values[c.Row][c.Column] == 0 {
+					last = c
+					empty++
+				}
+			}
+			if empty != 1 || g.candidates[last.Row][last.Column] == 0 {
+				continue
+			}
+			digit := bits.TrailingZeros16(g.candidates[last.Row][last.Column])
+			return singleStep(FullHouse, Unit{kind, index}, last, digit), true
+		}
+	}
+	return Step{}, false
+}
+
+// A cell with only one candidate left
+func findNakedSingle(g *grid) (Step, bool) {
+	for y := range sudokuSize {
+		for x := range sudokuSize {
+			if c := g.candidates[y][x]; c != 0 && c&(c-1) == 0 {
+				cell := Cell{y, x}
+				return singleStep(NakedSingle, Unit{Kind: Box, Index: cell.Box()}, cell, bits.TrailingZeros16(c)), true
+			}
+		}
+	}
+	return Step{}, false
+}
+
+// A digit with only one place left in a unit, boxes first since they are the easiest to spot
+func findHiddenSingle(g *grid) (Step, bool) {
+	for _, kind := range []UnitKind{Box, Row, Column} {
+		for index, cells := range unitCells[kind] {
+			for digit := 1; digit <= sudokuSize; digit++ {
+				places := placesFor(g, cells, digit)
+				if len(places) == 1 {
+					return singleStep(HiddenSingle, Unit{kind, index}, places[0], digit), true
+				}
+			}
+		}
+	}
+	return Step{}, false
+}
+
+// The candidates for a digit in a box all in one row or column: the digit goes in the box
+// on that line, so it can be eliminated from the rest of the line
+func findLockedPointing(g *grid) (Step, bool) {
+	for box, cells := range unitCells[Box] {
+		for digit := 1; digit <= sudokuSize; digit++ {
+			places := placesFor(g, cells, digit)
+			if len(places) < 2 {
+				continue
+			}
+			for _, kind := range []UnitKind{Row, Column} {
+				line, ok := commonLine(places, kind)
+				if !ok {
+					continue
+				}
+				eliminations := eliminate(g, unitCells[kind][line], digit, func(c Cell) bool { return c.Box() == box })
+				if len(eliminations) != 0 {
+					return Step{Technique: LockedPointing, Digits: []int{digit}, Cells: places, Unit: Unit{Box, box}, Eliminations: eliminations}, true
+				}
+			}
+		}
+	}
+	return Step{}, false
+}
+
+// The candidates for a digit in a row or column all in one box: the digit goes in the box
+// on that line, so it can be eliminated from the rest of the box
+func findLockedClaiming(g *grid) (Step, bool) {
+	for _, kind := range []UnitKind{Row, Column} {
+		for line, cells := range unitCells[kind] {
+			for digit := 1; digit <= sudokuSize; digit++ {
+				places := placesFor(g, cells, digit)
+				if len(places) < 2 {
+					continue
+				}
+				box := places[0].Box()
+				sameBox := true
+				for _, p := range places[1:] {
+					sameBox = sameBox && p.Box() == box
+				}
+				if !sameBox {
+					continue
+				}
+				onLine := func(c Cell) bool { return [...]int{c.Row, c.Column}[kind] == line }
+				eliminations := eliminate(g, unitCells[Box][box], digit, onLine)
+				if len(eliminations) != 0 {
+					return Step{Technique: LockedClaiming, Digits: []int{digit}, Cells: places, Unit: Unit{kind, line}, Eliminations: eliminations}, true
+				}
+			}
+		}
+	}
+	return Step{}, false
+}
+
+func findNakedPair(g *grid) (Step, bool)    { return findNakedSubset(g, NakedPair, 2) }
+func findHiddenPair(g *grid) (Step, bool)   { return findHiddenSubset(g, HiddenPair, 2) }
+func findNakedTriple(g *grid) (Step, bool)  { return findNakedSubset(g, NakedTriple, 3) }
+func findHiddenTriple(g *grid) (Step, bool) { return findHiddenSubset(g, HiddenTriple, 3) }
+
+// Size empty cells of a unit with size candidates between them: those digits go in those cells,
+// so they can be eliminated from the rest of the unit
+func findNakedSubset(g *grid, technique Technique, size int) (Step, bool) {
+	for _, kind := range []UnitKind{Row, Column, Box} {
+		for index, cells := range unitCells[kind] {
+			// Bit i is the i-th cell of the unit
+			var empty uint16
+			for i, c := range cells {
+				if g.candidates[c.Row][c.Column] != 0 {
+					empty |= 1 << i
+				}
+			}
+			for _, subset := range subsetMasks[size] {
+				if subset&empty != subset {
+					continue
+				}
+				var digits uint16
+				var pattern []Cell
+				for i, c := range cells {
+					if subset&(1<<i) != 0 {
+						digits |= g.candidates[c.Row][c.Column]
+						pattern = append(pattern, c)
+					}
+				}
+				if bits.OnesCount16(digits) != size {
+					continue
+				}
+				inPattern := func(c Cell) bool { return slices.Contains(pattern, c) }
+				var eliminations []Candidate
+				for _, digit := range digitsOf(digits) {
+					eliminations = append(eliminations, eliminate(g, cells, digit, inPattern)...)
+				}
+				if len(eliminations) != 0 {
+					sortCandidates(eliminations)
+					return Step{Technique: technique, Digits: digitsOf(digits), Cells: pattern, Unit: Unit{kind, index}, Eliminations: eliminations}, true
+				}
+			}
+		}
+	}
+	return Step{}, false
+}
+
+// Size digits with size places between them in a unit: those cells take those digits, so the
+// other candidates can be eliminated from them
+func findHiddenSubset(g *grid, technique Technique, size int) (Step, bool) {
+	for _, kind := range []UnitKind{Row, Column, Box} {
+		for index, cells := range unitCells[kind] {
+			// The places of each digit, bit i is the i-th cell of the unit. Digits placed already
+			// have none
+			var places [sudokuSize + 1]uint16
+			for i, c := range cells {
+				for _, digit := range digitsOf(g.candidates[c.Row][c.Column]) {
+					places[digit] |= 1 << i
+				}
+			}
+			// Bit i of subset is digit i+1
+			for _, subset := range subsetMasks[size] {
+				digits := subset << 1
+				var union uint16
+				missing := false
+				for _, digit := range digitsOf(digits) {
+					union |= places[digit]
+					missing = missing || places[digit] == 0
+				}
+				if missing || bits.OnesCount16(union) != size {
+					continue
+				}
+				var pattern []Cell
+				var eliminations []Candidate
+				for i, c := range cells {
+					if union&(1<<i) == 0 {
+						continue
+					}
+					pattern = append(pattern, c)
+					for _, digit := range digitsOf(g.candidates[c.Row][c.Column] &^ digits) {
+						eliminations = append(eliminations, Candidate{c, digit})
+					}
+				}
+				if len(eliminations) != 0 {
+					return Step{Technique: technique, Digits: digitsOf(digits), Cells: pattern, Unit: Unit{kind, index}, Eliminations: eliminations}, true
+				}
+			}
+		}
+	}
+	return Step{}, false
+}
+
+// The sets of each size of the 9 cells of a unit, or of the 9 digits, as bit masks in increasing order
+var subsetMasks = [...][]uint16{2: subsets(2), 3: subsets(3)}
+
+func subsets(size int) []uint16 {
+	var result []uint16
+	for mask := uint16(0); mask < 1<<sudokuSize; mask++ {
+		if bits.OnesCount16(mask) == size {
+			result = append(result, mask)
+		}
+	}
+	return result
+}
+
+// Sorts the eliminations by cell, then by digit
+func sortCandidates(candidates []Candidate) {
+	slices.SortFunc(candidates, func(a, b Candidate) int {
+		if a.Row != b.Row {
+			return a.Row - b.Row
+		}
+		if a.Column != b.Column {
+			return a.Column - b.Column
+		}
+		return a.Digit - b.Digit
+	})
+}
+
+func singleStep(technique Technique, unit Unit, cell Cell, digit int) Step {
+	return Step{
+		Technique:  technique,
+		Digits:     []int{digit},
+		Cells:      []Cell{cell},
+		Unit:       unit,
+		Placements: []Candidate{{cell, digit}},
+	}
+}
+
+// Returns the cells among the given ones that have the digit as a candidate
+func placesFor(g *grid, cells [sudokuSize]Cell, digit int) []Cell {
+	var places []Cell
+	for _, c := range cells {
+		if g.candidates[c.Row][c.Column]&(1<<digit) != 0 {
+			places = append(places, c)
+		}
+	}
+	return places
+}
+
+// Returns the row or column all the cells are in, if they are in the same one
+func commonLine(cells []Cell, kind UnitKind) (int, bool) {
+	line := func(c Cell) int { return [...]int{c.Row, c.Column}[kind] }
+	for _, c := range cells[1:] {
+		if line(c) != line(cells[0]) {
+			return 0, false
+		}
+	}
+	return line(cells[0]), true
+}
+
+// Returns the eliminations of the digit from the cells, except those the pattern is made of
+func eliminate(g *grid, cells [sudokuSize]Cell, digit int, inPattern func(Cell) bool) []Candidate {
+	var eliminations []Candidate
+	for _, c := range cells {
+		if !inPattern(c) && g.candidates[c.Row][c.Column]&(1<<digit) != 0 {
+			eliminations = append(eliminations, Candidate{c, digit})
+		}
+	}
+	return eliminations
+}
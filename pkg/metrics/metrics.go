@@ -0,0 +1,123 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Request metrics of the server modes, exposed in the Prometheus text format
+// (https://prometheus.io/docs/instrumenting/exposition_formats/). All the metrics
+// carry the 'endpoint' label, e.g. solve, count or grpc_enumerate
+
+// Upper bounds in seconds of the request duration histogram buckets
+var durationBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type endpoint struct {
+	requests   uint64
+	errors     uint64
+	solved     uint64 // puzzles with a solution found
+	solutions  uint64
+	iterations uint64
+	buckets    []uint64 // not cumulative, the last one is for durations over all the bounds
+	seconds    float64  // sum of all the durations
+}
+
+// A nil *Metrics is valid and records nothing, so that metrics can be optional
+type Metrics struct {
+	mu        sync.Mutex
+	endpoints map[string]*endpoint
+}
+
+func New() *Metrics {
+	return &Metrics{endpoints: map[string]*endpoint{}}
+}
+
+// Starts timing a request to the endpoint. Call the returned function when the request
+// is done with the number of solutions found and iterations taken. A request for a single
+// puzzle that found a solution counts as a puzzle solved
+func (m *Metrics) Start(name string) func(solutions, iterations int, failed bool) {
+	start := time.Now()
+	return func(solutions, iterations int, failed bool) {
+		solved := 0
+		if solutions > 0 && !failed {
+			solved = 1
+		}
+		m.observe(name, solved, solutions, iterations, time.Since(start), failed)
+	}
+}
+
+// Same as Start, for a request that goes through many puzzles, such as a batch job. solved is
+// the number of them that a solution was found for
+func (m *Metrics) StartBatch(name string) func(solved, solutions, iterations int, failed bool) {
+	start := time.Now()
+	return func(solved, solutions, iterations int, failed bool) {
+		m.observe(name, solved, solutions, iterations, time.Since(start), failed)
+	}
+}
+
+func (m *Metrics) observe(name string, solved, solutions, iterations int, d time.Duration, failed bool) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.endpoints[name]
+	if !ok {
+		e = &endpoint{buckets: make([]uint64, len(durationBuckets)+1)}
+		m.endpoints[name] = e
+	}
+	e.requests++
+	if failed {
+		e.errors++
+	}
+	e.solved += uint64(solved)
+	e.solutions += uint64(solutions)
+	e.iterations += uint64(iterations)
+	seconds := d.Seconds()
+	e.seconds += seconds
+	i := sort.SearchFloat64s(durationBuckets, seconds)
+	e.buckets[i]++
+}
+
+// Serves the metrics for Prometheus to scrape
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := []string{}
+	for name := range m.endpoints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	counter := func(metric, help string, value func(e *endpoint) uint64) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", metric, help, metric)
+		for _, name := range names {
+			fmt.Fprintf(w, "%s{endpoint=%q} %d\n", metric, name, value(m.endpoints[name]))
+		}
+	}
+	counter("sudocoo_requests_total", "Requests handled.", func(e *endpoint) uint64 { return e.requests })
+	counter("sudocoo_request_errors_total", "Requests that failed, e.g. because of an invalid puzzle.", func(e *endpoint) uint64 { return e.errors })
+	counter("sudocoo_puzzles_solved_total", "Puzzles a solution was found for.", func(e *endpoint) uint64 { return e.solved })
+	counter("sudocoo_solutions_total", "Solutions found.", func(e *endpoint) uint64 { return e.solutions })
+	counter("sudocoo_iterations_total", "Solver iterations taken.", func(e *endpoint) uint64 { return e.iterations })
+
+	const histogram = "sudocoo_request_duration_seconds"
+	fmt.Fprintf(w, "# HELP %s Time taken to handle a request.\n# TYPE %s histogram\n", histogram, histogram)
+	for _, name := range names {
+		e := m.endpoints[name]
+		cumulative := uint64(0)
+		for i, bound := range durationBuckets {
+			cumulative += e.buckets[i]
+			fmt.Fprintf(w, "%s_bucket{endpoint=%q,le=%q} %d\n", histogram, name, strconv.FormatFloat(bound, 'g', -1, 64), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket{endpoint=%q,le=\"+Inf\"} %d\n", histogram, name, e.requests)
+		fmt.Fprintf(w, "%s_sum{endpoint=%q} %g\n", histogram, name, e.seconds)
+		fmt.Fprintf(w, "%s_count{endpoint=%q} %d\n", histogram, name, e.requests)
+	}
+}
@@ -0,0 +1,93 @@
+package packed
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/AndrewSav/sudocoo/pkg/format"
+)
+
+// The b64 format is the encoding of a single puzzle in unpadded URL-safe base64, 55 characters that
+// can go into a URL or a config file as they are. A stream of them has one per line
+
+var base64Encoding = base64.RawURLEncoding
+
+// Characters per puzzle in the b64 format
+var Base64Size = base64Encoding.EncodedLen(PuzzleSize)
+
+func init() {
+	format.Register(format.FormatTemplate{
+		Name:        "b64",
+		Description: "Single line, the packed encoding in URL-safe base64, 55 characters",
+		Encode:      EncodeBase64,
+	})
+}
+
+// Returns the puzzle in the b64 format
+func EncodeBase64(puzzle [sudokuSize][sudokuSize]int) string {
+	return base64Encoding.EncodeToString(Encode(puzzle))
+}
+
+// Decodes a puzzle in the b64 format
+func DecodeBase64(s string) ([sudokuSize][sudokuSize]int, error) {
+	b, err := base64Encoding.DecodeString(s)
+	if err != nil || len(b) != PuzzleSize {
+		return [sudokuSize][sudokuSize]int{}, fmt.Errorf("invalid b64 puzzle %q", s)
+	}
+	if b[PuzzleSize-1]&0xf != 0 {
+		// The unused nibble of the last byte is always 0
+		return [sudokuSize][sudokuSize]int{}, fmt.Errorf("invalid b64 puzzle %q", s)
+	}
+	return Decode(b)
+}
+
+// Checks whether the data starts with a line that is a puzzle in the b64 format
+func HasBase64(b []byte) bool {
+	line, _, _ := bytes.Cut(b, []byte("\n"))
+	line = bytes.TrimSpace(line)
+	if len(line) != Base64Size {
+		return false
+	}
+	_, err := DecodeBase64(string(line))
+	return err == nil
+}
+
+// Checks whether the stream starts with a puzzle in the b64 format, without consuming anything
+func IsBase64(r *bufio.Reader) bool {
+	// Room for the line ending and some spaces around
+	b, _ := r.Peek(Base64Size + 8)
+	return HasBase64(b)
+}
+
+// The reader Text returns for b64 streams, the lines decoded to the inline format
+type base64Reader struct {
+	lines   *bufio.Scanner
+	pending []byte // the inline line of the last puzzle, as far as it has not been read
+}
+
+func (b *base64Reader) Read(p []byte) (int, error) {
+	for len(b.pending) == 0 {
+		if !b.lines.Scan() {
+			if err := b.lines.Err(); err != nil {
+				return 0, err
+			}
+			return 0, io.EOF
+		}
+		line := strings.TrimSpace(b.lines.Text())
+		if line == "" {
+			continue
+		}
+		puzzle, err := DecodeBase64(line)
+		if err != nil {
+			return 0, err
+		}
+		b.pending = []byte(format.Inline(puzzle) + "\n")
+	}
+	n := copy(p, b.pending)
+	b.pending = b.pending[n:]
+	return n, nil
+}
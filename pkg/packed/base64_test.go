@@ -0,0 +1,125 @@
+package packed
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/AndrewSav/sudocoo/pkg/parser"
+)
+
+// The b64 encodings of the puzzles of packedPuzzles
+var base64Puzzles = []string{
+	"QAAwAAAAYAgAAAAAABAABQCQCAAAYABwIAAAAAECcAUDAABAkAAAAAA",
+	"RokxUndRYkg5OSV4RhE0dWKYKJQTZ1Z1KJMUhGGSdTUThnlCknNFGGA",
+	"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA",
+}
+
+func TestBase64(t *testing.T) {
+	for i, p := range packedPuzzles {
+		puzzle, err := parser.ParsePuzzleString(p.inline)
+		if err != nil {
+			t.Fatal(err)
+		}
+		encoded := EncodeBase64(puzzle)
+		if encoded != base64Puzzles[i] {
+			t.Errorf("EncodeBase64(%s) = %s, want %s", p.inline, encoded, base64Puzzles[i])
+		}
+		if len(encoded) != Base64Size {
+			t.Errorf("EncodeBase64(%s) has %d characters, want %d", p.inline, len(encoded), Base64Size)
+		}
+		decoded, err := DecodeBase64(encoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded != puzzle {
+			t.Errorf("DecodeBase64(%s) = %v, want %s", encoded, decoded, p.inline)
+		}
+	}
+}
+
+func TestBase64Errors(t *testing.T) {
+	valid := base64Puzzles[0]
+	tests := []struct {
+		name string
+		s    string
+	}{
+		{"empty", ""},
+		{"short", valid[:Base64Size-1]},
+		{"long", valid + "A"},
+		{"padded", valid + "="},
+		{"standard alphabet", "+" + valid[1:]},
+		{"slash", valid[:10] + "/" + valid[11:]},
+		{"not base64", "!" + valid[1:]},
+		{"space inside", valid[:10] + " " + valid[11:]},
+		// The last character carries the unused nibble of the last byte
+		{"last nibble set", valid[:Base64Size-1] + "Q"},
+		// o is 101000, a 10 in the first cell
+		{"10 in a cell", "o" + valid[1:]},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if puzzle, err := DecodeBase64(test.s); err == nil {
+				t.Errorf("DecodeBase64(%q) = %v, want an error", test.s, puzzle)
+			}
+			if HasBase64([]byte(test.s + "\n")) {
+				t.Errorf("HasBase64(%q) is true", test.s)
+			}
+		})
+	}
+}
+
+func TestBase64Detection(t *testing.T) {
+	tests := []struct {
+		data string
+		want bool
+	}{
+		{base64Puzzles[0], true},
+		{base64Puzzles[0] + "\n" + base64Puzzles[1] + "\n", true},
+		{"  " + base64Puzzles[1] + " \r\n", true},
+		// Only the first line is looked at
+		{base64Puzzles[0] + "\nnot a puzzle\n", true},
+		{"\n" + base64Puzzles[0] + "\n", false},
+		{packedPuzzles[0].inline + "\n", false},
+		{"SDP1\x00", false},
+		{"", false},
+	}
+	for _, test := range tests {
+		if got := HasBase64([]byte(test.data)); got != test.want {
+			t.Errorf("HasBase64(%q) = %v, want %v", test.data, got, test.want)
+		}
+		r := bufio.NewReader(strings.NewReader(test.data))
+		if got := IsBase64(r); got != test.want {
+			t.Errorf("IsBase64(%q) = %v, want %v", test.data, got, test.want)
+		}
+		if rest, _ := io.ReadAll(r); string(rest) != test.data {
+			t.Errorf("IsBase64 consumed %q", test.data[:len(test.data)-len(rest)])
+		}
+	}
+}
+
+// Text turns a b64 stream into inline puzzles, skipping the blank lines
+func TestBase64Text(t *testing.T) {
+	stream := base64Puzzles[0] + "\n\n  " + base64Puzzles[1] + "\r\n" + base64Puzzles[2]
+	var want strings.Builder
+	for _, p := range packedPuzzles {
+		want.WriteString(p.inline + "\n")
+	}
+	text, err := io.ReadAll(Text(strings.NewReader(stream)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(text) != want.String() {
+		t.Errorf("Text gives\n%s\nwant\n%s", text, want.String())
+	}
+
+	// A bad line is an error, after the puzzles before it
+	text, err = io.ReadAll(Text(strings.NewReader(base64Puzzles[0] + "\n" + base64Puzzles[1][1:] + "\n")))
+	if err == nil {
+		t.Error("Text read a bad line without an error")
+	}
+	if string(text) != packedPuzzles[0].inline+"\n" {
+		t.Errorf("Text gives %q before the bad line, want the first puzzle", text)
+	}
+}
@@ -0,0 +1,71 @@
+package packed
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// How much of a stream decoders get to look at to recognise it
+const detectSize = 512
+
+// An input format another package adds, such as a plugin, that Text turns into puzzle text
+type Decoder struct {
+	Name string
+	// Reports whether the stream is in the format by its first bytes, up to 512 of them, all of
+	// them for a shorter stream
+	Detect func(start []byte) bool
+	// Returns a reader of the puzzles of the stream as text any puzzle reader takes, such as the
+	// inline format one per line
+	Text func(r io.Reader) io.Reader
+}
+
+var decoders []Decoder
+
+// Adds an input format. Call it from init, formats are tried in the order they were added, after
+// the packed and b64 ones
+func RegisterDecoder(d Decoder) {
+	if slices.ContainsFunc(decoders, func(other Decoder) bool { return other.Name == d.Name }) {
+		panic(fmt.Sprintf("decoder %s is already registered", d.Name))
+	}
+	decoders = append(decoders, d)
+}
+
+// Returns the names of the added input formats
+func Decoders() []string {
+	names := make([]string, len(decoders))
+	for i, d := range decoders {
+		names[i] = d.Name
+	}
+	return names
+}
+
+// Returns the added input format of the stream, without consuming anything
+func detect(r *bufio.Reader) (Decoder, bool) {
+	if len(decoders) == 0 {
+		return Decoder{}, false
+	}
+	b, _ := r.Peek(detectSize)
+	return detectBytes(b)
+}
+
+func detectBytes(b []byte) (Decoder, bool) {
+	b = b[:min(len(b), detectSize)]
+	for _, d := range decoders {
+		if d.Detect(b) {
+			return d, true
+		}
+	}
+	return Decoder{}, false
+}
+
+// Checks whether the data starts like a stream Text decodes rather than passes on as it is: packed,
+// b64 or an added input format
+func Encoded(b []byte) bool {
+	if HasHeader(b) || HasBase64(b) {
+		return true
+	}
+	_, ok := detectBytes(b)
+	return ok
+}
@@ -0,0 +1,218 @@
+// Package packed is a compact binary encoding for puzzle collections: 4 bits per cell,
+// 41 bytes per puzzle, half the size of the inline text format with its line endings.
+//
+// A stream starts with the 4 magic bytes "SDP1" and a flags byte. If the counts flag is set,
+// each puzzle is preceded by its solution count as an unsigned varint. Cells are stored
+// row by row, the first cell of each pair in the high nibble, 0 for an empty cell;
+// the last byte has its low nibble unused
+package packed
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/AndrewSav/sudocoo/pkg/format"
+)
+
+const sudokuSize = 9
+
+// Bytes per puzzle
+const PuzzleSize = (sudokuSize*sudokuSize + 1) / 2
+
+var magic = []byte("SDP1")
+
+// Bits of the flags byte
+const flagCounts = 1
+
+// Returned for a missing solution count when the stream has none
+const NoCount = -1
+
+// Returns the 41 byte encoding of the puzzle
+func Encode(puzzle [sudokuSize][sudokuSize]int) []byte {
+	b := make([]byte, PuzzleSize)
+	for i := 0; i < sudokuSize*sudokuSize; i++ {
+		v := byte(puzzle[i/sudokuSize][i%sudokuSize])
+		if i%2 == 0 {
+			b[i/2] |= v << 4
+		} else {
+			b[i/2] |= v
+		}
+	}
+	return b
+}
+
+// Decodes a puzzle from the first PuzzleSize bytes of b
+func Decode(b []byte) ([sudokuSize][sudokuSize]int, error) {
+	var puzzle [sudokuSize][sudokuSize]int
+	if len(b) < PuzzleSize {
+		return puzzle, fmt.Errorf("a packed puzzle is %d bytes, got %d", PuzzleSize, len(b))
+	}
+	for i := 0; i < sudokuSize*sudokuSize; i++ {
+		v := b[i/2] & 0xf
+		if i%2 == 0 {
+			v = b[i/2] >> 4
+		}
+		if v > sudokuSize {
+			return puzzle, fmt.Errorf("invalid value %d in cell %d", v, i+1)
+		}
+		puzzle[i/sudokuSize][i%sudokuSize] = int(v)
+	}
+	return puzzle, nil
+}
+
+// Writes a packed stream
+type Writer struct {
+	w      *bufio.Writer
+	counts bool
+}
+
+// Writes the stream header. With counts set every puzzle is written with its solution count
+func NewWriter(w io.Writer, counts bool) (*Writer, error) {
+	pw := &Writer{w: bufio.NewWriter(w), counts: counts}
+	flags := byte(0)
+	if counts {
+		flags |= flagCounts
+	}
+	pw.w.Write(magic)
+	if err := pw.w.WriteByte(flags); err != nil {
+		return nil, err
+	}
+	return pw, nil
+}
+
+// Writes a puzzle, the count is ignored if the stream has no counts
+func (pw *Writer) Write(puzzle [sudokuSize][sudokuSize]int, count int) error {
+	if pw.counts {
+		if count < 0 {
+			return fmt.Errorf("invalid solution count %d", count)
+		}
+		pw.w.Write(binary.AppendUvarint(nil, uint64(count)))
+	}
+	_, err := pw.w.Write(Encode(puzzle))
+	return err
+}
+
+func (pw *Writer) Flush() error {
+	return pw.w.Flush()
+}
+
+// Reads a packed stream
+type Reader struct {
+	r      *bufio.Reader
+	counts bool
+	buf    [PuzzleSize]byte
+}
+
+// Checks whether the stream starts with the packed header, without consuming anything
+func IsPacked(r *bufio.Reader) bool {
+	b, _ := r.Peek(len(magic))
+	return HasHeader(b)
+}
+
+// Checks whether the data starts with the packed header
+func HasHeader(b []byte) bool {
+	return bytes.HasPrefix(b, magic)
+}
+
+// Reads the stream header. r can also be what Text returned for a packed stream, if nothing
+// was read from it yet
+func NewReader(r io.Reader) (*Reader, error) {
+	if t, ok := r.(*textReader); ok {
+		r = t.source
+	}
+	br := bufio.NewReader(r)
+	if !IsPacked(br) {
+		return nil, fmt.Errorf("not a packed puzzle stream")
+	}
+	br.Discard(len(magic))
+	flags, err := br.ReadByte()
+	if err != nil {
+		return nil, fmt.Errorf("truncated packed stream header")
+	}
+	return &Reader{r: br, counts: flags&flagCounts != 0}, nil
+}
+
+// Returns the next puzzle and its solution count, NoCount if the stream has none.
+// Returns io.EOF at the end of the stream
+func (pr *Reader) Read() (puzzle [sudokuSize][sudokuSize]int, count int, err error) {
+	count = NoCount
+	if pr.counts {
+		c, err := binary.ReadUvarint(pr.r)
+		if err != nil {
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				err = fmt.Errorf("truncated packed stream")
+			}
+			return puzzle, 0, err
+		}
+		count = int(c)
+	}
+	if _, err = io.ReadFull(pr.r, pr.buf[:]); err != nil {
+		if errors.Is(err, io.EOF) && !pr.counts {
+			return puzzle, 0, io.EOF
+		}
+		return puzzle, 0, fmt.Errorf("truncated packed stream")
+	}
+	puzzle, err = Decode(pr.buf[:])
+	return puzzle, count, err
+}
+
+// The reader Text returns for packed streams. Decoding starts on the first read,
+// until then NewReader can take the packed stream back from it
+type textReader struct {
+	source *bufio.Reader
+	pipe   *io.PipeReader
+	once   sync.Once
+}
+
+func (t *textReader) Read(p []byte) (int, error) {
+	t.once.Do(t.start)
+	return t.pipe.Read(p)
+}
+
+func (t *textReader) start() {
+	pipeReader, pipeWriter := io.Pipe()
+	t.pipe = pipeReader
+	go func() {
+		pr, err := NewReader(t.source)
+		if err != nil {
+			pipeWriter.CloseWithError(err)
+			return
+		}
+		w := bufio.NewWriter(pipeWriter)
+		for {
+			puzzle, _, err := pr.Read()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				pipeWriter.CloseWithError(err)
+				return
+			}
+			fmt.Fprintln(w, format.Inline(puzzle))
+		}
+		pipeWriter.CloseWithError(w.Flush())
+	}()
+}
+
+// Returns a reader of the text of r: if r is a packed stream or a b64 one, its puzzles in the
+// inline format one per line, if it is in an added input format, what its decoder makes of it,
+// otherwise the contents of r as they are. This lets everything that reads puzzle text take packed
+// input as well
+func Text(r io.Reader) io.Reader {
+	br := bufio.NewReader(r)
+	if IsBase64(br) {
+		return &base64Reader{lines: bufio.NewScanner(br)}
+	}
+	if IsPacked(br) {
+		return &textReader{source: br}
+	}
+	if d, ok := detect(br); ok {
+		return d.Text(br)
+	}
+	return br
+}
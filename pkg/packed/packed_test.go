@@ -0,0 +1,221 @@
+package packed
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/AndrewSav/sudocoo/pkg/parser"
+)
+
+// Puzzles with their encodings, worked out by hand from the description of the format
+var packedPuzzles = []struct {
+	inline string
+	packed string // in hex
+}{
+	{
+		"4...3.......6..8..........1....5..9..8....6...7.2........1.27..5.3....4.9........",
+		"4000300000006008000000000010000500900800006000702000000001027005030000409000000000",
+	},
+	{
+		"468931527751624839392578461134756298289413675675289314846192753513867942927345186",
+		"4689315277516248393925784611347562982894136756752893148461927535138679429273451860",
+	},
+	{
+		".................................................................................",
+		"0000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+	},
+}
+
+func TestEncodeDecode(t *testing.T) {
+	for _, test := range packedPuzzles {
+		puzzle, err := parser.ParsePuzzleString(test.inline)
+		if err != nil {
+			t.Fatal(err)
+		}
+		encoded := Encode(puzzle)
+		if hex.EncodeToString(encoded) != test.packed {
+			t.Errorf("Encode(%s) = %x, want %s", test.inline, encoded, test.packed)
+		}
+		decoded, err := Decode(encoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded != puzzle {
+			t.Errorf("Decode(%x) = %v, want %s", encoded, decoded, test.inline)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	valid, _ := hex.DecodeString(packedPuzzles[0].packed)
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"empty", nil, "a packed puzzle is 41 bytes, got 0"},
+		{"short", valid[:PuzzleSize-1], "a packed puzzle is 41 bytes, got 40"},
+		{"10 in a high nibble", append([]byte{0xa0}, valid[1:]...), "invalid value 10 in cell 1"},
+		{"15 in a low nibble", append([]byte{0x4f}, valid[1:]...), "invalid value 15 in cell 2"},
+		{"10 in the last cell", append(valid[:PuzzleSize-1:PuzzleSize-1], 0xa0), "invalid value 10 in cell 81"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := Decode(test.data); err == nil || err.Error() != test.want {
+				t.Errorf("got error %v, want %q", err, test.want)
+			}
+		})
+	}
+}
+
+// Writes the puzzles as a stream and reads them back, with solution counts and without
+func TestStream(t *testing.T) {
+	// 300 takes two bytes as a varint
+	counts := []int{1, 0, 300}
+	puzzles := make([][sudokuSize][sudokuSize]int, len(packedPuzzles))
+	for i, p := range packedPuzzles {
+		var err error
+		if puzzles[i], err = parser.ParsePuzzleString(p.inline); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, withCounts := range []bool{false, true} {
+		var buf bytes.Buffer
+		w, err := NewWriter(&buf, withCounts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, puzzle := range puzzles {
+			if err := w.Write(puzzle, counts[i]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatal(err)
+		}
+
+		want := "5344503100" // SDP1 and the flags
+		if withCounts {
+			want = "5344503101"
+		}
+		for i, p := range packedPuzzles {
+			if withCounts {
+				want += []string{"01", "00", "ac02"}[i]
+			}
+			want += p.packed
+		}
+		if got := hex.EncodeToString(buf.Bytes()); got != want {
+			t.Errorf("counts %v: got stream %s, want %s", withCounts, got, want)
+		}
+
+		r, err := NewReader(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, p := range packedPuzzles {
+			puzzle, count, err := r.Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantCount := NoCount
+			if withCounts {
+				wantCount = counts[i]
+			}
+			if puzzle != puzzles[i] || count != wantCount {
+				t.Errorf("counts %v: read %v with count %d, want %s with %d", withCounts, puzzle, count, p.inline, wantCount)
+			}
+		}
+		if _, _, err := r.Read(); err != io.EOF {
+			t.Errorf("counts %v: got %v at the end of the stream, want io.EOF", withCounts, err)
+		}
+	}
+}
+
+func TestStreamErrors(t *testing.T) {
+	puzzle, _ := hex.DecodeString(packedPuzzles[0].packed)
+	stream := func(parts ...[]byte) []byte { return bytes.Join(parts, nil) }
+	tests := []struct {
+		name string
+		data []byte
+		want string // the error of NewReader, or of the first Read if it has none
+	}{
+		{"empty", nil, "not a packed puzzle stream"},
+		{"text", []byte("4...3......."), "not a packed puzzle stream"},
+		{"another version", stream([]byte("SDP2\x00"), puzzle), "not a packed puzzle stream"},
+		{"no flags", []byte("SDP1"), "truncated packed stream header"},
+		{"short puzzle", stream([]byte("SDP1\x00"), puzzle[:20]), "truncated packed stream"},
+		{"count without a puzzle", []byte("SDP1\x01\x05"), "truncated packed stream"},
+		{"short count", []byte("SDP1\x01\x80"), "truncated packed stream"},
+		{"count too large", stream([]byte("SDP1\x01"), bytes.Repeat([]byte{0xff}, 10), []byte{0x01}, puzzle), "binary: varint overflows a 64-bit integer"},
+		{"invalid cell", stream([]byte("SDP1\x00\xb0"), puzzle[1:]), "invalid value 11 in cell 1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := NewReader(bytes.NewReader(test.data))
+			if err == nil {
+				_, _, err = r.Read()
+			}
+			if err == nil || err.Error() != test.want {
+				t.Errorf("got error %v, want %q", err, test.want)
+			}
+		})
+	}
+
+	w, err := NewWriter(io.Discard, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write([sudokuSize][sudokuSize]int{}, -1); err == nil {
+		t.Error("a negative solution count was written")
+	}
+}
+
+// Text turns a packed stream into inline puzzles, and NewReader takes the stream back from it
+// as long as nothing was read from it
+func TestText(t *testing.T) {
+	var buf bytes.Buffer
+	w, _ := NewWriter(&buf, true)
+	var want strings.Builder
+	for _, p := range packedPuzzles {
+		puzzle, err := parser.ParsePuzzleString(p.inline)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(puzzle, 1)
+		want.WriteString(p.inline + "\n")
+	}
+	w.Flush()
+	data := buf.Bytes()
+
+	text, err := io.ReadAll(Text(bytes.NewReader(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(text) != want.String() {
+		t.Errorf("Text gives\n%s\nwant\n%s", text, want.String())
+	}
+
+	r, err := NewReader(Text(bytes.NewReader(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, count, err := r.Read(); err != nil || count != 1 {
+		t.Errorf("read count %d, %v from the reader of Text, want 1", count, err)
+	}
+
+	// A stream that ends in the middle of a puzzle is an error
+	if _, err := io.ReadAll(Text(bytes.NewReader(data[:len(data)-1]))); err == nil || err.Error() != "truncated packed stream" {
+		t.Errorf("got error %v reading a truncated stream, want it truncated", err)
+	}
+
+	plain := "4...3.......6..8..........1....5..9..8....6...7.2........1.27..5.3....4.9........\n"
+	if text, _ := io.ReadAll(Text(strings.NewReader(plain))); string(text) != plain {
+		t.Errorf("Text changes text input to %q", text)
+	}
+	if !Encoded(data) || Encoded([]byte(plain)) {
+		t.Errorf("Encoded tells the packed stream from the text wrong")
+	}
+}
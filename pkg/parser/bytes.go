@@ -0,0 +1,61 @@
+package parser
+
+import "io"
+
+// Maps input bytes to solver digits, -1 for the bytes that are not sudoku characters.
+// Bytes of multi-byte UTF-8 characters are all above 0x7f, so they never match
+var byteLookup = func() (t [256]int8) {
+	for i := range t {
+		t[i] = -1
+	}
+	for c, digit := range runeLookup {
+		t[c[0]] = int8(digit)
+	}
+	return
+}()
+
+// Reads puzzles straight out of a byte slice, such as a memory mapped file.
+// Accepts the same input as ReadNextPuzzleInput, but does not copy each character
+// into a scanner token, and it can tell where in the input it is
+type BytesReader struct {
+	data   []byte
+	offset int
+}
+
+// Creates a reader for the puzzles in data, data must not change while it is read
+func NewBytesReader(data []byte) *BytesReader {
+	return &BytesReader{data: data}
+}
+
+// Reads next puzzle input, returns io.EOF when no more input
+func (r *BytesReader) Next() (result [sudokuSize][sudokuSize]int, err error) {
+	defer func() { logParsed(result, err) }()
+	data := r.data
+	i := r.offset
+	for n := 0; n < sudokuSize*sudokuSize; n++ {
+		for i < len(data) && byteLookup[data[i]] < 0 {
+			i++
+		}
+		if i == len(data) {
+			r.offset = i
+			if n == 0 {
+				return result, io.EOF
+			}
+			return result, ErrNotEnoughCells
+		}
+		result[n/sudokuSize][n%sudokuSize] = int(byteLookup[data[i]])
+		i++
+	}
+	r.offset = i
+	return
+}
+
+// Returns the byte offset in data the next puzzle is read from
+func (r *BytesReader) Offset() int {
+	return r.offset
+}
+
+// Makes the next puzzle to be read from the byte offset in data, such as one returned by Offset
+func (r *BytesReader) Seek(offset int) {
+	r.offset = min(max(offset, 0), len(r.data))
+}
@@ -0,0 +1,115 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Returns the characters the digits of a grid of the size are written with, digit d as the d-th of
+// them: 1 to 9 up to the classic grid, 0 to 9 and A to F for the 16x16 one, as hexadoku has it, and
+// 1 to 9 followed by letters for the other big ones, e.g. 1 to 9 and A to P for the 25x25 one. Sizes
+// up to 35 have symbols
+func Symbols(size int) string {
+	if size == 16 {
+		return "0123456789ABCDEF"
+	}
+	return "123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ"[:size]
+}
+
+// Reads puzzles of a grid of any size, see Next
+type GridReader struct {
+	scanner      *bufio.Scanner
+	size         int
+	numbers      bool
+	pendingEmpty bool // the character that ended the last number was an empty cell
+}
+
+// Creates a reader of the puzzles of the size. With numbers set the digits are written as numbers,
+// 1 to the size, rather than with Symbols
+func NewGridReader(r io.Reader, size int, numbers bool) *GridReader {
+	return &GridReader{scanner: CreateInputScanner(r), size: size, numbers: numbers}
+}
+
+// Returns the next puzzle, size rows of size cells, 0 for an empty cell. The digits are the characters
+// Symbols has for the size, in upper or lower case, with '.' for an empty cell, and '0' too where it
+// is not a digit. The other characters are left out. When the digits are written as numbers, they are
+// separated by anything else, and empty cells are '.' or 0. Returns io.EOF when there is no more
+// input, ErrNotEnoughCells when the input ends in the middle of a puzzle
+func (g *GridReader) Next() ([][]int, error) {
+	next := g.nextSymbol
+	if g.numbers {
+		next = g.nextNumber
+	}
+	grid := make([][]int, g.size)
+	for y := range grid {
+		grid[y] = make([]int, g.size)
+		for x := range grid[y] {
+			digit, err := next()
+			if err == io.EOF && (x != 0 || y != 0) {
+				err = ErrNotEnoughCells
+			}
+			if err != nil {
+				return nil, err
+			}
+			grid[y][x] = digit
+		}
+	}
+	return grid, nil
+}
+
+// Returns the next cell written as a single character
+func (g *GridReader) nextSymbol() (int, error) {
+	symbols := Symbols(g.size)
+	for g.scanner.Scan() {
+		r := g.scanner.Text()
+		if r == "." || r == "0" && !strings.Contains(symbols, "0") {
+			return 0, nil
+		}
+		if i := strings.Index(symbols, strings.ToUpper(r)); i >= 0 {
+			return i + 1, nil
+		}
+	}
+	return 0, g.endOfInput()
+}
+
+// Returns the next cell written as a number
+func (g *GridReader) nextNumber() (int, error) {
+	if g.pendingEmpty {
+		g.pendingEmpty = false
+		return 0, nil
+	}
+	number, digits := 0, 0
+	for g.scanner.Scan() {
+		r := g.scanner.Text()
+		if len(r) == 1 && r[0] >= '0' && r[0] <= '9' {
+			number = number*10 + int(r[0]-'0')
+			digits++
+			if number > g.size {
+				return 0, fmt.Errorf("%d is not a digit of a %dx%d grid", number, g.size, g.size)
+			}
+			continue
+		}
+		if digits != 0 {
+			// The character is read already, it is the next cell if it is one
+			g.pendingEmpty = r == "."
+			return number, nil
+		}
+		if r == "." {
+			return 0, nil
+		}
+	}
+	if digits != 0 {
+		return number, nil
+	}
+	return 0, g.endOfInput()
+}
+
+// Returns io.EOF, or the error of the reader if it failed
+func (g *GridReader) endOfInput() error {
+	if err := g.scanner.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
@@ -0,0 +1,105 @@
+package parser
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Guards for reading puzzles from untrusted sources, such as uploads to a server. A zero field
+// is no limit
+type Limits struct {
+	MaxSize       int64 // bytes of input in all
+	MaxPuzzles    int   // puzzles in the input
+	MaxLineLength int   // bytes in a line
+	MaxPuzzleSize int   // bytes a puzzle takes up in the input, with whatever comes before it since the previous one
+}
+
+// Limits that let through any sensible input: a grid with borders and comments takes up about a
+// kilobyte, not 16
+var DefaultLimits = Limits{MaxSize: 16 << 20, MaxPuzzles: 100000, MaxLineLength: 64 << 10, MaxPuzzleSize: 16 << 10}
+
+var (
+	// Matches every *LimitError
+	ErrInputLimit = errors.New("the input is over a limit")
+	// Returned for input with NUL bytes, which text never has, as soon as one is read
+	ErrNotText = errors.New("the input is not text")
+)
+
+// Returned when the input goes over one of the Limits
+type LimitError struct {
+	What  string // the input size, the puzzles, the line length or the puzzle size
+	Limit int64
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s is over the limit of %d", e.What, e.Limit)
+}
+
+func (e *LimitError) Is(target error) bool {
+	return target == ErrInputLimit
+}
+
+// Reads puzzles like ReadNextPuzzleInput does, but stops with an error as soon as the input goes
+// over the limits or turns out not to be text, without reading the rest of it
+type LimitedReader struct {
+	limits   Limits
+	scanner  *bufio.Scanner
+	read     int64 // bytes of input read so far
+	line     int   // bytes of the current line read so far
+	puzzle   int64 // where the current puzzle starts in the input
+	puzzles  int   // puzzles read so far
+	finished error // the error that ended the input, the next calls return it again
+}
+
+// Creates a reader for the puzzles in r that enforces the limits
+func NewLimitedReader(r io.Reader, limits Limits) *LimitedReader {
+	l := &LimitedReader{limits: limits, scanner: bufio.NewScanner(r)}
+	l.scanner.Split(l.split)
+	return l
+}
+
+// Splits the input into runes, as CreateInputScanner does, checking each one against the limits
+func (l *LimitedReader) split(data []byte, atEOF bool) (int, []byte, error) {
+	advance, token, err := bufio.ScanRunes(data, atEOF)
+	if advance == 0 || err != nil {
+		return advance, token, err
+	}
+	l.read += int64(advance)
+	l.line += advance
+	switch {
+	case token[0] == 0:
+		return 0, nil, ErrNotText
+	case token[0] == '\n':
+		l.line = 0
+	case l.limits.MaxLineLength != 0 && l.line > l.limits.MaxLineLength:
+		return 0, nil, &LimitError{"the line length", int64(l.limits.MaxLineLength)}
+	}
+	if l.limits.MaxSize != 0 && l.read > l.limits.MaxSize {
+		return 0, nil, &LimitError{"the input size", l.limits.MaxSize}
+	}
+	if l.limits.MaxPuzzleSize != 0 && l.read-l.puzzle > int64(l.limits.MaxPuzzleSize) {
+		return 0, nil, &LimitError{"the puzzle size", int64(l.limits.MaxPuzzleSize)}
+	}
+	return advance, token, nil
+}
+
+// Reads next puzzle input, returns io.EOF when no more input, and a *LimitError or ErrNotText
+// for input that breaks the limits
+func (l *LimitedReader) Next() ([sudokuSize][sudokuSize]int, error) {
+	if l.finished != nil {
+		return [sudokuSize][sudokuSize]int{}, l.finished
+	}
+	l.puzzle = l.read
+	puzzle, err := ReadNextPuzzleInput(l.scanner)
+	if err == nil && l.limits.MaxPuzzles != 0 && l.puzzles == l.limits.MaxPuzzles {
+		err = &LimitError{"the number of puzzles", int64(l.limits.MaxPuzzles)}
+	}
+	if err != nil {
+		l.finished = err
+		return [sudokuSize][sudokuSize]int{}, err
+	}
+	l.puzzles++
+	return puzzle, nil
+}
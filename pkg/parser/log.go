@@ -0,0 +1,38 @@
+package parser
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync/atomic"
+)
+
+var logger atomic.Pointer[slog.Logger]
+
+// Makes the readers of the package log every puzzle they read, at the debug level, and every one they
+// reject, at the warning level. Logging is off by default, nil turns it off again. It is safe to call
+// while puzzles are being read
+func SetLogger(l *slog.Logger) {
+	logger.Store(l)
+}
+
+// Logs the outcome of reading a puzzle, if there is a logger. The end of the input is not logged
+func logParsed(puzzle [sudokuSize][sudokuSize]int, err error) {
+	l := logger.Load()
+	if l == nil || err == io.EOF {
+		return
+	}
+	if err != nil {
+		l.LogAttrs(context.Background(), slog.LevelWarn, "puzzle rejected", slog.String("error", err.Error()))
+		return
+	}
+	clues := 0
+	for _, row := range puzzle {
+		for _, digit := range row {
+			if digit != 0 {
+				clues++
+			}
+		}
+	}
+	l.LogAttrs(context.Background(), slog.LevelDebug, "puzzle parsed", slog.Int("clues", clues))
+}
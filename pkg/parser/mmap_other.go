@@ -0,0 +1,14 @@
+//go:build !unix
+
+package parser
+
+import "os"
+
+// Reads the whole file into memory, there is no memory mapping on this platform
+func MapFile(path string) (data []byte, unmap func() error, err error) {
+	data, err = os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() error { return nil }, nil
+}
@@ -0,0 +1,36 @@
+//go:build unix
+
+package parser
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// Maps the file into memory read only, so that huge collections do not have to be read
+// and copied through buffers. Call unmap when done with data
+func MapFile(path string) (data []byte, unmap func() error, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The mapping stays valid after the file is closed
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	if !fi.Mode().IsRegular() {
+		return nil, nil, fmt.Errorf("%s is not a regular file", path)
+	}
+	if fi.Size() == 0 {
+		// Empty files cannot be mapped
+		return nil, func() error { return nil }, nil
+	}
+	data, err = syscall.Mmap(int(f.Fd()), 0, int(fi.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() error { return syscall.Munmap(data) }, nil
+}
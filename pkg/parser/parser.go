@@ -2,12 +2,16 @@ package parser
 
 import (
 	"bufio"
-	"fmt"
+	"errors"
 	"io"
+	"strings"
 )
 
 const sudokuSize = 9
 
+// Returned when the input ends in the middle of a puzzle
+var ErrNotEnoughCells = errors.New("Not enough valid sudoku characters ('.',0-9) in the input")
+
 // Maps input characters to solver digits
 var runeLookup = map[string]int{
 	".": 0,
@@ -24,9 +28,10 @@ var runeLookup = map[string]int{
 }
 
 // Reads next puzzle input from bufio.Scanner,
-// returns io.EOF when no more input,
+// returns io.EOF when no more input and the error of the reader if it fails,
 // scanner needs to be created by parser.CreateInputScanner
 func ReadNextPuzzleInput(s *bufio.Scanner) (result [sudokuSize][sudokuSize]int, err error) {
+	defer func() { logParsed(result, err) }()
 	for y := 0; y < sudokuSize; y++ {
 		for x := 0; x < sudokuSize; x++ {
 			hasDigit := false
@@ -40,11 +45,15 @@ func ReadNextPuzzleInput(s *bufio.Scanner) (result [sudokuSize][sudokuSize]int,
 				break
 			}
 			if !hasDigit {
+				if err = s.Err(); err != nil {
+					// The reader failed, or the split function of a LimitedReader stopped the input
+					return
+				}
 				if x == 0 && y == 0 {
 					err = io.EOF
 					return
 				} else {
-					err = fmt.Errorf("Not enough valid sudoku characters ('.',0-9) in the input")
+					err = ErrNotEnoughCells
 					return
 				}
 			}
@@ -59,3 +68,9 @@ func CreateInputScanner(r io.Reader) *bufio.Scanner {
 	scanner.Split(bufio.ScanRunes)
 	return scanner
 }
+
+// Parses a single puzzle from a string, e.g. a line in the inline format.
+// Returns io.EOF if the string does not have any sudoku characters at all
+func ParsePuzzleString(s string) ([sudokuSize][sudokuSize]int, error) {
+	return ReadNextPuzzleInput(CreateInputScanner(strings.NewReader(s)))
+}
@@ -0,0 +1,108 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Pencil marks record the candidates a solver has left in each cell, which can be fewer than the givens
+// imply when some have been eliminated by hand. Two formats are read:
+//
+//   - 729 characters, 9 per cell in the reading order, the character at position d-1 of a cell is
+//     either the digit d, when it is a candidate, or '.' or '0', when it is not
+//   - the .sdx format, 81 tokens separated by white space, each the candidates of a cell, e.g. "1479".
+//     A token with a single digit is a placed digit, the 'u' prefix Simple Sudoku puts on the digits
+//     the user placed is accepted and ignored
+//
+// Either way a cell left with a single candidate is taken as placed
+
+const pencilmarksLength = sudokuSize * sudokuSize * sudokuSize
+
+// Reads puzzles with pencil marks one after another
+type PencilmarksReader struct {
+	scanner *bufio.Scanner
+}
+
+// Creates a reader for the puzzles with pencil marks in r, they can follow each other in either format
+func NewPencilmarksReader(r io.Reader) *PencilmarksReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	scanner.Split(bufio.ScanWords)
+	return &PencilmarksReader{scanner: scanner}
+}
+
+// Reads the next puzzle, returns its placed digits and the candidates of every cell as bit masks
+// with bit d-1 set for digit d. Returns io.EOF when no more input
+func (r *PencilmarksReader) Next() (puzzle [sudokuSize][sudokuSize]int, candidates [sudokuSize][sudokuSize]uint16, err error) {
+	defer func() { logParsed(puzzle, err) }()
+	for n := 0; n < sudokuSize*sudokuSize; n++ {
+		if !r.scanner.Scan() {
+			if err = r.scanner.Err(); err != nil {
+				return
+			}
+			if n == 0 {
+				err = io.EOF
+			} else {
+				err = fmt.Errorf("not enough cells in the pencil marks, got %d, want %d", n, sudokuSize*sudokuSize)
+			}
+			return
+		}
+		token := r.scanner.Text()
+		if n == 0 && len(token) == pencilmarksLength {
+			candidates, err = parseGridPencilmarks(token)
+			break
+		}
+		y, x := n/sudokuSize, n%sudokuSize
+		if candidates[y][x], err = parseCellPencilmarks(token); err != nil {
+			err = fmt.Errorf("r%dc%d: %v", y+1, x+1, err)
+			return
+		}
+	}
+	if err != nil {
+		return
+	}
+	for y := range sudokuSize {
+		for x := range sudokuSize {
+			c := candidates[y][x]
+			if c == 0 {
+				err = fmt.Errorf("r%dc%d has no candidates", y+1, x+1)
+				return
+			}
+			if c&(c-1) == 0 {
+				for puzzle[y][x] = 1; c != 1; c >>= 1 {
+					puzzle[y][x]++
+				}
+			}
+		}
+	}
+	return
+}
+
+// Parses pencil marks in the 729 character format
+func parseGridPencilmarks(s string) (candidates [sudokuSize][sudokuSize]uint16, err error) {
+	for i := range pencilmarksLength {
+		cell, digit := i/sudokuSize, i%sudokuSize+1
+		switch s[i] {
+		case '.', '0':
+		case byte('0' + digit):
+			candidates[cell/sudokuSize][cell%sudokuSize] |= 1 << (digit - 1)
+		default:
+			return candidates, fmt.Errorf("r%dc%d: unexpected %q in the pencil marks, want %d, '.' or '0'", cell/sudokuSize+1, cell%sudokuSize+1, s[i], digit)
+		}
+	}
+	return
+}
+
+// Parses the candidates of a single cell in the .sdx format
+func parseCellPencilmarks(token string) (uint16, error) {
+	var mask uint16
+	for _, c := range strings.TrimPrefix(token, "u") {
+		if c < '1' || c > '9' {
+			return 0, fmt.Errorf("unexpected %q in the pencil marks %q", c, token)
+		}
+		mask |= 1 << (c - '1')
+	}
+	return mask, nil
+}
@@ -0,0 +1,34 @@
+package parser
+
+import (
+	"bufio"
+	"io"
+)
+
+// The number of cells of a samurai puzzle: five 9x9 grids, four of which share a box with the fifth
+const samuraiCells = 369
+
+// Reads the next samurai puzzle from bufio.Scanner: the cells of its five grids as they are laid out
+// in the 21x21 square they fit in, row by row, leaving out the cells outside of the grids. The cells are
+// the characters the classic puzzles have, anything else is left out, so the square can be written
+// with spaces outside of the grids, or the cells can be all on one line. Returns io.EOF when no more
+// input and ErrNotEnoughCells when it ends in the middle of a puzzle, scanner needs to be created by
+// parser.CreateInputScanner
+func ReadNextSamurai(s *bufio.Scanner) ([]int, error) {
+	cells := make([]int, 0, samuraiCells)
+	for len(cells) < samuraiCells && s.Scan() {
+		if digit, ok := runeLookup[s.Text()]; ok {
+			cells = append(cells, digit)
+		}
+	}
+	if len(cells) == samuraiCells {
+		return cells, nil
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if len(cells) == 0 {
+		return nil, io.EOF
+	}
+	return nil, ErrNotEnoughCells
+}
@@ -0,0 +1,76 @@
+// Package plugins loads extensions from a directory of Go plugins, so that niche formats and
+// constraints do not all have to live in the core. A plugin is a main package built with
+// go build -buildmode=plugin against the same version of this module, whose init functions
+// register what it adds:
+//
+//   - output formats with format.Register
+//   - input formats with packed.RegisterDecoder
+//   - variant constraint types with variant.RegisterConstraint
+//
+// The command line loads them for the variant command, and for the other commands only when
+// SUDOCOO_PLUGINS is set, as most of them have no use for them
+//
+// Go plugins need cgo and are only supported on Linux, macOS and FreeBSD, elsewhere every plugin
+// fails to load
+package plugins
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"plugin"
+	"sort"
+)
+
+// The file extension of plugins, other files in the directory are skipped
+const Extension = ".so"
+
+// Where the directory is taken from, before the default one
+const EnvDir = "SUDOCOO_PLUGINS"
+
+// Returns the plugins directory: SUDOCOO_PLUGINS if it is set, otherwise sudocoo/plugins in the
+// user configuration directory, e.g. ~/.config/sudocoo/plugins. Returns "" when there is neither
+func Dir() string {
+	if dir, ok := os.LookupEnv(EnvDir); ok {
+		return dir
+	}
+	config, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(config, "sudocoo", "plugins")
+}
+
+// Loads the plugins of the directory in the order of their names, and returns the ones loaded.
+// A missing directory has no plugins. A plugin that fails to load does not stop the rest, the
+// errors are returned together. Loading the same plugin again does nothing
+func Load(dir string) ([]string, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && filepath.Ext(entry.Name()) == Extension {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	var loaded []string
+	var errs []error
+	for _, name := range names {
+		if _, err := plugin.Open(filepath.Join(dir, name)); err != nil {
+			errs = append(errs, fmt.Errorf("plugin %s: %w", name, err))
+			continue
+		}
+		loaded = append(loaded, name)
+	}
+	return loaded, errors.Join(errs...)
+}
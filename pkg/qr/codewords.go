@@ -0,0 +1,112 @@
+package qr
+
+// Returns the data and error correction codewords of the text, interleaved the way they are placed
+func codewords(data []byte, version int, level Level) []byte {
+	b := blockTable[version][level]
+	capacity := b.data()
+
+	// The bit stream: the byte mode indicator, the length, the bytes and a terminator
+	var w bitWriter
+	w.write(0b0100, 4)
+	w.write(len(data), countBits(version))
+	for _, d := range data {
+		w.write(int(d), 8)
+	}
+	w.write(0, min(4, capacity*8-w.n))
+	w.write(0, (8-w.n%8)%8)
+	stream := w.bytes
+	// Filled up with alternating pad bytes
+	for pad := 0; len(stream) < capacity; pad++ {
+		stream = append(stream, [2]byte{0xec, 0x11}[pad%2])
+	}
+
+	var dataBlocks, ecBlocks [][]byte
+	generator := rsGenerator(b.ecPerBlock)
+	for i := range b.short + b.long {
+		n := b.shortData
+		if i >= b.short {
+			n++
+		}
+		dataBlocks = append(dataBlocks, stream[:n])
+		ecBlocks = append(ecBlocks, rsRemainder(stream[:n], generator))
+		stream = stream[n:]
+	}
+	var result []byte
+	for i := range b.shortData + 1 {
+		for _, block := range dataBlocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+	for i := range b.ecPerBlock {
+		for _, block := range ecBlocks {
+			result = append(result, block[i])
+		}
+	}
+	return result
+}
+
+// Collects bits most significant first
+type bitWriter struct {
+	bytes []byte
+	n     int // bits written
+}
+
+func (w *bitWriter) write(value, bits int) {
+	for i := bits - 1; i >= 0; i-- {
+		if w.n%8 == 0 {
+			w.bytes = append(w.bytes, 0)
+		}
+		if value>>i&1 != 0 {
+			w.bytes[len(w.bytes)-1] |= 0x80 >> (w.n % 8)
+		}
+		w.n++
+	}
+}
+
+// Multiplies in GF(256) with the QR code polynomial x^8+x^4+x^3+x^2+1
+func gfMultiply(a, b byte) byte {
+	var result byte
+	for i := 7; i >= 0; i-- {
+		// Shift left, reducing by the polynomial when the top bit falls off
+		carry := result >> 7
+		result = result<<1 ^ carry*0x1d
+		if b>>i&1 != 0 {
+			result ^= a
+		}
+	}
+	return result
+}
+
+// Returns the coefficients of the Reed-Solomon generator polynomial of the degree, the product
+// of (x - 2^i) for i from 0 to degree-1, leading coefficient left out
+func rsGenerator(degree int) []byte {
+	g := make([]byte, degree)
+	g[degree-1] = 1
+	root := byte(1)
+	for range degree {
+		for j := range g {
+			g[j] = gfMultiply(g[j], root)
+			if j+1 < len(g) {
+				g[j] ^= g[j+1]
+			}
+		}
+		root = gfMultiply(root, 2)
+	}
+	return g
+}
+
+// Returns the remainder of the data polynomial divided by the generator, the error correction codewords
+func rsRemainder(data, generator []byte) []byte {
+	result := make([]byte, len(generator))
+	for _, d := range data {
+		factor := d ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, g := range generator {
+			result[i] ^= gfMultiply(g, factor)
+		}
+	}
+	return result
+}
@@ -0,0 +1,70 @@
+package qr
+
+import (
+	"bytes"
+	"testing"
+)
+
+// The error correction codewords of version 1 at level M, 16 data and 10 error correction codewords
+var rsVectors = []struct {
+	name      string
+	data, ecc []byte
+}{
+	// The example of ISO/IEC 18004, 01234567 in numeric mode
+	{
+		"01234567",
+		[]byte{0x10, 0x20, 0x0c, 0x56, 0x61, 0x80, 0xec, 0x11, 0xec, 0x11, 0xec, 0x11, 0xec, 0x11, 0xec, 0x11},
+		[]byte{0xa5, 0x24, 0xd4, 0xc1, 0xed, 0x36, 0xc7, 0x87, 0x2c, 0x55},
+	},
+	// HELLO WORLD in alphanumeric mode, the usual example of the tutorials
+	{
+		"HELLO WORLD",
+		[]byte{0x20, 0x5b, 0x0b, 0x78, 0xd1, 0x72, 0xdc, 0x4d, 0x43, 0x40, 0xec, 0x11, 0xec, 0x11, 0xec, 0x11},
+		[]byte{0xc4, 0x23, 0x27, 0x77, 0xeb, 0xd7, 0xe7, 0xe2, 0x5d, 0x17},
+	},
+}
+
+func TestReedSolomon(t *testing.T) {
+	generator := rsGenerator(10)
+	for _, test := range rsVectors {
+		if got := rsRemainder(test.data, generator); !bytes.Equal(got, test.ecc) {
+			t.Errorf("%s: got % x, want % x", test.name, got, test.ecc)
+		}
+	}
+}
+
+// The generator polynomial of degree 10 as the powers of 2 of its coefficients, as the standard lists them
+func TestGenerator(t *testing.T) {
+	var exp [255]byte
+	exp[0] = 1
+	for i := 1; i < len(exp); i++ {
+		exp[i] = gfMultiply(exp[i-1], 2)
+	}
+	if gfMultiply(exp[254], 2) != 1 {
+		t.Fatal("2 does not generate the field")
+	}
+	want := []int{251, 67, 46, 61, 118, 70, 64, 94, 32, 45}
+	for i, g := range rsGenerator(10) {
+		if g != exp[want[i]] {
+			t.Errorf("coefficient %d is %#x, want 2^%d = %#x", i+1, g, want[i], exp[want[i]])
+		}
+	}
+}
+
+func TestGFMultiply(t *testing.T) {
+	tests := []struct{ a, b, want byte }{
+		{0, 0x53, 0},
+		{1, 0x53, 0x53},
+		{2, 0x80, 0x1d}, // 2^8 reduced by the polynomial
+		{0x80, 0x80, 0x13},
+		{0x53, 0xca, 0x8f},
+	}
+	for _, test := range tests {
+		if got := gfMultiply(test.a, test.b); got != test.want {
+			t.Errorf("%#x * %#x = %#x, want %#x", test.a, test.b, got, test.want)
+		}
+		if got := gfMultiply(test.b, test.a); got != test.want {
+			t.Errorf("%#x * %#x = %#x, want %#x", test.b, test.a, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,383 @@
+// Package qr encodes text as QR codes, so that printed puzzles can carry a link to themselves.
+// It covers what that takes and no more: the byte mode, versions 1 to 10, which hold up to 271 bytes
+// at the lowest error correction level, and all four error correction levels. The version is the
+// smallest one the text fits in, the mask the one with the lowest penalty, as the standard says
+package qr
+
+import "fmt"
+
+// How much of the code can be damaged and still read
+type Level int
+
+const (
+	L Level = iota // about 7%
+	M              // about 15%
+	Q              // about 25%
+	H              // about 30%
+)
+
+// Parses a level given by its letter
+func ParseLevel(s string) (Level, error) {
+	switch s {
+	case "L", "l":
+		return L, nil
+	case "M", "m":
+		return M, nil
+	case "Q", "q":
+		return Q, nil
+	case "H", "h":
+		return H, nil
+	}
+	return 0, fmt.Errorf("invalid error correction level %s, want L, M, Q or H", s)
+}
+
+// The bits of the level in the format information
+var levelBits = [...]int{L: 1, M: 0, Q: 3, H: 2}
+
+// How the codewords of a version and level are split into blocks
+type blocks struct {
+	ecPerBlock int
+	short      int // blocks with shortData data codewords
+	shortData  int
+	long       int // blocks with shortData+1 data codewords
+}
+
+func (b blocks) data() int {
+	return b.short*b.shortData + b.long*(b.shortData+1)
+}
+
+const maxVersion = 10
+
+// Indexed by version and level
+var blockTable = [maxVersion + 1][4]blocks{
+	1:  {L: {7, 1, 19, 0}, M: {10, 1, 16, 0}, Q: {13, 1, 13, 0}, H: {17, 1, 9, 0}},
+	2:  {L: {10, 1, 34, 0}, M: {16, 1, 28, 0}, Q: {22, 1, 22, 0}, H: {28, 1, 16, 0}},
+	3:  {L: {15, 1, 55, 0}, M: {26, 1, 44, 0}, Q: {18, 2, 17, 0}, H: {22, 2, 13, 0}},
+	4:  {L: {20, 1, 80, 0}, M: {18, 2, 32, 0}, Q: {26, 2, 24, 0}, H: {16, 4, 9, 0}},
+	5:  {L: {26, 1, 108, 0}, M: {24, 2, 43, 0}, Q: {18, 2, 15, 2}, H: {22, 2, 11, 2}},
+	6:  {L: {18, 2, 68, 0}, M: {16, 4, 27, 0}, Q: {24, 4, 19, 0}, H: {28, 4, 15, 0}},
+	7:  {L: {20, 2, 78, 0}, M: {18, 4, 31, 0}, Q: {18, 2, 14, 4}, H: {26, 4, 13, 1}},
+	8:  {L: {24, 2, 97, 0}, M: {22, 2, 38, 2}, Q: {22, 4, 18, 2}, H: {26, 4, 14, 2}},
+	9:  {L: {30, 2, 116, 0}, M: {22, 3, 36, 2}, Q: {20, 4, 16, 4}, H: {24, 4, 12, 4}},
+	10: {L: {18, 2, 68, 2}, M: {26, 4, 43, 1}, Q: {24, 6, 19, 2}, H: {28, 6, 15, 2}},
+}
+
+// The centers of the alignment patterns along each axis, indexed by version
+var alignmentTable = [maxVersion + 1][]int{
+	2:  {6, 18},
+	3:  {6, 22},
+	4:  {6, 26},
+	5:  {6, 30},
+	6:  {6, 34},
+	7:  {6, 22, 38},
+	8:  {6, 24, 42},
+	9:  {6, 26, 46},
+	10: {6, 28, 50},
+}
+
+// A QR code, a square of dark and light modules
+type Code struct {
+	Size     int // modules along a side, without the quiet zone around
+	modules  [][]bool
+	function [][]bool // modules that are part of the patterns, not of the data
+}
+
+// Encodes the text at the level, returns error when it is too long for the versions supported
+func Encode(text string, level Level) (*Code, error) {
+	data := []byte(text)
+	version := 1
+	for ; version <= maxVersion; version++ {
+		if bitsNeeded(version, len(data)) <= blockTable[version][level].data()*8 {
+			break
+		}
+	}
+	if version > maxVersion {
+		return nil, fmt.Errorf("%d bytes do not fit in a QR code up to version %d at level %c", len(data), maxVersion, "LMQH"[level])
+	}
+	c := newCode(version)
+	c.drawFunctionPatterns(version)
+	c.drawCodewords(codewords(data, version, level))
+	best, penalty := 0, -1
+	for mask := range 8 {
+		c.applyMask(mask)
+		c.drawFormat(level, mask)
+		if p := c.penalty(); penalty < 0 || p < penalty {
+			best, penalty = mask, p
+		}
+		// Masking twice undoes it
+		c.applyMask(mask)
+	}
+	c.applyMask(best)
+	c.drawFormat(level, best)
+	return c, nil
+}
+
+// Reports whether the module at column x and row y is dark
+func (c *Code) Dark(x, y int) bool {
+	return c.modules[y][x]
+}
+
+// The number of bits the header and the data take in byte mode
+func bitsNeeded(version, length int) int {
+	return 4 + countBits(version) + 8*length
+}
+
+// The width of the character count of byte mode
+func countBits(version int) int {
+	if version <= 9 {
+		return 8
+	}
+	return 16
+}
+
+func newCode(version int) *Code {
+	size := 17 + 4*version
+	c := &Code{Size: size}
+	c.modules = make([][]bool, size)
+	c.function = make([][]bool, size)
+	for y := range size {
+		c.modules[y] = make([]bool, size)
+		c.function[y] = make([]bool, size)
+	}
+	return c
+}
+
+func (c *Code) set(x, y int, dark bool) {
+	c.modules[y][x] = dark
+	c.function[y][x] = true
+}
+
+// Draws the finder, timing and alignment patterns and reserves the format and version areas
+func (c *Code) drawFunctionPatterns(version int) {
+	size := c.Size
+	for i := range size {
+		c.set(6, i, i%2 == 0)
+		c.set(i, 6, i%2 == 0)
+	}
+	c.drawFinder(3, 3)
+	c.drawFinder(size-4, 3)
+	c.drawFinder(3, size-4)
+	positions := alignmentTable[version]
+	last := len(positions) - 1
+	for i, x := range positions {
+		for j, y := range positions {
+			// The corners with finders have none
+			if i == 0 && j == 0 || i == 0 && j == last || i == last && j == 0 {
+				continue
+			}
+			c.drawAlignment(x, y)
+		}
+	}
+	// Reserved now so that the data goes around them, the real bits are drawn with the mask
+	c.drawFormat(0, 0)
+	if version >= 7 {
+		c.drawVersion(version)
+	}
+}
+
+// Draws a finder pattern with its separator around the center
+func (c *Code) drawFinder(cx, cy int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			x, y := cx+dx, cy+dy
+			if x < 0 || x >= c.Size || y < 0 || y >= c.Size {
+				continue
+			}
+			d := max(abs(dx), abs(dy))
+			c.set(x, y, d != 2 && d != 4)
+		}
+	}
+}
+
+func (c *Code) drawAlignment(cx, cy int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			c.set(cx+dx, cy+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+// Draws both copies of the format information: the level and the mask, protected by a BCH code
+func (c *Code) drawFormat(level Level, mask int) {
+	data := levelBits[level]<<3 | mask
+	rem := data
+	for range 10 {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return bits>>i&1 != 0 }
+	size := c.Size
+	// Around the top left finder
+	for i := 0; i <= 5; i++ {
+		c.set(8, i, bit(i))
+	}
+	c.set(8, 7, bit(6))
+	c.set(8, 8, bit(7))
+	c.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		c.set(14-i, 8, bit(i))
+	}
+	// Split between the other two
+	for i := range 8 {
+		c.set(size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		c.set(8, size-15+i, bit(i))
+	}
+	// Always dark
+	c.set(8, size-8, true)
+}
+
+// Draws both copies of the version information, versions from 7 on have it
+func (c *Code) drawVersion(version int) {
+	rem := version
+	for range 12 {
+		rem = rem<<1 ^ (rem>>11)*0x1f25
+	}
+	bits := version<<12 | rem
+	for i := range 18 {
+		dark := bits>>i&1 != 0
+		a, b := c.Size-11+i%3, i/3
+		c.set(a, b, dark)
+		c.set(b, a, dark)
+	}
+}
+
+// Places the codewords in the zigzag order, two columns at a time from the right, up and down in turns
+func (c *Code) drawCodewords(data []byte) {
+	i := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			// The vertical timing pattern takes a column of its own
+			right = 5
+		}
+		for vertical := range c.Size {
+			for j := range 2 {
+				x := right - j
+				y := vertical
+				if (right+1)&2 == 0 {
+					y = c.Size - 1 - vertical
+				}
+				if !c.function[y][x] && i < len(data)*8 {
+					c.modules[y][x] = data[i>>3]>>(7-i&7)&1 != 0
+					i++
+				}
+			}
+		}
+	}
+}
+
+// Flips the data modules the mask pattern selects
+func (c *Code) applyMask(mask int) {
+	for y := range c.Size {
+		for x := range c.Size {
+			var flip bool
+			switch mask {
+			case 0:
+				flip = (x+y)%2 == 0
+			case 1:
+				flip = y%2 == 0
+			case 2:
+				flip = x%3 == 0
+			case 3:
+				flip = (x+y)%3 == 0
+			case 4:
+				flip = (x/3+y/2)%2 == 0
+			case 5:
+				flip = x*y%2+x*y%3 == 0
+			case 6:
+				flip = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				flip = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if flip && !c.function[y][x] {
+				c.modules[y][x] = !c.modules[y][x]
+			}
+		}
+	}
+}
+
+// Scores how hard the code would be to read, lower is better
+func (c *Code) penalty() int {
+	size := c.Size
+	p := 0
+	line := make([]bool, size)
+	for _, horizontal := range []bool{true, false} {
+		for i := range size {
+			for j := range size {
+				if horizontal {
+					line[j] = c.modules[i][j]
+				} else {
+					line[j] = c.modules[j][i]
+				}
+			}
+			p += linePenalty(line)
+		}
+	}
+	// 2x2 blocks of the same color
+	dark := 0
+	for y := range size {
+		for x := range size {
+			if c.modules[y][x] {
+				dark++
+			}
+			if x < size-1 && y < size-1 {
+				m := c.modules[y][x]
+				if m == c.modules[y][x+1] && m == c.modules[y+1][x] && m == c.modules[y+1][x+1] {
+					p += 3
+				}
+			}
+		}
+	}
+	// Too many dark or light modules, 10 for every 5% away from half
+	total := size * size
+	p += abs(dark*20-total*10) / total * 10
+	return p
+}
+
+// Scores runs of five or more modules of the same color and the patterns that look like a finder
+func linePenalty(line []bool) int {
+	p := 0
+	run := 1
+	for i := 1; i <= len(line); i++ {
+		if i < len(line) && line[i] == line[i-1] {
+			run++
+			continue
+		}
+		if run >= 5 {
+			p += run - 2
+		}
+		run = 1
+	}
+	finder := []bool{true, false, true, true, true, false, true}
+	for i := 0; i+len(finder) <= len(line); i++ {
+		match := true
+		for j, m := range finder {
+			if line[i+j] != m {
+				match = false
+				break
+			}
+		}
+		if match && (lightRun(line, i-4, i) || lightRun(line, i+len(finder), i+len(finder)+4)) {
+			p += 40
+		}
+	}
+	return p
+}
+
+// Reports whether the modules from start to end are all light, those outside of the code are
+func lightRun(line []bool, start, end int) bool {
+	for i := start; i < end; i++ {
+		if i >= 0 && i < len(line) && line[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
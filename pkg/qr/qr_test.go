@@ -0,0 +1,105 @@
+package qr
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The golden codes in testdata, one row of modules per line, # for dark and . for light. Each of them
+// was checked by decoding it with another library, and is module for module what two other encoders
+// give in byte mode: both of them where they take the same mask, one of them where the other takes
+// another, not all encoders score the masks the same way
+var goldenCodes = []struct {
+	name  string // of the file, the version and the level
+	text  string
+	level Level
+}{
+	{"1-H", "sudoku", H},
+	{"1-L", "hello, world", L},
+	{"3-Q", strings.Repeat("sudoku ", 4), Q},
+	// From version 7 on the code carries its version
+	{"7-Q", strings.Repeat("sudoku ", 12), Q},
+	{"7-M", strings.Repeat("puzzle", 20), M},
+	// From version 10 on the length takes 16 bits
+	{"10-H", strings.Repeat("x", 100), H},
+	{"10-L", strings.Repeat("ab", 135), L},
+}
+
+// Returns the modules the way the golden files have them
+func modules(c *Code) string {
+	var sb strings.Builder
+	for y := range c.Size {
+		for x := range c.Size {
+			if c.Dark(x, y) {
+				sb.WriteByte('#')
+			} else {
+				sb.WriteByte('.')
+			}
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+func TestEncode(t *testing.T) {
+	for _, test := range goldenCodes {
+		t.Run(test.name, func(t *testing.T) {
+			want, err := os.ReadFile(filepath.Join("testdata", test.name+".txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			c, err := Encode(test.text, test.level)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := modules(c); got != string(want) {
+				t.Errorf("got\n%swant\n%s", got, want)
+			}
+		})
+	}
+}
+
+// The smallest version the text fits in is taken, up to version 10
+func TestEncodeVersion(t *testing.T) {
+	tests := []struct {
+		length int
+		level  Level
+		size   int // 0 when the text does not fit
+	}{
+		{0, L, 21},
+		{17, L, 21},
+		{18, L, 25},
+		{7, H, 21},
+		{8, H, 25},
+		{271, L, 57},
+		{272, L, 0},
+		{119, H, 57},
+		{120, H, 0},
+	}
+	for _, test := range tests {
+		c, err := Encode(strings.Repeat("a", test.length), test.level)
+		switch {
+		case test.size == 0 && err == nil:
+			t.Errorf("%d bytes at level %c: got a code of size %d, want an error", test.length, "LMQH"[test.level], c.Size)
+		case test.size != 0 && err != nil:
+			t.Errorf("%d bytes at level %c: %v", test.length, "LMQH"[test.level], err)
+		case test.size != 0 && c.Size != test.size:
+			t.Errorf("%d bytes at level %c: got size %d, want %d", test.length, "LMQH"[test.level], c.Size, test.size)
+		}
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for i, s := range []string{"L", "M", "Q", "H"} {
+		for _, s := range []string{s, strings.ToLower(s)} {
+			if level, err := ParseLevel(s); err != nil || level != Level(i) {
+				t.Errorf("ParseLevel(%s) = %v, %v", s, level, err)
+			}
+		}
+	}
+	if _, err := ParseLevel("X"); err == nil {
+		t.Error("ParseLevel(X) returned no error")
+	}
+}
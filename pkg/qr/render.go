@@ -0,0 +1,74 @@
+package qr
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+)
+
+// Light modules around the code, readers need them to find it
+const quietZone = 4
+
+// Returns the code as an image, scale pixels per module, with the quiet zone around it
+func (c *Code) Image(scale int) *image.Gray {
+	side := (c.Size + 2*quietZone) * scale
+	img := image.NewGray(image.Rect(0, 0, side, side))
+	for py := range side {
+		for px := range side {
+			x, y := px/scale-quietZone, py/scale-quietZone
+			v := color.Gray{Y: 0xff}
+			if x >= 0 && x < c.Size && y >= 0 && y < c.Size && c.modules[y][x] {
+				v = color.Gray{Y: 0}
+			}
+			img.SetGray(px, py, v)
+		}
+	}
+	return img
+}
+
+// Returns the code as an SVG document, a unit per module, with the quiet zone around it
+func (c *Code) SVG() string {
+	side := c.Size + 2*quietZone
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+"\n", side, side)
+	fmt.Fprintf(&sb, `<rect width="%d" height="%d" fill="#fff"/>`+"\n", side, side)
+	sb.WriteString(`<path fill="#000" d="`)
+	for y := range c.Size {
+		for x := range c.Size {
+			if c.modules[y][x] {
+				fmt.Fprintf(&sb, "M%d %dh1v1h-1z", x+quietZone, y+quietZone)
+			}
+		}
+	}
+	sb.WriteString("\"/>\n</svg>\n")
+	return sb.String()
+}
+
+// Returns the code as lines of text for a terminal, two rows of modules per line drawn with half blocks.
+// It is dark on light as printed, so a terminal with a dark background has to show it inverted for phones to read it
+func (c *Code) Text() string {
+	dark := func(x, y int) bool {
+		x, y = x-quietZone, y-quietZone
+		return x >= 0 && x < c.Size && y >= 0 && y < c.Size && c.modules[y][x]
+	}
+	side := c.Size + 2*quietZone
+	var sb strings.Builder
+	for y := 0; y < side; y += 2 {
+		for x := range side {
+			top, bottom := dark(x, y), dark(x, y+1)
+			switch {
+			case top && bottom:
+				sb.WriteRune('█')
+			case top:
+				sb.WriteRune('▀')
+			case bottom:
+				sb.WriteRune('▄')
+			default:
+				sb.WriteByte(' ')
+			}
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
@@ -0,0 +1,176 @@
+package queue
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A connection to a NATS server that takes jobs from a subject one at a time, speaking the core
+// protocol (https://docs.nats.io/reference/reference-protocols/nats-protocol). Core NATS does not
+// keep messages: a job published while no worker listens is lost, and so is a job that arrives
+// just as the connection closes. Safe for concurrent use, but Next is meant for a single goroutine
+type NATS struct {
+	mu      sync.Mutex // serializes writes
+	conn    net.Conn
+	msgs    chan Message
+	done    chan struct{} // closed when the connection is gone, err tells why
+	err     error
+	nextSID int
+	pending int // the subscription waiting for a job, 0 if there is none
+}
+
+// A message received on a subject. Reply is the subject the sender waits for an answer on, if any
+type Message struct {
+	Subject string
+	Reply   string
+	Data    string
+}
+
+// Connects to the NATS server at addr (host:port)
+func DialNATS(addr string) (*NATS, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	r := bufio.NewReader(conn)
+	// The server greets with INFO, then a PING after CONNECT makes sure that it took the connection
+	if line, err := readNATSLine(r); err != nil || !strings.HasPrefix(line, "INFO ") {
+		conn.Close()
+		return nil, fmt.Errorf("not a NATS server at %s: %q %v", addr, line, err)
+	}
+	if _, err := io.WriteString(conn, "CONNECT {\"verbose\":false,\"pedantic\":false,\"name\":\"sudocoo\",\"lang\":\"go\"}\r\nPING\r\n"); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	for {
+		line, err := readNATSLine(r)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if strings.HasPrefix(line, "-ERR") {
+			conn.Close()
+			return nil, errors.New(strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+		if line == "PONG" {
+			break
+		}
+	}
+	c := &NATS{conn: conn, msgs: make(chan Message, 1), done: make(chan struct{})}
+	go c.read(r)
+	return c, nil
+}
+
+func (c *NATS) Close() error {
+	c.mu.Lock()
+	if c.pending != 0 {
+		// Best effort, the connection goes away anyway
+		fmt.Fprintf(c.conn, "UNSUB %d\r\n", c.pending)
+	}
+	c.mu.Unlock()
+	return c.conn.Close()
+}
+
+func readNATSLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// Reads what the server sends until the connection is gone, answering its pings
+func (c *NATS) read(r *bufio.Reader) {
+	defer close(c.done)
+	for {
+		line, err := readNATSLine(r)
+		if err != nil {
+			c.err = err
+			return
+		}
+		switch {
+		case line == "PING":
+			c.mu.Lock()
+			_, err = io.WriteString(c.conn, "PONG\r\n")
+			c.mu.Unlock()
+		case strings.HasPrefix(line, "MSG "):
+			err = c.readMessage(r, strings.Fields(line)[1:])
+		case strings.HasPrefix(line, "-ERR"):
+			err = errors.New(strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+		// +OK, PONG and INFO need nothing done
+		if err != nil {
+			c.err = err
+			c.conn.Close()
+			return
+		}
+	}
+}
+
+// Reads the payload of a MSG <subject> <sid> [reply-to] <#bytes> line
+func (c *NATS) readMessage(r *bufio.Reader, fields []string) error {
+	if len(fields) != 3 && len(fields) != 4 {
+		return fmt.Errorf("malformed MSG line %q", strings.Join(fields, " "))
+	}
+	size, err := strconv.Atoi(fields[len(fields)-1])
+	if err != nil {
+		return fmt.Errorf("malformed MSG line %q", strings.Join(fields, " "))
+	}
+	payload := make([]byte, size+2)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return err
+	}
+	m := Message{Subject: fields[0], Data: string(payload[:size])}
+	if len(fields) == 4 {
+		m.Reply = fields[2]
+	}
+	// Subscriptions take a single message, so there is never more than one waiting
+	c.msgs <- m
+	return nil
+}
+
+// Takes the next job published on the subject, waiting for one up to the timeout. Returns false
+// if there was none by then. Workers that subscribe with the same queue group share the jobs, each
+// goes to one of them. The subscription asks for a single message, so that jobs stay with the
+// server for other workers until this one calls Next again
+func (c *NATS) Next(subject, group string, timeout time.Duration) (Message, bool, error) {
+	c.mu.Lock()
+	if c.pending == 0 {
+		c.nextSID++
+		c.pending = c.nextSID
+		if _, err := fmt.Fprintf(c.conn, "SUB %s %s %d\r\nUNSUB %d 1\r\n", subject, group, c.pending, c.pending); err != nil {
+			c.mu.Unlock()
+			return Message{}, false, err
+		}
+	}
+	c.mu.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case m := <-c.msgs:
+		c.mu.Lock()
+		c.pending = 0
+		c.mu.Unlock()
+		return m, true, nil
+	case <-c.done:
+		return Message{}, false, fmt.Errorf("connection lost: %w", c.err)
+	case <-timer.C:
+		return Message{}, false, nil
+	}
+}
+
+// Publishes data on the subject
+func (c *NATS) Publish(subject, data string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := fmt.Fprintf(c.conn, "PUB %s %d\r\n%s\r\n", subject, len(data), data)
+	return err
+}
@@ -0,0 +1,157 @@
+package queue
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AndrewSav/sudocoo/internal/fakeserver"
+)
+
+const (
+	natsInfo    = "INFO {\"server_id\":\"test\",\"version\":\"2.10.0\",\"max_payload\":1048576}\r\n"
+	natsConnect = "CONNECT {\"verbose\":false,\"pedantic\":false,\"name\":\"sudocoo\",\"lang\":\"go\"}\r\nPING\r\n"
+)
+
+// Greets the client and takes its CONNECT the way a server does
+func handshake(t *testing.T, r *bufio.Reader, w net.Conn) {
+	t.Helper()
+	io.WriteString(w, natsInfo)
+	fakeserver.Expect(t, r, natsConnect)
+	io.WriteString(w, "PONG\r\n")
+}
+
+func TestNATSHandshakeErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		script fakeserver.Script
+		want   string // in the error
+	}{
+		{"not NATS", func(t *testing.T, r *bufio.Reader, w net.Conn) {
+			io.WriteString(w, "+OK\r\n")
+		}, "not a NATS server"},
+		{"closed before INFO", func(t *testing.T, r *bufio.Reader, w net.Conn) {}, "not a NATS server"},
+		{"CONNECT refused", func(t *testing.T, r *bufio.Reader, w net.Conn) {
+			io.WriteString(w, natsInfo)
+			fakeserver.Expect(t, r, natsConnect)
+			io.WriteString(w, "-ERR 'Authorization Violation'\r\n")
+		}, "'Authorization Violation'"},
+		{"closed before PONG", func(t *testing.T, r *bufio.Reader, w net.Conn) {
+			io.WriteString(w, natsInfo)
+			fakeserver.Expect(t, r, natsConnect)
+		}, "EOF"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, err := DialNATS(fakeserver.Serve(t, test.script))
+			if err == nil {
+				c.Close()
+				t.Fatal("connected, want an error")
+			}
+			if !strings.Contains(err.Error(), test.want) {
+				t.Errorf("got error %v, want %q in it", err, test.want)
+			}
+		})
+	}
+}
+
+func TestNATSNext(t *testing.T) {
+	addr := fakeserver.Serve(t, func(t *testing.T, r *bufio.Reader, w net.Conn) {
+		// An +OK before the PONG is skipped
+		io.WriteString(w, natsInfo)
+		fakeserver.Expect(t, r, natsConnect)
+		io.WriteString(w, "+OK\r\nPONG\r\n")
+		fakeserver.Expect(t, r, "SUB jobs workers 1\r\nUNSUB 1 1\r\n")
+		// The server pings, the client answers before the message comes in pieces
+		io.WriteString(w, "PING\r\n")
+		fakeserver.Expect(t, r, "PONG\r\n")
+		fakeserver.WriteSlowly(w, "MSG jobs 1 _INBOX.a 11\r\n{\"op\":\"x\"}\n\r\n")
+		fakeserver.Expect(t, r, "SUB jobs workers 2\r\nUNSUB 2 1\r\n")
+		io.WriteString(w, "MSG jobs 2 5\r\nhello\r\n")
+		fakeserver.Expect(t, r, "SUB jobs workers 3\r\nUNSUB 3 1\r\n")
+		// The subscription stays when Next times out
+		fakeserver.Expect(t, r, "PUB results 2\r\nok\r\n")
+		io.WriteString(w, "INFO {}\r\nMSG jobs 3 0\r\n\r\n")
+		io.Copy(io.Discard, r)
+	})
+	c, err := DialNATS(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	want := []Message{
+		{Subject: "jobs", Reply: "_INBOX.a", Data: "{\"op\":\"x\"}\n"},
+		{Subject: "jobs", Data: "hello"},
+	}
+	for _, w := range want {
+		m, ok, err := c.Next("jobs", "workers", time.Second)
+		if err != nil || !ok || m != w {
+			t.Errorf("Next = %+v, %v, %v, want %+v", m, ok, err, w)
+		}
+	}
+	if m, ok, err := c.Next("jobs", "workers", 10*time.Millisecond); ok || err != nil {
+		t.Errorf("Next = %+v, %v, %v with no message, want nothing", m, ok, err)
+	}
+	if err := c.Publish("results", "ok"); err != nil {
+		t.Fatal(err)
+	}
+	if m, ok, err := c.Next("jobs", "workers", time.Second); err != nil || !ok || m != (Message{Subject: "jobs"}) {
+		t.Errorf("Next = %+v, %v, %v, want an empty message", m, ok, err)
+	}
+}
+
+// Whatever goes wrong after the handshake, Next tells the connection is lost
+func TestNATSConnectionLost(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply string // sent after the subscription, the connection is closed after it
+		want  string // in the error
+	}{
+		{"error", "-ERR 'Stale Connection'\r\n", "'Stale Connection'"},
+		{"closed", "", "EOF"},
+		{"malformed MSG", "MSG jobs\r\n", "malformed MSG line"},
+		{"bad size", "MSG jobs 1 x\r\nabc\r\n", "malformed MSG line"},
+		{"short payload", "MSG jobs 1 10\r\nabc", "EOF"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			addr := fakeserver.Serve(t, func(t *testing.T, r *bufio.Reader, w net.Conn) {
+				handshake(t, r, w)
+				fakeserver.Expect(t, r, "SUB jobs workers 1\r\nUNSUB 1 1\r\n")
+				io.WriteString(w, test.reply)
+			})
+			c, err := DialNATS(addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+			m, ok, err := c.Next("jobs", "workers", 5*time.Second)
+			if ok || err == nil {
+				t.Fatalf("Next = %+v, %v, %v, want an error", m, ok, err)
+			}
+			if !strings.Contains(err.Error(), "connection lost") || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("got error %v, want a lost connection with %q", err, test.want)
+			}
+		})
+	}
+}
+
+// Closing unsubscribes the subscription that is waiting for a job
+func TestNATSClose(t *testing.T) {
+	addr := fakeserver.Serve(t, func(t *testing.T, r *bufio.Reader, w net.Conn) {
+		handshake(t, r, w)
+		fakeserver.Expect(t, r, "SUB jobs workers 1\r\nUNSUB 1 1\r\n")
+		fakeserver.Expect(t, r, "UNSUB 1\r\n")
+	})
+	c, err := DialNATS(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := c.Next("jobs", "workers", time.Millisecond); ok || err != nil {
+		t.Fatalf("Next = %v, %v with no message, want nothing", ok, err)
+	}
+	c.Close()
+}
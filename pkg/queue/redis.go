@@ -0,0 +1,144 @@
+// Package queue has minimal Redis and NATS clients, just enough to use a Redis list or a NATS
+// subject as a job queue. They speak the protocols (for Redis RESP,
+// https://redis.io/docs/reference/protocol-spec/) directly so that the module does not need
+// client libraries for them
+package queue
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A connection to a Redis server, safe for concurrent use. Commands are serialized,
+// so a blocking Pop holds up Push on the same connection: use one connection for each
+type Redis struct {
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// An error reply of the server
+type RedisError string
+
+func (e RedisError) Error() string {
+	return string(e)
+}
+
+// Connects to the Redis server at addr (host:port)
+func Dial(addr string) (*Redis, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &Redis{conn: conn, r: bufio.NewReader(conn)}, nil
+}
+
+func (c *Redis) Close() error {
+	return c.conn.Close()
+}
+
+// Sends a command and returns its reply: a string, an int64, a []any or nil
+func (c *Redis) Do(args ...string) (any, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&sb, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if _, err := io.WriteString(c.conn, sb.String()); err != nil {
+		return nil, err
+	}
+	return c.readReply()
+}
+
+func (c *Redis) readLine() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasSuffix(line, "\r\n") {
+		return "", fmt.Errorf("malformed reply line %q", line)
+	}
+	return line[:len(line)-2], nil
+}
+
+func (c *Redis) readReply() (any, error) {
+	line, err := c.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if line == "" {
+		return nil, fmt.Errorf("empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, RedisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = c.readReply(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unknown reply type %q", line[0])
+	}
+}
+
+// Appends a value to the list at key
+func (c *Redis) Push(key, value string) error {
+	_, err := c.Do("RPUSH", key, value)
+	return err
+}
+
+// Takes the first value off the list at key, waiting for one up to the timeout
+// (whole seconds, at least one). Returns false if there was none by then
+func (c *Redis) Pop(key string, timeout time.Duration) (string, bool, error) {
+	seconds := max(int(timeout/time.Second), 1)
+	reply, err := c.Do("BLPOP", key, strconv.Itoa(seconds))
+	if err != nil || reply == nil {
+		return "", false, err
+	}
+	// The reply is the key and the value
+	items, ok := reply.([]any)
+	if !ok || len(items) != 2 {
+		return "", false, fmt.Errorf("unexpected BLPOP reply %v", reply)
+	}
+	value, ok := items[1].(string)
+	if !ok {
+		return "", false, fmt.Errorf("unexpected BLPOP reply %v", reply)
+	}
+	return value, true, nil
+}
@@ -0,0 +1,149 @@
+package queue
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/AndrewSav/sudocoo/internal/fakeserver"
+)
+
+// Every reply type, each read from a single write and from a byte at a time
+func TestRedisReplies(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply string
+		want  any
+	}{
+		{"simple string", "+OK\r\n", "OK"},
+		{"integer", ":42\r\n", int64(42)},
+		{"negative integer", ":-3\r\n", int64(-3)},
+		{"bulk string", "$5\r\nhello\r\n", "hello"},
+		{"bulk string with CRLF", "$7\r\nab\r\ncd\r\r\n", "ab\r\ncd\r"},
+		{"empty bulk string", "$0\r\n\r\n", ""},
+		{"null bulk string", "$-1\r\n", nil},
+		{"array", "*2\r\n$3\r\nkey\r\n:7\r\n", []any{"key", int64(7)}},
+		{"nested array", "*2\r\n*1\r\n+a\r\n$-1\r\n", []any{[]any{"a"}, nil}},
+		{"empty array", "*0\r\n", []any{}},
+		{"null array", "*-1\r\n", nil},
+	}
+	for _, test := range tests {
+		for _, slowly := range []bool{false, true} {
+			name := test.name
+			if slowly {
+				name += " in pieces"
+			}
+			t.Run(name, func(t *testing.T) {
+				addr := fakeserver.Serve(t, func(t *testing.T, r *bufio.Reader, w net.Conn) {
+					fakeserver.Expect(t, r, "*2\r\n$3\r\nGET\r\n$3\r\nkey\r\n")
+					if slowly {
+						fakeserver.WriteSlowly(w, test.reply)
+					} else {
+						io.WriteString(w, test.reply)
+					}
+				})
+				c, err := Dial(addr)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer c.Close()
+				got, err := c.Do("GET", "key")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(got, test.want) {
+					t.Errorf("got %#v, want %#v", got, test.want)
+				}
+			})
+		}
+	}
+}
+
+func TestRedisErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply string // the connection is closed after it
+		check func(error) bool
+	}{
+		{"error reply", "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n", func(err error) bool {
+			var re RedisError
+			return errors.As(err, &re) && re == "WRONGTYPE Operation against a key holding the wrong kind of value"
+		}},
+		{"error in an array", "*2\r\n+a\r\n-ERR no\r\n", func(err error) bool {
+			var re RedisError
+			return errors.As(err, &re) && re == "ERR no"
+		}},
+		{"unknown type", "!3\r\nabc\r\n", nil},
+		{"empty line", "\r\n", nil},
+		{"line without CR", "+OK\n", nil},
+		{"bad integer", ":4x\r\n", nil},
+		{"bad bulk length", "$x\r\n", nil},
+		{"bad array length", "*x\r\n", nil},
+		{"short bulk string", "$10\r\nhello", func(err error) bool { return errors.Is(err, io.ErrUnexpectedEOF) }},
+		{"short array", "*3\r\n+a\r\n", func(err error) bool { return errors.Is(err, io.EOF) }},
+		{"no reply", "", func(err error) bool { return errors.Is(err, io.EOF) }},
+		{"partial line", "+O", func(err error) bool { return errors.Is(err, io.EOF) }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			addr := fakeserver.Serve(t, func(t *testing.T, r *bufio.Reader, w net.Conn) {
+				fakeserver.Expect(t, r, "*1\r\n$4\r\nPING\r\n")
+				io.WriteString(w, test.reply)
+				w.Close()
+			})
+			c, err := Dial(addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+			got, err := c.Do("PING")
+			if err == nil {
+				t.Fatalf("got %#v, want an error", got)
+			}
+			if test.check != nil && !test.check(err) {
+				t.Errorf("got error %v", err)
+			}
+		})
+	}
+}
+
+func TestRedisPushPop(t *testing.T) {
+	addr := fakeserver.Serve(t, func(t *testing.T, r *bufio.Reader, w net.Conn) {
+		fakeserver.Expect(t, r, "*3\r\n$5\r\nRPUSH\r\n$4\r\njobs\r\n$7\r\n{\"a\":1}\r\n")
+		io.WriteString(w, ":1\r\n")
+		// A timeout of less than a second is rounded up to one
+		fakeserver.Expect(t, r, "*3\r\n$5\r\nBLPOP\r\n$4\r\njobs\r\n$1\r\n1\r\n")
+		fakeserver.WriteSlowly(w, "*2\r\n$4\r\njobs\r\n$7\r\n{\"a\":1}\r\n")
+		fakeserver.Expect(t, r, "*3\r\n$5\r\nBLPOP\r\n$4\r\njobs\r\n$1\r\n2\r\n")
+		io.WriteString(w, "*-1\r\n")
+		fakeserver.Expect(t, r, "*3\r\n$5\r\nBLPOP\r\n$4\r\njobs\r\n$1\r\n1\r\n")
+		io.WriteString(w, "*1\r\n$4\r\njobs\r\n")
+		fakeserver.Expect(t, r, "*3\r\n$5\r\nBLPOP\r\n$4\r\njobs\r\n$1\r\n1\r\n")
+		io.WriteString(w, "-ERR unknown command\r\n")
+	})
+	c, err := Dial(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.Push("jobs", `{"a":1}`); err != nil {
+		t.Fatal(err)
+	}
+	if value, ok, err := c.Pop("jobs", 10*time.Millisecond); value != `{"a":1}` || !ok || err != nil {
+		t.Errorf("Pop = %q, %v, %v, want the value pushed", value, ok, err)
+	}
+	if value, ok, err := c.Pop("jobs", 2*time.Second); ok || err != nil {
+		t.Errorf("Pop = %q, %v, %v on a timeout, want nothing", value, ok, err)
+	}
+	if _, ok, err := c.Pop("jobs", time.Second); ok || err == nil {
+		t.Errorf("Pop = %v, %v on a reply without the value, want an error", ok, err)
+	}
+	var re RedisError
+	if _, _, err := c.Pop("jobs", time.Second); !errors.As(err, &re) {
+		t.Errorf("Pop returned %v on an error reply, want a RedisError", err)
+	}
+}
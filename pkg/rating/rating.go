@@ -0,0 +1,101 @@
+// Package rating tells how difficult a puzzle is for a person. The level comes from what it takes
+// to solve the puzzle by reasoning: the techniques of the logic engine, then trials, see
+// logic.TrialDepth. The score is the backtracking effort of the search, a finer scale that mostly
+// grows with the level but not always, as the search does not reason the way people do
+package rating
+
+import (
+	"fmt"
+
+	"github.com/AndrewSav/sudocoo/pkg/analysis"
+	"github.com/AndrewSav/sudocoo/pkg/logic"
+)
+
+const sudokuSize = 9
+
+// How difficult a puzzle is, from the easiest
+type Level int
+
+const (
+	Easy    Level = iota + 1 // singles solve it
+	Medium                   // it needs locked candidates or naked and hidden pairs and triples as well
+	Hard                     // it needs trials: assuming a candidate and reasoning with singles until a contradiction
+	Extreme                  // it needs trials within trials, or a puzzle without a unique solution that cannot be reasoned out
+)
+
+var levelNames = map[Level]string{Easy: "easy", Medium: "medium", Hard: "hard", Extreme: "extreme"}
+
+func (l Level) String() string {
+	if name, ok := levelNames[l]; ok {
+		return name
+	}
+	return fmt.Sprintf("Level(%d)", int(l))
+}
+
+// Returns the level with the name, e.g. hard, and false for an unknown name
+func ParseLevel(name string) (Level, bool) {
+	for level, n := range levelNames {
+		if n == name {
+			return level, true
+		}
+	}
+	return 0, false
+}
+
+// Returns the names of the levels from the easiest
+func LevelNames() []string {
+	names := make([]string, 0, len(levelNames))
+	for level := Easy; level <= Extreme; level++ {
+		names = append(names, levelNames[level])
+	}
+	return names
+}
+
+// A difficulty rating
+type Rating struct {
+	Level Level
+	Score float64 // the mean dead ends of the search over random search orders, see analysis.SearchHardness
+}
+
+func (r Rating) String() string {
+	return fmt.Sprintf("%s %.1f", r.Level, r.Score)
+}
+
+// Returns the level of the puzzle. It only reasons about the puzzle, so it takes a fraction of the
+// time Rate does. Returns an error for inconsistent givens and for a puzzle that turns out to have
+// no solution
+func LevelOf(puzzle [sudokuSize][sudokuSize]int) (Level, error) {
+	result, err := logic.Solve(puzzle)
+	if err != nil {
+		return 0, err
+	}
+	if result.Solved {
+		if hardest, _ := result.Hardest(); hardest > logic.HiddenSingle {
+			return Medium, nil
+		}
+		return Easy, nil
+	}
+	// The engine got stuck, singles with trials of a single candidate are the next step
+	depth, found, err := logic.TrialDepth(puzzle, 1)
+	if err != nil {
+		return 0, err
+	}
+	if found && depth <= 1 {
+		return Hard, nil
+	}
+	return Extreme, nil
+}
+
+// Rates the puzzle, the score averaged over the number of random search orders, 0 is
+// analysis.DefaultSearchOrders, the same seed gives the same score. Returns the errors LevelOf does
+func Rate(puzzle [sudokuSize][sudokuSize]int, orders int, seed uint64) (Rating, error) {
+	level, err := LevelOf(puzzle)
+	if err != nil {
+		return Rating{}, err
+	}
+	h, err := analysis.MeasureSearchHardness(puzzle, orders, seed)
+	if err != nil {
+		return Rating{}, err
+	}
+	return Rating{Level: level, Score: h.DeadEnds}, nil
+}
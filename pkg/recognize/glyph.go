@@ -0,0 +1,86 @@
+package recognize
+
+import (
+	"encoding/hex"
+	"image"
+)
+
+// Glyphs are compared scaled to this many pixels, their height filling it and the width in proportion,
+// centered. Wider glyphs are squeezed
+const (
+	glyphWidth  = 12
+	glyphHeight = 16
+)
+
+// How dark each pixel of a scaled glyph is, from 0 to 1, row by row
+type glyph [glyphWidth * glyphHeight]float64
+
+// A digit as some font draws it
+type digitTemplate struct {
+	digit int
+	glyph glyph
+}
+
+var digitTemplates []digitTemplate
+
+func init() {
+	for digit, bitmaps := range templateBitmaps {
+		for _, s := range bitmaps {
+			bits, err := hex.DecodeString(s)
+			if err != nil || len(bits)*8 != len(glyph{}) {
+				panic("malformed digit template " + s)
+			}
+			t := digitTemplate{digit: digit}
+			for i := range t.glyph {
+				if bits[i/8]>>(7-i%8)&1 != 0 {
+					t.glyph[i] = 1
+				}
+			}
+			digitTemplates = append(digitTemplates, t)
+		}
+	}
+}
+
+// Samples taken along each side of a glyph pixel
+const samples = 4
+
+// Scales what is dark within the bounds, apart from the grid, down or up to a glyph
+func (b *bitmap) glyph(bounds image.Rectangle, grid int) glyph {
+	var g glyph
+	w, h := float64(bounds.Dx()), float64(bounds.Dy())
+	// Glyph pixels across, in proportion to the height
+	width := min(glyphWidth, max(1, int(w*glyphHeight/h+0.5)))
+	offset := (glyphWidth - width) / 2
+	for gy := range glyphHeight {
+		for gx := range width {
+			dark := 0
+			for sy := range samples {
+				for sx := range samples {
+					x := bounds.Min.X + int((float64(gx)+(float64(sx)+0.5)/samples)*w/float64(width))
+					y := bounds.Min.Y + int((float64(gy)+(float64(sy)+0.5)/samples)*h/glyphHeight)
+					if i := y*b.width + x; b.dark[i] && b.labels[i] != grid {
+						dark++
+					}
+				}
+			}
+			g[gy*glyphWidth+offset+gx] = float64(dark) / (samples * samples)
+		}
+	}
+	return g
+}
+
+// Returns the digit whose template is the closest to the glyph
+func classify(g glyph) int {
+	best, bestDistance := 0, -1.0
+	for _, t := range digitTemplates {
+		distance := 0.0
+		for i, v := range g {
+			d := v - t.glyph[i]
+			distance += d * d
+		}
+		if bestDistance < 0 || distance < bestDistance {
+			best, bestDistance = t.digit, distance
+		}
+	}
+	return best
+}
@@ -0,0 +1,232 @@
+// Package recognize reads a puzzle from a picture of its grid. It is experimental and made for clean
+// screenshots and scans, not for photos: the grid has to be upright, with an unbroken outer border,
+// and the digits printed, not handwritten. Pencil marks are not told apart from digits.
+//
+// The picture is split into dark and light at the threshold that separates them best, dark on light
+// or light on dark. The largest square of connected dark lines is taken as the grid, and whatever else
+// is dark in a cell and large enough is the digit in it, told apart by comparing it with the digits of
+// a few common fonts
+package recognize
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"image"
+	_ "image/jpeg" // registers the decoders for image.Decode
+	_ "image/png"
+	"io"
+)
+
+const sudokuSize = 9
+
+// Returned when nothing in the picture looks like a grid
+var ErrNoGrid = errors.New("no sudoku grid found in the image")
+
+var (
+	pngMagic  = []byte("\x89PNG\r\n\x1a\n")
+	jpegMagic = []byte("\xff\xd8\xff")
+)
+
+// Checks whether the data starts like a PNG or JPEG image
+func IsImage(b []byte) bool {
+	return bytes.HasPrefix(b, pngMagic) || bytes.HasPrefix(b, jpegMagic)
+}
+
+// Checks whether the reader is at the start of a PNG or JPEG image, without consuming anything
+func IsImageReader(r *bufio.Reader) bool {
+	b, _ := r.Peek(len(pngMagic))
+	return IsImage(b)
+}
+
+// Decodes a PNG or JPEG image and reads the puzzle from it
+func Read(r io.Reader) ([sudokuSize][sudokuSize]int, error) {
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return [sudokuSize][sudokuSize]int{}, err
+	}
+	return Grid(img)
+}
+
+// Reads the puzzle from the image of its grid, empty cells are 0
+func Grid(img image.Image) ([sudokuSize][sudokuSize]int, error) {
+	var puzzle [sudokuSize][sudokuSize]int
+	b := newBitmap(img)
+	components := b.components()
+	grid := findGrid(components)
+	if grid < 0 {
+		return puzzle, ErrNoGrid
+	}
+	box := components[grid].bounds
+	cellWidth := float64(box.Dx()) / sudokuSize
+	cellHeight := float64(box.Dy()) / sudokuSize
+
+	// What is dark in each cell apart from the grid and specks, the bounds of it all
+	var glyphs [sudokuSize][sudokuSize]image.Rectangle
+	for i, c := range components {
+		if i == grid {
+			continue
+		}
+		h := float64(c.bounds.Dy())
+		if h < minPartHeight*cellHeight || h > maxDigitSize*cellHeight || float64(c.bounds.Dx()) > maxDigitSize*cellWidth {
+			continue
+		}
+		center := c.bounds.Min.Add(c.bounds.Max).Div(2)
+		if !center.In(box) {
+			continue
+		}
+		x := int(float64(center.X-box.Min.X) / cellWidth)
+		y := int(float64(center.Y-box.Min.Y) / cellHeight)
+		glyphs[y][x] = glyphs[y][x].Union(c.bounds)
+	}
+	for y := range sudokuSize {
+		for x := range sudokuSize {
+			if float64(glyphs[y][x].Dy()) >= minDigitHeight*cellHeight {
+				puzzle[y][x] = classify(b.glyph(glyphs[y][x], grid))
+			}
+		}
+	}
+	return puzzle, nil
+}
+
+// Digits are at least this part of the cell height and at most this part of the cell size. Smaller
+// parts of them count too, antialiased strokes of small digits can come apart, but not specks
+const (
+	minDigitHeight = 0.25
+	maxDigitSize   = 0.95
+	minPartHeight  = 0.1
+)
+
+// A picture split into dark and light pixels
+type bitmap struct {
+	width, height int
+	dark          []bool
+	labels        []int // the component of each dark pixel, -1 for light pixels
+}
+
+// Makes a bitmap of the image. The background is the most common gray level, and what is on the other
+// side of the threshold that best separates dark from light is dark, light on a dark background. The
+// threshold is moved halfway towards the background, thin strokes of small digits are never quite dark
+func newBitmap(img image.Image) *bitmap {
+	r := img.Bounds()
+	b := &bitmap{width: r.Dx(), height: r.Dy()}
+	luma := make([]uint8, b.width*b.height)
+	var histogram [256]int
+	for y := range b.height {
+		for x := range b.width {
+			cr, cg, cb, _ := img.At(r.Min.X+x, r.Min.Y+y).RGBA()
+			l := uint8((19595*cr + 38470*cg + 7471*cb + 1<<15) >> 24)
+			luma[y*b.width+x] = l
+			histogram[l]++
+		}
+	}
+	background := 0
+	for level, n := range histogram {
+		if n > histogram[background] {
+			background = level
+		}
+	}
+	threshold := int(otsu(histogram, len(luma)))
+	lightBackground := background > threshold
+	threshold = (threshold + background) / 2
+	b.dark = make([]bool, len(luma))
+	for i, l := range luma {
+		b.dark[i] = (int(l) < threshold) == lightBackground
+	}
+	return b
+}
+
+// Returns the threshold of Otsu's method, the gray level that splits the histogram into the two
+// classes with the largest variance between them. Levels up to and including it are dark
+func otsu(histogram [256]int, total int) uint8 {
+	sum := 0
+	for level, n := range histogram {
+		sum += level * n
+	}
+	var best uint8
+	bestVariance := -1.0
+	darkCount, darkSum := 0, 0
+	for level, n := range histogram {
+		darkCount += n
+		darkSum += level * n
+		lightCount := total - darkCount
+		if darkCount == 0 || lightCount == 0 {
+			continue
+		}
+		darkMean := float64(darkSum) / float64(darkCount)
+		lightMean := float64(sum-darkSum) / float64(lightCount)
+		variance := float64(darkCount) * float64(lightCount) * (darkMean - lightMean) * (darkMean - lightMean)
+		if variance > bestVariance {
+			best, bestVariance = uint8(level), variance
+		}
+	}
+	return best
+}
+
+// A set of dark pixels connected to each other
+type component struct {
+	bounds image.Rectangle
+	pixels int
+}
+
+// Labels the connected sets of dark pixels, diagonal neighbors are connected too
+func (b *bitmap) components() []component {
+	b.labels = make([]int, len(b.dark))
+	for i := range b.labels {
+		b.labels[i] = -1
+	}
+	var result []component
+	var stack []int
+	for start, dark := range b.dark {
+		if !dark || b.labels[start] >= 0 {
+			continue
+		}
+		label := len(result)
+		c := component{bounds: image.Rect(start%b.width, start/b.width, start%b.width+1, start/b.width+1)}
+		b.labels[start] = label
+		stack = append(stack[:0], start)
+		for len(stack) > 0 {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			x, y := i%b.width, i/b.width
+			c.pixels++
+			c.bounds = c.bounds.Union(image.Rect(x, y, x+1, y+1))
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					nx, ny := x+dx, y+dy
+					if nx < 0 || nx >= b.width || ny < 0 || ny >= b.height {
+						continue
+					}
+					if n := ny*b.width + nx; b.dark[n] && b.labels[n] < 0 {
+						b.labels[n] = label
+						stack = append(stack, n)
+					}
+				}
+			}
+		}
+		result = append(result, c)
+	}
+	return result
+}
+
+// Cells have to be at least that many pixels wide for the digits to be told apart
+const minCellSize = 8
+
+// Returns the component that is the grid, the largest one that is about square and made of lines
+// rather than filled, -1 if there is none
+func findGrid(components []component) int {
+	grid, area := -1, 0
+	for i, c := range components {
+		w, h := c.bounds.Dx(), c.bounds.Dy()
+		if w < sudokuSize*minCellSize || h < sudokuSize*minCellSize || w*4 < h*3 || h*4 < w*3 {
+			continue
+		}
+		if c.pixels*2 > w*h {
+			continue
+		}
+		if w*h > area {
+			grid, area = i, w*h
+		}
+	}
+	return grid
+}
@@ -0,0 +1,57 @@
+package recognize
+
+import (
+	"bufio"
+	"bytes"
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// The first puzzle of data/input1.txt, every digit is in it
+const fixturePuzzle = "4...3.......6..8..........1....5..9..8....6...7.2........1.27..5.3....4.9........"
+
+// Pictures of fixturePuzzle, drawn with golang.org/x/image: a screenshot in Go Regular, dark on light,
+// and a JPEG in DejaVu Sans Bold, light on dark with a title above the grid
+var fixtures = []string{"screenshot.png", "dark.jpg"}
+
+func TestRead(t *testing.T) {
+	var want [sudokuSize][sudokuSize]int
+	for i, c := range fixturePuzzle {
+		if c != '.' {
+			want[i/sudokuSize][i%sudokuSize] = int(c - '0')
+		}
+	}
+	for _, name := range fixtures {
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !IsImage(data) || !IsImageReader(bufio.NewReader(bytes.NewReader(data))) {
+				t.Error("not taken for an image")
+			}
+			got, err := Read(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != want {
+				t.Errorf("got %v, want %s", got, fixturePuzzle)
+			}
+		})
+	}
+}
+
+func TestNoGrid(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 200, 200))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	if _, err := Grid(img); err != ErrNoGrid {
+		t.Errorf("got error %v, want ErrNoGrid", err)
+	}
+	if IsImage([]byte(fixturePuzzle)) {
+		t.Error("text taken for an image")
+	}
+}
@@ -0,0 +1,124 @@
+package recognize
+
+// Digit glyphs, indexed by the digit, a bit per pixel row by row in hex. Each is a digit of one
+// of the fonts: Go Regular, Medium, Bold, Mono and Mono Bold, DejaVu Sans, Sans Bold, Serif,
+// Serif Bold, Sans Mono and Sans Mono Bold
+var templateBitmaps = [sudokuSize + 1][]string{
+	1: {
+		"0607e07e00e00e00e00e00e00e00e00e00e00e00e07fc7fc",
+		"0607e07e00e00e00e00e00e00e00e00e00e00e00e07fc7fc",
+		"0607e07e04e00e00e00e00e00e00e00e00e00e00e07fc7fc",
+		"0201e0fe0c60060060060060060060060060060060060fff",
+		"0201e0fe0fe08e00e00e00e00e00e00e00e00e00e0ffeffe",
+		"3e07e04600600600600600600600600600600600607fc7fc",
+		"7f0ff0ff09f01f01f01f01f01f01f01f01f01f0ffeffeffe",
+		"0701f03702700700700700700700700700700700700703fc",
+		"0f03f07f04f00f00f00f00f00f00f00f00f00f00f00f07fe",
+		"3e07e04e00e00e00e00e00e00e00e00e00e00e00e07fc7fc",
+		"3f07f07f00f00f00f00f00f00f00f00f00f00f07fe7fe7fe",
+	},
+	2: {
+		"3f07f841c01c01c01c01c0380700e01c01803007007fc7fc",
+		"3f07f861c01c01c01c01c0380700e01c03803807007fc7fc",
+		"3f87fc63e01e01e01e01e03c0780f01e03c03807fe7fe7fe",
+		"1f07fc60c60e60e00c00c0180380600c01803007007fe7fe",
+		"3f07fc61e60e60e01e01c03c0780e01c03803807fe7fe7fe",
+		"3f07fc61e00e00600600e01c0380700e01c01803007fe7fe",
+		"3f8ffcffec1f01f01f01f03e07c0f81f03e0fc0fffffffff",
+		"1f071c60c40e00e00e00c01c0180300600c01823027fe7fe",
+		"7f8e3ec1fc1f01f01f01f01e03c0780e01c1703fffffffff",
+		"3f07fc61c00e00e00e00c01c0380300601c03803007fe7fe",
+		"3f87fc7fe41e00e00e01e03c0780f01e01c03807fe7fe7fe",
+	},
+	3: {
+		"3f03f801801c01c0180f01f007801c00c00c00c01c3f83f0",
+		"3f07f84380180180381f03e01f803c01c01c01c43c7f87f0",
+		"7f07f843803c03c0381f03e01f803c03c01c03c43c7f87e0",
+		"7f073861861c0180180f03e007801c01c41c41c6187387e0",
+		"7f07fc61c61c01c01c1f83f01f803c01e01e61e61c7fc7f0",
+		"3f07fc00e00e00e00c0fc1f80fc00e00600600640e7fc7f0",
+		"7f87fe7fe01f01f01e1fe3fc3fe03f00f00f81fffeffe7f8",
+		"1f071c60c60e00c00c0380f001c00e00e00e40e60e71c3f8",
+		"7f8e7ec1ec1e01e01e0fc1f803e01f01f81f81fc1fe3e7f8",
+		"3f07fc00c00e00e00c0fc1f80fc00e00600600e40e7fc3f8",
+		"3f07fc7fc01e01e01c0fc1f80fc01e00e00e41e7fe7fc7f8",
+	},
+	4: {
+		"0180380780f80d8198318318618e18ffeffe018018018018",
+		"03c03c07c0fc0dc19c39c71c61cffeffeffe01c01c01c01c",
+		"03c03c07c0fc1dc19c39c71c61cffeffeffe01c01c01c01c",
+		"0380380780f80b81b8338638638c38ffeffe0380380380fe",
+		"03c07c07c0fc1fc1bc33c73c63cffeffeffe03c03c0fe1fe",
+		"03c03c07c0dc0dc19c31c31c61ce1cfffffffff01c01c01c",
+		"07c07c0fc0fc1fc3bc3bc73c63ce3cfffffffff03c03c03c",
+		"0380380780d8098198118318618418ffefff0180180180fe",
+		"03c07c07c0fc1bc1bc33c23c63c43cffffff03c03c03c0ff",
+		"0380780780d8198198318318618c18ffeffeffe018018018",
+		"03c07c0fc0fc1fc3bc33c73c63ce3cffeffeffe03c03c03c",
+	},
+	5: {
+		"7fc7fc6006006006007c07f003801c01c01c01c0387f07e0",
+		"7fc7fc6006006006007c07f007801c01c01c01c0387f87e0",
+		"7fc7fc7f87007007007e07f807803c03c03c03c4787f87e0",
+		"7fc7fc6006006006007e007801801c00c00c41c6186787e0",
+		"7fe7fe7fe6006006007e07f803c01e01e01e61e63c7f87f0",
+		"7fc7fc7007007007e07f863c00e00e00600600e41c7fc7f0",
+		"7fe7fe7fe7007007f07fc7fe43f00f00f00fc1fffeffe7f8",
+		"7fc7fc6006006006e07f860c00e00e00600640e60c71c3f0",
+		"7fe7fe7fe4004005f07fc41e01f01f01f81fc1fc1ee3c7f8",
+		"3fc3fc3003003003e03f821c00e00e00e00e00e41c7fc7f0",
+		"7fc7fc7fc7007007e07f87fc41e00e00e00e01e7fc7fc3f0",
+	},
+	6: {
+		"07c1fc3803007007006f87fc70e70670670630630e1fc0f8",
+		"07c1fc3803807007007787fc78e70e70e70e70e38e1fc0f8",
+		"07c1fe3c03807807007787fc78e78e70e70e38e39e1fc0f8",
+		"07c1ce3863067007006f878c70670670670630630c1dc0f8",
+		"0fc3fc38c70c700f00ff8ffcf1ef0ef0ef0e70e71e3fc1f0",
+		"0fc3fc704600e00c40ff8f1ce0ee06e06e0660e70c3fc1f0",
+		"0fc1fe3fe780f00f00ffcffef9ff0ff0ff0f78f7fe3fe1f8",
+		"0fc38c704600600c00ff8f0ce0ee06e06e0660660c31c1f0",
+		"0fe3cf787702f00f30ffcf9ef0ff0ff0ff0f70f70e39e1f8",
+		"0f81fc3803006006206f879c70e70670670670630c3fc0f8",
+		"0fc1fc3fc3807007007fc7fc79e70e70e70e78e3fc3fc0f8",
+	},
+	7: {
+		"7fe7fe00600c00c0180300300600e00c01c0180380380380",
+		"7fe7fe7fe00c01c0180380700700e00c01c01c0380380380",
+		"7fe7fe7fe00e01c0180380700700e00e01c03c03c03c0380",
+		"7fe7fe00600c0080180300300600e00c01c01c0180180380",
+		"7fe7fe7fe00c01c0380300700e00e01c01c03c0380380780",
+		"7fe7fe00c00c01c0180380380300700600600e00c00c01c0",
+		"fffffffff01f01e01e03c07c0780780f00f01e01e03e03c0",
+		"ffeffe80c80c0180180180300300600600400c00c0180180",
+		"fffffffffc07c0600e00c01c0180380300700600e00c01c0",
+		"7fe7fe00c00c01c01c0180380300700600600e00c01c01c0",
+		"7fe7fe7fe01e01c03c0380380780700f00f00e01e01c03c0",
+	},
+	8: {
+		"0f83fc30c30c30c38c1f81f03f871c60e60e60670e3fc1f8",
+		"0f83fc30c30c30c39c1f81f03fc71c70e70e70e70e3fc1f0",
+		"0f83fc39c39c39c3dc1f81f83fc7be70e70e70e79c3fc1f0",
+		"0f839c30c70e30c38c1f81f03f831c60e60e60e70e39c1f8",
+		"1f03fc71c71c71c79c3f81f83fc73ee1ee0ee0ef1e7fc3f0",
+		"1f07fc60ce0ee0e60c7fc3f87fce0ec06c06c0ee0e7fc3f8",
+		"1f87fe7fef1ff0ff0e7fe3fc7fef0ff0ff0ff0ffff7fe1f8",
+		"1f071c60c60ce0c60c7183f071ce0ec0ec06c0ee0e71c3f8",
+		"1f879e79ef9ff9f79e79c3f879ef0ff0ff0ff0ff0f79e1f8",
+		"1f03fc30c70e70e70c3fc1f83fc70e60660660670e3fc1f8",
+		"0f03fc3fc71e70e71c3fc1f83fc79e70e70e70e7fe3fc1f8",
+	},
+	9: {
+		"1f03f870c60c60e60e60e70e3fe1f600e00c00c01c3f83e0",
+		"1f03f871c70c70e70e70e71e3fe1ee00e00c01c01c7f83e0",
+		"1f03f879c71e70e70e71e71e3fe1ee00e01e01c03c7f83e0",
+		"0f03b830c60e60e60e60e70e31e1f600e00e60c61c7383e0",
+		"1f07f871ce1ce1ee1ee1ef1e7fe3ee01e01c61c6387f87e0",
+		"1f07f861ce0cc0ec0ec0ee0e71e3fe0ce00c00c01c7f87e0",
+		"1f07fc7fef1ef0ff0ff0ff9f7ff3ff0cf00f01e7fe7f87f0",
+		"1f071860cc0cc0ec0ec0ee0e61e3fe00600e00c41c6387e0",
+		"1f079c70ef0ef0ff0ff0ff0f79f3ff0cf00f40ee1ee3c7f0",
+		"1f03f871c60c60e60e60e70e31e3fe04600e00c01c3f83f0",
+		"1f03f87fc71c70e70e71e79e3fe3fe04e00e01c3fc3f83f0",
+	},
+}
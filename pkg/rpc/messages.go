@@ -0,0 +1,213 @@
+package rpc
+
+// Go counterparts of the service messages in api/sudocoo.proto. Field numbers in
+// Marshal and Unmarshal must match the .proto file
+
+type SolveRequest struct {
+	Puzzle string
+}
+
+type SolveResponse struct {
+	Solution   string
+	Iterations int64
+}
+
+type CountRequest struct {
+	Puzzle string
+	Limit  int32 // 0 means the server maximum
+}
+
+type CountResponse struct {
+	Count        int32
+	LimitReached bool // there are more solutions than the limit
+	Iterations   int64
+}
+
+type GenerateRequest struct {
+	Clues int32 // the number of clues to aim for, 0 leaves it to the generator
+	Seed  int64
+}
+
+type GenerateResponse struct {
+	Puzzle string
+}
+
+type RateRequest struct {
+	Puzzle string
+}
+
+type RateResponse struct {
+	Level string
+	Score float64
+}
+
+type EnumerateRequest struct {
+	Puzzle string
+	Limit  int32 // 0 means the server maximum
+}
+
+type EnumerateResponse struct {
+	Index    int32 // 1-based number of the solution
+	Solution string
+}
+
+func (m *SolveRequest) Marshal() []byte {
+	return appendString(nil, 1, m.Puzzle)
+}
+
+func (m *SolveRequest) Unmarshal(b []byte) error {
+	return parseFields(b, func(f field) error {
+		if f.number == 1 {
+			m.Puzzle = f.string()
+		}
+		return nil
+	})
+}
+
+func (m *SolveResponse) Marshal() []byte {
+	b := appendString(nil, 1, m.Solution)
+	return appendInt(b, 2, m.Iterations)
+}
+
+func (m *SolveResponse) Unmarshal(b []byte) error {
+	return parseFields(b, func(f field) error {
+		switch f.number {
+		case 1:
+			m.Solution = f.string()
+		case 2:
+			m.Iterations = f.int()
+		}
+		return nil
+	})
+}
+
+func (m *CountRequest) Marshal() []byte {
+	b := appendString(nil, 1, m.Puzzle)
+	return appendInt(b, 2, int64(m.Limit))
+}
+
+func (m *CountRequest) Unmarshal(b []byte) error {
+	return parseFields(b, func(f field) error {
+		switch f.number {
+		case 1:
+			m.Puzzle = f.string()
+		case 2:
+			m.Limit = int32(f.int())
+		}
+		return nil
+	})
+}
+
+func (m *CountResponse) Marshal() []byte {
+	b := appendInt(nil, 1, int64(m.Count))
+	b = appendBool(b, 2, m.LimitReached)
+	return appendInt(b, 3, m.Iterations)
+}
+
+func (m *CountResponse) Unmarshal(b []byte) error {
+	return parseFields(b, func(f field) error {
+		switch f.number {
+		case 1:
+			m.Count = int32(f.int())
+		case 2:
+			m.LimitReached = f.bool()
+		case 3:
+			m.Iterations = f.int()
+		}
+		return nil
+	})
+}
+
+func (m *GenerateRequest) Marshal() []byte {
+	b := appendInt(nil, 1, int64(m.Clues))
+	return appendInt(b, 2, m.Seed)
+}
+
+func (m *GenerateRequest) Unmarshal(b []byte) error {
+	return parseFields(b, func(f field) error {
+		switch f.number {
+		case 1:
+			m.Clues = int32(f.int())
+		case 2:
+			m.Seed = f.int()
+		}
+		return nil
+	})
+}
+
+func (m *GenerateResponse) Marshal() []byte {
+	return appendString(nil, 1, m.Puzzle)
+}
+
+func (m *GenerateResponse) Unmarshal(b []byte) error {
+	return parseFields(b, func(f field) error {
+		if f.number == 1 {
+			m.Puzzle = f.string()
+		}
+		return nil
+	})
+}
+
+func (m *RateRequest) Marshal() []byte {
+	return appendString(nil, 1, m.Puzzle)
+}
+
+func (m *RateRequest) Unmarshal(b []byte) error {
+	return parseFields(b, func(f field) error {
+		if f.number == 1 {
+			m.Puzzle = f.string()
+		}
+		return nil
+	})
+}
+
+func (m *RateResponse) Marshal() []byte {
+	b := appendString(nil, 1, m.Level)
+	return appendDouble(b, 2, m.Score)
+}
+
+func (m *RateResponse) Unmarshal(b []byte) error {
+	return parseFields(b, func(f field) error {
+		switch f.number {
+		case 1:
+			m.Level = f.string()
+		case 2:
+			m.Score = f.double()
+		}
+		return nil
+	})
+}
+
+func (m *EnumerateRequest) Marshal() []byte {
+	b := appendString(nil, 1, m.Puzzle)
+	return appendInt(b, 2, int64(m.Limit))
+}
+
+func (m *EnumerateRequest) Unmarshal(b []byte) error {
+	return parseFields(b, func(f field) error {
+		switch f.number {
+		case 1:
+			m.Puzzle = f.string()
+		case 2:
+			m.Limit = int32(f.int())
+		}
+		return nil
+	})
+}
+
+func (m *EnumerateResponse) Marshal() []byte {
+	b := appendInt(nil, 1, int64(m.Index))
+	return appendString(b, 2, m.Solution)
+}
+
+func (m *EnumerateResponse) Unmarshal(b []byte) error {
+	return parseFields(b, func(f field) error {
+		switch f.number {
+		case 1:
+			m.Index = int32(f.int())
+		case 2:
+			m.Solution = f.string()
+		}
+		return nil
+	})
+}
@@ -0,0 +1,165 @@
+package rpc
+
+import (
+	"fmt"
+	"time"
+)
+
+// Go counterparts of the messages in api/model.proto. Field numbers in
+// Marshal and Unmarshal must match the .proto file
+
+const sudokuSize = 9
+
+type Puzzle struct {
+	Cells       []byte // 81 cells row by row, 0 for empty
+	Label       string
+	Source      string // where the puzzle comes from, a file name or a URL
+	Fingerprint string // equal for equivalent puzzles, see pkg/canon
+}
+
+type Solution struct {
+	Cells []byte // 81 cells row by row
+	Index int32  // 1-based number of the solution among the solutions of its puzzle
+}
+
+type SolveStats struct {
+	Solutions    int32
+	LimitReached bool // there are more solutions than the limit
+	Iterations   int64
+	DurationUs   int64
+}
+
+type Rating struct {
+	Level string
+	Score float64
+}
+
+// Returns the cells of a grid in the wire layout
+func gridCells(grid [sudokuSize][sudokuSize]int) []byte {
+	cells := make([]byte, 0, sudokuSize*sudokuSize)
+	for _, row := range grid {
+		for _, v := range row {
+			cells = append(cells, byte(v))
+		}
+	}
+	return cells
+}
+
+// Converts cells in the wire layout back to a grid, checking the size and the values
+func cellsGrid(cells []byte) ([sudokuSize][sudokuSize]int, error) {
+	var grid [sudokuSize][sudokuSize]int
+	if len(cells) != sudokuSize*sudokuSize {
+		return grid, fmt.Errorf("a grid has %d cells, got %d", sudokuSize*sudokuSize, len(cells))
+	}
+	for i, v := range cells {
+		if v > sudokuSize {
+			return grid, fmt.Errorf("invalid value %d in cell %d", v, i+1)
+		}
+		grid[i/sudokuSize][i%sudokuSize] = int(v)
+	}
+	return grid, nil
+}
+
+func NewPuzzle(grid [sudokuSize][sudokuSize]int) *Puzzle {
+	return &Puzzle{Cells: gridCells(grid)}
+}
+
+func (m *Puzzle) Grid() ([sudokuSize][sudokuSize]int, error) {
+	return cellsGrid(m.Cells)
+}
+
+func NewSolution(grid [sudokuSize][sudokuSize]int, index int) *Solution {
+	return &Solution{Cells: gridCells(grid), Index: int32(index)}
+}
+
+func (m *Solution) Grid() ([sudokuSize][sudokuSize]int, error) {
+	return cellsGrid(m.Cells)
+}
+
+func (m *SolveStats) Duration() time.Duration {
+	return time.Duration(m.DurationUs) * time.Microsecond
+}
+
+func (m *SolveStats) SetDuration(d time.Duration) {
+	m.DurationUs = d.Microseconds()
+}
+
+func (m *Puzzle) Marshal() []byte {
+	b := appendBytes(nil, 1, m.Cells)
+	b = appendString(b, 2, m.Label)
+	b = appendString(b, 3, m.Source)
+	return appendString(b, 4, m.Fingerprint)
+}
+
+func (m *Puzzle) Unmarshal(b []byte) error {
+	return parseFields(b, func(f field) error {
+		switch f.number {
+		case 1:
+			m.Cells = append([]byte(nil), f.bytes...)
+		case 2:
+			m.Label = f.string()
+		case 3:
+			m.Source = f.string()
+		case 4:
+			m.Fingerprint = f.string()
+		}
+		return nil
+	})
+}
+
+func (m *Solution) Marshal() []byte {
+	b := appendBytes(nil, 1, m.Cells)
+	return appendInt(b, 2, int64(m.Index))
+}
+
+func (m *Solution) Unmarshal(b []byte) error {
+	return parseFields(b, func(f field) error {
+		switch f.number {
+		case 1:
+			m.Cells = append([]byte(nil), f.bytes...)
+		case 2:
+			m.Index = int32(f.int())
+		}
+		return nil
+	})
+}
+
+func (m *SolveStats) Marshal() []byte {
+	b := appendInt(nil, 1, int64(m.Solutions))
+	b = appendBool(b, 2, m.LimitReached)
+	b = appendInt(b, 3, m.Iterations)
+	return appendInt(b, 4, m.DurationUs)
+}
+
+func (m *SolveStats) Unmarshal(b []byte) error {
+	return parseFields(b, func(f field) error {
+		switch f.number {
+		case 1:
+			m.Solutions = int32(f.int())
+		case 2:
+			m.LimitReached = f.bool()
+		case 3:
+			m.Iterations = f.int()
+		case 4:
+			m.DurationUs = f.int()
+		}
+		return nil
+	})
+}
+
+func (m *Rating) Marshal() []byte {
+	b := appendString(nil, 1, m.Level)
+	return appendDouble(b, 2, m.Score)
+}
+
+func (m *Rating) Unmarshal(b []byte) error {
+	return parseFields(b, func(f field) error {
+		switch f.number {
+		case 1:
+			m.Level = f.string()
+		case 2:
+			m.Score = f.double()
+		}
+		return nil
+	})
+}
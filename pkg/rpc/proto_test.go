@@ -0,0 +1,155 @@
+package rpc
+
+import (
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// A field of a message as the .proto file declares it
+type protoField struct {
+	name   string // the Go name, lower_snake_case turned into CamelCase
+	kind   string
+	number int
+}
+
+var (
+	protoMessage = regexp.MustCompile(`(?s)message (\w+) \{(.*?)\n\}`)
+	protoScalar  = regexp.MustCompile(`(?m)^\s*(string|bytes|bool|int32|int64|double) (\w+) = (\d+);`)
+)
+
+// Reads the messages of a .proto file, with their fields by number
+func readProto(t *testing.T, path string) map[string]map[int]protoField {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := map[string]map[int]protoField{}
+	for _, m := range protoMessage.FindAllStringSubmatch(string(data), -1) {
+		fields := map[int]protoField{}
+		for _, f := range protoScalar.FindAllStringSubmatch(m[2], -1) {
+			number, _ := strconv.Atoi(f[3])
+			fields[number] = protoField{name: goName(f[2]), kind: f[1], number: number}
+		}
+		messages[m[1]] = fields
+	}
+	return messages
+}
+
+func goName(snake string) string {
+	var sb strings.Builder
+	for _, part := range strings.Split(snake, "_") {
+		sb.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return sb.String()
+}
+
+func wireTypeOf(kind string) int {
+	switch kind {
+	case "string", "bytes":
+		return wireBytes
+	case "double":
+		return wireFixed64
+	}
+	return wireVarint
+}
+
+// Sets every field of the message to a value that is not the default, so that all of them are encoded.
+// Negative numbers check the sign extension
+func fillFields(t *testing.T, m reflect.Value, fields map[int]protoField) {
+	t.Helper()
+	for _, f := range fields {
+		v := m.FieldByName(f.name)
+		if !v.IsValid() {
+			t.Fatalf("%s has no field %s", m.Type(), f.name)
+		}
+		switch f.kind {
+		case "string":
+			v.SetString("value of " + f.name)
+		case "bytes":
+			v.SetBytes([]byte{0, 1, 2, 9})
+		case "bool":
+			v.SetBool(true)
+		case "int32", "int64":
+			v.SetInt(-int64(f.number) * 1000)
+		case "double":
+			v.SetFloat(float64(f.number) + 0.25)
+		}
+	}
+}
+
+// Checks that the hand-written messages encode the fields the .proto files declare, with the right
+// numbers and wire types, and that they decode back to the same values
+func TestMessagesMatchProto(t *testing.T) {
+	messages := map[string]message{
+		"SolveRequest":      &SolveRequest{},
+		"SolveResponse":     &SolveResponse{},
+		"CountRequest":      &CountRequest{},
+		"CountResponse":     &CountResponse{},
+		"GenerateRequest":   &GenerateRequest{},
+		"GenerateResponse":  &GenerateResponse{},
+		"RateRequest":       &RateRequest{},
+		"RateResponse":      &RateResponse{},
+		"EnumerateRequest":  &EnumerateRequest{},
+		"EnumerateResponse": &EnumerateResponse{},
+		"Puzzle":            &Puzzle{},
+		"Solution":          &Solution{},
+		"SolveStats":        &SolveStats{},
+		"Rating":            &Rating{},
+	}
+	declared := readProto(t, "../../api/sudocoo.proto")
+	for name, fields := range readProto(t, "../../api/model.proto") {
+		declared[name] = fields
+	}
+	for name, fields := range declared {
+		t.Run(name, func(t *testing.T) {
+			m, ok := messages[name]
+			if !ok {
+				t.Fatalf("no Go type for message %s", name)
+			}
+			value := reflect.ValueOf(m).Elem()
+			if value.NumField() != len(fields) {
+				t.Errorf("%s has %d fields, the .proto file declares %d", name, value.NumField(), len(fields))
+			}
+			fillFields(t, value, fields)
+
+			seen := map[int]bool{}
+			err := parseFields(m.Marshal(), func(f field) error {
+				d, ok := fields[f.number]
+				switch {
+				case !ok:
+					t.Errorf("field %d is not in the .proto file", f.number)
+				case f.wireType != wireTypeOf(d.kind):
+					t.Errorf("field %d (%s) has wire type %d, want %d", f.number, d.name, f.wireType, wireTypeOf(d.kind))
+				}
+				seen[f.number] = true
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for number, d := range fields {
+				if !seen[number] {
+					t.Errorf("field %d (%s) is not encoded", number, d.name)
+				}
+			}
+
+			decoded := reflect.New(value.Type())
+			if err := decoded.Interface().(message).Unmarshal(m.Marshal()); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(decoded.Elem().Interface(), value.Interface()) {
+				t.Errorf("decodes as %+v, want %+v", decoded.Elem().Interface(), value.Interface())
+			}
+		})
+	}
+	for name := range messages {
+		if _, ok := declared[name]; !ok {
+			t.Errorf("%s is not a message of the .proto files", name)
+		}
+	}
+}
@@ -0,0 +1,402 @@
+package rpc
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/AndrewSav/sudocoo/pkg/analysis"
+	"github.com/AndrewSav/sudocoo/pkg/format"
+	"github.com/AndrewSav/sudocoo/pkg/metrics"
+	"github.com/AndrewSav/sudocoo/pkg/parser"
+	"github.com/AndrewSav/sudocoo/pkg/rating"
+	"github.com/AndrewSav/sudocoo/pkg/solver"
+	"github.com/AndrewSav/sudocoo/pkg/sudocoo"
+)
+
+// gRPC over HTTP/2 as described in https://github.com/grpc/grpc/blob/master/doc/PROTOCOL-HTTP2.md
+// Compression is not supported, clients have to send uncompressed messages (the default)
+//
+// The server and the messages are written by hand rather than generated with protoc-gen-go-grpc:
+// the module's only dependency is the SQLite driver of pkg/store, and google.golang.org/grpc would
+// bring in six more modules, protobuf, genproto and golang.org/x among them, and a protoc step to
+// serve five methods with flat messages.
+// What it takes is the framing here and the part of the wire format in wire.go. proto_test.go keeps the
+// messages in step with api/sudocoo.proto, and server_test.go calls the methods over HTTP/2 the way
+// gRPC clients do. What is left out: compression, the grpc-timeout header (a call ends when its
+// client goes away), reflection and the health service
+
+// Status codes, see https://grpc.github.io/grpc/core/md_doc_statuscodes.html
+const (
+	CodeOK              = 0
+	CodeCanceled        = 1
+	CodeInvalidArgument = 3
+	CodeNotFound        = 5
+	CodeUnimplemented   = 12
+	CodeInternal        = 13
+)
+
+// Full method names are /<package>.<service>/<method>
+const servicePrefix = "/sudocoo.v1.Sudocoo/"
+
+// The search of a call comes back after that many iterations without a solution, to see whether
+// the call is still wanted. A few milliseconds of search
+const solveBudget = 1 << 16
+
+// Requests larger than that are rejected, a puzzle is less than a hundred bytes
+const maxMessageSize = 64 * 1024
+
+// An error carrying a gRPC status code
+type StatusError struct {
+	Code    int
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("rpc error: code = %d desc = %s", e.Code, e.Message)
+}
+
+func statusErrorf(code int, f string, args ...any) *StatusError {
+	return &StatusError{Code: code, Message: fmt.Sprintf(f, args...)}
+}
+
+type message interface {
+	Marshal() []byte
+	Unmarshal(b []byte) error
+}
+
+// Implements the Sudocoo service from api/sudocoo.proto
+type Server struct {
+	maxLimit int           // the most solutions Count and Enumerate are allowed to look for
+	limits   parser.Limits // what the puzzles sent can take up
+	metrics  *metrics.Metrics
+}
+
+// Metrics are recorded with the 'grpc_' prefix for the endpoint name, m can be nil
+func NewServer(maxLimit int, m *metrics.Metrics) *Server {
+	return &Server{maxLimit: maxLimit, limits: parser.DefaultLimits, metrics: m}
+}
+
+// Sets the line length and the puzzle size the puzzles of requests can have. The default is
+// parser.DefaultLimits
+func (s *Server) SetInputLimits(limits parser.Limits) {
+	s.limits = limits
+}
+
+// Returns an HTTP server serving h on the address using HTTP/2 without TLS, which is what
+// gRPC clients use with insecure credentials. h is normally s, possibly wrapped in middleware
+func (s *Server) HTTPServer(addr string, h http.Handler) *http.Server {
+	srv := &http.Server{Addr: addr, Handler: h, Protocols: new(http.Protocols)}
+	srv.Protocols.SetUnencryptedHTTP2(true)
+	return srv
+}
+
+func (s *Server) ListenAndServe(addr string) error {
+	return s.HTTPServer(addr, s).ListenAndServe()
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.ProtoMajor != 2 || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		http.Error(w, "only gRPC requests are served here", http.StatusUnsupportedMediaType)
+		return
+	}
+	w.Header().Set("Content-Type", "application/grpc")
+	body, err := readMessage(r.Body)
+	if err == nil {
+		err = s.dispatch(w, r, body)
+	}
+	writeStatus(w, err)
+}
+
+func (s *Server) dispatch(w http.ResponseWriter, r *http.Request, body []byte) error {
+	switch strings.TrimPrefix(r.URL.Path, servicePrefix) {
+	case "Solve":
+		req := &SolveRequest{}
+		return unary(w, body, req, func() (message, error) { return s.Solve(r.Context(), req) })
+	case "Count":
+		req := &CountRequest{}
+		return unary(w, body, req, func() (message, error) { return s.Count(r.Context(), req) })
+	case "Generate":
+		req := &GenerateRequest{}
+		return unary(w, body, req, func() (message, error) { return s.Generate(req) })
+	case "Rate":
+		req := &RateRequest{}
+		return unary(w, body, req, func() (message, error) { return s.Rate(r.Context(), req) })
+	case "Enumerate":
+		req := &EnumerateRequest{}
+		if err := req.Unmarshal(body); err != nil {
+			return statusErrorf(CodeInvalidArgument, "%v", err)
+		}
+		flusher, _ := w.(http.Flusher)
+		return s.Enumerate(r.Context(), req, func(resp *EnumerateResponse) error {
+			if err := r.Context().Err(); err != nil {
+				return statusErrorf(CodeCanceled, "%v", err)
+			}
+			if err := writeMessage(w, resp); err != nil {
+				return err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+			return nil
+		})
+	default:
+		return statusErrorf(CodeUnimplemented, "unknown method %s", r.URL.Path)
+	}
+}
+
+// Decodes the request, calls the method and writes its response
+func unary(w http.ResponseWriter, body []byte, req message, call func() (message, error)) error {
+	if err := req.Unmarshal(body); err != nil {
+		return statusErrorf(CodeInvalidArgument, "%v", err)
+	}
+	resp, err := call()
+	if err != nil {
+		return err
+	}
+	return writeMessage(w, resp)
+}
+
+// Reads a single length-prefixed message: 1 byte compression flag, 4 bytes big-endian length
+func readMessage(r io.Reader) ([]byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, statusErrorf(CodeInvalidArgument, "reading message header: %v", err)
+	}
+	if header[0] != 0 {
+		return nil, statusErrorf(CodeUnimplemented, "compressed messages are not supported")
+	}
+	length := binary.BigEndian.Uint32(header[1:])
+	if length > maxMessageSize {
+		return nil, statusErrorf(CodeInvalidArgument, "message of %d bytes is too large", length)
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, statusErrorf(CodeInvalidArgument, "reading message: %v", err)
+	}
+	return body, nil
+}
+
+func writeMessage(w io.Writer, m message) error {
+	body := m.Marshal()
+	frame := make([]byte, 5, 5+len(body))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(body)))
+	_, err := w.Write(append(frame, body...))
+	return err
+}
+
+// gRPC reports the outcome of a call in the HTTP trailers
+func writeStatus(w http.ResponseWriter, err error) {
+	code, msg := CodeOK, ""
+	if err != nil {
+		var se *StatusError
+		if errors.As(err, &se) {
+			code, msg = se.Code, se.Message
+		} else {
+			code, msg = CodeInternal, err.Error()
+		}
+	}
+	w.Header().Set(http.TrailerPrefix+"Grpc-Status", strconv.Itoa(code))
+	if msg != "" {
+		w.Header().Set(http.TrailerPrefix+"Grpc-Message", encodeGrpcMessage(msg))
+	}
+}
+
+// Grpc-Message is percent-encoded, but only outside of printable ASCII and the percent sign itself
+func encodeGrpcMessage(msg string) string {
+	var sb strings.Builder
+	for i := 0; i < len(msg); i++ {
+		c := msg[i]
+		if c < 0x20 || c > 0x7e || c == '%' {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		} else {
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// Parses the puzzle of a request
+func (s *Server) parsePuzzle(puzzle string) ([9][9]int, error) {
+	p, err := parser.NewLimitedReader(strings.NewReader(puzzle), s.limits).Next()
+	if errors.Is(err, io.EOF) {
+		return p, statusErrorf(CodeInvalidArgument, "puzzle is empty")
+	}
+	if err != nil {
+		return p, statusErrorf(CodeInvalidArgument, "%v", err)
+	}
+	return p, nil
+}
+
+// Parses the puzzle and checks the requested limit against the server maximum.
+// A zero limit is replaced with the maximum
+func (s *Server) newSolver(puzzle string, limit int) (*solver.Solver, int, error) {
+	p, err := s.parsePuzzle(puzzle)
+	if err != nil {
+		return nil, 0, err
+	}
+	sv, err := solver.NewSolver(p)
+	if err != nil {
+		return nil, 0, statusErrorf(CodeInvalidArgument, "%v", err)
+	}
+	if limit < 0 || limit > s.maxLimit {
+		return nil, 0, statusErrorf(CodeInvalidArgument, "limit %d is out of range, want 0 to %d", limit, s.maxLimit)
+	}
+	if limit == 0 {
+		limit = s.maxLimit
+	}
+	sv.SetSolveBudget(solveBudget)
+	return sv, limit, nil
+}
+
+// Finds the next solution of a solver with solveBudget set. Returns a canceled status once ctx is
+// done, that is when the client cancels the call or goes away
+func nextSolution(ctx context.Context, sv *solver.Solver) (bool, error) {
+	for {
+		if sv.SolveContext(ctx) {
+			return true, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return false, statusErrorf(CodeCanceled, "%v", err)
+		}
+		if !sv.BudgetExceeded() {
+			return false, nil
+		}
+	}
+}
+
+func (s *Server) Solve(ctx context.Context, req *SolveRequest) (*SolveResponse, error) {
+	done := s.metrics.Start("grpc_solve")
+	sv, _, err := s.newSolver(req.Puzzle, 0)
+	if err != nil {
+		done(0, 0, true)
+		return nil, err
+	}
+	found, err := nextSolution(ctx, sv)
+	if err != nil {
+		done(0, sv.Iterations(), true)
+		return nil, err
+	}
+	if !found {
+		done(0, sv.Iterations(), false)
+		return nil, statusErrorf(CodeNotFound, "no solution")
+	}
+	done(1, sv.Iterations(), false)
+	return &SolveResponse{Solution: format.Inline(sv.Solution()), Iterations: int64(sv.Iterations())}, nil
+}
+
+func (s *Server) Count(ctx context.Context, req *CountRequest) (*CountResponse, error) {
+	done := s.metrics.Start("grpc_count")
+	sv, limit, err := s.newSolver(req.Puzzle, int(req.Limit))
+	if err != nil {
+		done(0, 0, true)
+		return nil, err
+	}
+	resp := &CountResponse{}
+	for {
+		found, err := nextSolution(ctx, sv)
+		if err != nil {
+			done(int(resp.Count), sv.Iterations(), true)
+			return nil, err
+		}
+		if !found {
+			break
+		}
+		if int(resp.Count) == limit {
+			resp.LimitReached = true
+			break
+		}
+		resp.Count++
+	}
+	resp.Iterations = int64(sv.Iterations())
+	done(int(resp.Count), sv.Iterations(), false)
+	return resp, nil
+}
+
+// Makes a puzzle with a unique solution, see sudocoo.Generate. The same seed and clues give the same puzzle
+func (s *Server) Generate(req *GenerateRequest) (*GenerateResponse, error) {
+	done := s.metrics.Start("grpc_generate")
+	puzzle, err := sudocoo.Generate(sudocoo.WithClues(int(req.Clues)), sudocoo.WithSeed(uint64(req.Seed)))
+	if err != nil {
+		done(0, 0, true)
+		return nil, statusErrorf(CodeInvalidArgument, "%v", err)
+	}
+	done(1, 0, false)
+	return &GenerateResponse{Puzzle: format.Inline(puzzle)}, nil
+}
+
+// Rates a puzzle with a unique solution, see rating.Rate. Puzzles without one have no rating
+func (s *Server) Rate(ctx context.Context, req *RateRequest) (*RateResponse, error) {
+	done := s.metrics.Start("grpc_rate")
+	puzzle, err := s.parsePuzzle(req.Puzzle)
+	if err != nil {
+		done(0, 0, true)
+		return nil, err
+	}
+	sv, err := solver.NewSolver(puzzle)
+	if err != nil {
+		done(0, 0, true)
+		return nil, statusErrorf(CodeInvalidArgument, "%v", err)
+	}
+	sv.SetSolveBudget(solveBudget)
+	count := 0
+	for count < 2 {
+		found, err := nextSolution(ctx, sv)
+		if err != nil {
+			done(count, sv.Iterations(), true)
+			return nil, err
+		}
+		if !found {
+			break
+		}
+		count++
+	}
+	switch count {
+	case 0:
+		done(0, sv.Iterations(), false)
+		return nil, statusErrorf(CodeNotFound, "no solution")
+	case 2:
+		done(count, sv.Iterations(), false)
+		return nil, statusErrorf(CodeInvalidArgument, "the puzzle has more than one solution")
+	}
+	rated, err := rating.Rate(puzzle, analysis.DefaultSearchOrders, 1)
+	if err != nil {
+		done(1, sv.Iterations(), true)
+		return nil, err
+	}
+	done(1, sv.Iterations(), false)
+	return &RateResponse{Level: rated.Level.String(), Score: rated.Score}, nil
+}
+
+// Calls send for each solution found, stops on the first send error
+func (s *Server) Enumerate(ctx context.Context, req *EnumerateRequest, send func(*EnumerateResponse) error) error {
+	done := s.metrics.Start("grpc_enumerate")
+	sv, limit, err := s.newSolver(req.Puzzle, int(req.Limit))
+	if err != nil {
+		done(0, 0, true)
+		return err
+	}
+	count := 0
+	for count < limit {
+		found, err := nextSolution(ctx, sv)
+		if err != nil {
+			done(count, sv.Iterations(), true)
+			return err
+		}
+		if !found {
+			break
+		}
+		count++
+		if err := send(&EnumerateResponse{Index: int32(count), Solution: format.Inline(sv.Solution())}); err != nil {
+			done(count, sv.Iterations(), true)
+			return err
+		}
+	}
+	done(count, sv.Iterations(), false)
+	return nil
+}
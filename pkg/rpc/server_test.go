@@ -0,0 +1,183 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// The first puzzle of data/input1.txt and its solution
+const (
+	rpcPuzzle   = "4...3.......6..8..........1....5..9..8....6...7.2........1.27..5.3....4.9........"
+	rpcSolution = "468931527751624839392578461134756298289413675675289314846192753513867942927345186"
+)
+
+// A gRPC client as far as the tests need one: it posts a request over HTTP/2 without TLS, the way
+// clients with insecure credentials do, and reads the messages and the status of the response
+type testClient struct {
+	url    string
+	client *http.Client
+}
+
+// Serves s the way 'sudocoo serve -grpc-addr' does and returns a client for it
+func startServer(t *testing.T, s *Server) *testClient {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := s.HTTPServer("", s)
+	go srv.Serve(l)
+	t.Cleanup(func() { srv.Close() })
+	transport := &http.Transport{Protocols: new(http.Protocols)}
+	transport.Protocols.SetUnencryptedHTTP2(true)
+	t.Cleanup(transport.CloseIdleConnections)
+	return &testClient{url: "http://" + l.Addr().String(), client: &http.Client{Transport: transport}}
+}
+
+// Calls the method with the request, returns the messages of the response and the status
+func (c *testClient) call(t *testing.T, method string, req message) (messages [][]byte, code int, msg string) {
+	t.Helper()
+	var body bytes.Buffer
+	if err := writeMessage(&body, req); err != nil {
+		t.Fatal(err)
+	}
+	return c.post(t, servicePrefix+method, "application/grpc", body.Bytes())
+}
+
+func (c *testClient) post(t *testing.T, path, contentType string, body []byte) (messages [][]byte, code int, msg string) {
+	t.Helper()
+	httpReq, err := http.NewRequestWithContext(context.Background(), http.MethodPost, c.url+path, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpReq.Header.Set("Content-Type", contentType)
+	httpReq.Header.Set("TE", "trailers")
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.ProtoMajor != 2 {
+		t.Fatalf("got a response over %s, want HTTP/2", resp.Proto)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, -resp.StatusCode, ""
+	}
+	for {
+		m, err := readMessage(resp.Body)
+		if err != nil {
+			// The status comes in the trailers, once the body is read to the end
+			io.Copy(io.Discard, resp.Body)
+			break
+		}
+		messages = append(messages, m)
+	}
+	code, err = strconv.Atoi(resp.Trailer.Get("Grpc-Status"))
+	if err != nil {
+		t.Fatalf("no Grpc-Status trailer: %v", resp.Trailer)
+	}
+	return messages, code, resp.Trailer.Get("Grpc-Message")
+}
+
+func TestServerUnary(t *testing.T) {
+	c := startServer(t, NewServer(100, nil))
+
+	messages, code, msg := c.call(t, "Solve", &SolveRequest{Puzzle: rpcPuzzle})
+	var solved SolveResponse
+	if code != CodeOK || len(messages) != 1 || solved.Unmarshal(messages[0]) != nil {
+		t.Fatalf("Solve: status %d %q with %d messages", code, msg, len(messages))
+	}
+	if solved.Solution != rpcSolution || solved.Iterations <= 0 {
+		t.Errorf("Solve = %+v, want the solution", solved)
+	}
+
+	// With the first clue taken out the puzzle has more solutions than the limit
+	messages, code, msg = c.call(t, "Count", &CountRequest{Puzzle: "." + rpcPuzzle[1:], Limit: 3})
+	var counted CountResponse
+	if code != CodeOK || len(messages) != 1 || counted.Unmarshal(messages[0]) != nil {
+		t.Fatalf("Count: status %d %q with %d messages", code, msg, len(messages))
+	}
+	if counted.Count != 3 || !counted.LimitReached {
+		t.Errorf("Count = %+v, want 3 with the limit reached", counted)
+	}
+
+	messages, code, msg = c.call(t, "Rate", &RateRequest{Puzzle: rpcPuzzle})
+	var rated RateResponse
+	if code != CodeOK || len(messages) != 1 || rated.Unmarshal(messages[0]) != nil {
+		t.Fatalf("Rate: status %d %q with %d messages", code, msg, len(messages))
+	}
+	if rated.Level == "" || rated.Score <= 0 {
+		t.Errorf("Rate = %+v, want a level and a score", rated)
+	}
+
+	messages, code, msg = c.call(t, "Generate", &GenerateRequest{Seed: 7})
+	var generated GenerateResponse
+	if code != CodeOK || len(messages) != 1 || generated.Unmarshal(messages[0]) != nil {
+		t.Fatalf("Generate: status %d %q with %d messages", code, msg, len(messages))
+	}
+	if len(generated.Puzzle) != 81 {
+		t.Errorf("Generate = %+v, want a puzzle", generated)
+	}
+}
+
+// Enumerate streams a message for each solution, then the status
+func TestServerEnumerate(t *testing.T) {
+	c := startServer(t, NewServer(100, nil))
+	messages, code, msg := c.call(t, "Enumerate", &EnumerateRequest{Puzzle: strings.Repeat(".", 81), Limit: 3})
+	if code != CodeOK || len(messages) != 3 {
+		t.Fatalf("status %d %q with %d messages, want 3", code, msg, len(messages))
+	}
+	seen := map[string]bool{}
+	for i, m := range messages {
+		var resp EnumerateResponse
+		if err := resp.Unmarshal(m); err != nil {
+			t.Fatal(err)
+		}
+		if int(resp.Index) != i+1 || len(resp.Solution) != 81 || seen[resp.Solution] {
+			t.Errorf("message %d is %+v, want solution %d", i, resp, i+1)
+		}
+		seen[resp.Solution] = true
+	}
+}
+
+func TestServerErrors(t *testing.T) {
+	c := startServer(t, NewServer(100, nil))
+	tests := []struct {
+		name    string
+		method  string
+		req     message
+		code    int
+		message string // in the Grpc-Message trailer
+	}{
+		{"inconsistent puzzle", "Solve", &SolveRequest{Puzzle: "44" + rpcPuzzle[2:]}, CodeInvalidArgument, "invalid (inconsistent) puzzle input"},
+		{"no puzzle", "Solve", &SolveRequest{}, CodeInvalidArgument, ""},
+		{"limit over the maximum", "Count", &CountRequest{Puzzle: rpcPuzzle, Limit: 101}, CodeInvalidArgument, ""},
+		{"unknown method", "Guess", &SolveRequest{Puzzle: rpcPuzzle}, CodeUnimplemented, "unknown method /sudocoo.v1.Sudocoo/Guess"},
+		{"more than one solution", "Rate", &RateRequest{Puzzle: "." + rpcPuzzle[1:]}, CodeInvalidArgument, "the puzzle has more than one solution"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			messages, code, msg := c.call(t, test.method, test.req)
+			if code != test.code || len(messages) != 0 {
+				t.Errorf("got status %d %q with %d messages, want %d", code, msg, len(messages), test.code)
+			}
+			if test.message != "" && msg != test.message {
+				t.Errorf("got message %q, want %q", msg, test.message)
+			}
+		})
+	}
+
+	// A compressed message is refused, and so is a request that is not gRPC
+	if _, code, _ := c.post(t, servicePrefix+"Solve", "application/grpc", []byte{1, 0, 0, 0, 0}); code != CodeUnimplemented {
+		t.Errorf("got status %d for a compressed message, want %d", code, CodeUnimplemented)
+	}
+	if _, code, _ := c.post(t, servicePrefix+"Solve", "application/json", []byte("{}")); code != -http.StatusUnsupportedMediaType {
+		t.Errorf("got status %d for a JSON request, want HTTP %d", code, http.StatusUnsupportedMediaType)
+	}
+}
@@ -0,0 +1,130 @@
+package rpc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Protocol buffers wire format, just the parts that the messages in api/*.proto use.
+// See https://protobuf.dev/programming-guides/encoding/
+
+// Wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errTruncated = errors.New("truncated protobuf message")
+
+func appendTag(b []byte, field, wireType int) []byte {
+	return binary.AppendUvarint(b, uint64(field)<<3|uint64(wireType))
+}
+
+// Proto3 does not encode fields with default values, so zero values are skipped
+
+func appendString(b []byte, field int, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = appendTag(b, field, wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+func appendBytes(b []byte, field int, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+	b = appendTag(b, field, wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+// Works for int32 and int64 fields, negative numbers are sign extended to 64 bits as protobuf requires
+func appendInt(b []byte, field int, v int64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = appendTag(b, field, wireVarint)
+	return binary.AppendUvarint(b, uint64(v))
+}
+
+func appendBool(b []byte, field int, v bool) []byte {
+	if !v {
+		return b
+	}
+	return appendInt(b, field, 1)
+}
+
+func appendDouble(b []byte, field int, v float64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = appendTag(b, field, wireFixed64)
+	return binary.LittleEndian.AppendUint64(b, math.Float64bits(v))
+}
+
+// A decoded field. For varint and fixed types the value is in 'number', for length delimited in 'bytes'
+type field struct {
+	number   int
+	wireType int
+	value    uint64
+	bytes    []byte
+}
+
+func (f field) int() int64     { return int64(f.value) }
+func (f field) bool() bool     { return f.value != 0 }
+func (f field) string() string { return string(f.bytes) }
+func (f field) double() float64 {
+	return math.Float64frombits(f.value)
+}
+
+// Calls fn for every field in the message. Unknown fields are passed
+// to fn as well, which is expected to ignore them for forward compatibility
+func parseFields(b []byte, fn func(f field) error) error {
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errTruncated
+		}
+		b = b[n:]
+		f := field{number: int(tag >> 3), wireType: int(tag & 7)}
+		switch f.wireType {
+		case wireVarint:
+			f.value, n = binary.Uvarint(b)
+			if n <= 0 {
+				return errTruncated
+			}
+			b = b[n:]
+		case wireFixed64:
+			if len(b) < 8 {
+				return errTruncated
+			}
+			f.value = binary.LittleEndian.Uint64(b)
+			b = b[8:]
+		case wireFixed32:
+			if len(b) < 4 {
+				return errTruncated
+			}
+			f.value = uint64(binary.LittleEndian.Uint32(b))
+			b = b[4:]
+		case wireBytes:
+			length, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < length {
+				return errTruncated
+			}
+			f.bytes = b[n : n+int(length)]
+			b = b[n+int(length):]
+		default:
+			return fmt.Errorf("unsupported protobuf wire type %d", f.wireType)
+		}
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
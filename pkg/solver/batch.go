@@ -0,0 +1,139 @@
+package solver
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// A puzzle or a solution, empty cells are zeroes
+type Puzzle = [sudokuSize][sudokuSize]int
+
+// Controls how SolveBatch solves the puzzles, the zero value finds the first solution
+// of each puzzle on all CPUs
+type BatchOptions struct {
+	Workers       int          // the number of puzzles solved at the same time, 0 is one per CPU
+	Limit         int          // the most solutions to find for each puzzle, 0 is the first one only
+	CountOnly     bool         // count the solutions without keeping them
+	MaxIterations int          // give up on a puzzle after that many iterations, 0 is no limit
+	Logger        *slog.Logger // gets the result of every puzzle at the debug level if set
+}
+
+// The outcome for a single puzzle of the batch
+type Result struct {
+	Solutions      []Puzzle // the solutions found, unless BatchOptions.CountOnly is set
+	Count          int      // the number of solutions found
+	LimitReached   bool     // there are more solutions than BatchOptions.Limit
+	BudgetExceeded bool     // the search gave up at BatchOptions.MaxIterations, there may be more solutions
+	Iterations     int      // iterations taken on this puzzle
+	Err            error    // the puzzle is inconsistent, nothing else is set
+}
+
+// Totals over the results of a batch
+type BatchStats struct {
+	Puzzles        int // all the puzzles in the batch
+	Solved         int // puzzles with at least one solution
+	Solutions      int // solutions found across all the puzzles
+	LimitReached   int // puzzles stopped at the limit
+	BudgetExceeded int // puzzles given up on because of the iteration budget
+	Invalid        int // inconsistent puzzles
+	Iterations     int // iterations taken across all the puzzles
+}
+
+// Solves the puzzles concurrently and returns a result for each of them, in the same order.
+// Solvers are reused across the puzzles, so with CountOnly the batch does not allocate per puzzle
+func SolveBatch(puzzles []Puzzle, opts BatchOptions) []Result {
+	results := make([]Result, len(puzzles))
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	workers = min(workers, len(puzzles))
+
+	var (
+		pool Pool
+		next atomic.Int64
+		wg   sync.WaitGroup
+	)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1) - 1)
+				if i >= len(puzzles) {
+					return
+				}
+				results[i] = solveOne(&pool, puzzles[i], opts)
+			}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// Solves a single puzzle of the batch. With a limit the search goes on for one more solution,
+// to tell whether there are more than the limit
+func solveOne(pool *Pool, puzzle Puzzle, opts BatchOptions) (r Result) {
+	if opts.Logger != nil {
+		defer func() { logResult(opts.Logger, r) }()
+	}
+	s, err := pool.Get(puzzle)
+	if err != nil {
+		r.Err = err
+		return
+	}
+	defer pool.Put(s)
+	s.maxIterations = opts.MaxIterations
+	var solution Puzzle
+	for s.SolveInto(&solution) {
+		if opts.Limit != 0 && r.Count == opts.Limit {
+			r.LimitReached = true
+			break
+		}
+		r.Count++
+		if !opts.CountOnly {
+			r.Solutions = append(r.Solutions, solution)
+		}
+		if opts.Limit == 0 {
+			break
+		}
+	}
+	r.BudgetExceeded = s.budgetExceeded
+	r.Iterations = s.Iterations()
+	return
+}
+
+// Logs the result of a puzzle of the batch
+func logResult(l *slog.Logger, r Result) {
+	if r.Err != nil {
+		l.LogAttrs(context.Background(), slog.LevelDebug, "puzzle rejected", slog.String("error", r.Err.Error()))
+		return
+	}
+	l.LogAttrs(context.Background(), slog.LevelDebug, "puzzle solved", slog.Int("solutions", r.Count), slog.Bool("limitReached", r.LimitReached),
+		slog.Bool("budgetExceeded", r.BudgetExceeded), slog.Int("iterations", r.Iterations))
+}
+
+// Adds up the results of a batch
+func Summarize(results []Result) (stats BatchStats) {
+	stats.Puzzles = len(results)
+	for _, r := range results {
+		switch {
+		case r.Err != nil:
+			stats.Invalid++
+			continue
+		case r.LimitReached:
+			stats.LimitReached++
+		case r.BudgetExceeded:
+			stats.BudgetExceeded++
+		}
+		if r.Count > 0 {
+			stats.Solved++
+		}
+		stats.Solutions += r.Count
+		stats.Iterations += r.Iterations
+	}
+	return
+}
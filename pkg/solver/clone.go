@@ -0,0 +1,34 @@
+package solver
+
+// Returns a copy of the solver that searches on from where the solver is, on its own: either of them
+// can go on solving, or be reset, without the other noticing. The copy keeps the limits, the
+// restrictions, the constraints and the logger, but starts without an observer, a tracer and
+// snapshots, those are for the search they were set up for. Neither must be solving while it is cloned
+func (s *Solver) Clone() *Solver {
+	return &Solver{
+		geometry:          s.geometry,
+		globalCandidates:  s.globalCandidates,
+		unitCandidates:    s.unitCandidates,
+		cells:             s.cells,
+		cellSearchSpace:   s.cellSearchSpace,
+		searchSpaceSize:   s.searchSpaceSize,
+		currentSearchCell: s.currentSearchCell,
+		cellCandidates:    s.cellCandidates,
+		nextCandidate:     s.nextCandidate,
+		allowed:           s.allowed,
+		restricted:        s.restricted,
+		lastSolution:      s.lastSolution,
+		done:              s.done,
+		haveSolution:      s.haveSolution,
+		iterations:        s.iterations,
+		guesses:           s.guesses,
+		deadEnds:          s.deadEnds,
+		maxIterations:     s.maxIterations,
+		solveBudget:       s.solveBudget,
+		parallelism:       s.parallelism,
+		budgetExceeded:    s.budgetExceeded,
+		logger:            s.logger,
+		// The constraints of the layout are never appended to in place, see AddConstraint, so they can be shared
+		constraints: s.constraints,
+	}
+}
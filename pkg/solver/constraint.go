@@ -0,0 +1,84 @@
+package solver
+
+// A rule on top of the classic ones, such as the anti-knight one, that Solver keeps to once it is
+// registered with AddConstraint. The digits are bit masks, bit d-1 for digit d, the way
+// NewRestrictedSolver takes them, and 0 for an empty cell. The search asks the rule about a cell
+// every time it counts the cell's candidates, so RuledOut has to be fast
+type Constraint interface {
+	// Returns the digits the rule takes from the candidates of the empty cell at the row and the
+	// column, both 0-based, given the digits in the cells
+	RuledOut(cells *[sudokuSize][sudokuSize]int, row, column int) int
+	// Returns whether the digits in the cells keep to the rule. The givens are checked with it
+	// before the search starts
+	Valid(cells *[sudokuSize][sudokuSize]int) bool
+}
+
+// Makes the solver keep to the constraint as well as to the rules it already has. Call it before
+// Solve. Returns ErrInvalidPuzzle when the givens break the constraint
+func (s *Solver) AddConstraint(c Constraint) error {
+	if !c.Valid(&s.cells) {
+		return ErrInvalidPuzzle
+	}
+	// The constraints of the layout are shared by all its solvers, so they are copied, not appended to
+	s.constraints = append(s.constraints[:len(s.constraints):len(s.constraints)], c)
+	return nil
+}
+
+// Returns whether the digits in the cells keep to all the constraints
+func (s *Solver) keepsConstraints() bool {
+	for _, c := range s.constraints {
+		if !c.Valid(&s.cells) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns whether no filled cell has a digit the constraint rules out for it, which is all Valid
+// has to check for the constraints that only look at the cells around
+func noneRuledOut(c Constraint, cells *[sudokuSize][sudokuSize]int) bool {
+	for y, row := range cells {
+		for x, bit := range row {
+			if c.RuledOut(cells, y, x)&bit != 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Returns the digits in the cells, the empty ones have none
+func digitsIn(cells *[sudokuSize][sudokuSize]int, of []coordinates) int {
+	digits := 0
+	for _, cell := range of {
+		digits |= cells[cell.row][cell.column]
+	}
+	return digits
+}
+
+// No two cells a knight's move apart have the same digit, see AntiKnight
+type knightsConstraint struct {
+	knights [][][]coordinates // the cells a knight's move away from each cell, by row and column
+}
+
+func (c knightsConstraint) RuledOut(cells *[sudokuSize][sudokuSize]int, row, column int) int {
+	return digitsIn(cells, c.knights[row][column])
+}
+
+func (c knightsConstraint) Valid(cells *[sudokuSize][sudokuSize]int) bool {
+	return noneRuledOut(c, cells)
+}
+
+// No two cells side by side have digits one apart, see NonConsecutive
+type besideConstraint struct {
+	beside [][][]coordinates // the cells side by side with each cell, by row and column
+}
+
+func (c besideConstraint) RuledOut(cells *[sudokuSize][sudokuSize]int, row, column int) int {
+	beside := digitsIn(cells, c.beside[row][column])
+	return (beside<<1 | beside>>1) & initialCandidatesMask
+}
+
+func (c besideConstraint) Valid(cells *[sudokuSize][sudokuSize]int) bool {
+	return noneRuledOut(c, cells)
+}
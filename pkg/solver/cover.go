@@ -0,0 +1,66 @@
+package solver
+
+// The exact cover matrix of a puzzle, for the tools that run cover algorithms of their own. A
+// row is a candidate, a digit in a cell, and it has a 1 in the four columns it covers, see
+// CoverRow. A set of rows that has exactly one 1 in every column is a solution of the puzzle
+type CoverMatrix struct {
+	Columns int        // the number of columns, always CoverColumns
+	Rows    []CoverRow // the candidates, by cell and digit
+}
+
+// A row of the exact cover matrix
+type CoverRow struct {
+	Row, Column, Digit int    // the candidate, the row and the column 0-based, the digit 1 to 9
+	Ones               [4]int // the columns the row has a 1 in, from the lowest
+}
+
+// The number of columns of the exact cover matrix. Columns 0 to 80 are the cells, row*9 + column,
+// each cell has to have a digit. Columns 81 to 161 are the digits of the rows, 81 + row*9 + digit-1,
+// each row has to have each digit. Then, the same way, 162 to 242 are the digits of the columns and
+// 243 to 323 are the digits of the boxes, the boxes numbered row by row, all 0-based
+const CoverColumns = 4 * sudokuSize * sudokuSize
+
+// Returns the columns the digit in the cell covers, the row, the column and the digit all 0-based
+func coverColumns(y, x, d int) [4]int {
+	const n = sudokuSize * sudokuSize
+	box := y/3*3 + x/3
+	return [4]int{y*sudokuSize + x, n + y*sudokuSize + d, 2*n + x*sudokuSize + d, 3*n + box*sudokuSize + d}
+}
+
+// Returns the exact cover matrix of the puzzle. The givens are rows of their own, and the rows that
+// clash with them, the other digits of their cells and the same digit in their rows, columns and
+// boxes, are left out, so a given is the only row that covers its cell and every cover has it.
+// Returns ErrInvalidPuzzle when the puzzle is inconsistent, as NewSolver does
+func ExactCover(puzzle [sudokuSize][sudokuSize]int) (CoverMatrix, error) {
+	var covered [CoverColumns]bool
+	for y, row := range puzzle {
+		for x, digit := range row {
+			if digit == 0 {
+				continue
+			}
+			if digit < 0 || digit > sudokuSize {
+				return CoverMatrix{}, ErrInvalidPuzzle
+			}
+			for _, c := range coverColumns(y, x, digit-1) {
+				if covered[c] {
+					return CoverMatrix{}, ErrInvalidPuzzle
+				}
+				covered[c] = true
+			}
+		}
+	}
+	m := CoverMatrix{Columns: CoverColumns}
+	for y := range sudokuSize {
+		for x := range sudokuSize {
+			for d := range sudokuSize {
+				ones := coverColumns(y, x, d)
+				given := puzzle[y][x] == d+1
+				clashes := covered[ones[0]] || covered[ones[1]] || covered[ones[2]] || covered[ones[3]]
+				if given || !clashes {
+					m.Rows = append(m.Rows, CoverRow{Row: y, Column: x, Digit: d + 1, Ones: ones})
+				}
+			}
+		}
+	}
+	return m, nil
+}
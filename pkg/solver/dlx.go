@@ -0,0 +1,211 @@
+package solver
+
+// Solves classic puzzles with Knuth's Algorithm X and dancing links, as a second engine to check
+// Solver against and to compare its speed with. Sudoku is an exact cover problem: every cell has to
+// have a digit, and every row, column and box has to have every digit, once each, which makes 324
+// columns. Each of the 729 candidates, a digit in a cell, is a row that covers four of them. The
+// search picks the column with the fewest rows left, tries each of them in turn, and takes the
+// columns the row covers out of the matrix along with the rows that clash with it
+type DLXSolver struct {
+	// The links of the nodes, the left and the right ones of a row, the up and the down ones of a column.
+	// Node 0 is the root, nodes 1 to 324 are the column headers, the rows follow
+	left, right, up, down []int32
+	column                []int32 // the header of the column of each node
+	candidate             []int16 // the candidate the row of each node stands for, cell*9 + digit-1
+	size                  []int32 // the number of rows left in each column, by header
+	puzzle                [sudokuSize][sudokuSize]int
+	stack                 []int32 // a node of the row chosen at each depth of the search
+	lastSolution          [sudokuSize][sudokuSize]int
+	started               bool // Solve has been called
+	done                  bool // the search is over
+	haveSolution          bool // .lastSolution contains a solution
+	iterations            int  // for statistics purposes
+}
+
+// The columns of the matrix, see CoverColumns. Their headers are numbered from 1, after the root
+const dlxColumns = CoverColumns
+
+// Creates a DLX solver for the puzzle. Returns ErrInvalidPuzzle when the puzzle is inconsistent, as
+// NewSolver does
+func NewDLXSolver(puzzle [sudokuSize][sudokuSize]int) (*DLXSolver, error) {
+	const nodes = 1 + dlxColumns + 4*sudokuSize*sudokuSize*sudokuSize
+	s := &DLXSolver{
+		left:      make([]int32, 1+dlxColumns, nodes),
+		right:     make([]int32, 1+dlxColumns, nodes),
+		up:        make([]int32, 1+dlxColumns, nodes),
+		down:      make([]int32, 1+dlxColumns, nodes),
+		column:    make([]int32, 1+dlxColumns, nodes),
+		candidate: make([]int16, 1+dlxColumns, nodes),
+		size:      make([]int32, 1+dlxColumns),
+		puzzle:    puzzle,
+	}
+	for c := int32(0); c <= dlxColumns; c++ {
+		s.left[c], s.right[c] = (c+dlxColumns)%(dlxColumns+1), (c+1)%(dlxColumns+1)
+		s.up[c], s.down[c], s.column[c] = c, c, c
+	}
+	// The first node of the row of each candidate
+	var rows [sudokuSize * sudokuSize * sudokuSize]int32
+	for y := range sudokuSize {
+		for x := range sudokuSize {
+			for d := range sudokuSize {
+				rows[(y*sudokuSize+x)*sudokuSize+d] = s.addRow(y, x, d)
+			}
+		}
+	}
+	var covered [CoverColumns]bool
+	for y, row := range puzzle {
+		for x, digit := range row {
+			if digit == 0 {
+				continue
+			}
+			if digit < 0 || digit > sudokuSize {
+				return nil, ErrInvalidPuzzle
+			}
+			first := rows[(y*sudokuSize+x)*sudokuSize+digit-1]
+			// A column already covered by another given means the two clash
+			for _, c := range coverColumns(y, x, digit-1) {
+				if covered[c] {
+					return nil, ErrInvalidPuzzle
+				}
+				covered[c] = true
+			}
+			s.cover(s.column[first])
+			s.choose(first)
+		}
+	}
+	return s, nil
+}
+
+// Adds the row of the candidate to the bottom of its columns and returns its first node
+func (s *DLXSolver) addRow(y, x, d int) int32 {
+	first := int32(len(s.left))
+	for i, column := range coverColumns(y, x, d) {
+		c, n := 1+int32(column), int32(len(s.left))
+		s.left = append(s.left, first+int32((i+3)%4))
+		s.right = append(s.right, first+int32((i+1)%4))
+		s.up = append(s.up, s.up[c])
+		s.down = append(s.down, c)
+		s.column = append(s.column, c)
+		s.candidate = append(s.candidate, int16((y*sudokuSize+x)*sudokuSize+d))
+		s.down[s.up[c]] = n
+		s.up[c] = n
+		s.size[c]++
+	}
+	return first
+}
+
+// Takes the column out of the header list, and the rows that have a node in it out of the other columns
+func (s *DLXSolver) cover(c int32) {
+	s.right[s.left[c]], s.left[s.right[c]] = s.right[c], s.left[c]
+	for i := s.down[c]; i != c; i = s.down[i] {
+		for j := s.right[i]; j != i; j = s.right[j] {
+			s.down[s.up[j]], s.up[s.down[j]] = s.down[j], s.up[j]
+			s.size[s.column[j]]--
+		}
+	}
+}
+
+// Puts the column back, undoing cover. The links are restored in the reverse order, this is the dance
+func (s *DLXSolver) uncover(c int32) {
+	for i := s.up[c]; i != c; i = s.up[i] {
+		for j := s.left[i]; j != i; j = s.left[j] {
+			s.size[s.column[j]]++
+			s.down[s.up[j]], s.up[s.down[j]] = j, j
+		}
+	}
+	s.right[s.left[c]], s.left[s.right[c]] = c, c
+}
+
+// Covers the columns of the row of the node other than the node's own, which is covered already
+func (s *DLXSolver) choose(n int32) {
+	for j := s.right[n]; j != n; j = s.right[j] {
+		s.cover(s.column[j])
+	}
+}
+
+// Undoes choose
+func (s *DLXSolver) unchoose(n int32) {
+	for j := s.left[n]; j != n; j = s.left[j] {
+		s.uncover(s.column[j])
+	}
+}
+
+// Undoes the row chosen last and chooses the next one down its column instead. When the column has
+// no rows left the search goes up a level and does the same there. Returns false when there are no
+// levels left, the search is over then
+func (s *DLXSolver) next() bool {
+	for len(s.stack) > 0 {
+		n := s.stack[len(s.stack)-1]
+		s.unchoose(n)
+		c := s.column[n]
+		if n = s.down[n]; n != c {
+			s.stack[len(s.stack)-1] = n
+			s.choose(n)
+			return true
+		}
+		s.uncover(c)
+		s.stack = s.stack[:len(s.stack)-1]
+	}
+	return false
+}
+
+// Call this to find next solution. Returns false when no more solutions
+// and true, when a solution is found. After true is returned call
+// .Solution() to get last solution
+func (s *DLXSolver) Solve() bool {
+	if s.done {
+		return false
+	}
+	if s.started && !s.next() {
+		s.done = true
+		return false
+	}
+	s.started = true
+	for {
+		s.iterations++
+		if s.right[0] == 0 {
+			s.record()
+			return true
+		}
+		// The column with the fewest rows left, it is the most constrained one
+		best := s.right[0]
+		for c := s.right[best]; c != 0 && s.size[best] > 1; c = s.right[c] {
+			if s.size[c] < s.size[best] {
+				best = c
+			}
+		}
+		if s.size[best] == 0 {
+			if !s.next() {
+				s.done = true
+				return false
+			}
+			continue
+		}
+		s.cover(best)
+		s.stack = append(s.stack, s.down[best])
+		s.choose(s.down[best])
+	}
+}
+
+// Keeps the givens and the rows chosen as the last solution
+func (s *DLXSolver) record() {
+	s.lastSolution = s.puzzle
+	for _, n := range s.stack {
+		cell, digit := int(s.candidate[n])/sudokuSize, int(s.candidate[n])%sudokuSize+1
+		s.lastSolution[cell/sudokuSize][cell%sudokuSize] = digit
+	}
+	s.haveSolution = true
+}
+
+// Call this after a prior call to .Solve() returned true
+func (s *DLXSolver) Solution() [sudokuSize][sudokuSize]int {
+	if !s.haveSolution {
+		panic("Solution is called before Solve returned true")
+	}
+	return s.lastSolution
+}
+
+// Returns the number of iterations performed for statistical purposes
+func (s *DLXSolver) Iterations() int {
+	return s.iterations
+}
@@ -0,0 +1,14 @@
+package solver
+
+// What the search engines have in common, so that the callers can take either: Solver, the
+// backtracking search over candidate bit masks, and DLXSolver, dancing links. They find the same
+// solutions, not necessarily in the same order
+type Engine interface {
+	// Finds the next solution. Returns false when there are no more
+	Solve() bool
+	// Returns the last solution found. Call it after Solve returned true
+	Solution() [sudokuSize][sudokuSize]int
+	// Returns the number of iterations performed for statistical purposes. What an iteration is
+	// differs between the engines
+	Iterations() int
+}
@@ -0,0 +1,13 @@
+package solver
+
+import "errors"
+
+// Errors the solver and the packages built on it return, wrapped with details at times, so check
+// for them with errors.Is
+var (
+	ErrInvalidPuzzle   = errors.New("invalid (inconsistent) puzzle input") // the givens break the rules
+	ErrNoSolution      = errors.New("the puzzle has no solution")
+	ErrLimitReached    = errors.New("the search gave up at its iteration limit") // there may be more solutions
+	ErrCancelled       = errors.New("the search was cancelled")
+	ErrUnsupportedSize = errors.New("unsupported grid size") // see GridSolver
+)
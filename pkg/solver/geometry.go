@@ -0,0 +1,193 @@
+package solver
+
+import (
+	"slices"
+	"sync"
+)
+
+// The shape of a grid: the number of cells on a side and the size of the boxes, which tile the grid
+type Shape struct {
+	Size       int
+	BoxRows    int
+	BoxColumns int
+}
+
+// The classic 9x9 grid with 3x3 boxes
+var standardShape = Shape{sudokuSize, 3, 3}
+
+// A grid shape with the variant rules and constraints that come on top of the classic ones
+type layout struct {
+	Shape
+	variant Variant
+}
+
+// Lookup tables for a grid layout. They are generated when a solver for the layout is
+// first created, and shared by all the solvers of that layout after that
+type geometry struct {
+	layout
+	cells     [][]coordinates   // the coordinates of each cell with its box, by row and column
+	cellUnits [][]int           // the variant units each cell is in, bit i for unit i, by row and column, nil without any
+	units     [][]coordinates   // the cells of every row, then every column, then every box, then the variant units
+	peers     [][][]coordinates // the cells that cannot have the same digit as each cell, by row and column, the cell itself excluded
+	knights   [][][]coordinates // the cells a knight's move away from each cell, by row and column, nil without AntiKnight
+	beside    [][][]coordinates // the cells side by side with each cell, by row and column, nil without NonConsecutive
+
+	constraints []Constraint // the rules of the constraints of the layout, such as AntiKnight, nil without any
+}
+
+var (
+	geometriesLock sync.Mutex
+	geometries     = map[layout]*geometry{}
+)
+
+// Returns the lookup tables for the layout, generating them the first time they are needed
+func geometryFor(l layout) *geometry {
+	geometriesLock.Lock()
+	defer geometriesLock.Unlock()
+	g, ok := geometries[l]
+	if !ok {
+		g = newGeometry(l)
+		geometries[l] = g
+	}
+	return g
+}
+
+func newGeometry(l layout) *geometry {
+	sh := l.Shape
+	g := &geometry{layout: l}
+	boxesAcross := sh.Size / sh.BoxColumns
+	g.cells = make([][]coordinates, sh.Size)
+	for y := range g.cells {
+		g.cells[y] = make([]coordinates, sh.Size)
+		for x := range g.cells[y] {
+			box := y/sh.BoxRows*boxesAcross + x/sh.BoxColumns
+			g.cells[y][x] = coordinates{row: y, column: x, box: box}
+		}
+	}
+
+	// The variant units, numbered in the order they are added. They are kept apart from the
+	// coordinates, so that classic puzzles neither carry nor check them
+	var extraCells [][][2]int // the row and column of the cells of each variant unit
+	if l.variant&Diagonals != 0 {
+		var diagonal, anti [][2]int
+		for i := range sh.Size {
+			diagonal = append(diagonal, [2]int{i, i})
+			anti = append(anti, [2]int{i, sh.Size - 1 - i})
+		}
+		extraCells = append(extraCells, diagonal, anti)
+	}
+	if l.variant&Hyper != 0 {
+		windows := make([][][2]int, 4)
+		for y := range sh.Size {
+			for x := range sh.Size {
+				if w := hyperWindow(y, x); w != 0 {
+					windows[w-1] = append(windows[w-1], [2]int{y, x})
+				}
+			}
+		}
+		extraCells = append(extraCells, windows...)
+	}
+	if len(extraCells) != 0 {
+		g.cellUnits = make([][]int, sh.Size)
+		for y := range g.cellUnits {
+			g.cellUnits[y] = make([]int, sh.Size)
+		}
+		for i, unit := range extraCells {
+			for _, cell := range unit {
+				g.cellUnits[cell[0]][cell[1]] |= 1 << i
+			}
+		}
+	}
+
+	rows := make([][]coordinates, sh.Size)
+	columns := make([][]coordinates, sh.Size)
+	boxes := make([][]coordinates, sh.Size)
+	for y := 0; y < sh.Size; y++ {
+		for x := 0; x < sh.Size; x++ {
+			cell := g.cells[y][x]
+			rows[y] = append(rows[y], cell)
+			columns[x] = append(columns[x], cell)
+			boxes[cell.box] = append(boxes[cell.box], cell)
+		}
+	}
+	g.units = append(append(rows, columns...), boxes...)
+	extra := make([][]coordinates, len(extraCells))
+	for i, unit := range extraCells {
+		for _, cell := range unit {
+			extra[i] = append(extra[i], g.cells[cell[0]][cell[1]])
+		}
+	}
+	g.units = append(g.units, extra...)
+
+	if l.variant&AntiKnight != 0 {
+		g.knights = g.moves(knightMoves)
+		g.constraints = append(g.constraints, knightsConstraint{g.knights})
+	}
+	if l.variant&NonConsecutive != 0 {
+		g.beside = g.moves(besideMoves)
+		g.constraints = append(g.constraints, besideConstraint{g.beside})
+	}
+
+	g.peers = make([][][]coordinates, sh.Size)
+	for y := range g.peers {
+		g.peers[y] = make([][]coordinates, sh.Size)
+		for x := range g.peers[y] {
+			cell := g.cells[y][x]
+			seen := map[coordinates]bool{cell: true}
+			units := [][]coordinates{rows[y], columns[x], boxes[cell.box]}
+			for _, unit := range extra {
+				if slices.Contains(unit, cell) {
+					units = append(units, unit)
+				}
+			}
+			if g.knights != nil {
+				units = append(units, g.knights[y][x])
+			}
+			for _, unit := range units {
+				for _, peer := range unit {
+					if !seen[peer] {
+						seen[peer] = true
+						g.peers[y][x] = append(g.peers[y][x], peer)
+					}
+				}
+			}
+		}
+	}
+	return g
+}
+
+// Returns the cells the moves lead to from each cell, by row and column, the ones that stay in the grid
+func (g *geometry) moves(moves [][2]int) [][][]coordinates {
+	cells := make([][][]coordinates, g.Size)
+	for y := range cells {
+		cells[y] = make([][]coordinates, g.Size)
+		for x := range cells[y] {
+			for _, move := range moves {
+				my, mx := y+move[0], x+move[1]
+				if my >= 0 && my < g.Size && mx >= 0 && mx < g.Size {
+					cells[y][x] = append(cells[y][x], g.cells[my][mx])
+				}
+			}
+		}
+	}
+	return cells
+}
+
+// The moves of a knight, as rows down and columns right
+var knightMoves = [][2]int{{-2, -1}, {-2, 1}, {-1, -2}, {-1, 2}, {1, -2}, {1, 2}, {2, -1}, {2, 1}}
+
+// The moves to the cells side by side, up, left, right and down
+var besideMoves = [][2]int{{-1, 0}, {0, -1}, {0, 1}, {1, 0}}
+
+// The most variant units a layout can have, the ones of X-Sudoku and hyper sudoku together
+const maxExtraUnits = 6
+
+// Returns the window of the hyper variant the cell is in, 1 to 4, or 0 for none. The windows are 3x3
+// and one cell away from the edges of the grid and from each other: rows and columns 2 to 4 and 6 to 8
+func hyperWindow(y, x int) int {
+	inWindow := func(i int) bool { return i%4 != 0 }
+	if !inWindow(y) || !inWindow(x) {
+		return 0
+	}
+	return 1 + y/4*2 + x/4
+}
@@ -0,0 +1,229 @@
+package solver
+
+import (
+	"math/bits"
+	"slices"
+)
+
+// A puzzle or a solution of any size GridSolver supports: Size rows of Size cells, 0 for an empty
+// cell and 1 to Size for the digits
+type Grid [][]int
+
+// Returns an empty grid of the size
+func NewGrid(size int) Grid {
+	g := make(Grid, size)
+	for y := range g {
+		g[y] = make([]int, size)
+	}
+	return g
+}
+
+// Returns the 9x9 puzzle as a grid
+func GridOf(puzzle [sudokuSize][sudokuSize]int) Grid {
+	g := NewGrid(sudokuSize)
+	for y := range puzzle {
+		copy(g[y], puzzle[y][:])
+	}
+	return g
+}
+
+// The usual shapes of the grids, by size: the mini sudokus for children, the classic one, hexadoku and
+// the giant ones. The boxes are as wide as they are tall or one column wider. GridSolver takes
+// any shape whose boxes tile the grid, these are the ones that go without saying
+var gridShapes = map[int]Shape{
+	4:          {4, 2, 2},
+	6:          {6, 2, 3},
+	sudokuSize: standardShape,
+	12:         {12, 3, 4},
+	16:         {16, 4, 4},
+	25:         {25, 5, 5},
+}
+
+// Returns the usual shape of the grids of the size, false if there is none
+func ShapeOf(size int) (Shape, bool) {
+	sh, ok := gridShapes[size]
+	return sh, ok
+}
+
+// Returns whether GridSolver can solve grids of the shape: the boxes tile the grid, and the grid is
+// no bigger than MaxGridSize
+func (sh Shape) Valid() bool {
+	return sh.Size >= 1 && sh.Size <= MaxGridSize && sh.BoxRows >= 1 && sh.BoxRows*sh.BoxColumns == sh.Size
+}
+
+// Returns the sizes of the usual shapes, from the smallest
+func GridSizes() []int {
+	sizes := make([]int, 0, len(gridShapes))
+	for size := range gridShapes {
+		sizes = append(sizes, size)
+	}
+	slices.Sort(sizes)
+	return sizes
+}
+
+// Solves grids of other sizes than the classic one, with the same search as Solver: the empty cell with
+// the fewest candidates is filled first. Solver keeps to 9x9, its fixed size arrays and lookup tables
+// make it faster than this one can be, and so does the rest of the package: there is no Pool of grid
+// solvers, no Clone, SolveContext, Snapshot, constraints or variant units. A grid is solved from its
+// givens, one solution after another, and that is all other sizes get, here and on the command line
+type GridSolver struct {
+	geometry   *geometry
+	cells      []uint32 // the digit of each cell as a bit, row by row, 0 for an empty cell
+	units      []uint32 // the candidates left in each row, then each column, then each box
+	empty      []int    // the indexes of the empty cells, the ones the search has filled first
+	tried      []uint32 // the candidates still to try for each cell the search has filled, by depth
+	depth      int      // how many of the empty cells the search has filled
+	solution   Grid     // the last solution found
+	started    bool     // Solve has been called
+	done       bool     // the search is over
+	iterations int      // for statistics purposes
+}
+
+// Creates a solver for the puzzle, which must be a grid of the size of the shape. The shape can be
+// any valid one, see Shape.Valid, e.g. 6x6 with boxes of 3 rows and 2 columns. Returns
+// ErrUnsupportedSize for a shape that is not valid, and
+// ErrInvalidPuzzle when the puzzle does not fit the shape or its givens break the rules
+func NewGridSolver(sh Shape, puzzle Grid) (*GridSolver, error) {
+	if !sh.Valid() {
+		return nil, ErrUnsupportedSize
+	}
+	if len(puzzle) != sh.Size {
+		return nil, ErrInvalidPuzzle
+	}
+	g := geometryFor(layout{Shape: sh})
+	all := uint32(1)<<sh.Size - 1
+	s := &GridSolver{
+		geometry: g,
+		cells:    make([]uint32, sh.Size*sh.Size),
+		units:    make([]uint32, 3*sh.Size),
+		tried:    make([]uint32, 0, sh.Size*sh.Size),
+	}
+	for i := range s.units {
+		s.units[i] = all
+	}
+	for y, row := range puzzle {
+		if len(row) != sh.Size {
+			return nil, ErrInvalidPuzzle
+		}
+		for x, digit := range row {
+			if digit < 0 || digit > sh.Size {
+				return nil, ErrInvalidPuzzle
+			}
+			if digit == 0 {
+				s.empty = append(s.empty, y*sh.Size+x)
+				continue
+			}
+			bit := uint32(1) << (digit - 1)
+			if s.candidates(y*sh.Size+x)&bit == 0 {
+				return nil, ErrInvalidPuzzle
+			}
+			s.set(y*sh.Size+x, bit)
+		}
+	}
+	s.tried = s.tried[:len(s.empty)]
+	return s, nil
+}
+
+// The largest grid GridSolver can solve, the candidates of a cell are a 32 bit mask
+const MaxGridSize = 32
+
+// Returns the digits that can go in the cell without breaking the rules
+func (s *GridSolver) candidates(index int) uint32 {
+	c := s.geometry.cells[index/s.geometry.Size][index%s.geometry.Size]
+	return s.units[c.row] & s.units[s.geometry.Size+int(c.column)] & s.units[2*s.geometry.Size+int(c.box)]
+}
+
+// Puts the digit in the cell, or takes it out of the cell when it is there, updating the candidates of its units
+func (s *GridSolver) set(index int, bit uint32) {
+	c := s.geometry.cells[index/s.geometry.Size][index%s.geometry.Size]
+	s.units[c.row] ^= bit
+	s.units[s.geometry.Size+int(c.column)] ^= bit
+	s.units[2*s.geometry.Size+int(c.box)] ^= bit
+	s.cells[index] ^= bit
+}
+
+// Fills the cell at the current depth with the next candidate to try for it and moves one cell deeper
+func (s *GridSolver) place() {
+	bit := s.tried[s.depth] & -s.tried[s.depth]
+	s.tried[s.depth] ^= bit
+	s.set(s.empty[s.depth], bit)
+	s.depth++
+}
+
+// Takes the digits back out of the cells until one of them has a candidate left to try and puts it
+// in. Returns false when there is none left, the search is over then
+func (s *GridSolver) backtrack() bool {
+	for s.depth > 0 {
+		s.depth--
+		index := s.empty[s.depth]
+		s.set(index, s.cells[index])
+		if s.tried[s.depth] != 0 {
+			s.place()
+			return true
+		}
+	}
+	return false
+}
+
+// Finds the next solution. Returns false when there are no more, after true call Solution to get it
+func (s *GridSolver) Solve() bool {
+	if s.done {
+		return false
+	}
+	if s.started && !s.backtrack() {
+		s.done = true
+		return false
+	}
+	s.started = true
+	for {
+		s.iterations++
+		if s.depth == len(s.empty) {
+			s.solution = s.grid()
+			return true
+		}
+		best, fewest := -1, uint32(0)
+		for i := s.depth; i < len(s.empty); i++ {
+			c := s.candidates(s.empty[i])
+			if best == -1 || bits.OnesCount32(c) < bits.OnesCount32(fewest) {
+				best, fewest = i, c
+				if bits.OnesCount32(c) <= 1 {
+					break
+				}
+			}
+		}
+		if fewest == 0 {
+			if !s.backtrack() {
+				s.done = true
+				return false
+			}
+			continue
+		}
+		s.empty[s.depth], s.empty[best] = s.empty[best], s.empty[s.depth]
+		s.tried[s.depth] = fewest
+		s.place()
+	}
+}
+
+// Returns the cells as they are now
+func (s *GridSolver) grid() Grid {
+	g := NewGrid(s.geometry.Size)
+	for i, bit := range s.cells {
+		if bit != 0 {
+			g[i/s.geometry.Size][i%s.geometry.Size] = bits.TrailingZeros32(bit) + 1
+		}
+	}
+	return g
+}
+
+// Returns the last solution found. Call it after Solve returned true
+func (s *GridSolver) Solution() Grid {
+	if s.solution == nil {
+		panic("Solution is called before Solve returned true")
+	}
+	return s.solution
+}
+
+// Returns the number of iterations performed for statistical purposes
+func (s *GridSolver) Iterations() int {
+	return s.iterations
+}
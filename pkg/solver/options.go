@@ -0,0 +1,49 @@
+package solver
+
+import "math/rand/v2"
+
+// Controls how a solver searches, the zero value is the search NewSolver does
+type SolverOptions struct {
+	// When not 0, the search breaks the ties between the cells with the fewest candidates and picks the candidates
+	// to try in an order shuffled by the seed, instead of always the same one, so the first solution is a random one.
+	// It is not uniformly random, see Sample for that, but any solution can come first. The same seed gives the same order
+	RandomSeed uint64
+	// The number of goroutines CountSolutions and AllSolutions split the search across, 0 and 1 are the calling
+	// one only. The search is split by the candidates of a single cell, so there are at most 9 branches to
+	// share out. It pays off on the searches with many solutions, such as the near empty grids
+	Parallelism int
+}
+
+// Same as NewSolver, with the options
+func NewSolverWithOptions(puzzle [sudokuSize][sudokuSize]int, opts SolverOptions) (*Solver, error) {
+	s, err := NewSolver(puzzle)
+	if err != nil {
+		return nil, err
+	}
+	if opts.RandomSeed != 0 {
+		s.randomize(opts.RandomSeed)
+	}
+	s.parallelism = opts.Parallelism
+	return s, nil
+}
+
+// Shuffles the order the search goes in. The cell with the fewest candidates is the first of them in the
+// search space, so shuffling it shuffles the ties. The candidates are tried in the order of a random
+// permutation of the digits, which leftmostBitLookup is for the identity
+func (s *Solver) randomize(seed uint64) {
+	random := rand.New(rand.NewPCG(seed, seed))
+	random.Shuffle(s.searchSpaceSize, func(i, j int) {
+		s.cellSearchSpace[i], s.cellSearchSpace[j] = s.cellSearchSpace[j], s.cellSearchSpace[i]
+	})
+	order := random.Perm(sudokuSize)
+	next := new([1 << sudokuSize]int)
+	for mask := range next {
+		for _, digit := range order {
+			if bit := 1 << digit; mask&bit != 0 {
+				next[mask] = bit
+				break
+			}
+		}
+	}
+	s.nextCandidate = next
+}
@@ -0,0 +1,105 @@
+package solver
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// The search is split into branches, a clone of the solver for each candidate of an empty cell, each of them only
+// allowing its own candidate in the cell. Every solution has one of the candidates there, so it is found in exactly
+// one branch. Up to SolverOptions.Parallelism goroutines take the branches one at a time
+
+// Searches the branches and returns the number of solutions found, stopping them all once there are limit of them,
+// 0 is no limit, and the solutions if keep is set. The statistics of the branches are added to the solver's.
+// The search of the solver is over afterwards
+func (s *Solver) searchParallel(limit int, keep bool) (int, []Puzzle) {
+	branches := s.branches()
+	var (
+		next      atomic.Int64
+		found     atomic.Int64
+		stop      = make(chan struct{})
+		stopOnce  sync.Once
+		mu        sync.Mutex
+		solutions []Puzzle
+		wg        sync.WaitGroup
+	)
+	for range min(s.parallelism, len(branches)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1) - 1)
+				if i >= len(branches) {
+					return
+				}
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				b := branches[i]
+				b.cancel = stop
+				b.counting = !keep
+				for b.solve() {
+					n := int(found.Add(1))
+					if limit != 0 && n > limit {
+						break
+					}
+					if keep {
+						mu.Lock()
+						solutions = append(solutions, b.Solution())
+						mu.Unlock()
+					}
+					if limit != 0 && n == limit {
+						stopOnce.Do(func() { close(stop) })
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The branches start with the statistics of the solver
+	iterations, guesses, deadEnds := s.iterations, s.guesses, s.deadEnds
+	for _, b := range branches {
+		s.iterations += b.iterations - iterations
+		s.guesses += b.guesses - guesses
+		s.deadEnds += b.deadEnds - deadEnds
+		s.budgetExceeded = s.budgetExceeded || b.budgetExceeded
+	}
+	s.done = true
+	count := int(found.Load())
+	if limit != 0 {
+		count = min(count, limit)
+	}
+	return count, solutions
+}
+
+// Returns the branches of the rest of the search, split by the candidates of the empty cell with the fewest of
+// them above one. When there is no such cell a clone of the solver is the only branch, and there are none when
+// the search is over
+func (s *Solver) branches() []*Solver {
+	if s.done {
+		return nil
+	}
+	var split coordinates
+	splitCandidates := 0
+	for _, cell := range s.searchSpace()[s.currentSearchCell+1:] {
+		cc := s.variantCandidates(cell, s.globalCandidates.getCellCandidates(cell)) & s.allowed[cell.row][cell.column]
+		if n := bitCount[cc]; n > 1 && (splitCandidates == 0 || n < bitCount[splitCandidates]) {
+			split, splitCandidates = cell, cc
+		}
+	}
+	if splitCandidates == 0 {
+		return []*Solver{s.Clone()}
+	}
+	var branches []*Solver
+	for cc := splitCandidates; cc != 0; cc &= cc - 1 {
+		b := s.Clone()
+		b.allowed[split.row][split.column] = cc & -cc
+		b.restricted = true
+		branches = append(branches, b)
+	}
+	return branches
+}
@@ -0,0 +1,38 @@
+package solver
+
+import "sync"
+
+// Keeps solvers for reuse, so that solving a stream of puzzles does not allocate
+// a solver for each of them. The zero value is ready to use, and it is safe for concurrent use
+type Pool struct {
+	pool sync.Pool
+}
+
+// Returns a solver for the puzzle, reusing one from the pool if there is one.
+// Returns error when the puzzle is inconsistent, as NewSolver does
+func (p *Pool) Get(puzzle [sudokuSize][sudokuSize]int) (*Solver, error) {
+	return p.GetVariant(puzzle, 0)
+}
+
+// Same as Get, for a puzzle that follows the variant rules as well, see NewVariantSolver. Solvers
+// for all the variants share the pool
+func (p *Pool) GetVariant(puzzle [sudokuSize][sudokuSize]int, v Variant) (*Solver, error) {
+	s, _ := p.pool.Get().(*Solver)
+	if s == nil {
+		s = &Solver{}
+	}
+	if s.geometry == nil || s.geometry.variant != v {
+		s.geometry = geometryFor(layout{standardShape, v})
+	}
+	if err := s.reset(puzzle); err != nil {
+		p.pool.Put(s)
+		return nil, err
+	}
+	return s, nil
+}
+
+// Gives the solver back to the pool once the caller is done with it,
+// it must not be used after that
+func (p *Pool) Put(s *Solver) {
+	p.pool.Put(s)
+}
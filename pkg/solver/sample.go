@@ -0,0 +1,183 @@
+package solver
+
+import (
+	"math/bits"
+	"math/rand/v2"
+)
+
+// Sampling walks down from the puzzle one cell at a time, choosing each digit with a probability
+// proportional to the number of solutions it leaves. Once a subtree has fewer than sampleExactLimit
+// solutions they are all enumerated and one is picked, so small solution spaces are sampled exactly
+// uniformly. Above that the sizes of the subtrees are estimated with Knuth's random probes, which
+// makes the sampling approximately uniform: the better the estimates, the closer to uniform it is
+
+// Subtrees with fewer solutions than this are counted exactly
+const sampleExactLimit = 256
+
+// The number of random probes averaged to estimate the size of a larger subtree
+const sampleProbes = 128
+
+var samplePool Pool
+
+// Returns k solutions of the puzzle sampled approximately uniformly at random, with replacement,
+// so the same solution may come up more than once. The same random source state gives the same samples.
+// Returns error when the puzzle is inconsistent or has no solution
+func Sample(puzzle Puzzle, k int, random *rand.Rand) ([]Puzzle, error) {
+	s, err := samplePool.Get(puzzle)
+	if err != nil {
+		return nil, err
+	}
+	solvable := s.Solve()
+	samplePool.Put(s)
+	if !solvable {
+		return nil, ErrNoSolution
+	}
+	sm := sampler{random: random, nodes: map[Puzzle]*sampleNode{}}
+	samples := make([]Puzzle, k)
+	for i := range samples {
+		samples[i] = sm.sample(puzzle)
+	}
+	return samples, nil
+}
+
+// A point of the walk down, it is kept so that the samples share the work done for the top of the walk,
+// and so that the estimates there are the same for all of them
+type sampleNode struct {
+	solutions []Puzzle  // all the solutions, when there are fewer than sampleExactLimit
+	weights   []float64 // otherwise the sizes of the subtrees of the digits of the cell with the fewest candidates
+}
+
+type sampler struct {
+	random *rand.Rand
+	nodes  map[Puzzle]*sampleNode
+}
+
+// Walks down from a solvable puzzle to a single solution
+func (sm *sampler) sample(puzzle Puzzle) Puzzle {
+	for {
+		node := sm.nodes[puzzle]
+		if node == nil {
+			node = &sampleNode{solutions: enumerateUpTo(puzzle, sampleExactLimit)}
+			sm.nodes[puzzle] = node
+		}
+		if node.weights == nil && len(node.solutions) < sampleExactLimit {
+			return node.solutions[sm.random.IntN(len(node.solutions))]
+		}
+		y, x, candidates := fewestCandidates(&puzzle)
+		digits := digitsOf(candidates)
+		if node.weights == nil {
+			// There are too many solutions to pick from, the weights take their place
+			node.solutions = nil
+			for _, digit := range digits {
+				child := puzzle
+				child[y][x] = digit
+				node.weights = append(node.weights, sm.subtreeSize(child))
+			}
+		}
+		total := 0.0
+		for _, w := range node.weights {
+			total += w
+		}
+		pick := sm.random.Float64() * total
+		chosen := len(digits) - 1
+		for i, w := range node.weights {
+			if pick < w {
+				chosen = i
+				break
+			}
+			pick -= w
+		}
+		puzzle[y][x] = digits[chosen]
+	}
+}
+
+// Returns the number of solutions of the puzzle, exact when it is below sampleExactLimit
+// and estimated otherwise. A small subtree is kept with its solutions, the walk is likely to get there
+func (sm *sampler) subtreeSize(puzzle Puzzle) float64 {
+	if solutions := enumerateUpTo(puzzle, sampleExactLimit); len(solutions) < sampleExactLimit {
+		sm.nodes[puzzle] = &sampleNode{solutions: solutions}
+		return float64(len(solutions))
+	}
+	estimate := 0.0
+	for range sampleProbes {
+		estimate += probe(puzzle, sm.random) / sampleProbes
+	}
+	// The subtree has at least that many, whatever the probes say
+	return max(estimate, sampleExactLimit)
+}
+
+// Knuth's estimator: follows a random path down, always through the cell with the fewest candidates,
+// and returns the product of the numbers of choices on the way, or 0 if the path runs into a dead end.
+// Its expected value is the number of solutions
+func probe(puzzle Puzzle, random *rand.Rand) float64 {
+	product := 1.0
+	for {
+		y, x, candidates := fewestCandidates(&puzzle)
+		if y < 0 {
+			return product
+		}
+		n := bits.OnesCount16(candidates)
+		if n == 0 {
+			return 0
+		}
+		product *= float64(n)
+		puzzle[y][x] = digitsOf(candidates)[random.IntN(n)]
+	}
+}
+
+// Returns up to limit solutions of the puzzle
+func enumerateUpTo(puzzle Puzzle, limit int) []Puzzle {
+	s, err := samplePool.Get(puzzle)
+	if err != nil {
+		return nil
+	}
+	defer samplePool.Put(s)
+	var solutions []Puzzle
+	for len(solutions) < limit && s.Solve() {
+		solutions = append(solutions, s.Solution())
+	}
+	return solutions
+}
+
+// Returns the empty cell with the fewest candidates and its candidates as a mask with bit d-1 set
+// for digit d, y is -1 when there are no empty cells. The puzzle is passed by pointer to save a copy
+func fewestCandidates(puzzle *Puzzle) (int, int, uint16) {
+	var rows, columns, boxes [sudokuSize]uint16
+	for y := range sudokuSize {
+		for x := range sudokuSize {
+			if digit := puzzle[y][x]; digit != 0 {
+				bit := uint16(1) << (digit - 1)
+				rows[y] |= bit
+				columns[x] |= bit
+				boxes[y/3*3+x/3] |= bit
+			}
+		}
+	}
+	bestY, bestX, best, bestCount := -1, -1, uint16(0), sudokuSize+1
+	for y := range sudokuSize {
+		for x := range sudokuSize {
+			if puzzle[y][x] != 0 {
+				continue
+			}
+			candidates := initialCandidatesMask &^ (rows[y] | columns[x] | boxes[y/3*3+x/3])
+			if n := bits.OnesCount16(candidates); n < bestCount {
+				bestY, bestX, best, bestCount = y, x, candidates, n
+				if n == 0 {
+					return bestY, bestX, best
+				}
+			}
+		}
+	}
+	return bestY, bestX, best
+}
+
+// Returns the digits of a candidates mask in increasing order
+func digitsOf(candidates uint16) []int {
+	var digits []int
+	for candidates != 0 {
+		bit := bits.TrailingZeros16(candidates)
+		digits = append(digits, bit+1)
+		candidates &^= 1 << bit
+	}
+	return digits
+}
@@ -0,0 +1,232 @@
+package solver
+
+import "math/bits"
+
+// The side of the square the five grids of a samurai puzzle fit in
+const SamuraiSize = 21
+
+// The row and the column of the top left cell of each of the five grids of a samurai puzzle: the four
+// corner ones and the one in the middle, which shares its corner boxes with them
+var SamuraiGrids = [5][2]int{{0, 0}, {0, 12}, {6, 6}, {12, 0}, {12, 12}}
+
+// The number of cells of a samurai puzzle, the four boxes the grids share are only counted once
+const SamuraiCells = 5*sudokuSize*sudokuSize - 4*sudokuSize
+
+// A samurai puzzle or a solution of one, five overlapping 9x9 grids laid out in a square. The cells
+// of the square outside of the grids are 0, as the empty cells are
+type Samurai [SamuraiSize][SamuraiSize]int
+
+// Reports whether the cell of the square, both 0-based, is in one of the grids of a samurai puzzle
+func InSamurai(row, column int) bool {
+	for _, g := range SamuraiGrids {
+		if row >= g[0] && row < g[0]+sudokuSize && column >= g[1] && column < g[1]+sudokuSize {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the samurai puzzle with the cells, SamuraiCells of them, in the order they are in the
+// square, row by row, leaving out the cells outside of the grids. Returns ErrInvalidPuzzle when there
+// are not as many cells
+func SamuraiOf(cells []int) (Samurai, error) {
+	var s Samurai
+	if len(cells) != SamuraiCells {
+		return s, ErrInvalidPuzzle
+	}
+	i := 0
+	for y := range SamuraiSize {
+		for x := range SamuraiSize {
+			if InSamurai(y, x) {
+				s[y][x] = cells[i]
+				i++
+			}
+		}
+	}
+	return s, nil
+}
+
+// Returns the grid at the index of SamuraiGrids as a classic puzzle
+func (s *Samurai) Grid(i int) (grid [sudokuSize][sudokuSize]int) {
+	top, left := SamuraiGrids[i][0], SamuraiGrids[i][1]
+	for y := range sudokuSize {
+		copy(grid[y][:], s[top+y][left:left+sudokuSize])
+	}
+	return
+}
+
+// Solves samurai puzzles with the same search as GridSolver. The five grids are solved together: every
+// grid has units of its own, rows, columns and boxes, but the boxes they share are single units, and
+// the cells of those boxes are in the rows and the columns of both grids. So a digit put in one of
+// them takes the candidates of both grids at once
+type SamuraiSolver struct {
+	positions  [][2]int // the row and the column in the square of each cell
+	cellUnits  [][]int  // the units each cell is in, 3 of them, or 5 for the cells of the shared boxes
+	cells      []uint16 // the digit of each cell as a bit, 0 for an empty cell
+	units      []uint16 // the candidates left in each unit
+	empty      []int    // the indexes of the empty cells, the ones the search has filled first
+	tried      []uint16 // the candidates still to try for each cell the search has filled, by depth
+	depth      int      // how many of the empty cells the search has filled
+	solution   *Samurai // the last solution found
+	started    bool     // Solve has been called
+	done       bool     // the search is over
+	iterations int      // for statistics purposes
+}
+
+// Creates a solver for the samurai puzzle. Returns ErrInvalidPuzzle when a cell outside of the grids is
+// not 0, a digit is not 1 to 9, or the givens break the rules
+func NewSamuraiSolver(puzzle Samurai) (*SamuraiSolver, error) {
+	s := &SamuraiSolver{}
+	// The units of the rows and the columns are by grid, the ones of the boxes by their place in the
+	// square, so that the grids that share a box get the same unit for it
+	boxUnits := map[[2]int]int{}
+	unitCount := len(SamuraiGrids) * 2 * sudokuSize
+	for y := range SamuraiSize {
+		for x := range SamuraiSize {
+			if !InSamurai(y, x) {
+				if puzzle[y][x] != 0 {
+					return nil, ErrInvalidPuzzle
+				}
+				continue
+			}
+			box := [2]int{y / 3, x / 3}
+			if _, ok := boxUnits[box]; !ok {
+				boxUnits[box] = unitCount
+				unitCount++
+			}
+			units := []int{boxUnits[box]}
+			for i, g := range SamuraiGrids {
+				if y >= g[0] && y < g[0]+sudokuSize && x >= g[1] && x < g[1]+sudokuSize {
+					units = append(units, i*2*sudokuSize+y-g[0], i*2*sudokuSize+sudokuSize+x-g[1])
+				}
+			}
+			s.positions = append(s.positions, [2]int{y, x})
+			s.cellUnits = append(s.cellUnits, units)
+		}
+	}
+	s.cells = make([]uint16, len(s.positions))
+	s.units = make([]uint16, unitCount)
+	for i := range s.units {
+		s.units[i] = initialCandidatesMask
+	}
+	for i, p := range s.positions {
+		digit := puzzle[p[0]][p[1]]
+		if digit < 0 || digit > sudokuSize {
+			return nil, ErrInvalidPuzzle
+		}
+		if digit == 0 {
+			s.empty = append(s.empty, i)
+			continue
+		}
+		bit := uint16(1) << (digit - 1)
+		if s.candidates(i)&bit == 0 {
+			return nil, ErrInvalidPuzzle
+		}
+		s.set(i, bit)
+	}
+	s.tried = make([]uint16, len(s.empty))
+	return s, nil
+}
+
+// Returns the digits that can go in the cell without breaking the rules of any of its grids
+func (s *SamuraiSolver) candidates(index int) uint16 {
+	c := uint16(initialCandidatesMask)
+	for _, u := range s.cellUnits[index] {
+		c &= s.units[u]
+	}
+	return c
+}
+
+// Puts the digit in the cell, or takes it out of the cell when it is there, updating the candidates of its units
+func (s *SamuraiSolver) set(index int, bit uint16) {
+	for _, u := range s.cellUnits[index] {
+		s.units[u] ^= bit
+	}
+	s.cells[index] ^= bit
+}
+
+// Fills the cell at the current depth with the next candidate to try for it and moves one cell deeper
+func (s *SamuraiSolver) place() {
+	bit := s.tried[s.depth] & -s.tried[s.depth]
+	s.tried[s.depth] ^= bit
+	s.set(s.empty[s.depth], bit)
+	s.depth++
+}
+
+// Takes the digits back out of the cells until one of them has a candidate left to try and puts it
+// in. Returns false when there is none left, the search is over then
+func (s *SamuraiSolver) backtrack() bool {
+	for s.depth > 0 {
+		s.depth--
+		index := s.empty[s.depth]
+		s.set(index, s.cells[index])
+		if s.tried[s.depth] != 0 {
+			s.place()
+			return true
+		}
+	}
+	return false
+}
+
+// Finds the next solution. Returns false when there are no more, after true call Solution to get it
+func (s *SamuraiSolver) Solve() bool {
+	if s.done {
+		return false
+	}
+	if s.started && !s.backtrack() {
+		s.done = true
+		return false
+	}
+	s.started = true
+	for {
+		s.iterations++
+		if s.depth == len(s.empty) {
+			solution := s.grid()
+			s.solution = &solution
+			return true
+		}
+		best, fewest := -1, uint16(0)
+		for i := s.depth; i < len(s.empty); i++ {
+			c := s.candidates(s.empty[i])
+			if best == -1 || bits.OnesCount16(c) < bits.OnesCount16(fewest) {
+				best, fewest = i, c
+				if bits.OnesCount16(c) <= 1 {
+					break
+				}
+			}
+		}
+		if fewest == 0 {
+			if !s.backtrack() {
+				s.done = true
+				return false
+			}
+			continue
+		}
+		s.empty[s.depth], s.empty[best] = s.empty[best], s.empty[s.depth]
+		s.tried[s.depth] = fewest
+		s.place()
+	}
+}
+
+// Returns the cells as they are now
+func (s *SamuraiSolver) grid() (result Samurai) {
+	for i, bit := range s.cells {
+		if bit != 0 {
+			result[s.positions[i][0]][s.positions[i][1]] = bitToNumber[bit]
+		}
+	}
+	return
+}
+
+// Returns the last solution found. Call it after Solve returned true
+func (s *SamuraiSolver) Solution() Samurai {
+	if s.solution == nil {
+		panic("Solution is called before Solve returned true")
+	}
+	return *s.solution
+}
+
+// Returns the number of iterations performed for statistical purposes
+func (s *SamuraiSolver) Iterations() int {
+	return s.iterations
+}
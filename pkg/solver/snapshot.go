@@ -0,0 +1,64 @@
+package solver
+
+// The search publishes a snapshot of itself every this many iterations once somebody asks for one,
+// and at the end of every Solve call. Building one takes about as long as a few hundred iterations
+const snapshotInterval = 4096
+
+// The state of a search at a point in time
+type Snapshot struct {
+	Grid       [sudokuSize][sudokuSize]int    // the givens and the digits the search has put in so far
+	Candidates [sudokuSize][sudokuSize]uint16 // of the empty cells, bit d-1 set for digit d
+	Iterations int
+	Progress   float64 // the estimated part of the search tree covered, from 0 to 1
+	Done       bool    // the search is over, there are no more solutions
+}
+
+// Returns the latest snapshot of the search. It can be called from another goroutine while Solve
+// runs, and never blocks or slows the search down beyond building a snapshot every few thousand
+// iterations. Snapshots are only built after the first call asks for them, that call and the ones
+// before the next snapshot is ready return false. A reset solver, e.g. one taken from a Pool, starts
+// over, and it must not be reset while its snapshots are read
+func (s *Solver) Snapshot() (Snapshot, bool) {
+	s.watched.Store(true)
+	snapshot := s.snapshot.Load()
+	if snapshot == nil {
+		return Snapshot{}, false
+	}
+	return *snapshot, true
+}
+
+// Builds a snapshot of the search as it is now and makes it the one Snapshot returns
+func (s *Solver) publish() {
+	snapshot := &Snapshot{Grid: s.grid(), Iterations: s.iterations, Done: s.done}
+	for _, cell := range s.searchSpace()[s.currentSearchCell+1:] {
+		snapshot.Candidates[cell.row][cell.column] = uint16(s.variantCandidates(cell, s.globalCandidates.getCellCandidates(cell)) & s.allowed[cell.row][cell.column])
+	}
+	if s.done {
+		snapshot.Progress = 1
+	} else {
+		snapshot.Progress = s.progress()
+	}
+	s.snapshot.Store(snapshot)
+}
+
+// Estimates the part of the search tree covered as the sum over the cells filled in, from the first
+// one, of the share of the subtree under it taken by the candidates already tried in it. Subtrees are
+// taken to be the same size, which they are not, so progress is uneven, but it never goes back
+func (s *Solver) progress() float64 {
+	// The candidates each cell had when it was picked, found by taking the digits back out from the last one
+	var total [sudokuSize * sudokuSize]int
+	global := s.globalCandidates
+	for i := s.currentSearchCell; i >= 0; i-- {
+		cell := s.cellSearchSpace[i]
+		global.flipBit(cell, s.cells[cell.row][cell.column])
+		total[i] = bitCount[global.getCellCandidates(cell)&s.allowed[cell.row][cell.column]]
+	}
+	progress, share := 0.0, 1.0
+	for i := 0; i <= s.currentSearchCell && total[i] != 0; i++ {
+		cell := s.cellSearchSpace[i]
+		tried := total[i] - bitCount[s.cellCandidates[cell.row][cell.column]] - 1
+		share /= float64(total[i])
+		progress += float64(tried) * share
+	}
+	return progress
+}
@@ -0,0 +1,43 @@
+package solver
+
+// A cell of a solution: its digit and whether the digit was given or filled in by the solver
+type Cell struct {
+	Digit int
+	Given bool
+}
+
+// A solution that remembers which of its digits were givens, so that the outputs can show
+// the difference without having the puzzle at hand
+type Solution [sudokuSize][sudokuSize]Cell
+
+// Pairs a solution with the puzzle it solves, the digits present in the puzzle are the givens
+func NewSolution(solution, puzzle [sudokuSize][sudokuSize]int) (result Solution) {
+	for y := range sudokuSize {
+		for x := range sudokuSize {
+			result[y][x] = Cell{Digit: solution[y][x], Given: puzzle[y][x] != 0}
+		}
+	}
+	return
+}
+
+// Returns the digits of all the cells
+func (s *Solution) Digits() (result [sudokuSize][sudokuSize]int) {
+	for y := range sudokuSize {
+		for x := range sudokuSize {
+			result[y][x] = s[y][x].Digit
+		}
+	}
+	return
+}
+
+// Returns the givens only, which is the puzzle the solution is for
+func (s *Solution) Givens() (result [sudokuSize][sudokuSize]int) {
+	for y := range sudokuSize {
+		for x := range sudokuSize {
+			if s[y][x].Given {
+				result[y][x] = s[y][x].Digit
+			}
+		}
+	}
+	return
+}
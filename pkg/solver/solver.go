@@ -1,7 +1,11 @@
 package solver
 
 import (
-	"fmt"
+	"context"
+	"iter"
+	"log/slog"
+	"math/bits"
+	"sync/atomic"
 )
 
 // Algorithm outline: find the cell with fewest candidates. Put one of the candidates in the cell.
@@ -32,19 +36,6 @@ import (
 
 const sudokuSize = 9
 
-// Used to determine which box a particular cell identified by its row and column belongs to
-var boxLookup = [sudokuSize][sudokuSize]int{
-	{0, 0, 0, 1, 1, 1, 2, 2, 2},
-	{0, 0, 0, 1, 1, 1, 2, 2, 2},
-	{0, 0, 0, 1, 1, 1, 2, 2, 2},
-	{3, 3, 3, 4, 4, 4, 5, 5, 5},
-	{3, 3, 3, 4, 4, 4, 5, 5, 5},
-	{3, 3, 3, 4, 4, 4, 5, 5, 5},
-	{6, 6, 6, 7, 7, 7, 8, 8, 8},
-	{6, 6, 6, 7, 7, 7, 8, 8, 8},
-	{6, 6, 6, 7, 7, 7, 8, 8, 8},
-}
-
 // A candidate for a cell, row, column or box is any number
 // that can go into that cell, row, column or box without conflicting with
 // other numbers that are already in the grid according to sudoku rules.
@@ -54,7 +45,8 @@ var boxLookup = [sudokuSize][sudokuSize]int{
 // only the right 9 bits are used of each int
 // 0b100000100 means that 9 and 3 are possible candidates
 // and the remaining numbers are eliminated because
-// they are already present in this row, column or box
+// they are already present in this row, column or box.
+// The units the variants add have masks of their own, see Solver.unitCandidates
 type candidates struct {
 	row    [sudokuSize]int
 	column [sudokuSize]int
@@ -65,57 +57,95 @@ type candidates struct {
 const initialCandidatesMask = 0b111111111
 
 // This method is called when a number is added or remove to/from a cell in the solution
-// x and y represent column and row respectively
 // bit represents the number, e.g for 9 it will be 0b100000000 = 256,
 // for 3 it will be 0b000000100 = 4, etc
-func (c *candidates) flipBit(x, y, bit int) {
-	c.row[y] ^= bit
-	c.column[x] ^= bit
-	c.box[boxLookup[y][x]] ^= bit
+func (c *candidates) flipBit(cell coordinates, bit int) {
+	c.row[cell.row] ^= bit
+	c.column[cell.column] ^= bit
+	c.box[cell.box] ^= bit
 }
 
 // This is a version of flipBit which is called during the puzzle initialization.
 // Since all candidate bits start as '1' if after the flip the candidate is not
 // zero, it means that the passed number already appeared in the row, column or box
 // and hence the input is invalid
-func (c *candidates) flipBitWithCheck(x, y, bit int) bool {
-	c.flipBit(x, y, bit)
-	return (c.row[y]&bit == 0) && (c.column[x]&bit == 0) && (c.box[boxLookup[y][x]]&bit == 0)
+func (c *candidates) flipBitWithCheck(cell coordinates, bit int) bool {
+	c.flipBit(cell, bit)
+	return (c.row[cell.row]&bit == 0) && (c.column[cell.column]&bit == 0) && (c.box[cell.box]&bit == 0)
 }
 
 // For a given cell return all possible candidates, intersecting
-// row, column and box candidates
-func (c *candidates) getCellCandidates(x, y int) int {
-	return c.row[y] & c.column[x] & c.box[boxLookup[y][x]]
+// row, column and box candidates. The cells of the variants have more to
+// intersect, see Solver.variantCandidates
+func (c *candidates) getCellCandidates(cell coordinates) int {
+	return c.row[cell.row] & c.column[cell.column] & c.box[cell.box]
 }
 
-// This represents a cell position in the sudoku grid
+// This represents a cell position in the sudoku grid, along with the box it is in,
+// so that the box does not have to be looked up every time the cell's candidates are
 type coordinates struct {
 	row    int
 	column int
+	box    int
 }
 
-// Integers in 'cells' and 'cellCandidates' are bit fields
+// Elements of 'cells' and 'cellCandidates' are bit fields
 // 'cells' elements always have a single bit set - corresponding to the number in the cell
 // or none if the cell is empty
 // 'cellCandidates' will have as many bits set as there are candidates remaining to try
 // In both case only nine right bits are used
 type Solver struct {
-	globalCandidates  candidates                  // candidates for each row, column and box
-	cells             [sudokuSize][sudokuSize]int // sudoku cells, empty cells are zeroes
-	cellSearchSpace   []coordinates               // list of empty cells that we are trying to fill to find solutions
-	currentSearchCell int                         // the index of the current cell in the cellSearchSpace
-	cellCandidates    [sudokuSize][sudokuSize]int // candidates for each cell to still try
-	lastSolution      [sudokuSize][sudokuSize]int // copy of .cells as of last found solution
-	done              bool                        // indicator that the solver has finished
-	haveSolution      bool                        // indicator the .lastSolution contains a solution
-	iterations        int                         // current iteration number for statistics purposes
+	geometry          *geometry                            // lookup tables for the grid shape
+	globalCandidates  candidates                           // candidates for each row, column and box
+	unitCandidates    [maxExtraUnits]int                   // candidates for each variant unit, numbered the way geometry.cellUnits numbers them
+	cells             [sudokuSize][sudokuSize]int          // sudoku cells, empty cells are zeroes
+	cellSearchSpace   [sudokuSize * sudokuSize]coordinates // list of empty cells that we are trying to fill to find solutions, the first searchSpaceSize of it
+	searchSpaceSize   int                                  // the number of empty cells in the puzzle
+	currentSearchCell int                                  // the index of the current cell in the cellSearchSpace
+	cellCandidates    [sudokuSize][sudokuSize]int          // candidates for each cell to still try
+	nextCandidate     *[1 << sudokuSize]int                // picks the candidate to try next out of the ones left, leftmostBitLookup unless randomized
+	allowed           [sudokuSize][sudokuSize]int          // candidates each cell may take at all, all of them unless restricted
+	restricted        bool                                 // indicator that .allowed is not unrestricted, see plain
+	lastSolution      [sudokuSize][sudokuSize]int          // copy of .cells as of last found solution
+	done              bool                                 // indicator that the solver has finished
+	haveSolution      bool                                 // indicator the .lastSolution contains a solution
+	counting          bool                                 // solutions are only counted, .lastSolution is left alone, see CountSolutions
+	parallelism       int                                  // the goroutines CountSolutions and AllSolutions search on, see SolverOptions
+	iterations        int                                  // current iteration number for statistics purposes
+	guesses           int                                  // cells filled in while they had more than one candidate, for statistics purposes
+	deadEnds          int                                  // times the search ran into a cell with no candidates left, for statistics purposes
+	maxIterations     int                                  // the search gives up after that many iterations, 0 is no limit
+	solveBudget       int                                  // each Solve call gives up after that many iterations of its own, 0 is no limit
+	budgetExceeded    bool                                 // indicator that the search gave up because of maxIterations or solveBudget
+	observer          Observer                             // called after every iteration if set
+	tracer            Tracer                               // called for every step of the search if set
+	logger            *slog.Logger                         // gets the outcome of every Solve if set
+	watched           atomic.Bool                          // somebody wants snapshots of the search
+	snapshot          atomic.Pointer[Snapshot]             // the latest one
+	constraints       []Constraint                         // the rules on top of the classic ones and the variant units, see AddConstraint
+	cancel            <-chan struct{}                      // the search stops when it is closed, only during SolveContext
 }
 
 // Flips the candidate bits for the current search cell, adding or removing the number in the current search cell to/from
 // the candidate lists
 func (s *Solver) flip() {
-	s.globalCandidates.flipBit(s.cellSearchSpace[s.currentSearchCell].column, s.cellSearchSpace[s.currentSearchCell].row, s.getCurrentCell())
+	cell, bit := s.cellSearchSpace[s.currentSearchCell], s.getCurrentCell()
+	s.globalCandidates.flipBit(cell, bit)
+	if s.geometry.cellUnits != nil {
+		s.flipUnits(cell, bit)
+	}
+}
+
+// Same as candidates.flipBit for the variant units the cell is in. Returns false if the digit was
+// already in one of them, which only matters while the puzzle is set up, as in flipBitWithCheck
+func (s *Solver) flipUnits(cell coordinates, bit int) bool {
+	ok := true
+	for units := s.geometry.cellUnits[cell.row][cell.column]; units != 0; units &= units - 1 {
+		unit := bits.TrailingZeros(uint(units))
+		s.unitCandidates[unit] ^= bit
+		ok = ok && s.unitCandidates[unit]&bit == 0
+	}
+	return ok
 }
 
 // Returns the remaining to try candidates for the current cell
@@ -158,6 +188,9 @@ var leftmostBitLookup [1 << sudokuSize]int
 // This lookup is used to get the number of remaining candidates from a candidates bit mask
 var bitCount [1 << sudokuSize]int
 
+// This is the initial .allowed value, every cell may take every candidate
+var unrestricted [sudokuSize][sudokuSize]int
+
 // This is the initial .globalCandidates value (all rows, cells and boxes have all possible candidates) used in initialization of the Solver object
 var initialCandidates candidates
 
@@ -179,30 +212,125 @@ func init() {
 		initialCandidates.column[i] = initialCandidatesMask
 		initialCandidates.box[i] = initialCandidatesMask
 	}
+	// Populate unrestricted above
+	for y := range unrestricted {
+		for x := range unrestricted[y] {
+			unrestricted[y][x] = initialCandidatesMask
+		}
+	}
 }
 
 // Create a new solver from 9x9 integer array of sudoku input
 // Returns error when the input array is inconsistent (same number in a row, column or box)
 func NewSolver(s [sudokuSize][sudokuSize]int) (*Solver, error) {
-	sudoku := Solver{globalCandidates: initialCandidates, currentSearchCell: -1}
-	for y, row := range sudoku.cells {
+	sudoku := &Solver{}
+	if err := sudoku.reset(s); err != nil {
+		return nil, err
+	}
+	return sudoku, nil
+}
+
+// Same as NewSolver, but each empty cell may only take the candidates given for it, as if the others
+// had been eliminated by hand. The candidates are bit masks with bit d-1 set for digit d, the masks
+// of the givens are ignored. This is how a position in the middle of a solve can be analyzed exactly
+// as the solver has it, pencil marks and all
+func NewRestrictedSolver(puzzle [sudokuSize][sudokuSize]int, candidates [sudokuSize][sudokuSize]uint16) (*Solver, error) {
+	s, err := NewSolver(puzzle)
+	if err != nil {
+		return nil, err
+	}
+	for y := range sudokuSize {
+		for x := range sudokuSize {
+			if puzzle[y][x] == 0 {
+				s.allowed[y][x] = int(candidates[y][x]) & initialCandidatesMask
+			}
+		}
+	}
+	s.restricted = true
+	return s, nil
+}
+
+// Sets the solver up for another puzzle, as NewSolver does, but reuses its memory, so that a single solver
+// can go through many puzzles without allocating. The settings stay: the iteration limits, the observer,
+// the tracer, the logger and the options, RandomSeed only for the order of the candidates. The restrictions
+// and the constraints added with AddConstraint go, the variant rules stay. Returns error when the puzzle is
+// inconsistent, the solver has to be reset again before it is used then
+func (s *Solver) Reset(puzzle [sudokuSize][sudokuSize]int) error {
+	maxIterations, solveBudget, parallelism := s.maxIterations, s.solveBudget, s.parallelism
+	observer, tracer, logger, nextCandidate := s.observer, s.tracer, s.logger, s.nextCandidate
+	err := s.reset(puzzle)
+	s.maxIterations, s.solveBudget, s.parallelism = maxIterations, solveBudget, parallelism
+	s.observer, s.tracer, s.logger = observer, tracer, logger
+	if nextCandidate != nil {
+		s.nextCandidate = nextCandidate
+	}
+	return err
+}
+
+// Initializes the solver for a new puzzle. The search space is an array with room for the empty grid,
+// so neither a new solver nor a reused one allocates for it
+func (s *Solver) reset(puzzle [sudokuSize][sudokuSize]int) error {
+	geometry := s.geometry
+	if geometry == nil {
+		geometry = geometryFor(layout{Shape: standardShape})
+	}
+	*s = Solver{geometry: geometry, globalCandidates: initialCandidates, allowed: unrestricted, currentSearchCell: -1, constraints: geometry.constraints, nextCandidate: &leftmostBitLookup}
+	if geometry.cellUnits != nil {
+		for i := range s.unitCandidates {
+			s.unitCandidates[i] = initialCandidatesMask
+		}
+	}
+	for y, row := range s.cells {
 		for x := range row {
-			digit := s[y][x]
+			digit := puzzle[y][x]
+			cell := geometry.cells[y][x]
+			bit := 0
+			if digit > sudokuSize {
+				return ErrInvalidPuzzle
+			}
 			if digit > 0 {
-				digit = 1 << (digit - 1)
+				bit = 1 << (digit - 1)
 				// Adjust candidates table to account for this non-empty cell
-				if !sudoku.globalCandidates.flipBitWithCheck(x, y, digit) {
-					return nil, fmt.Errorf("invalid (inconsistent) puzzle input")
+				if !s.globalCandidates.flipBitWithCheck(cell, bit) {
+					return ErrInvalidPuzzle
+				}
+				if geometry.cellUnits != nil && !s.flipUnits(cell, bit) {
+					return ErrInvalidPuzzle
 				}
 			} else {
 				// Add this empty cell into the search space
-				sudoku.cellSearchSpace = append(sudoku.cellSearchSpace, coordinates{y, x})
+				s.cellSearchSpace[s.searchSpaceSize] = cell
+				s.searchSpaceSize++
 			}
 			// put the cell in the grid
-			sudoku.cells[y][x] = digit
+			s.cells[y][x] = bit
+		}
+	}
+	if !s.keepsConstraints() {
+		return ErrInvalidPuzzle
+	}
+	return nil
+}
+
+// Returns the empty cells of the puzzle, the ones filled so far first, in the order they were filled
+func (s *Solver) searchSpace() []coordinates {
+	return s.cellSearchSpace[:s.searchSpaceSize]
+}
+
+// Returns the candidates of the cell, the ones of its row, column and box given in cc, that the
+// variant rules leave it
+func (s *Solver) variantCandidates(cell coordinates, cc int) int {
+	if s.geometry.cellUnits != nil {
+		for units := s.geometry.cellUnits[cell.row][cell.column]; units != 0; units &= units - 1 {
+			cc &= s.unitCandidates[bits.TrailingZeros(uint(units))]
 		}
 	}
-	return &sudoku, nil
+	// Unlike the rules of the units, the constraints have no masks kept up to date, they read the
+	// digits of the cells every time
+	for _, c := range s.constraints {
+		cc &^= c.RuledOut(&s.cells, int(cell.row), int(cell.column))
+	}
+	return cc
 }
 
 // Call this after a prior call to .Solve() returned true
@@ -218,11 +346,166 @@ func (s *Solver) Solution() (result [sudokuSize][sudokuSize]int) {
 	return
 }
 
+// Same as .Solution(), but each cell also tells whether it was given or filled in by the solver
+func (s *Solver) AnnotatedSolution() (result Solution) {
+	if !s.haveSolution {
+		panic("AnnotatedSolution is called before Solve returned true")
+	}
+	for y, row := range s.lastSolution {
+		for x := range row {
+			result[y][x] = Cell{Digit: bitToNumber[s.lastSolution[y][x]], Given: true}
+		}
+	}
+	// The search space holds every cell that was empty in the puzzle
+	for _, cell := range s.searchSpace() {
+		result[cell.row][cell.column].Given = false
+	}
+	return
+}
+
+// Same as .Solve() followed by .Solution(), but writes the solution straight to dst,
+// leaving it as it was if there are no more solutions
+func (s *Solver) SolveInto(dst *[sudokuSize][sudokuSize]int) bool {
+	if !s.Solve() {
+		return false
+	}
+	for y, row := range s.lastSolution {
+		for x, v := range row {
+			dst[y][x] = bitToNumber[v]
+		}
+	}
+	return true
+}
+
+// Returns the solutions the search finds from here on, to range over instead of calling .Solve()
+// and .Solution(). Breaking out of the loop leaves the search where it was, ranging again goes on
+// from there
+func (s *Solver) Solutions() iter.Seq[[sudokuSize][sudokuSize]int] {
+	return func(yield func([sudokuSize][sudokuSize]int) bool) {
+		var solution [sudokuSize][sudokuSize]int
+		for s.SolveInto(&solution) {
+			if !yield(solution) {
+				return
+			}
+		}
+	}
+}
+
+// Counts the solutions the search finds from here on, but stops as soon as there are more than max,
+// so the count is at most max+1: a puzzle is unique when CountSolutions(1) is 1. 0 is no limit.
+// The solutions are not copied anywhere, so .Solution() keeps returning the one the last .Solve() found.
+// With SolverOptions.Parallelism the search is split across goroutines, and it is over afterwards
+func (s *Solver) CountSolutions(max int) int {
+	if s.parallelism > 1 {
+		limit := max
+		if max != 0 {
+			limit = max + 1
+		}
+		count, _ := s.searchParallel(limit, false)
+		return count
+	}
+	s.counting = true
+	defer func() { s.counting = false }()
+	count := 0
+	for (max == 0 || count <= max) && s.Solve() {
+		count++
+	}
+	return count
+}
+
+// Returns the solutions the search finds from here on, up to max, 0 is no limit. With SolverOptions.Parallelism
+// the search is split across goroutines, the solutions come in no particular order then, and the search
+// is over afterwards
+func (s *Solver) AllSolutions(max int) []Puzzle {
+	if s.parallelism > 1 {
+		_, solutions := s.searchParallel(max, true)
+		return solutions
+	}
+	var solutions []Puzzle
+	for solution := range s.Solutions() {
+		solutions = append(solutions, solution)
+		if len(solutions) == max {
+			break
+		}
+	}
+	return solutions
+}
+
 // Returns the number of iterations performed for statistical purposes
 func (s *Solver) Iterations() int {
 	return s.iterations
 }
 
+// Returns the number of times the search had to pick one of several candidates for a cell
+func (s *Solver) Guesses() int {
+	return s.guesses
+}
+
+// Returns the number of times the search ran out of candidates and had to backtrack
+func (s *Solver) DeadEnds() int {
+	return s.deadEnds
+}
+
+// Makes the search give up after n iterations in all, Solve then returns false and BudgetExceeded
+// true. 0 is no limit, which is the default
+func (s *Solver) SetMaxIterations(n int) {
+	s.maxIterations = n
+}
+
+// Makes each Solve call give up after n iterations of its own, Solve then returns false and BudgetExceeded
+// true. Unlike SetMaxIterations this does not end the search, the next call goes on where the last one
+// gave up with a budget of its own. 0 is no limit, which is the default
+func (s *Solver) SetSolveBudget(n int) {
+	s.solveBudget = n
+}
+
+// Returns whether the search gave up because of SetMaxIterations, or the last Solve call because of
+// SetSolveBudget, there may be more solutions
+func (s *Solver) BudgetExceeded() bool {
+	return s.budgetExceeded
+}
+
+// Gets the grid as the search has it: the givens and the digits it has tried so far
+type Observer func(grid [sudokuSize][sudokuSize]int)
+
+// Makes the search call f after every iteration, so that it can be shown how the search goes. It slows
+// the search down
+func (s *Solver) Observe(f Observer) {
+	s.observer = f
+}
+
+// Makes Solve log every solution it finds, at the debug level, and the end of the search with its
+// statistics, at the info level. Logging is off by default, nil turns it off again. Solvers from a
+// Pool start without a logger
+func (s *Solver) SetLogger(l *slog.Logger) {
+	s.logger = l
+}
+
+// Logs the outcome of a Solve call, the last solution can be found on the iteration that ends the search
+func (s *Solver) logSolve(found bool) {
+	iterations, guesses, deadEnds := slog.Int("iterations", s.iterations), slog.Int("guesses", s.guesses), slog.Int("deadEnds", s.deadEnds)
+	if found {
+		s.logger.LogAttrs(context.Background(), slog.LevelDebug, "solution found", iterations, guesses, deadEnds)
+	}
+	if s.done {
+		s.logger.LogAttrs(context.Background(), slog.LevelInfo, "search finished", iterations, guesses, deadEnds, slog.Bool("budgetExceeded", s.budgetExceeded))
+	}
+}
+
+// Returns the givens and the digits the search has put in the cells so far
+func (s *Solver) grid() (result [sudokuSize][sudokuSize]int) {
+	for y, row := range s.cells {
+		for x, v := range row {
+			result[y][x] = bitToNumber[v]
+		}
+	}
+	// Cells past the current one are empty, whatever digit was last tried in them
+	for _, cell := range s.searchSpace()[s.currentSearchCell+1:] {
+		result[cell.row][cell.column] = 0
+	}
+	return
+}
+
 // Find next empty cell to try. Returns true if no more cells to try, and thus
 // we found a solution. There are three possible outcomes:
 //  1. As above, no more cells to try, all are filled, we always fill according to the rules
@@ -239,15 +522,21 @@ func searchNextCellToTry(s *Solver) bool {
 		indexFound = -1
 		// Cell candidates of the "best" found so far cell in cellSearchSpace
 		cellCandidates = 0
+		// The variants are checked apart, so that classic puzzles do not pay for them
+		constrained = s.geometry.cellUnits != nil || len(s.constraints) != 0
 	)
 	// If we ran out of empty cells we have a solution
-	if s.currentSearchCell == len(s.cellSearchSpace)-1 {
+	if s.currentSearchCell == s.searchSpaceSize-1 {
 		return true
 	}
 	// All the empty cells has higher index than the current cell in cellSearchSpace
-	for i := s.currentSearchCell + 1; i < len(s.cellSearchSpace); i++ {
+	for i := s.currentSearchCell + 1; i < s.searchSpaceSize; i++ {
 		// Get cell candidates for the cell
-		cc := s.globalCandidates.getCellCandidates(s.cellSearchSpace[i].column, s.cellSearchSpace[i].row)
+		cell := s.cellSearchSpace[i]
+		cc := s.globalCandidates.getCellCandidates(cell)
+		if constrained {
+			cc = s.variantCandidates(cell, cc)
+		}
 		// Get the number of candidates
 		bc := bitCount[cc]
 		// If no candidates, no point searching further,
@@ -275,7 +564,21 @@ func searchNextCellToTry(s *Solver) bool {
 	if indexFound != s.currentSearchCell {
 		s.cellSearchSpace[indexFound], s.cellSearchSpace[s.currentSearchCell] = s.cellSearchSpace[s.currentSearchCell], s.cellSearchSpace[indexFound]
 	}
+	// The restrictions are only applied to the cell picked, so that the loop above stays as fast as it is
+	// without them. The cell is picked by its count before the restrictions, which makes the pick worse,
+	// but a cell left without allowed candidates is still a dead end as soon as it is picked.
+	// Returning false above for it would not do: the current cell may have candidates left, and the code
+	// that tries them relies on the digit in the cell having no way out, so it does not flip it back.
+	// Instead the cell is made current with nothing in it, the backtrack from it has nothing to flip
+	current := s.cellSearchSpace[s.currentSearchCell]
+	cellCandidates &= s.allowed[current.row][current.column]
+	if cellCandidates == 0 {
+		s.setCurrentCell(0)
+	}
 	s.setCurrentCellCandidates(cellCandidates)
+	if fewestCandidatesCount > 1 {
+		s.guesses++
+	}
 	return false
 }
 
@@ -285,9 +588,17 @@ func searchNextCellToTry(s *Solver) bool {
 func (s *Solver) backtrack() int {
 	// If we are here the current cell has no candidates left
 	for {
+		if s.tracer != nil && s.getCurrentCell() != 0 {
+			s.traceEvent(TraceUndo, s.cellSearchSpace[s.currentSearchCell], bitToNumber[s.getCurrentCell()], 0)
+		}
 		// Restore global candidates table by removing
 		// the number in the current cell
 		s.flip()
+		// The constraints read the digits of the cells,
+		// so the cell is emptied for them, the search does not need that
+		if len(s.constraints) != 0 {
+			s.setCurrentCell(0)
+		}
 		// Make previous cell current
 		s.currentSearchCell--
 		// If we are back to start we finished the search
@@ -310,6 +621,66 @@ func (s *Solver) backtrack() int {
 // and true, when a solution is found. After true is returned call
 // .Solution() to get last solution
 func (s *Solver) Solve() bool {
+	if s.logger == nil && !s.watched.Load() {
+		return s.solve()
+	}
+	if s.done {
+		return false
+	}
+	found := s.solve()
+	if s.logger != nil {
+		s.logSolve(found)
+	}
+	if s.watched.Load() {
+		s.publish()
+	}
+	return found
+}
+
+// How a call to Search ended
+type Outcome int
+
+const (
+	Exhausted  Outcome = iota // there are no more solutions, the search is over
+	Found                     // a solution was found, see Solution
+	OverBudget                // the search gave up at its iteration budget, see SetMaxIterations and SetSolveBudget
+	Cancelled                 // the context of SolveContext was done, the search can go on
+)
+
+// Same as Solve, but tells why there is no solution: the search may be over, or it may have
+// given up before it was
+func (s *Solver) Search() Outcome {
+	switch {
+	case s.Solve():
+		return Found
+	case s.budgetExceeded:
+		return OverBudget
+	case !s.done:
+		// Only a cancelled search stops without being done or over its budget
+		return Cancelled
+	}
+	return Exhausted
+}
+
+// How often SolveContext checks whether it has to stop, in iterations. Checking on every iteration
+// would slow the search down, this many take well under a millisecond
+const cancelInterval = 1024
+
+// Same as Solve, but stops when the context is done, whether it has found a solution or not, and
+// returns false then. ctx.Err() tells it from the end of the search. The search is not over, the
+// next call to Solve or SolveContext goes on where it stopped
+func (s *Solver) SolveContext(ctx context.Context) bool {
+	// The search only checks every cancelInterval iterations, it may find a solution before that
+	if ctx.Err() != nil {
+		return false
+	}
+	s.cancel = ctx.Done()
+	defer func() { s.cancel = nil }()
+	return s.Solve()
+}
+
+// Does what Solve does, without logging and publishing snapshots at the end
+func (s *Solver) solve() bool {
 	// Sometimes we discover that we completed the full search
 	// and cannot backtrack any further on the same iteration
 	// when we find the last solution, but since Solve() returns
@@ -318,19 +689,51 @@ func (s *Solver) Solve() bool {
 	if s.done {
 		return false
 	}
+	s.budgetExceeded = false
+	if s.plain() {
+		return s.solvePlain()
+	}
+	budget := s.iterations + s.solveBudget
 	for {
+		if s.maxIterations != 0 && s.iterations >= s.maxIterations {
+			s.done = true
+			s.budgetExceeded = true
+			return false
+		}
+		if s.solveBudget != 0 && s.iterations >= budget {
+			s.budgetExceeded = true
+			return false
+		}
+		if s.cancel != nil && s.iterations%cancelInterval == 0 {
+			select {
+			case <-s.cancel:
+				return false
+			default:
+			}
+		}
 		s.iterations++ // in theory this can overflow, in practice it would take too long
 		// Find next cell to try
+		previous := s.currentSearchCell
 		haveSolution := searchNextCellToTry(s)
 		// If all cells are filled it's a solution
-		if haveSolution {
+		if haveSolution && !s.counting {
 			s.haveSolution = true    // so .Solution() could panic if there is no solution yey
 			s.lastSolution = s.cells // we'll move on soon, so store it for .Solution() to return
 		}
+		// If no cell has been selected yet there is nothing to try or backtrack to:
+		// either the puzzle has no empty cells or one of them has no candidates from the start
+		if s.currentSearchCell == -1 {
+			s.done = true
+			return haveSolution
+		}
 		// Get candidates for the selected cell
 		lcc := s.getCurrentCellCandidates()
+		if s.tracer != nil {
+			s.traceSearch(previous, haveSolution, lcc)
+		}
 		// If no candidates, we need to backtrack
 		if lcc == 0 {
+			s.deadEnds++
 			lcc = s.backtrack()
 			// If we cannot backtrack any further the search if finished
 			if lcc == 0 {
@@ -339,9 +742,14 @@ func (s *Solver) Solve() bool {
 				// if so, indicate it to the caller
 				return haveSolution
 			}
+		} else if s.currentSearchCell == previous && !haveSolution {
+			// A dead end with candidates left to try in the current cell. Under the classic rules the digit
+			// just put in the cell takes at most one candidate from any other cell, so this only happens
+			// with the constraints that take more, such as the non-consecutive one. The digit has to come out first
+			s.flip()
 		}
 		// Get next candidate
-		candidate := leftmostBitLookup[lcc]
+		candidate := s.nextCandidate[lcc]
 		// Remove the candidate from the cell's candidates list
 		s.setCurrentCellCandidates(lcc ^ candidate)
 		// Write the candidate to the cell
@@ -349,9 +757,120 @@ func (s *Solver) Solve() bool {
 		// Update global candidates table, to indicate that this number is no longer candidate
 		// for the respective row, column and box
 		s.flip()
+		if s.tracer != nil {
+			action := TracePlace
+			if s.currentSearchCell <= previous {
+				action = TraceRetry
+			}
+			s.traceEvent(action, s.cellSearchSpace[s.currentSearchCell], bitToNumber[candidate], bitCount[lcc^candidate])
+		}
+		if s.observer != nil {
+			s.observer(s.grid())
+		}
+		if s.iterations%snapshotInterval == 0 && s.watched.Load() {
+			s.publish()
+		}
 		// if we found a solution earlier, indicate it to the caller
 		if haveSolution {
 			return true
 		}
 	}
 }
+
+// Whether the search is a classic one with little to check on the way: no limits, no tracer or
+// observer, no restrictions, no variant units and no constraints. Enumerating puzzles with many
+// solutions spends most of its time in such searches, see solvePlain
+func (s *Solver) plain() bool {
+	return s.maxIterations == 0 && s.solveBudget == 0 && s.tracer == nil && s.observer == nil &&
+		!s.restricted && s.geometry.variant == 0 && len(s.constraints) == 0
+}
+
+// Same as solve, but for plain searches only, so that the loop is as tight as it was before
+// the limits, the variants and the rest of them were added. The statistics, the snapshots and the
+// cancellation are kept
+func (s *Solver) solvePlain() bool {
+	for {
+		if s.iterations%cancelInterval == 0 && s.cancel != nil {
+			select {
+			case <-s.cancel:
+				return false
+			default:
+			}
+		}
+		s.iterations++
+		haveSolution := searchNextCellToTryPlain(s)
+		if haveSolution && !s.counting {
+			s.haveSolution = true
+			s.lastSolution = s.cells
+		}
+		if s.currentSearchCell == -1 {
+			s.done = true
+			return haveSolution
+		}
+		lcc := s.getCurrentCellCandidates()
+		if lcc == 0 {
+			s.deadEnds++
+			lcc = s.backtrackPlain()
+			if lcc == 0 {
+				s.done = true
+				return haveSolution
+			}
+		}
+		candidate := s.nextCandidate[lcc]
+		s.setCurrentCellCandidates(lcc ^ candidate)
+		s.setCurrentCell(candidate)
+		s.globalCandidates.flipBit(s.cellSearchSpace[s.currentSearchCell], candidate)
+		if s.iterations%snapshotInterval == 0 && s.watched.Load() {
+			s.publish()
+		}
+		if haveSolution {
+			return true
+		}
+	}
+}
+
+// Same as searchNextCellToTry, without the variant units, the constraints and the restrictions
+func searchNextCellToTryPlain(s *Solver) bool {
+	fewestCandidatesCount, indexFound, cellCandidates := 10, -1, 0
+	if s.currentSearchCell == s.searchSpaceSize-1 {
+		return true
+	}
+	for i := s.currentSearchCell + 1; i < s.searchSpaceSize; i++ {
+		cc := s.globalCandidates.getCellCandidates(s.cellSearchSpace[i])
+		bc := bitCount[cc]
+		if bc == 0 {
+			return false
+		}
+		if fewestCandidatesCount > bc {
+			cellCandidates, indexFound, fewestCandidatesCount = cc, i, bc
+			if bc == 1 {
+				break
+			}
+		}
+	}
+	s.currentSearchCell++
+	if indexFound != s.currentSearchCell {
+		s.cellSearchSpace[indexFound], s.cellSearchSpace[s.currentSearchCell] = s.cellSearchSpace[s.currentSearchCell], s.cellSearchSpace[indexFound]
+	}
+	s.setCurrentCellCandidates(cellCandidates)
+	if fewestCandidatesCount > 1 {
+		s.guesses++
+	}
+	return false
+}
+
+// Same as backtrack, without the tracer, the variant units and the constraints
+func (s *Solver) backtrackPlain() int {
+	for {
+		s.globalCandidates.flipBit(s.cellSearchSpace[s.currentSearchCell], s.getCurrentCell())
+		s.currentSearchCell--
+		if s.currentSearchCell == -1 {
+			return 0
+		}
+		lcc := s.getCurrentCellCandidates()
+		if lcc != 0 {
+			s.globalCandidates.flipBit(s.cellSearchSpace[s.currentSearchCell], s.getCurrentCell())
+			return lcc
+		}
+	}
+}
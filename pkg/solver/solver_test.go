@@ -0,0 +1,101 @@
+package solver
+
+import "testing"
+
+// Hard 17 clue puzzles, the first ones of data/input1.txt
+var benchmarkPuzzles = []string{
+	"4...3.......6..8..........1....5..9..8....6...7.2........1.27..5.3....4.9........",
+	"7.8...3.....2.1...5.........4.....263...8.......1...9..9.6....4....7.5...........",
+	"7.8...3.....6.1...5.........4.....263...8.......1...9..9.2....4....7.5...........",
+	"3.7.4...........918........4.....7.....16.......25..........38..9....5...2.6.....",
+	"5..7..6....38...........2..62.4............917............35.8.4.....1......9....",
+	"4..7..6....38...........2..62.5............917............43.8.5.....1......9....",
+	".4..1.2.......9.7..1..........43.6..8......5....2.....7.5..8......6..3..9........",
+	"7.5.....2...4.1...3.........1.6..4..2...5...........9....37.....8....6...9.....8.",
+}
+
+// Reads puzzles in the inline format, the parser package cannot be used here as it imports this one
+func parseBenchmarkPuzzles(b *testing.B, lines []string) [][sudokuSize][sudokuSize]int {
+	b.Helper()
+	puzzles := make([][sudokuSize][sudokuSize]int, len(lines))
+	for i, line := range lines {
+		if len(line) != sudokuSize*sudokuSize {
+			b.Fatalf("puzzle %d has %d cells", i, len(line))
+		}
+		for j, c := range line {
+			if c != '.' {
+				puzzles[i][j/sudokuSize][j%sudokuSize] = int(c - '0')
+			}
+		}
+	}
+	return puzzles
+}
+
+// A new solver for every puzzle, the way a single puzzle is solved
+func BenchmarkSolve(b *testing.B) {
+	puzzles := parseBenchmarkPuzzles(b, benchmarkPuzzles)
+	b.ReportAllocs()
+	for i := 0; b.Loop(); i++ {
+		s, err := NewSolver(puzzles[i%len(puzzles)])
+		if err != nil {
+			b.Fatal(err)
+		}
+		if !s.Solve() {
+			b.Fatal("no solution")
+		}
+		_ = s.Solution()
+	}
+}
+
+// Solvers reused from a pool with the solution written to the caller's grid, the way batches are solved.
+// This path should not allocate at all
+func BenchmarkPoolSolveInto(b *testing.B) {
+	puzzles := parseBenchmarkPuzzles(b, benchmarkPuzzles)
+	var pool Pool
+	var solution [sudokuSize][sudokuSize]int
+	b.ReportAllocs()
+	for i := 0; b.Loop(); i++ {
+		s, err := pool.Get(puzzles[i%len(puzzles)])
+		if err != nil {
+			b.Fatal(err)
+		}
+		if !s.SolveInto(&solution) {
+			b.Fatal("no solution")
+		}
+		pool.Put(s)
+	}
+}
+
+// A single solver set up for every puzzle with Reset. The search space is a fixed size array,
+// so this does not allocate either
+func BenchmarkResetSolve(b *testing.B) {
+	puzzles := parseBenchmarkPuzzles(b, benchmarkPuzzles)
+	s := &Solver{}
+	b.ReportAllocs()
+	for i := 0; b.Loop(); i++ {
+		if err := s.Reset(puzzles[i%len(puzzles)]); err != nil {
+			b.Fatal(err)
+		}
+		if !s.Solve() {
+			b.Fatal("no solution")
+		}
+	}
+}
+
+// The first solutions of the empty grid one Solve call at a time, the way '-a' lists them.
+// Every call finds a solution within a few iterations, so this is all search and no setup
+func BenchmarkEnumerate(b *testing.B) {
+	const solutions = 10000
+	b.ReportAllocs()
+	for b.Loop() {
+		s, err := NewSolver([sudokuSize][sudokuSize]int{})
+		if err != nil {
+			b.Fatal(err)
+		}
+		for range solutions {
+			if !s.Solve() {
+				b.Fatal("no solution")
+			}
+		}
+	}
+}
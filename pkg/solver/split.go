@@ -0,0 +1,101 @@
+package solver
+
+// An exhaustive search is split into subtrees by filling in the first few empty cells, in row major order,
+// every way that is consistent with the givens. Each subtree is a puzzle of its own, together their
+// solutions are exactly the solutions of the original puzzle. The order of the subtrees only depends
+// on the puzzle and the depth, so that machines that split the same puzzle agree on the numbering
+
+// Returns the smallest split depth that gives at least n subtrees, or the number of empty cells
+// if even filling all of them in gives fewer
+func SplitDepth(puzzle Puzzle, n int) (int, error) {
+	if _, err := NewSolver(puzzle); err != nil {
+		return 0, err
+	}
+	empty := 0
+	for y := range sudokuSize {
+		for x := range sudokuSize {
+			if puzzle[y][x] == 0 {
+				empty++
+			}
+		}
+	}
+	for depth := 0; depth < empty; depth++ {
+		count := 0
+		walkSubtrees(puzzle, depth, func(*Puzzle) bool {
+			count++
+			return count < n
+		})
+		if count >= n {
+			return depth, nil
+		}
+	}
+	return empty, nil
+}
+
+// Returns the subtrees of a shard: of all the subtrees at the depth, in order, every shards-th one
+// starting from the shard, which is 0-based
+func ShardSubtrees(puzzle Puzzle, depth, shard, shards int) ([]Puzzle, error) {
+	if _, err := NewSolver(puzzle); err != nil {
+		return nil, err
+	}
+	var subtrees []Puzzle
+	index := 0
+	walkSubtrees(puzzle, depth, func(p *Puzzle) bool {
+		if index%shards == shard {
+			subtrees = append(subtrees, *p)
+		}
+		index++
+		return true
+	})
+	return subtrees, nil
+}
+
+// Calls visit with each subtree at the depth in order, until it returns false.
+// The puzzle has to be consistent
+func walkSubtrees(puzzle Puzzle, depth int, visit func(*Puzzle) bool) {
+	var rows, columns, boxes [sudokuSize]int
+	var empty []coordinates
+	for y := range sudokuSize {
+		for x := range sudokuSize {
+			box := y/3*3 + x/3
+			if puzzle[y][x] == 0 {
+				empty = append(empty, coordinates{row: y, column: x, box: box})
+				continue
+			}
+			bit := 1 << (puzzle[y][x] - 1)
+			rows[y] |= bit
+			columns[x] |= bit
+			boxes[box] |= bit
+		}
+	}
+	depth = min(depth, len(empty))
+
+	var walk func(level int) bool
+	walk = func(level int) bool {
+		if level == depth {
+			return visit(&puzzle)
+		}
+		cell := empty[level]
+		used := rows[cell.row] | columns[cell.column] | boxes[cell.box]
+		for digit := 1; digit <= sudokuSize; digit++ {
+			bit := 1 << (digit - 1)
+			if used&bit != 0 {
+				continue
+			}
+			puzzle[cell.row][cell.column] = digit
+			rows[cell.row] ^= bit
+			columns[cell.column] ^= bit
+			boxes[cell.box] ^= bit
+			more := walk(level + 1)
+			rows[cell.row] ^= bit
+			columns[cell.column] ^= bit
+			boxes[cell.box] ^= bit
+			puzzle[cell.row][cell.column] = 0
+			if !more {
+				return false
+			}
+		}
+		return true
+	}
+	walk(0)
+}
@@ -0,0 +1,94 @@
+package solver
+
+import "fmt"
+
+// What the search does in a step, see Trace
+type TraceAction int
+
+const (
+	TracePlace    TraceAction = iota + 1 // a digit put in the next cell the search picked, the one with the fewest candidates
+	TraceRetry                           // the next candidate put in a cell, the digit it had led nowhere
+	TraceDeadEnd                         // a cell has no candidates left, the digits placed last have to be changed
+	TraceUndo                            // a digit taken out of a cell while backtracking, the cell has no candidates left to try
+	TraceSolution                        // all the cells are filled
+)
+
+// A step of the search. Rows, columns and digits count from 1
+type TraceEvent struct {
+	Action    TraceAction
+	Iteration int // the iteration of the search the step belongs to, see Solver.Iterations
+	Depth     int // the number of cells the search has filled, the givens not counted
+	Row       int // the cell, 0 for TraceSolution
+	Column    int
+	Digit     int // the digit placed or taken out, 0 for TraceDeadEnd and TraceSolution
+	Left      int // the candidates of the cell left to try after this one, for TracePlace and TraceRetry
+}
+
+// Returns the step as a line of text, e.g. "place r4c7=2 (guess, 2 other candidates left)"
+func (e TraceEvent) String() string {
+	cell := fmt.Sprintf("r%dc%d", e.Row, e.Column)
+	switch e.Action {
+	case TracePlace:
+		if e.Left == 0 {
+			return fmt.Sprintf("place %s=%d (only candidate)", cell, e.Digit)
+		}
+		return fmt.Sprintf("place %s=%d (guess, %s left)", cell, e.Digit, otherCandidates(e.Left))
+	case TraceRetry:
+		if e.Left == 0 {
+			return fmt.Sprintf("retry %s=%d (last candidate)", cell, e.Digit)
+		}
+		return fmt.Sprintf("retry %s=%d (%s left)", cell, e.Digit, otherCandidates(e.Left))
+	case TraceDeadEnd:
+		return fmt.Sprintf("dead end: %s has no candidates", cell)
+	case TraceUndo:
+		return fmt.Sprintf("undo %s=%d", cell, e.Digit)
+	case TraceSolution:
+		return "solution"
+	}
+	return fmt.Sprintf("TraceAction(%d)", int(e.Action))
+}
+
+func otherCandidates(n int) string {
+	if n == 1 {
+		return "1 other candidate"
+	}
+	return fmt.Sprintf("%d other candidates", n)
+}
+
+// Gets every step of the search
+type Tracer func(e TraceEvent)
+
+// Makes the search call f for every step it takes: the digits it places, the dead ends it runs into
+// and the digits it takes out again. It slows the search down a lot, it is meant for studying how the
+// search goes on a puzzle
+func (s *Solver) Trace(f Tracer) {
+	s.tracer = f
+}
+
+func (s *Solver) traceEvent(action TraceAction, cell coordinates, digit, left int) {
+	e := TraceEvent{Action: action, Iteration: s.iterations, Depth: s.currentSearchCell + 1, Digit: digit, Left: left}
+	if action != TraceSolution {
+		e.Row, e.Column = int(cell.row)+1, int(cell.column)+1
+	}
+	s.tracer(e)
+}
+
+// Traces what searchNextCellToTry found, before the solver acts on it: a solution, or a dead end when
+// no cell was picked or the one picked has no candidates
+func (s *Solver) traceSearch(previous int, haveSolution bool, lcc int) {
+	switch {
+	case haveSolution:
+		s.traceEvent(TraceSolution, coordinates{}, 0, 0)
+	case s.currentSearchCell != previous && lcc != 0:
+	case s.currentSearchCell != previous:
+		// The cell picked has no candidates it is allowed to take
+		s.traceEvent(TraceDeadEnd, s.cellSearchSpace[s.currentSearchCell], 0, 0)
+	default:
+		for _, cell := range s.searchSpace()[s.currentSearchCell+1:] {
+			if s.variantCandidates(cell, s.globalCandidates.getCellCandidates(cell)) == 0 {
+				s.traceEvent(TraceDeadEnd, cell, 0, 0)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package solver
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Rules on top of the classic ones. The variants add units that have to hold all the digits too, the
+// constraints keep digits apart from each other in other ways. They combine, e.g. Diagonals|Hyper
+type Variant uint8
+
+const (
+	Diagonals      Variant = 1 << iota // both main diagonals, also known as X-Sudoku
+	Hyper                              // four extra 3x3 windows, also known as Windoku
+	AntiKnight                         // no two cells a knight's move apart have the same digit
+	NonConsecutive                     // no two cells side by side have digits one apart
+)
+
+// A name of a variant or a constraint
+type variantName struct {
+	variant Variant
+	name    string
+}
+
+// The names of the variants, as ParseVariant takes them
+var variantNames = []variantName{
+	{Diagonals, "x"},
+	{Hyper, "hyper"},
+}
+
+// The names of the constraints, as ParseConstraint takes them
+var constraintNames = []variantName{
+	{AntiKnight, "antiknight"},
+	{NonConsecutive, "nonconsecutive"},
+}
+
+// Returns the names of the variants
+func VariantNames() []string {
+	return namesOf(variantNames)
+}
+
+// Returns the names of the constraints
+func ConstraintNames() []string {
+	return namesOf(constraintNames)
+}
+
+func namesOf(table []variantName) []string {
+	names := make([]string, len(table))
+	for i, n := range table {
+		names[i] = n.name
+	}
+	return names
+}
+
+// Returns the variant with the names, separated by commas, e.g. "x" or "x,hyper". An empty string is the classic rules
+func ParseVariant(names string) (Variant, error) {
+	return parseNames(names, variantNames, "variant")
+}
+
+// Returns the constraints with the names, separated by commas, e.g. "antiknight" or "antiknight,nonconsecutive". An empty string is none
+func ParseConstraint(names string) (Variant, error) {
+	return parseNames(names, constraintNames, "constraint")
+}
+
+func parseNames(names string, table []variantName, kind string) (Variant, error) {
+	var v Variant
+	if names == "" {
+		return v, nil
+	}
+	for _, name := range strings.Split(names, ",") {
+		found := false
+		for _, n := range table {
+			if n.name == strings.TrimSpace(name) {
+				v |= n.variant
+				found = true
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("unknown %s %s, want one of %s", kind, name, strings.Join(namesOf(table), ", "))
+		}
+	}
+	return v, nil
+}
+
+// Returns the names of the variants and the constraints, separated by commas, "classic" for none
+func (v Variant) String() string {
+	var names []string
+	for _, n := range slices.Concat(variantNames, constraintNames) {
+		if v&n.variant != 0 {
+			names = append(names, n.name)
+		}
+	}
+	if len(names) == 0 {
+		return "classic"
+	}
+	return strings.Join(names, ",")
+}
+
+// Same as NewSolver, but the puzzle follows the variant rules as well as the classic ones
+func NewVariantSolver(puzzle [sudokuSize][sudokuSize]int, v Variant) (*Solver, error) {
+	s := &Solver{geometry: geometryFor(layout{standardShape, v})}
+	if err := s.reset(puzzle); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
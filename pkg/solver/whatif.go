@@ -0,0 +1,111 @@
+package solver
+
+import "fmt"
+
+// What a single assignment would do to the puzzle of a solver
+type WhatIfResult struct {
+	Solutions    int    // the number of solutions left, up to the limit
+	LimitReached bool   // there are more solutions left than the limit
+	Forced       Puzzle // the empty cells, other than the assigned one, that have the same digit in every solution left
+}
+
+// Reports what putting the digit in the cell at row and column, both 0-based, would do to the puzzle
+// the solver was created for, with its restrictions if it has any: how many solutions are left, counting
+// up to the limit (0 is no limit), and which cells become forced. The solver itself is not touched, its search
+// goes on where it was. A digit that clashes with the givens or is not allowed in the cell leaves no solutions. Returns error when the cell is out of the grid
+// or is a given, or the digit is not 1 to 9
+func (s *Solver) WhatIf(row, column, digit, limit int) (WhatIfResult, error) {
+	var r WhatIfResult
+	if row < 0 || row >= sudokuSize || column < 0 || column >= sudokuSize {
+		return r, fmt.Errorf("invalid cell r%dc%d", row+1, column+1)
+	}
+	if digit < 1 || digit > sudokuSize {
+		return r, fmt.Errorf("invalid digit %d", digit)
+	}
+	puzzle := s.puzzle()
+	if puzzle[row][column] != 0 {
+		return r, fmt.Errorf("cell r%dc%d is a given", row+1, column+1)
+	}
+	if s.allowed[row][column]&(1<<(digit-1)) == 0 {
+		return r, nil
+	}
+	puzzle[row][column] = digit
+
+	trial := &Solver{geometry: s.geometry}
+	// Sets the trial up for the puzzle with the restrictions and the constraints of the solver,
+	// returns false when the puzzle breaks the rules
+	start := func(puzzle Puzzle) bool {
+		if trial.reset(puzzle) != nil {
+			return false
+		}
+		trial.allowed, trial.restricted = s.allowed, s.restricted
+		trial.constraints = s.constraints
+		return trial.keepsConstraints()
+	}
+	if !start(puzzle) {
+		return r, nil
+	}
+	var first Puzzle
+	var differ [sudokuSize][sudokuSize]bool // cells that have seen two different digits
+	mark := func(solution Puzzle) {
+		for y := range sudokuSize {
+			for x := range sudokuSize {
+				differ[y][x] = differ[y][x] || solution[y][x] != first[y][x]
+			}
+		}
+	}
+	for trial.Solve() {
+		if limit != 0 && r.Solutions == limit {
+			r.LimitReached = true
+			break
+		}
+		if r.Solutions == 0 {
+			first = trial.Solution()
+		} else {
+			mark(trial.Solution())
+		}
+		r.Solutions++
+	}
+	if r.Solutions == 0 {
+		return r, nil
+	}
+	if r.LimitReached {
+		// The solutions were not all seen, so a cell that agreed so far is only forced
+		// if none of its other digits leads to a solution
+		for y := range sudokuSize {
+			for x := range sudokuSize {
+				for d := 1; d <= sudokuSize && puzzle[y][x] == 0 && !differ[y][x]; d++ {
+					if d == first[y][x] {
+						continue
+					}
+					tried := puzzle
+					tried[y][x] = d
+					if start(tried) && trial.Solve() {
+						mark(trial.Solution())
+					}
+				}
+			}
+		}
+	}
+	for y := range sudokuSize {
+		for x := range sudokuSize {
+			if puzzle[y][x] == 0 && !differ[y][x] {
+				r.Forced[y][x] = first[y][x]
+			}
+		}
+	}
+	return r, nil
+}
+
+// Returns the puzzle the solver was created for, the search only ever fills in the cells of the search space
+func (s *Solver) puzzle() (result Puzzle) {
+	for y, row := range s.cells {
+		for x := range row {
+			result[y][x] = bitToNumber[s.cells[y][x]]
+		}
+	}
+	for _, cell := range s.searchSpace() {
+		result[cell.row][cell.column] = 0
+	}
+	return
+}
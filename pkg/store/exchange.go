@@ -0,0 +1,156 @@
+package store
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/AndrewSav/sudocoo/pkg/format"
+	"github.com/AndrewSav/sudocoo/pkg/packed"
+	"github.com/AndrewSav/sudocoo/pkg/parser"
+)
+
+// External formats records can be imported from and exported to
+const (
+	FormatText   = "text"   // just the puzzles, in the inline format one per line on export
+	FormatJSONL  = "jsonl"  // one JSON record per line, what the store was before it was an SQLite database
+	FormatCSV    = "csv"    // with a header row naming the columns below
+	FormatPacked = "packed" // the binary format of pkg/packed with solution counts, no metadata
+)
+
+var ExchangeFormats = []string{FormatText, FormatJSONL, FormatCSV, FormatPacked}
+
+var csvColumns = []string{"puzzle", "canonical", "clues", "solutions", "rating", "source", "label", "added"}
+
+// Writes the records in the given format
+func WriteRecords(w io.Writer, f string, records []Record) error {
+	switch f {
+	case FormatText:
+		bw := bufio.NewWriter(w)
+		for _, r := range records {
+			fmt.Fprintln(bw, r.Puzzle)
+		}
+		return bw.Flush()
+	case FormatJSONL:
+		encoder := json.NewEncoder(w)
+		for _, r := range records {
+			if err := encoder.Encode(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		cw.Write(csvColumns)
+		for _, r := range records {
+			cw.Write([]string{r.Puzzle, r.Canonical, strconv.Itoa(r.Clues), strconv.Itoa(r.Solutions),
+				r.Rating, r.Source, r.Label, r.Added.Format(time.RFC3339Nano)})
+		}
+		cw.Flush()
+		return cw.Error()
+	case FormatPacked:
+		pw, err := packed.NewWriter(w, true)
+		if err != nil {
+			return err
+		}
+		for _, r := range records {
+			puzzle, err := parser.ParsePuzzleString(r.Puzzle)
+			if err != nil {
+				return err
+			}
+			if err := pw.Write(puzzle, r.Solutions); err != nil {
+				return err
+			}
+		}
+		return pw.Flush()
+	default:
+		return fmt.Errorf("unknown exchange format %s", f)
+	}
+}
+
+// Reads records in the given format. Only the fields present in the input are set:
+// for the text and packed formats that is the puzzle and its 1-based number as the label.
+// The fields derived from the puzzle are not checked, see NewRecord
+func ReadRecords(r io.Reader, f string) ([]Record, error) {
+	records := []Record{}
+	switch f {
+	case FormatText:
+		scanner := parser.CreateInputScanner(r)
+		for {
+			puzzle, err := parser.ReadNextPuzzleInput(scanner)
+			if errors.Is(err, io.EOF) {
+				return records, nil
+			}
+			if err != nil {
+				return nil, fmt.Errorf("puzzle %d: %v", len(records)+1, err)
+			}
+			records = append(records, Record{Puzzle: format.Inline(puzzle), Label: strconv.Itoa(len(records) + 1)})
+		}
+	case FormatJSONL:
+		decoder := json.NewDecoder(r)
+		for {
+			var record Record
+			err := decoder.Decode(&record)
+			if errors.Is(err, io.EOF) {
+				return records, nil
+			}
+			if err != nil {
+				return nil, fmt.Errorf("record %d: %v", len(records)+1, err)
+			}
+			records = append(records, record)
+		}
+	case FormatCSV:
+		rows, err := csv.NewReader(r).ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		if len(rows) == 0 {
+			return records, nil
+		}
+		columns := map[string]int{}
+		for i, name := range rows[0] {
+			columns[name] = i
+		}
+		if _, ok := columns["puzzle"]; !ok {
+			return nil, fmt.Errorf("the CSV header has no puzzle column")
+		}
+		for n, row := range rows[1:] {
+			get := func(name string) string {
+				if i, ok := columns[name]; ok && i < len(row) {
+					return row[i]
+				}
+				return ""
+			}
+			record := Record{Puzzle: get("puzzle"), Canonical: get("canonical"), Rating: get("rating"), Source: get("source"), Label: get("label")}
+			if added := get("added"); added != "" {
+				if record.Added, err = time.Parse(time.RFC3339Nano, added); err != nil {
+					return nil, fmt.Errorf("row %d: %v", n+2, err)
+				}
+			}
+			records = append(records, record)
+		}
+		return records, nil
+	case FormatPacked:
+		pr, err := packed.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		for {
+			puzzle, _, err := pr.Read()
+			if errors.Is(err, io.EOF) {
+				return records, nil
+			}
+			if err != nil {
+				return nil, fmt.Errorf("puzzle %d: %v", len(records)+1, err)
+			}
+			records = append(records, Record{Puzzle: format.Inline(puzzle), Label: strconv.Itoa(len(records) + 1)})
+		}
+	default:
+		return nil, fmt.Errorf("unknown exchange format %s", f)
+	}
+}
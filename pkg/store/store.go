@@ -0,0 +1,280 @@
+package store
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AndrewSav/sudocoo/pkg/analysis"
+	"github.com/AndrewSav/sudocoo/pkg/canon"
+	"github.com/AndrewSav/sudocoo/pkg/format"
+
+	// The SQLite driver in pure Go, so that the program still builds without cgo
+	_ "modernc.org/sqlite"
+)
+
+// A puzzle collection kept in an SQLite database, a row per puzzle in the puzzles table, indexed
+// by the canonical form, the clue count and the rating so that subsets of millions of puzzles can
+// be selected without reading them all. The file can be opened with the sqlite3 shell too.
+// Stores of earlier versions, JSON Lines files, can be moved over with the import command.
+
+const sudokuSize = 9
+
+// Solution counts are only stored up to this number, which stands for 'multiple'
+const MultipleSolutions = 2
+
+// Query.Solutions value that matches any solution count
+const AnySolutions = -1
+
+type Record struct {
+	Puzzle    string    `json:"puzzle"`           // inline format, as it was added
+	Canonical string    `json:"canonical"`        // inline format of canon.Canonical, the same for equivalent puzzles
+	Clues     int       `json:"clues"`            // number of givens
+	Solutions int       `json:"solutions"`        // 0, 1 or MultipleSolutions
+	Rating    string    `json:"rating,omitempty"` // difficulty, see rating.Level, if known. Only puzzles with a unique solution have one
+	Source    string    `json:"source,omitempty"` // where the puzzle came from, e.g. the input file
+	Label     string    `json:"label,omitempty"`  // name or number of the puzzle in its source
+	Added     time.Time `json:"added"`
+}
+
+// Fills in the fields derived from the puzzle itself
+func NewRecord(puzzle [sudokuSize][sudokuSize]int, solutions int, source string) Record {
+	if solutions > MultipleSolutions {
+		solutions = MultipleSolutions
+	}
+	return Record{
+		Puzzle:    format.Inline(puzzle),
+		Canonical: format.Inline(canon.Canonical(puzzle)),
+		Clues:     analysis.ClueCount(puzzle),
+		Solutions: solutions,
+		Source:    source,
+		Added:     time.Now().UTC(),
+	}
+}
+
+// Selects records. Zero values match everything, except for Solutions where it is AnySolutions
+type Query struct {
+	MinClues  int
+	MaxClues  int
+	Solutions int
+	Rating    string
+	Source    string
+	Limit     int // the maximum number of records to return
+}
+
+const schema = `
+CREATE TABLE IF NOT EXISTS puzzles (
+	id        INTEGER PRIMARY KEY,
+	puzzle    TEXT NOT NULL,
+	canonical TEXT NOT NULL UNIQUE,
+	clues     INTEGER NOT NULL,
+	solutions INTEGER NOT NULL,
+	rating    TEXT NOT NULL DEFAULT '',
+	source    TEXT NOT NULL DEFAULT '',
+	label     TEXT NOT NULL DEFAULT '',
+	added     TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS puzzles_clues ON puzzles (clues);
+CREATE INDEX IF NOT EXISTS puzzles_rating ON puzzles (rating);
+`
+
+// Records added are committed in batches of this many, a transaction per record would take a
+// sync of the file each
+const batchSize = 1000
+
+// What an SQLite file starts with
+var sqliteHeader = []byte("SQLite format 3\x00")
+
+// A store open for adding and querying records. It is safe for concurrent use
+type Store struct {
+	mu      sync.Mutex
+	db      *sql.DB
+	tx      *sql.Tx // the batch being added, nil when there is none
+	pending int     // records added in tx
+}
+
+// Opens the store, creating the database if it does not exist
+func Open(path string) (*Store, error) {
+	if err := checkFile(path); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// A single connection, so that the batch is seen by the queries and nothing waits for a lock
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Refuses a file that is not an SQLite database, rather than have the driver tell it is not one.
+// An empty or missing file is fine, the database is created in it
+func checkFile(path string) error {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	header, _ := bufio.NewReader(file).Peek(len(sqliteHeader))
+	if len(header) == 0 || string(header) == string(sqliteHeader) {
+		return nil
+	}
+	if header[0] == '{' {
+		return fmt.Errorf("%s is a puzzle store of an earlier version, a JSON Lines file. Move it over with 'import -format jsonl -f %s -db NEW'", path, path)
+	}
+	return fmt.Errorf("%s is not a puzzle store", path)
+}
+
+// Commits what has been added and closes the store
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.commit()
+	return errors.Join(err, s.db.Close())
+}
+
+func (s *Store) commit() error {
+	if s.tx == nil {
+		return nil
+	}
+	err := s.tx.Commit()
+	s.tx, s.pending = nil, 0
+	return err
+}
+
+// Adds the record unless an equivalent puzzle is already in the store, returns whether it was added.
+// A rating the puzzle in the store does not have yet is filled in from the record
+func (s *Store) Add(r Record) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tx == nil {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return false, err
+		}
+		s.tx = tx
+	}
+	result, err := s.tx.Exec(`INSERT INTO puzzles (puzzle, canonical, clues, solutions, rating, source, label, added)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT (canonical) DO NOTHING`,
+		r.Puzzle, r.Canonical, r.Clues, r.Solutions, r.Rating, r.Source, r.Label, r.Added.UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return false, err
+	}
+	added, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if added == 0 && r.Rating != "" {
+		if _, err := s.tx.Exec(`UPDATE puzzles SET rating = ? WHERE canonical = ? AND rating = ''`, r.Rating, r.Canonical); err != nil {
+			return false, err
+		}
+	}
+	if s.pending++; s.pending == batchSize {
+		if err := s.commit(); err != nil {
+			return false, err
+		}
+	}
+	return added != 0, nil
+}
+
+const columns = `puzzle, canonical, clues, solutions, rating, source, label, added`
+
+// Returns the record of an equivalent puzzle if there is one
+func (s *Store) Find(canonical string) (Record, bool, error) {
+	records, err := s.query(`SELECT `+columns+` FROM puzzles WHERE canonical = ?`, canonical)
+	if err != nil || len(records) == 0 {
+		return Record{}, false, err
+	}
+	return records[0], true, nil
+}
+
+// Returns the matching records in the order they were added
+func (s *Store) Query(q Query) ([]Record, error) {
+	var where []string
+	var args []any
+	add := func(condition string, arg any) {
+		where = append(where, condition)
+		args = append(args, arg)
+	}
+	if q.MinClues != 0 {
+		add("clues >= ?", q.MinClues)
+	}
+	if q.MaxClues != 0 {
+		add("clues <= ?", q.MaxClues)
+	}
+	if q.Solutions != AnySolutions {
+		add("solutions = ?", q.Solutions)
+	}
+	if q.Rating != "" {
+		add("rating = ?", q.Rating)
+	}
+	if q.Source != "" {
+		add("source = ?", q.Source)
+	}
+	query := `SELECT ` + columns + ` FROM puzzles`
+	if len(where) != 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	query += ` ORDER BY id`
+	if q.Limit != 0 {
+		query += ` LIMIT ?`
+		args = append(args, q.Limit)
+	}
+	return s.query(query, args...)
+}
+
+func (s *Store) query(query string, args ...any) ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var rows *sql.Rows
+	var err error
+	if s.tx != nil {
+		rows, err = s.tx.Query(query, args...)
+	} else {
+		rows, err = s.db.Query(query, args...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	records := []Record{}
+	for rows.Next() {
+		var r Record
+		var added string
+		if err := rows.Scan(&r.Puzzle, &r.Canonical, &r.Clues, &r.Solutions, &r.Rating, &r.Source, &r.Label, &added); err != nil {
+			return nil, err
+		}
+		if r.Added, err = time.Parse(time.RFC3339Nano, added); err != nil {
+			return nil, fmt.Errorf("puzzle %s: %v", r.Puzzle, err)
+		}
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+// Number of records in the store
+func (s *Store) Len() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var row *sql.Row
+	if s.tx != nil {
+		row = s.tx.QueryRow(`SELECT count(*) FROM puzzles`)
+	} else {
+		row = s.db.QueryRow(`SELECT count(*) FROM puzzles`)
+	}
+	var n int
+	err := row.Scan(&n)
+	return n, err
+}
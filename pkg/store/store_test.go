@@ -0,0 +1,159 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AndrewSav/sudocoo/pkg/parser"
+)
+
+const (
+	// The first puzzle of data/input1.txt, and the same puzzle with the digits 1 and 2 swapped
+	storePuzzle   = "4...3.......6..8..........1....5..9..8....6...7.2........1.27..5.3....4.9........"
+	storeRelabled = "4...3.......6..8..........2....5..9..8....6...7.1........2.17..5.3....4.9........"
+	// Its solution, without a few digits
+	storeEasy = "....31527751624839392578461134756298289413675675289314846192753513867942927345186"
+)
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "puzzles.db")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The puzzles of hard, easy, multiple and relabeled below
+	var puzzles [4][sudokuSize][sudokuSize]int
+	for i, p := range []string{storePuzzle, storeEasy, "." + storePuzzle[1:], storeRelabled} {
+		if puzzles[i], err = parser.ParsePuzzleString(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hard := NewRecord(puzzles[0], 1, "input1.txt")
+	easy := NewRecord(puzzles[1], 1, "input1.txt")
+	easy.Rating, easy.Label = "easy", "solution"
+	multiple := NewRecord(puzzles[2], 5, "elsewhere")
+	for _, r := range []Record{hard, easy, multiple} {
+		if added, err := s.Add(r); !added || err != nil {
+			t.Fatalf("Add(%s) = %v, %v", r.Puzzle, added, err)
+		}
+	}
+	if multiple.Solutions != MultipleSolutions {
+		t.Errorf("got %d solutions, want them stored as %d", multiple.Solutions, MultipleSolutions)
+	}
+
+	// An equivalent puzzle is not added again, but fills in the rating
+	relabeled := NewRecord(puzzles[3], 1, "other.txt")
+	relabeled.Rating = "hard"
+	if added, err := s.Add(relabeled); added || err != nil {
+		t.Errorf("Add of an equivalent puzzle = %v, %v, want it not added", added, err)
+	}
+	hard.Rating = "hard"
+	// and a rating that is there stays
+	relabeled.Rating = "extreme"
+	s.Add(relabeled)
+
+	check := func(t *testing.T, s *Store) {
+		t.Helper()
+		if n, err := s.Len(); n != 3 || err != nil {
+			t.Errorf("Len = %d, %v, want 3", n, err)
+		}
+		got, ok, err := s.Find(hard.Canonical)
+		if !ok || err != nil || got.Puzzle != storePuzzle || got.Rating != "hard" || got.Source != "input1.txt" {
+			t.Errorf("Find = %+v, %v, %v, want the first puzzle rated hard", got, ok, err)
+		}
+		if !got.Added.Equal(hard.Added) {
+			t.Errorf("got the record added at %v, want %v", got.Added, hard.Added)
+		}
+		if _, ok, err := s.Find("not there"); ok || err != nil {
+			t.Errorf("Find of a puzzle not in the store = %v, %v", ok, err)
+		}
+		tests := []struct {
+			name  string
+			query Query
+			want  []string // the puzzles in the order they were added
+		}{
+			{"all", Query{Solutions: AnySolutions}, []string{hard.Puzzle, easy.Puzzle, multiple.Puzzle}},
+			{"unique", Query{Solutions: 1}, []string{hard.Puzzle, easy.Puzzle}},
+			{"multiple", Query{Solutions: MultipleSolutions}, []string{multiple.Puzzle}},
+			{"rating", Query{Solutions: AnySolutions, Rating: "hard"}, []string{hard.Puzzle}},
+			{"clues", Query{Solutions: AnySolutions, MinClues: 18, MaxClues: 77}, []string{hard.Puzzle, easy.Puzzle}},
+			{"max clues", Query{Solutions: AnySolutions, MaxClues: 17}, []string{multiple.Puzzle}},
+			{"source", Query{Solutions: AnySolutions, Source: "elsewhere"}, []string{multiple.Puzzle}},
+			{"limit", Query{Solutions: AnySolutions, Limit: 2}, []string{hard.Puzzle, easy.Puzzle}},
+			{"nothing", Query{Solutions: 0}, nil},
+		}
+		for _, test := range tests {
+			records, err := s.Query(test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, r := range records {
+				got = append(got, r.Puzzle)
+			}
+			if strings.Join(got, ",") != strings.Join(test.want, ",") {
+				t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+			}
+		}
+	}
+	// Before and after what was added is committed
+	check(t, s)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	s, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	check(t, s)
+}
+
+// Files that are not SQLite databases are refused, with a hint for the stores of earlier versions
+func TestOpenOtherFiles(t *testing.T) {
+	dir := t.TempDir()
+	jsonl := filepath.Join(dir, "puzzles.jsonl")
+	os.WriteFile(jsonl, []byte(`{"puzzle":"`+storePuzzle+`"}`+"\n"), 0o644)
+	if _, err := Open(jsonl); err == nil || !strings.Contains(err.Error(), "import -format jsonl") {
+		t.Errorf("got error %v opening a JSON Lines store, want a hint to import it", err)
+	}
+	text := filepath.Join(dir, "puzzles.txt")
+	os.WriteFile(text, []byte(storePuzzle+"\n"), 0o644)
+	if _, err := Open(text); err == nil {
+		t.Error("opened a text file as a store")
+	}
+	// An empty file is taken for a new store
+	empty := filepath.Join(dir, "empty.db")
+	os.WriteFile(empty, nil, 0o644)
+	s, err := Open(empty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+}
+
+// Adding more records than a batch commits them as it goes
+func TestStoreBatches(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "puzzles.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	added := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+	for i := range batchSize + 10 {
+		// Puzzles with a single clue in different places are equivalent, different numbers of clues are not
+		var puzzle [sudokuSize][sudokuSize]int
+		for j := range i%80 + 1 {
+			puzzle[j/sudokuSize][j%sudokuSize] = j%sudokuSize + 1
+		}
+		r := NewRecord(puzzle, MultipleSolutions, "")
+		r.Added = added
+		s.Add(r)
+	}
+	if n, err := s.Len(); n != 80 || err != nil {
+		t.Errorf("Len = %d, %v, want 80", n, err)
+	}
+}
@@ -0,0 +1,276 @@
+// Package sudocoo is the API for using the solver as a library. It puts the packages under pkg behind
+// a few entry points that take the puzzle and options, check what they are given instead of panicking,
+// and report everything that can go wrong as errors:
+//
+//	solutions, err := sudocoo.Solve(puzzle, sudocoo.WithLimit(2))
+//	count, err := sudocoo.Count(puzzle, sudocoo.WithMaxIterations(1_000_000))
+//	puzzle, err := sudocoo.Generate(sudocoo.WithSeed(42), sudocoo.WithSymmetry(analysis.Rotational180))
+//	rating, err := sudocoo.Rate(puzzle)
+//
+// Applications that have puzzles as text can skip the parsing and the formatting:
+//
+//	solution, err := sudocoo.SolveString("4...3.......6..8..........1....5..9..8....6...7.2........1.27..5.3....4.9........")
+//	count, err := sudocoo.CountSolutions(text, 2)
+package sudocoo
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+
+	"github.com/AndrewSav/sudocoo/pkg/analysis"
+	"github.com/AndrewSav/sudocoo/pkg/format"
+	"github.com/AndrewSav/sudocoo/pkg/logic"
+	"github.com/AndrewSav/sudocoo/pkg/rating"
+	"github.com/AndrewSav/sudocoo/pkg/solver"
+)
+
+const sudokuSize = 9
+
+// A puzzle or a solution, row by row, empty cells are zeroes
+type Puzzle = solver.Puzzle
+
+// Everything the entry points can be told, each of them uses the fields that apply to it. The zero
+// value is the defaults
+type Options struct {
+	Limit         int               // the most solutions to find, 0 is the first one only for Solve and no limit for Count
+	MaxIterations int               // give up after that many search iterations, 0 is no limit
+	Seed          uint64            // makes Generate and Rate repeatable, the same seed gives the same result
+	Clues         int               // Generate stops removing clues at this many, 0 is as few as it can
+	Symmetry      analysis.Symmetry // the clue pattern Generate makes has these symmetries
+	Orders        int               // the number of search orders Rate averages over, 0 is analysis.DefaultSearchOrders
+	Logger        *slog.Logger      // the solver logs to, see solver.Solver.SetLogger
+	Format        string            // SolveString writes the solutions in, see format.GetKnownFormats, "" is inline
+}
+
+// Sets one of the Options
+type Option func(*Options)
+
+// Sets Options.Limit
+func WithLimit(n int) Option {
+	return func(o *Options) { o.Limit = n }
+}
+
+// Sets Options.MaxIterations
+func WithMaxIterations(n int) Option {
+	return func(o *Options) { o.MaxIterations = n }
+}
+
+// Sets Options.Seed
+func WithSeed(seed uint64) Option {
+	return func(o *Options) { o.Seed = seed }
+}
+
+// Sets Options.Clues
+func WithClues(n int) Option {
+	return func(o *Options) { o.Clues = n }
+}
+
+// Sets Options.Symmetry
+func WithSymmetry(s analysis.Symmetry) Option {
+	return func(o *Options) { o.Symmetry = s }
+}
+
+// Sets Options.Orders
+func WithOrders(n int) Option {
+	return func(o *Options) { o.Orders = n }
+}
+
+// Sets Options.Logger
+func WithLogger(l *slog.Logger) Option {
+	return func(o *Options) { o.Logger = l }
+}
+
+// Sets Options.Format
+func WithFormat(name string) Option {
+	return func(o *Options) { o.Format = name }
+}
+
+// Applies the options to the defaults and checks them
+func newOptions(opts []Option) (Options, error) {
+	var o Options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.Limit < 0 || o.MaxIterations < 0 || o.Orders < 0 {
+		return o, fmt.Errorf("the limit, the iterations and the orders cannot be negative")
+	}
+	if o.Clues < 0 || o.Clues > sudokuSize*sudokuSize {
+		return o, fmt.Errorf("the clues have to be between 0 and %d, got %d", sudokuSize*sudokuSize, o.Clues)
+	}
+	return o, nil
+}
+
+// Checks that every cell of the puzzle is empty or has a digit
+func validate(puzzle Puzzle) error {
+	for y, row := range puzzle {
+		for x, digit := range row {
+			if digit < 0 || digit > sudokuSize {
+				return fmt.Errorf("r%dc%d: %d is not a digit", y+1, x+1, digit)
+			}
+		}
+	}
+	return nil
+}
+
+// Returns a new solver for the puzzle with the options applied
+func newSolver(puzzle Puzzle, o Options) (*solver.Solver, error) {
+	if err := validate(puzzle); err != nil {
+		return nil, err
+	}
+	s, err := solver.NewSolver(puzzle)
+	if err != nil {
+		return nil, err
+	}
+	s.SetMaxIterations(o.MaxIterations)
+	s.SetLogger(o.Logger)
+	return s, nil
+}
+
+// Returns the solutions of the puzzle in search order, the first one only unless Options.Limit
+// asks for more. Returns an error when the puzzle is inconsistent, when it has no solution and when
+// the search gives up at Options.MaxIterations before finding the solutions asked for
+func Solve(puzzle Puzzle, opts ...Option) ([]Puzzle, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	s, err := newSolver(puzzle, o)
+	if err != nil {
+		return nil, err
+	}
+	limit := max(o.Limit, 1)
+	var solutions []Puzzle
+	var solution Puzzle
+	for len(solutions) < limit && s.SolveInto(&solution) {
+		solutions = append(solutions, solution)
+	}
+	if len(solutions) < limit && s.BudgetExceeded() {
+		return solutions, fmt.Errorf("%w, %d iterations", solver.ErrLimitReached, s.Iterations())
+	}
+	if len(solutions) == 0 {
+		return nil, solver.ErrNoSolution
+	}
+	return solutions, nil
+}
+
+// Counts the solutions of the puzzle, up to Options.Limit if it is set: a count equal to the limit
+// means there may be more. Returns an error when the puzzle is inconsistent and when the search
+// gives up at Options.MaxIterations
+func Count(puzzle Puzzle, opts ...Option) (int, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return 0, err
+	}
+	s, err := newSolver(puzzle, o)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for (o.Limit == 0 || count < o.Limit) && s.Solve() {
+		count++
+	}
+	if s.BudgetExceeded() {
+		return count, fmt.Errorf("%w, %d iterations, %d solutions", solver.ErrLimitReached, s.Iterations(), count)
+	}
+	return count, nil
+}
+
+// Makes a random puzzle with a unique solution. A random grid is filled in and its clues are removed
+// one at a time in random order, or a set at a time to keep Options.Symmetry, as long as the solution
+// stays unique, down to Options.Clues. The puzzle can end up with more clues than that when no more
+// can go, then it is minimal. Options.Seed picks the puzzle
+func Generate(opts ...Option) (Puzzle, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return Puzzle{}, err
+	}
+	random := rand.New(rand.NewPCG(o.Seed, o.Seed))
+
+	// The diagonal boxes do not share units, so any digits can go in them, and the search fills in
+	// the rest. Not every grid is equally likely, but there are 9!^3 starts
+	var puzzle Puzzle
+	for box := range 3 {
+		for i, digit := range random.Perm(sudokuSize) {
+			puzzle[box*3+i/3][box*3+i%3] = digit + 1
+		}
+	}
+	solutions, err := Solve(puzzle, WithLogger(o.Logger))
+	if err != nil {
+		return Puzzle{}, err
+	}
+	puzzle = solutions[0]
+
+	clues := sudokuSize * sudokuSize
+	for _, cell := range random.Perm(sudokuSize * sudokuSize) {
+		orbit := o.Symmetry.Orbit(cell/sudokuSize, cell%sudokuSize)
+		if puzzle[orbit[0][0]][orbit[0][1]] == 0 || clues-len(orbit) < o.Clues {
+			continue
+		}
+		next := puzzle
+		for _, c := range orbit {
+			next[c[0]][c[1]] = 0
+		}
+		if count, err := Count(next, WithLimit(2)); err == nil && count == 1 {
+			puzzle = next
+			clues -= len(orbit)
+		}
+	}
+	return puzzle, nil
+}
+
+// How hard a puzzle is, by the search effort and by the techniques a person would need
+type Rating struct {
+	Search      analysis.SearchHardness // the backtracking search statistics, DeadEnds is the score
+	LogicSolved bool                    // the techniques of the logic engine solve the puzzle without guessing
+	Hardest     logic.Technique         // the hardest technique the logic engine used, if any
+	Level       rating.Level            // the difficulty, see the rating package
+}
+
+// Rates a puzzle with a unique solution. Returns an error when the puzzle is inconsistent and when it
+// does not have exactly one solution. Options.Seed and Options.Orders control the search orders
+func Rate(puzzle Puzzle, opts ...Option) (Rating, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return Rating{}, err
+	}
+	count, err := Count(puzzle, WithLimit(2), WithMaxIterations(o.MaxIterations))
+	if err != nil {
+		return Rating{}, err
+	}
+	if count != 1 {
+		return Rating{}, fmt.Errorf("only a puzzle with a unique solution can be rated, it has %d", count)
+	}
+	var r Rating
+	if r.Search, err = analysis.MeasureSearchHardness(puzzle, o.Orders, o.Seed); err != nil {
+		return Rating{}, err
+	}
+	result, err := logic.Solve(puzzle)
+	if err != nil {
+		return Rating{}, err
+	}
+	r.LogicSolved = result.Solved
+	r.Hardest, _ = result.Hardest()
+	if r.Level, err = rating.LevelOf(puzzle); err != nil {
+		return Rating{}, err
+	}
+	return r, nil
+}
+
+// Parses a puzzle in any of the input formats the command line reads, e.g. the inline one
+func Parse(s string) (Puzzle, error) {
+	return parseText(s)
+}
+
+// Formats the puzzle in the format with the name, see format.GetKnownFormats. Returns an error
+// for an unknown name
+func Format(puzzle Puzzle, formatName string) (string, error) {
+	if err := validate(puzzle); err != nil {
+		return "", err
+	}
+	f, err := format.Lookup(formatName)
+	if err != nil {
+		return "", err
+	}
+	return format.FormatFromTemplate(puzzle, f), nil
+}
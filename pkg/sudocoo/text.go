@@ -0,0 +1,67 @@
+package sudocoo
+
+import (
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/AndrewSav/sudocoo/pkg/format"
+	"github.com/AndrewSav/sudocoo/pkg/packed"
+	"github.com/AndrewSav/sudocoo/pkg/parser"
+)
+
+// Parses the first puzzle of the text, in any of the formats the command line reads, b64 included
+func parseText(input string) (Puzzle, error) {
+	puzzle, err := parser.ReadNextPuzzleInput(parser.CreateInputScanner(packed.Text(strings.NewReader(input))))
+	if errors.Is(err, io.EOF) {
+		return puzzle, parser.ErrNotEnoughCells
+	}
+	return puzzle, err
+}
+
+// Solves the puzzle in the text, see Parse, and returns its solution in the inline format, or in
+// Options.Format. With Options.Limit over 1 the solutions found are returned a line each, for the
+// formats that take up several lines separated by an empty line. Returns the errors Solve does
+// and an error for text without a puzzle
+func SolveString(input string, opts ...Option) (string, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return "", err
+	}
+	name := o.Format
+	if name == "" {
+		name = "inline"
+	}
+	f, err := format.Lookup(name)
+	if err != nil {
+		return "", err
+	}
+	puzzle, err := parseText(input)
+	if err != nil {
+		return "", err
+	}
+	solutions, err := Solve(puzzle, opts...)
+	if err != nil {
+		return "", err
+	}
+	separator := "\n"
+	texts := make([]string, len(solutions))
+	for i, solution := range solutions {
+		texts[i] = format.FormatFromTemplate(solution, f)
+		if strings.Contains(texts[i], "\n") {
+			separator = "\n\n"
+		}
+	}
+	return strings.Join(texts, separator), nil
+}
+
+// Counts the solutions of the puzzle in the text, see Parse, up to the limit, 0 is no limit: a
+// count equal to the limit means there may be more. Returns the errors Count does and an error
+// for text without a puzzle
+func CountSolutions(input string, limit int, opts ...Option) (int, error) {
+	puzzle, err := parseText(input)
+	if err != nil {
+		return 0, err
+	}
+	return Count(puzzle, append(opts, WithLimit(limit))...)
+}
@@ -0,0 +1,277 @@
+package variant
+
+import (
+	"fmt"
+	"math/bits"
+
+	"github.com/AndrewSav/sudocoo/pkg/solver"
+)
+
+// The puzzles are solved by the classic solver with the constraints added to it as a solver.Constraint,
+// which rules out the digits of a cell that would break a constraint on it given the cells filled in
+// so far. The constraints check partial grids soundly: a cage can still reach its sum with the digits
+// left, a thermometer has room for the digits between the filled in cells, and so on. That way the
+// search does not have to enumerate classic solutions to filter them, which would be hopeless for
+// puzzles that have few givens and rely on the constraints
+
+// A constraint compiled for the search
+type rule struct {
+	kind   string
+	cells  []Cell
+	sum    int
+	even   bool
+	custom func(grid *[sudokuSize][sudokuSize]int, cells []Cell, index, digit int, sum int) bool // Allows of a registered type
+}
+
+// A rule a cell is part of, with the position of the cell in it
+type ruleRef struct {
+	rule  *rule
+	index int
+}
+
+// The givens and the constraints of a puzzle, ready for the search
+type rules struct {
+	givens [sudokuSize][sudokuSize]int
+	byCell [sudokuSize][sudokuSize][]ruleRef
+}
+
+func compile(p *Puzzle) (*rules, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	r := &rules{}
+	r.givens, _ = p.Givens()
+	for _, c := range p.Constraints {
+		cells, _ := c.cells()
+		compiled := &rule{kind: c.Type, cells: cells, sum: c.Sum, even: c.Parity == Even, custom: constraintTypes[c.Type].Allows}
+		for i, cell := range cells {
+			r.byCell[cell.Row][cell.Column] = append(r.byCell[cell.Row][cell.Column], ruleRef{rule: compiled, index: i})
+		}
+	}
+	return r, nil
+}
+
+// Reports whether the digit can go into the empty cell of the grid
+func (r *rules) allows(grid *[sudokuSize][sudokuSize]int, y, x, digit int) bool {
+	boxY, boxX := y/3*3, x/3*3
+	for i := range sudokuSize {
+		if grid[y][i] == digit || grid[i][x] == digit || grid[boxY+i/3][boxX+i%3] == digit {
+			return false
+		}
+	}
+	return r.constraintsAllow(grid, y, x, digit)
+}
+
+// Same as allows, for the constraints only
+func (r *rules) constraintsAllow(grid *[sudokuSize][sudokuSize]int, y, x, digit int) bool {
+	for _, ref := range r.byCell[y][x] {
+		if !ref.rule.allows(grid, ref.index, digit) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the digits the constraints on the empty cell rule out, see solver.Constraint. The solver
+// keeps to the classic rules itself
+func (r *rules) RuledOut(cells *[sudokuSize][sudokuSize]int, row, column int) int {
+	if len(r.byCell[row][column]) == 0 {
+		return 0
+	}
+	grid := digits(cells)
+	ruledOut := 0
+	for digit := 1; digit <= sudokuSize; digit++ {
+		if !r.constraintsAllow(&grid, row, column, digit) {
+			ruledOut |= 1 << (digit - 1)
+		}
+	}
+	return ruledOut
+}
+
+// Returns whether the digits in the cells keep to the constraints, see solver.Constraint
+func (r *rules) Valid(cells *[sudokuSize][sudokuSize]int) bool {
+	grid := digits(cells)
+	for y := range sudokuSize {
+		for x := range sudokuSize {
+			digit := grid[y][x]
+			if digit == 0 {
+				continue
+			}
+			grid[y][x] = 0
+			allowed := r.constraintsAllow(&grid, y, x, digit)
+			grid[y][x] = digit
+			if !allowed {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Returns the digits of cells the way the solver has them, as bit masks
+func digits(cells *[sudokuSize][sudokuSize]int) (grid [sudokuSize][sudokuSize]int) {
+	for y, row := range cells {
+		for x, bit := range row {
+			if bit != 0 {
+				grid[y][x] = bits.TrailingZeros(uint(bit)) + 1
+			}
+		}
+	}
+	return
+}
+
+// Reports whether the digit can go into the cell at the index of the rule
+func (r *rule) allows(grid *[sudokuSize][sudokuSize]int, index, digit int) bool {
+	switch r.kind {
+	case Diagonal, AntiDiagonal, Region:
+		return !r.has(grid, index, digit)
+	case Cage:
+		if r.has(grid, index, digit) {
+			return false
+		}
+		if r.sum == 0 {
+			return true
+		}
+		sum, empty := digit, 0
+		for i, cell := range r.cells {
+			if v := grid[cell.Row][cell.Column]; i != index && v != 0 {
+				sum += v
+			} else if i != index {
+				empty++
+			}
+		}
+		return sum+minSum(empty) <= r.sum && r.sum <= sum+maxSum(empty)
+	case Thermo:
+		n := len(r.cells)
+		if digit < index+1 || digit > sudokuSize-(n-1-index) {
+			return false
+		}
+		for i, cell := range r.cells {
+			v := grid[cell.Row][cell.Column]
+			if i == index || v == 0 {
+				continue
+			}
+			// Every cell in between needs a digit of its own
+			if i < index && v+(index-i) > digit || i > index && digit+(i-index) > v {
+				return false
+			}
+		}
+		return true
+	case Parity:
+		return (digit%2 == 0) == r.even
+	case Sandwich:
+		return r.sandwichAllows(grid, index, digit)
+	}
+	if r.custom != nil {
+		return r.custom(grid, r.cells, index, digit, r.sum)
+	}
+	panic(fmt.Sprintf("unknown constraint type %s", r.kind))
+}
+
+// Reports whether the sandwich can still add up with the digit in the cell at the index of the line.
+// Every place the 1 and the 9 can still go to is tried, the digits between them that are not there yet
+// have to come from the ones the line does not have
+func (r *rule) sandwichAllows(grid *[sudokuSize][sudokuSize]int, index, digit int) bool {
+	var line [sudokuSize]int
+	used := 0 // the digits the line has, as bits
+	for i, cell := range r.cells {
+		line[i] = grid[cell.Row][cell.Column]
+		if i == index {
+			line[i] = digit
+		}
+		used |= 1 << line[i]
+	}
+	// The places the 1 and the 9 can go to: where they are, or else any empty cell
+	places := func(end int) []int {
+		var found []int
+		for i, v := range line {
+			if v == end {
+				return []int{i}
+			}
+			if v == 0 {
+				found = append(found, i)
+			}
+		}
+		return found
+	}
+	// The digits that can fill the empty cells in between, from the smallest
+	var free []int
+	for d := 2; d < sudokuSize; d++ {
+		if used&(1<<d) == 0 {
+			free = append(free, d)
+		}
+	}
+	for _, one := range places(1) {
+		for _, nine := range places(sudokuSize) {
+			if one == nine {
+				continue
+			}
+			sum, empty := 0, 0
+			for i := min(one, nine) + 1; i < max(one, nine); i++ {
+				sum += line[i]
+				if line[i] == 0 {
+					empty++
+				}
+			}
+			if empty > len(free) {
+				continue
+			}
+			lowest, highest := sum, sum
+			for i := range empty {
+				lowest += free[i]
+				highest += free[len(free)-1-i]
+			}
+			if lowest <= r.sum && r.sum <= highest {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Reports whether a cell of the rule other than the one at the index has the digit
+func (r *rule) has(grid *[sudokuSize][sudokuSize]int, index, digit int) bool {
+	for i, cell := range r.cells {
+		if i != index && grid[cell.Row][cell.Column] == digit {
+			return true
+		}
+	}
+	return false
+}
+
+// Finds the solutions of the puzzle, up to the limit (0 is no limit), and reports whether there
+// are more than the limit. Returns error when the puzzle is not well formed or the givens already
+// break the rules
+func (p *Puzzle) Solve(limit int) ([][sudokuSize][sudokuSize]int, bool, error) {
+	r, err := compile(p)
+	if err != nil {
+		return nil, false, err
+	}
+	var grid [sudokuSize][sudokuSize]int
+	for y := range sudokuSize {
+		for x := range sudokuSize {
+			if digit := r.givens[y][x]; digit != 0 {
+				if !r.allows(&grid, y, x, digit) {
+					return nil, false, fmt.Errorf("%w: %d in %s breaks the rules", solver.ErrInvalidPuzzle, digit, Cell{y, x})
+				}
+				grid[y][x] = digit
+			}
+		}
+	}
+	sudoku, err := solver.NewSolver(r.givens)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := sudoku.AddConstraint(r); err != nil {
+		return nil, false, err
+	}
+	if limit == 0 {
+		return sudoku.AllSolutions(0), false, nil
+	}
+	// One more than the limit tells whether there are more
+	solutions := sudoku.AllSolutions(limit + 1)
+	if len(solutions) > limit {
+		return solutions[:limit], true, nil
+	}
+	return solutions, false, nil
+}
@@ -0,0 +1,103 @@
+package variant
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/AndrewSav/sudocoo/pkg/solver"
+)
+
+func readPuzzle(t *testing.T, s string) *Puzzle {
+	t.Helper()
+	p, err := Read(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// Puzzles with their solution counts, worked out by a search that tried every digit in every cell
+// against all the rules
+var countedPuzzles = []struct {
+	name   string
+	puzzle string
+	count  int
+}{
+	{"diagonal, sandwich and cage", `{"version": 1, "grid": "....3.......6....9........49.....78..45.....1.8..95..6812....4.49........6..4....",
+		"constraints": [{"type": "diagonal"}, {"type": "sandwich", "row": 4, "sum": 12}, {"type": "cage", "cells": ["r1c2", "r1c3"], "sum": 14}]}`, 2},
+	{"anti-diagonal, thermo, parity and sandwich", `{"version": 1, "grid": ".5...........6..........1.....1..72..1362..5.....5...6.2........415....3.8..13..2",
+		"constraints": [{"type": "anti-diagonal"}, {"type": "thermo", "cells": ["r2c1", "r3c1", "r3c2", "r4c3"]},
+		{"type": "parity", "cells": ["r5c5"], "parity": "even"}, {"type": "sandwich", "column": 3, "sum": 0}]}`, 14},
+	{"few givens", `{"version": 1, "grid": ".5.2.4..8..........78........6....8...5..........95.........9.....3..56.5...4.8..",
+		"constraints": [{"type": "diagonal"}, {"type": "sandwich", "row": 4, "sum": 12}, {"type": "cage", "cells": ["r1c2", "r1c3"], "sum": 14}]}`, 25184},
+	{"classic", `{"version": 1, "grid": "4...3.......6..8..........1....5..9..8....6...7.2........1.27..5.3....4.9........"}`, 1},
+}
+
+func TestSolve(t *testing.T) {
+	data, err := os.ReadFile("../../data/variant.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := append(countedPuzzles[:len(countedPuzzles):len(countedPuzzles)], struct {
+		name   string
+		puzzle string
+		count  int
+	}{"data/variant.json", string(data), 1})
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := readPuzzle(t, test.puzzle)
+			solutions, more, err := p.Solve(0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(solutions) != test.count || more {
+				t.Fatalf("got %d solutions, more %v, want %d", len(solutions), more, test.count)
+			}
+			seen := map[[sudokuSize][sudokuSize]int]bool{}
+			for _, solution := range solutions {
+				if ok, err := p.Satisfied(solution); !ok || err != nil {
+					t.Fatalf("solution %v breaks the rules: %v", solution, err)
+				}
+				if seen[solution] {
+					t.Fatalf("solution %v found twice", solution)
+				}
+				seen[solution] = true
+			}
+		})
+	}
+}
+
+func TestSolveLimit(t *testing.T) {
+	p := readPuzzle(t, countedPuzzles[2].puzzle)
+	for _, limit := range []int{1, 100, 25184} {
+		solutions, more, err := p.Solve(limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(solutions) != limit || more != (limit < countedPuzzles[2].count) {
+			t.Errorf("limit %d: got %d solutions, more %v", limit, len(solutions), more)
+		}
+	}
+}
+
+// Givens that break the rules are reported with the cell, whichever rule they break
+func TestSolveInvalid(t *testing.T) {
+	tests := []struct {
+		puzzle string
+		want   string
+	}{
+		{`{"version": 1, "grid": "44..............................................................................."}`, "4 in r1c2 breaks the rules"},
+		{`{"version": 1, "grid": "4................................................................................",
+			"constraints": [{"type": "parity", "cells": ["r1c1"], "parity": "odd"}]}`, "4 in r1c1 breaks the rules"},
+		{`{"version": 1, "grid": "1...............................................................................1",
+			"constraints": [{"type": "diagonal"}]}`, "1 in r9c9 breaks the rules"},
+	}
+	for _, test := range tests {
+		_, _, err := readPuzzle(t, test.puzzle).Solve(1)
+		if !errors.Is(err, solver.ErrInvalidPuzzle) || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("got error %v, want %q", err, test.want)
+		}
+	}
+}
@@ -0,0 +1,307 @@
+// Package variant reads and writes variant puzzles: a classic grid of givens bundled with extra
+// constraints (diagonals, cages, regions, thermometers, parity cells and sandwich sums) in a single JSON file, so that
+// the same file can be exchanged, solved and printed. A file looks like this:
+//
+//	{
+//	  "version": 1,
+//	  "grid": "4...3.......6..8..........1....5..9..8....6...7.2........1.27..5.3....4.9........",
+//	  "constraints": [
+//	    {"type": "diagonal"},
+//	    {"type": "cage", "cells": ["r1c2", "r1c3"], "sum": 14},
+//	    {"type": "thermo", "cells": ["r2c1", "r3c1", "r3c2"]},
+//	    {"type": "parity", "cells": ["r5c5"], "parity": "even"},
+//	    {"type": "sandwich", "row": 4, "sum": 12}
+//	  ]
+//	}
+//
+// The grid is in the inline format, '.' or '0' for an empty cell. Cells are written as r1c1 to r9c9,
+// rows and columns as 1 to 9.
+// YAML is not supported, reading it would need a third party parser, but a JSON file is valid YAML,
+// so YAML tools can produce and consume these files.
+package variant
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/AndrewSav/sudocoo/pkg/parser"
+)
+
+const sudokuSize = 9
+
+// The version of the file format this package reads and writes
+const Version = 1
+
+// The constraint types
+const (
+	Diagonal     = "diagonal"      // the cells of the main diagonal, r1c1 to r9c9, have different digits
+	AntiDiagonal = "anti-diagonal" // the cells of the other diagonal, r1c9 to r9c1, have different digits
+	Region       = "region"        // the cells have different digits, a region of 9 cells has all of them
+	Cage         = "cage"          // the cells have different digits that add up to the sum, if there is one
+	Thermo       = "thermo"        // the digits increase along the cells, from the bulb which is the first cell
+	Parity       = "parity"        // the cells have even or odd digits
+	Sandwich     = "sandwich"      // the digits between the 1 and the 9 of the row or the column add up to the sum
+)
+
+// A constraint type another package adds, such as a plugin. Its constraints have cells and may have
+// a sum, what the sum means is up to the type
+type ConstraintType struct {
+	Name string
+	// Checks the constraint beyond its cells, which are valid and different. Nil accepts any
+	Validate func(c Constraint, cells []Cell) error
+	// Reports whether the digit can go into the cell at the index of the cells, given the digits the
+	// other cells of the grid have so far, 0 in the empty ones. It must only say no when the digit can
+	// never work out, whatever goes into the empty cells
+	Allows func(grid *[sudokuSize][sudokuSize]int, cells []Cell, index, digit int, sum int) bool
+}
+
+var constraintTypes = map[string]ConstraintType{}
+
+// Adds a constraint type. Call it from init, the built-in types cannot be replaced
+func RegisterConstraint(t ConstraintType) {
+	if slices.Contains(builtinTypes, t.Name) {
+		panic(fmt.Sprintf("constraint type %s is built in", t.Name))
+	}
+	constraintTypes[t.Name] = t
+}
+
+var builtinTypes = []string{Diagonal, AntiDiagonal, Region, Cage, Thermo, Parity, Sandwich}
+
+// Returns the names of the constraint types, the built-in ones first
+func ConstraintTypes() []string {
+	return append(slices.Clone(builtinTypes), slices.Sorted(maps.Keys(constraintTypes))...)
+}
+
+// The parity constraint values
+const (
+	Even = "even"
+	Odd  = "odd"
+)
+
+// A variant puzzle as it is stored in a file
+type Puzzle struct {
+	Version     int          `json:"version"`
+	Grid        string       `json:"grid"`
+	Constraints []Constraint `json:"constraints,omitempty"`
+}
+
+// A constraint of a variant puzzle, which fields are used depends on the type
+type Constraint struct {
+	Type   string   `json:"type"`
+	Cells  []string `json:"cells,omitempty"`  // all the types but the diagonals and the sandwiches
+	Sum    int      `json:"sum,omitempty"`    // cage, 0 for a cage without a sum, and sandwich, 0 for a 1 and a 9 side by side
+	Parity string   `json:"parity,omitempty"` // parity only, even or odd
+	Row    int      `json:"row,omitempty"`    // sandwich only, 1 to 9, the row the sum is for, or else
+	Column int      `json:"column,omitempty"` // the column
+}
+
+// A cell position, 0-based
+type Cell struct {
+	Row, Column int
+}
+
+func (c Cell) String() string {
+	return fmt.Sprintf("r%dc%d", c.Row+1, c.Column+1)
+}
+
+// Parses a cell written as r1c1 to r9c9
+func ParseCell(s string) (Cell, error) {
+	if len(s) != 4 || strings.ToLower(s[:1]) != "r" || strings.ToLower(s[2:3]) != "c" ||
+		s[1] < '1' || s[1] > '9' || s[3] < '1' || s[3] > '9' {
+		return Cell{}, fmt.Errorf("invalid cell %q, want r1c1 to r9c9", s)
+	}
+	return Cell{Row: int(s[1] - '1'), Column: int(s[3] - '1')}, nil
+}
+
+// Reads a variant puzzle and checks that it is well formed, see Validate
+func Read(r io.Reader) (*Puzzle, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	var p Puzzle
+	if err := decoder.Decode(&p); err != nil {
+		return nil, fmt.Errorf("invalid variant puzzle: %w", err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Writes the puzzle as indented JSON
+func (p *Puzzle) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(p)
+}
+
+// Returns the givens of the grid
+func (p *Puzzle) Givens() ([sudokuSize][sudokuSize]int, error) {
+	givens, err := parser.ParsePuzzleString(p.Grid)
+	if err != nil {
+		return givens, fmt.Errorf("invalid grid: %w", err)
+	}
+	return givens, nil
+}
+
+// Checks that the puzzle is well formed: a known version, a grid of 81 cells and constraints with
+// valid cells and the fields their types need. It does not check that the puzzle has a solution
+func (p *Puzzle) Validate() error {
+	if p.Version != Version {
+		return fmt.Errorf("unsupported version %d, want %d", p.Version, Version)
+	}
+	if _, err := p.Givens(); err != nil {
+		return err
+	}
+	for i, c := range p.Constraints {
+		if err := c.validate(); err != nil {
+			return fmt.Errorf("constraint %d (%s): %w", i+1, c.Type, err)
+		}
+	}
+	return nil
+}
+
+func (c *Constraint) validate() error {
+	switch c.Type {
+	case Diagonal, AntiDiagonal:
+		if len(c.Cells) != 0 {
+			return fmt.Errorf("a diagonal has no cells of its own")
+		}
+	case Sandwich:
+		if len(c.Cells) != 0 {
+			return fmt.Errorf("a sandwich has no cells of its own, it has a row or a column")
+		}
+		if (c.Row == 0) == (c.Column == 0) || c.Row < 0 || c.Row > sudokuSize || c.Column < 0 || c.Column > sudokuSize {
+			return fmt.Errorf("invalid row %d and column %d, want either a row or a column, 1 to %d", c.Row, c.Column, sudokuSize)
+		}
+	case Region, Cage, Thermo, Parity:
+		if len(c.Cells) == 0 {
+			return fmt.Errorf("no cells")
+		}
+	default:
+		if _, ok := constraintTypes[c.Type]; !ok {
+			return fmt.Errorf("unknown type, want one of %s", strings.Join(ConstraintTypes(), ", "))
+		}
+		if len(c.Cells) == 0 {
+			return fmt.Errorf("no cells")
+		}
+	}
+	cells, err := c.cells()
+	if err != nil {
+		return err
+	}
+	seen := map[Cell]bool{}
+	for _, cell := range cells {
+		if seen[cell] {
+			return fmt.Errorf("%s is there twice", cell)
+		}
+		seen[cell] = true
+	}
+	t, registered := constraintTypes[c.Type]
+	if c.Type != Cage && c.Type != Sandwich && !registered && c.Sum != 0 {
+		return fmt.Errorf("only a cage or a sandwich has a sum")
+	}
+	if c.Type != Sandwich && (c.Row != 0 || c.Column != 0) {
+		return fmt.Errorf("only a sandwich has a row or a column")
+	}
+	if c.Type != Parity && c.Parity != "" {
+		return fmt.Errorf("only a parity constraint has a parity")
+	}
+	switch c.Type {
+	case Region, Cage:
+		if len(cells) > sudokuSize {
+			return fmt.Errorf("%d cells, cannot have more than %d different digits", len(cells), sudokuSize)
+		}
+		if lowest, highest := minSum(len(cells)), maxSum(len(cells)); c.Type == Cage && c.Sum != 0 && (c.Sum < lowest || c.Sum > highest) {
+			return fmt.Errorf("%d different digits cannot add up to %d, want %d to %d", len(cells), c.Sum, lowest, highest)
+		}
+	case Thermo:
+		if len(cells) > sudokuSize {
+			return fmt.Errorf("%d cells, the digits cannot increase along more than %d", len(cells), sudokuSize)
+		}
+		for i := 1; i < len(cells); i++ {
+			if dy, dx := cells[i].Row-cells[i-1].Row, cells[i].Column-cells[i-1].Column; dy < -1 || dy > 1 || dx < -1 || dx > 1 {
+				return fmt.Errorf("%s does not touch %s, a thermometer is a path of touching cells", cells[i], cells[i-1])
+			}
+		}
+	case Parity:
+		if c.Parity != Even && c.Parity != Odd {
+			return fmt.Errorf("invalid parity %q, want %s or %s", c.Parity, Even, Odd)
+		}
+	case Sandwich:
+		// All the digits but the 1 and the 9
+		if highest := minSum(sudokuSize) - 1 - sudokuSize; c.Sum < 0 || c.Sum > highest {
+			return fmt.Errorf("invalid sum %d, the digits between the 1 and the 9 add up to 0 to %d", c.Sum, highest)
+		}
+	default:
+		if t.Validate != nil {
+			return t.Validate(*c, cells)
+		}
+	}
+	return nil
+}
+
+// Returns the cells of the constraint, the diagonals and the sandwiches have theirs implied, a
+// sandwich the cells of its row from left to right or of its column from top to bottom
+func (c *Constraint) cells() ([]Cell, error) {
+	switch c.Type {
+	case Diagonal, AntiDiagonal:
+		cells := make([]Cell, sudokuSize)
+		for i := range cells {
+			cells[i] = Cell{Row: i, Column: i}
+			if c.Type == AntiDiagonal {
+				cells[i].Column = sudokuSize - 1 - i
+			}
+		}
+		return cells, nil
+	case Sandwich:
+		cells := make([]Cell, sudokuSize)
+		for i := range cells {
+			if c.Row != 0 {
+				cells[i] = Cell{Row: c.Row - 1, Column: i}
+			} else {
+				cells[i] = Cell{Row: i, Column: c.Column - 1}
+			}
+		}
+		return cells, nil
+	}
+	cells := make([]Cell, len(c.Cells))
+	for i, s := range c.Cells {
+		cell, err := ParseCell(s)
+		if err != nil {
+			return nil, err
+		}
+		cells[i] = cell
+	}
+	return cells, nil
+}
+
+// Returns the smallest and the largest sums of n different digits
+func minSum(n int) int { return n * (n + 1) / 2 }
+func maxSum(n int) int { return n * (2*sudokuSize + 1 - n) / 2 }
+
+// Reports whether a complete grid satisfies the classic rules, the givens and all the constraints
+func (p *Puzzle) Satisfied(grid [sudokuSize][sudokuSize]int) (bool, error) {
+	rules, err := compile(p)
+	if err != nil {
+		return false, err
+	}
+	for y := range sudokuSize {
+		for x := range sudokuSize {
+			digit := grid[y][x]
+			if digit < 1 || digit > sudokuSize || rules.givens[y][x] != 0 && rules.givens[y][x] != digit {
+				return false, nil
+			}
+			grid[y][x] = 0
+			allowed := rules.allows(&grid, y, x, digit)
+			grid[y][x] = digit
+			if !allowed {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,194 @@
+// Package webhook delivers signed JSON notifications. The receiver checks the signature
+// to know the notification comes from a server that shares its secret:
+//
+//	X-Sudocoo-Signature: sha256=HEX
+//
+// where HEX is the HMAC-SHA256 of the request body keyed with the secret
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const SignatureHeader = "X-Sudocoo-Signature"
+
+const signaturePrefix = "sha256="
+
+// Attempts before a delivery is given up
+const maxAttempts = 3
+
+// Returns the value of the signature header for the body
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Checks the value of the signature header against the body, in constant time
+func Verify(secret, body []byte, signature string) bool {
+	sum, err := hex.DecodeString(strings.TrimPrefix(signature, signaturePrefix))
+	if err != nil || !strings.HasPrefix(signature, signaturePrefix) {
+		return false
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hmac.Equal(sum, mac.Sum(nil))
+}
+
+type Sender struct {
+	secret []byte
+	client *http.Client
+}
+
+// Returns a sender signing with the secret. Unless allowPrivate is set, it only calls public
+// addresses, see NewClient
+func NewSender(secret string, allowPrivate bool) *Sender {
+	return &Sender{secret: []byte(secret), client: NewClient(10*time.Second, allowPrivate)}
+}
+
+var errPrivate = errors.New("loopback, private and link-local addresses are not allowed")
+
+// The networks a URL given by a client must not lead to: this host and the networks it may be on,
+// such as a cloud metadata service at 169.254.169.254, along with the IPv6 prefixes that carry an
+// IPv4 address, which may be one of those
+var deniedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),      // this network
+	netip.MustParsePrefix("10.0.0.0/8"),     // private
+	netip.MustParsePrefix("100.64.0.0/10"),  // carrier-grade NAT
+	netip.MustParsePrefix("127.0.0.0/8"),    // loopback
+	netip.MustParsePrefix("169.254.0.0/16"), // link-local
+	netip.MustParsePrefix("172.16.0.0/12"),  // private
+	netip.MustParsePrefix("192.0.0.0/24"),   // IETF protocol assignments
+	netip.MustParsePrefix("192.168.0.0/16"), // private
+	netip.MustParsePrefix("198.18.0.0/15"),  // benchmarking
+	netip.MustParsePrefix("224.0.0.0/4"),    // multicast
+	netip.MustParsePrefix("240.0.0.0/4"),    // reserved, and the broadcast address
+	netip.MustParsePrefix("::/96"),          // unspecified, loopback and IPv4-compatible
+	netip.MustParsePrefix("64:ff9b::/96"),   // NAT64
+	netip.MustParsePrefix("64:ff9b:1::/48"), // local NAT64
+	netip.MustParsePrefix("2001::/32"),      // Teredo
+	netip.MustParsePrefix("2002::/16"),      // 6to4
+	netip.MustParsePrefix("fc00::/7"),       // unique local
+	netip.MustParsePrefix("fe80::/10"),      // link-local
+	netip.MustParsePrefix("ff00::/8"),       // multicast
+}
+
+// Whether the address is one a URL given by a client must not lead to, see deniedPrefixes.
+// IPv4-mapped IPv6 addresses are checked as the IPv4 addresses they are
+func isPrivate(ip net.IP) bool {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return true
+	}
+	addr = addr.Unmap()
+	for _, prefix := range deniedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Checks that a URL given by a client is one we are willing to call: an http or https URL with a host.
+// Unless allowPrivate is set, none of the addresses of the host may be private, see isPrivate, which
+// takes a DNS lookup. The host may resolve to another address when it is called, so the URL has
+// to be called with a client from NewClient all the same
+func ValidateURL(ctx context.Context, rawURL string, allowPrivate bool) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("invalid URL %q, want an http or https URL", rawURL)
+	}
+	if allowPrivate {
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return fmt.Errorf("invalid URL %q: %w", rawURL, err)
+	}
+	for _, addr := range addrs {
+		if isPrivate(addr.IP) {
+			return fmt.Errorf("invalid URL %q: %s: %w", rawURL, addr.IP, errPrivate)
+		}
+	}
+	return nil
+}
+
+// Returns a client for the URLs given by clients. Unless allowPrivate is set, it checks every
+// address it connects to, redirects included, and refuses private ones, so that a host cannot
+// pass ValidateURL with a public address and then be called at a private one. It ignores the
+// proxy settings of the environment, a proxy would connect on its behalf
+func NewClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isPrivate(ip) {
+				return fmt.Errorf("dial %s: %w", address, errPrivate)
+			}
+			return nil
+		}
+	}
+	transport := &http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: timeout,
+		MaxIdleConns:        10,
+		IdleConnTimeout:     90 * time.Second,
+	}
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// Posts the payload as JSON to the URL. Network errors and 5xx responses are retried
+// with a growing delay, any 2xx response is a successful delivery
+func (s *Sender) Send(ctx context.Context, url string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	signature := Sign(s.secret, body)
+	delay := time.Second
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(SignatureHeader, signature)
+		resp, err := s.client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode < 300 {
+				return nil
+			}
+			err = fmt.Errorf("webhook %s returned %s", url, resp.Status)
+			if resp.StatusCode < 500 {
+				return err
+			}
+		}
+		if attempt == maxAttempts {
+			return err
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return err
+		}
+		delay *= 2
+	}
+}
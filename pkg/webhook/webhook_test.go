@@ -0,0 +1,173 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestValidateURL(t *testing.T) {
+	tests := []struct {
+		url          string
+		allowPrivate bool
+		valid        bool
+	}{
+		// Literal addresses, so that no DNS lookup is needed for them
+		{"http://93.184.216.34/hook", false, true},
+		{"https://93.184.216.34:8443/hook?job=1", false, true},
+		{"http://[2606:2800:220:1:248:1893:25c8:1946]/hook", false, true},
+		{"http://127.0.0.1/hook", false, false},
+		{"http://127.0.0.2:8080/hook", false, false},
+		{"http://[::1]/hook", false, false},
+		{"http://[::ffff:127.0.0.1]/hook", false, false},
+		{"http://10.1.2.3/hook", false, false},
+		{"http://172.16.0.1/hook", false, false},
+		{"http://192.168.1.1/hook", false, false},
+		{"http://[fd00::1]/hook", false, false},
+		{"http://169.254.169.254/latest/meta-data/", false, false},
+		{"http://[fe80::1]/hook", false, false},
+		{"http://0.0.0.0/hook", false, false},
+		{"http://localhost/hook", false, false},
+		{"http://127.0.0.1/hook", true, true},
+		{"http://localhost:8080/hook", true, true},
+		{"ftp://93.184.216.34/hook", false, false},
+		{"93.184.216.34/hook", false, false},
+		{"http:///hook", false, false},
+		{"http://%zz/hook", false, false},
+		{"", true, false},
+	}
+	for _, test := range tests {
+		err := ValidateURL(context.Background(), test.url, test.allowPrivate)
+		if (err == nil) != test.valid {
+			t.Errorf("ValidateURL(%q, %v) = %v, want valid %v", test.url, test.allowPrivate, err, test.valid)
+		}
+	}
+}
+
+func TestIsPrivate(t *testing.T) {
+	tests := []struct {
+		ip      string
+		private bool
+	}{
+		{"93.184.216.34", false},
+		{"8.8.8.8", false},
+		{"2606:2800:220:1:248:1893:25c8:1946", false},
+		{"127.0.0.1", true},
+		{"10.0.0.1", true},
+		{"172.31.255.255", true},
+		{"172.32.0.1", false},
+		{"192.168.0.1", true},
+		{"169.254.169.254", true},
+		{"0.0.0.0", true},
+		{"255.255.255.255", true},
+		{"224.0.0.1", true},
+		{"198.18.0.1", true},
+		// Carrier-grade NAT
+		{"100.64.0.1", true},
+		{"100.127.255.255", true},
+		{"100.63.255.255", false},
+		{"100.128.0.1", false},
+		// IPv4-mapped, the way they are parsed and the way they are written out
+		{"::ffff:127.0.0.1", true},
+		{"::ffff:10.0.0.1", true},
+		{"::ffff:100.64.0.1", true},
+		{"::ffff:93.184.216.34", false},
+		{"0:0:0:0:0:ffff:7f00:1", true},
+		// IPv4-compatible, 6to4, NAT64 and Teredo addresses carry an IPv4 address
+		{"::7f00:1", true},
+		{"2002:7f00:1::", true},
+		{"2002:a00:1::1", true},
+		{"64:ff9b::7f00:1", true},
+		{"64:ff9b::a00:1", true},
+		{"64:ff9b:1::a00:1", true},
+		{"2001:0:4136:e378:8000:63bf:3fff:fdd2", true},
+		{"::", true},
+		{"::1", true},
+		{"fd00::1", true},
+		{"fe80::1", true},
+		{"ff02::1", true},
+	}
+	for _, test := range tests {
+		ip := net.ParseIP(test.ip)
+		if ip == nil {
+			t.Fatalf("invalid address %s", test.ip)
+		}
+		if got := isPrivate(ip); got != test.private {
+			t.Errorf("isPrivate(%s) = %v, want %v", test.ip, got, test.private)
+		}
+		// Addresses come as 16 bytes from net.ParseIP, and as 4 from the resolver for IPv4
+		if ip4 := ip.To4(); ip4 != nil && isPrivate(ip4) != test.private {
+			t.Errorf("isPrivate(%s) as 4 bytes = %v, want %v", test.ip, !test.private, test.private)
+		}
+	}
+	if !isPrivate(nil) {
+		t.Error("isPrivate(nil) = false, want an address that cannot be read refused")
+	}
+}
+
+// The client checks the address it connects to, whatever the URL was when it was validated
+func TestClientRefusesPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	_, err := NewClient(time.Second, false).Get(server.URL)
+	if !errors.Is(err, errPrivate) {
+		t.Errorf("got %v calling %s, want an error for a private address", err, server.URL)
+	}
+	resp, err := NewClient(time.Second, true).Get(server.URL)
+	if err != nil {
+		t.Fatalf("got %v calling %s with private addresses allowed", err, server.URL)
+	}
+	resp.Body.Close()
+}
+
+// A redirect to a private address is refused as well
+func TestClientRefusesRedirectsToPrivateAddresses(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer target.Close()
+	client := NewClient(time.Second, false)
+	// The redirecting server is on the loopback interface too, so the client is made to skip the check
+	// for the first request only
+	redirect := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	defer redirect.Close()
+	client.Transport = &firstAllowed{private: NewClient(time.Second, true).Transport, guarded: client.Transport}
+
+	_, err := client.Get(redirect.URL)
+	if !errors.Is(err, errPrivate) {
+		t.Errorf("got %v following a redirect to %s, want an error for a private address", err, target.URL)
+	}
+}
+
+// Sends the first request with a transport that allows private addresses and the rest with a guarded one
+type firstAllowed struct {
+	private, guarded http.RoundTripper
+	sent             bool
+}
+
+func (f *firstAllowed) RoundTrip(r *http.Request) (*http.Response, error) {
+	if !f.sent {
+		f.sent = true
+		return f.private.RoundTrip(r)
+	}
+	return f.guarded.RoundTrip(r)
+}
+
+func TestSignature(t *testing.T) {
+	secret, body := []byte("secret"), []byte(`{"id":"1"}`)
+	signature := Sign(secret, body)
+	if !Verify(secret, body, signature) {
+		t.Errorf("Verify rejects the signature %s of Sign", signature)
+	}
+	for _, bad := range []string{"", signature[len(signaturePrefix):], signature[:len(signature)-1] + "0", "sha256=zz"} {
+		if bad != signature && Verify(secret, body, bad) {
+			t.Errorf("Verify accepts %q", bad)
+		}
+	}
+	if Verify([]byte("other"), body, signature) {
+		t.Errorf("Verify accepts a signature made with another secret")
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+)
+
+// Where to write the profiles to, empty for no profile
+type profileFlags struct {
+	CPUProfile string
+	MemProfile string
+	Trace      string
+}
+
+// Starts the CPU profile and the execution trace as requested. The returned function stops
+// them and writes the heap profile, it has to be called before the program exits
+func startProfiling(flags profileFlags) (func(), error) {
+	var stops []func()
+	stop := func() {
+		for i := len(stops) - 1; i >= 0; i-- {
+			stops[i]()
+		}
+	}
+	if flags.CPUProfile != "" {
+		f, err := os.Create(flags.CPUProfile)
+		if err != nil {
+			return nil, err
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return nil, err
+		}
+		stops = append(stops, func() {
+			pprof.StopCPUProfile()
+			f.Close()
+		})
+	}
+	if flags.Trace != "" {
+		f, err := os.Create(flags.Trace)
+		if err != nil {
+			stop()
+			return nil, err
+		}
+		if err := trace.Start(f); err != nil {
+			f.Close()
+			stop()
+			return nil, err
+		}
+		stops = append(stops, func() {
+			trace.Stop()
+			f.Close()
+		})
+	}
+	if flags.MemProfile != "" {
+		stops = append(stops, func() {
+			if err := writeHeapProfile(flags.MemProfile); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			}
+		})
+	}
+	return stop, nil
+}
+
+// Writes the heap profile as of the end of the run
+func writeHeapProfile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	// Get up-to-date statistics
+	runtime.GC()
+	return pprof.WriteHeapProfile(f)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/AndrewSav/sudocoo/pkg/analysis"
+	"github.com/AndrewSav/sudocoo/pkg/solver"
+)
+
+// Looks for two solutions of the puzzle for '--proof'. A unique puzzle gets its solution printed as it
+// would be without the flag, a puzzle with more gets the proof that it is not unique: two of its solutions
+// and the cells where they differ. The second solution is picked so that those cells are a deadly pattern,
+// see analysis.DeadlyPattern, one of them has to become a clue to fix the puzzle
+func solveProof(flags *Flags, s *solver.Solver, r *puzzleResult) error {
+	var solutions [][9][9]int
+	for len(solutions) < 2 && s.Solve() {
+		r.addSolution(flags, s.Iterations())
+		solutions = append(solutions, s.Solution())
+	}
+	r.count = len(solutions)
+	// Two solutions are all the proof needs, there may well be more
+	r.limitHit = r.count == 2
+	if flags.ShowStats && flags.Quiet {
+		return nil
+	}
+	var text string
+	var err error
+	switch r.count {
+	case 0:
+		return nil
+	case 1:
+		text, err = formatSolution(*flags, solver.NewSolution(solutions[0], r.puzzle))
+	case 2:
+		second := analysis.DeadlyPattern(solutions[0], solutions[1])
+		text, err = formatProof(*flags, solutions[0], second)
+	}
+	if err != nil {
+		return err
+	}
+	r.records = append(r.records, formatRecord(flags, text))
+	return nil
+}
+
+// Formats two solutions of a puzzle with a line listing the cells where they differ. With colors enabled
+// those cells are the ones highlighted in the solutions, rather than all the solved ones
+func formatProof(flags Flags, first, second [9][9]int) (string, error) {
+	var differ []string
+	var a, b solver.Solution
+	for y := range 9 {
+		for x := range 9 {
+			same := first[y][x] == second[y][x]
+			if !same {
+				differ = append(differ, fmt.Sprintf("r%dc%d %d/%d", y+1, x+1, first[y][x], second[y][x]))
+			}
+			a[y][x] = solver.Cell{Digit: first[y][x], Given: same}
+			b[y][x] = solver.Cell{Digit: second[y][x], Given: same}
+		}
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Not unique, two solutions differ in a deadly pattern of %d cells: %s\n", len(differ), strings.Join(differ, ", "))
+	text, err := formatSolution(flags, a)
+	if err != nil {
+		return "", err
+	}
+	sb.WriteString(text)
+	sb.WriteString("\n")
+	if text, err = formatSolution(flags, b); err != nil {
+		return "", err
+	}
+	if strings.Contains(text, "\n") {
+		// Grids that take several lines are told apart by an empty line
+		sb.WriteString("\n")
+	}
+	sb.WriteString(text)
+	return sb.String(), nil
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/AndrewSav/sudocoo/pkg/batch"
+	"github.com/AndrewSav/sudocoo/pkg/format"
+	"github.com/AndrewSav/sudocoo/pkg/parser"
+	"github.com/AndrewSav/sudocoo/pkg/qr"
+)
+
+type qrFlags struct {
+	InputFile  string // input can come from a file
+	Input      string // or form a string
+	URL        string // template of the text to encode
+	Format     string // text, png or svg
+	OutputFile string // template of the file name of each code, for png and svg
+	Scale      int    // pixels per module of png
+	Level      string // error correction level
+}
+
+var qrFormats = []string{"text", "png", "svg"}
+
+// What the URL and the file name templates of a puzzle are made from
+type qrData struct {
+	Index  int    // 0-based position of the puzzle in the input
+	Label  string // 1-based number of the puzzle
+	Puzzle string // inline format
+}
+
+// A puzzle with the text its code holds and the name of the file to write it to
+type qrPuzzle struct {
+	text string
+	path string
+}
+
+// Encodes each puzzle, or a URL made from it, as a QR code, so that a printed puzzle can link back
+// to an online solver or to itself. Codes are printed to the terminal or written to a file each
+func runQR(args []string) {
+	var flags qrFlags
+
+	fs := flag.NewFlagSet("qr", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Println("Encodes puzzles, or URLs made from them, as QR codes")
+		fmt.Printf("Usage: %s qr [FLAGS...]\n", filepath.Base(os.Args[0]))
+		fmt.Println("Flags:")
+		fs.PrintDefaults()
+	}
+	addInputFlags(fs, &flags.InputFile, &flags.Input)
+	fs.StringVar(&flags.URL, "url", "{{.Puzzle}}", "Go text/template of the text to encode, e.g. 'https://example.com/solve?p={{.Puzzle}}'. The fields are .Index (0-based), .Label (the 1-based number of the puzzle) and .Puzzle (inline)")
+	fs.StringVar(&flags.Format, "format", "text", fmt.Sprintf("output format: %s. text is printed, the others are written to the files '-o' names", strings.Join(qrFormats, ", ")))
+	fs.StringVar(&flags.OutputFile, "o", "", "Go text/template of the file to write each code to, with the same fields as '-url', e.g. 'puzzle-{{.Label}}.png'. Required for png and svg")
+	fs.IntVar(&flags.Scale, "scale", 8, "pixels per module of png")
+	fs.StringVar(&flags.Level, "level", "M", "error correction level: L, M, Q or H")
+	parseFlags(fs, args)
+
+	if !slices.Contains(qrFormats, flags.Format) {
+		fmt.Printf("invalid format %s, want one of %s\n", flags.Format, strings.Join(qrFormats, ", "))
+		fs.Usage()
+		os.Exit(2)
+	}
+	if flags.Format != "text" && flags.OutputFile == "" {
+		fmt.Printf("format %s needs '-o'\n", flags.Format)
+		fs.Usage()
+		os.Exit(2)
+	}
+	if flags.Scale < 1 {
+		fmt.Printf("invalid scale %d, want at least 1\n", flags.Scale)
+		fs.Usage()
+		os.Exit(2)
+	}
+	level, err := qr.ParseLevel(flags.Level)
+	if err != nil {
+		fmt.Println(err)
+		fs.Usage()
+		os.Exit(2)
+	}
+	url, err := template.New("url").Parse(flags.URL)
+	if err != nil {
+		fmt.Println(err)
+		fs.Usage()
+		os.Exit(2)
+	}
+	path, err := template.New("o").Parse(flags.OutputFile)
+	if err != nil {
+		fmt.Println(err)
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	out := newOutput(false)
+	defer out.Flush()
+
+	scanner := parser.CreateInputScanner(openInput(fs, flags.InputFile, flags.Input))
+	next := func() ([9][9]int, error) { return parser.ReadNextPuzzleInput(scanner) }
+	expand := func(_ context.Context, puzzle [9][9]int) (qrData, error) {
+		return qrData{Puzzle: format.Inline(puzzle)}, nil
+	}
+	stats, err := batch.Run(context.Background(), batch.Config{}, next, expand, func(item batch.Item[qrData]) bool {
+		data := item.Value
+		data.Index = item.Index
+		data.Label = strconv.Itoa(item.Index + 1)
+		p, err := expandQR(url, path, data)
+		if err != nil {
+			out.fail(err)
+		}
+		code, err := qr.Encode(p.text, level)
+		if err != nil {
+			out.fail(fmt.Errorf("puzzle %s: %w", data.Label, err))
+		}
+		switch flags.Format {
+		case "text":
+			fmt.Fprintln(out, p.text)
+			out.WriteString(code.Text())
+		case "png":
+			err = writeFile(p.path, func(w io.Writer) error { return png.Encode(w, code.Image(flags.Scale)) })
+		case "svg":
+			err = writeFile(p.path, func(w io.Writer) error {
+				_, err := io.WriteString(w, code.SVG())
+				return err
+			})
+		}
+		if err != nil {
+			out.fail(err)
+		}
+		if flags.Format != "text" {
+			fmt.Fprintln(out, p.path)
+		}
+		out.endRecord()
+		return true
+	})
+	if err != nil {
+		out.fail(err)
+	}
+	if stats.Items == 0 {
+		// Not a single puzzle in the input
+		out.fail(io.EOF)
+	}
+}
+
+// Executes the templates of the text to encode and of the file name
+func expandQR(url, path *template.Template, data qrData) (qrPuzzle, error) {
+	var text, name strings.Builder
+	if err := url.Execute(&text, data); err != nil {
+		return qrPuzzle{}, err
+	}
+	if err := path.Execute(&name, data); err != nil {
+		return qrPuzzle{}, err
+	}
+	return qrPuzzle{text: text.String(), path: name.String()}, nil
+}
+
+// Creates the file and writes to it, a code or a formula
+func writeFile(path string, write func(w io.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
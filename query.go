@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/AndrewSav/sudocoo/pkg/format"
+	"github.com/AndrewSav/sudocoo/pkg/parser"
+	"github.com/AndrewSav/sudocoo/pkg/rating"
+	"github.com/AndrewSav/sudocoo/pkg/store"
+)
+
+type queryFlags struct {
+	Database     string // puzzle store to query
+	MinClues     int
+	MaxClues     int
+	Solutions    int    // -1 for any
+	Rating       string // empty for any
+	Source       string // empty for any
+	Limit        int    // the maximum number of puzzles to print
+	OutputFormat string // how to print out the puzzles
+	JSON         bool   // print whole records instead of puzzles
+}
+
+// Prints the puzzles from the store that match the criteria
+func runQuery(args []string) {
+	var flags queryFlags
+
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Println("Selects puzzles from a puzzle store")
+		fmt.Printf("Usage: %s query -db FILE [FLAGS...]\n", filepath.Base(os.Args[0]))
+		fmt.Println("Flags:")
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&flags.Database, "db", "", "puzzle store to query")
+	fs.IntVar(&flags.MinClues, "min-clues", 0, "only puzzles with at least this many clues")
+	fs.IntVar(&flags.MaxClues, "max-clues", 0, "only puzzles with at most this many clues. 0 is no limit")
+	fs.IntVar(&flags.Solutions, "solutions", store.AnySolutions, fmt.Sprintf("only puzzles with this many solutions: 0, 1 or %d for multiple. -1 is any. Default: -1", store.MultipleSolutions))
+	fs.StringVar(&flags.Rating, "rating", "", "only puzzles with this rating: "+strings.Join(rating.LevelNames(), ", ")+" for the puzzles rated here, which are the ones with a unique solution, or a rating imported with them")
+	fs.StringVar(&flags.Source, "source", "", "only puzzles from this source")
+	fs.IntVar(&flags.Limit, "limit", 0, "print no more than this many puzzles. 0 is no limit")
+	fs.StringVar(&flags.OutputFormat, "v", "inline", fmt.Sprintf("output format for puzzles: %s. Default: inline", getAvailableFormats()))
+	fs.BoolVar(&flags.JSON, "json", false, "print the whole records as JSON lines instead of the puzzles")
+	parseFlags(fs, args)
+
+	if flags.Database == "" {
+		fmt.Println("you have to specify the store with -db")
+		fs.Usage()
+		os.Exit(2)
+	}
+	if _, err := format.Lookup(flags.OutputFormat); err != nil {
+		fmt.Printf("invalid output format %s\n", flags.OutputFormat)
+		fs.Usage()
+		os.Exit(exitCode(err))
+	}
+	if _, err := os.Stat(flags.Database); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	out := newOutput(false)
+	defer out.Flush()
+
+	db, err := store.Open(flags.Database)
+	if err != nil {
+		out.fail(err)
+	}
+	defer db.Close()
+
+	records, err := db.Query(store.Query{
+		MinClues:  flags.MinClues,
+		MaxClues:  flags.MaxClues,
+		Solutions: flags.Solutions,
+		Rating:    flags.Rating,
+		Source:    flags.Source,
+		Limit:     flags.Limit,
+	})
+	if err != nil {
+		out.fail(err)
+	}
+	for _, r := range records {
+		if flags.JSON {
+			data, _ := json.Marshal(r)
+			fmt.Fprintf(out, "%s\n", data)
+			continue
+		}
+		puzzle, err := parser.ParsePuzzleString(r.Puzzle)
+		if err != nil {
+			out.fail(err)
+		}
+		text, err := format.Format(puzzle, flags.OutputFormat)
+		if err != nil {
+			out.fail(err)
+		}
+		fmt.Fprintf(out, "%s\n", text)
+	}
+}
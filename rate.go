@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/AndrewSav/sudocoo/pkg/analysis"
+	"github.com/AndrewSav/sudocoo/pkg/format"
+	"github.com/AndrewSav/sudocoo/pkg/rating"
+	"github.com/AndrewSav/sudocoo/pkg/solver"
+)
+
+// Rates the puzzle for '-rate', printing it in the inline format with its difficulty level and score.
+// Only a puzzle with a unique solution is rated, the others are reported as having no solution or
+// several
+func solveRating(flags *Flags, s *solver.Solver, r *puzzleResult) error {
+	for r.count < 2 && s.Solve() {
+		r.count++
+		r.addSolution(flags, s.Iterations())
+	}
+	var result string
+	switch r.count {
+	case 0:
+		result = "no solution"
+	case 2:
+		r.limitHit = true
+		result = "not unique"
+	default:
+		rated, err := rating.Rate(r.puzzle, analysis.DefaultSearchOrders, 1)
+		if err != nil {
+			return err
+		}
+		r.level = rated.Level
+		result = rated.String()
+	}
+	if !(flags.ShowStats && flags.Quiet) {
+		r.records = append(r.records, formatRecord(flags, fmt.Sprintf("%s: %s", format.Inline(r.puzzle), result)))
+	}
+	return nil
+}
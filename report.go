@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/AndrewSav/sudocoo/pkg/analysis"
+	"github.com/AndrewSav/sudocoo/pkg/batch"
+	"github.com/AndrewSav/sudocoo/pkg/canon"
+	"github.com/AndrewSav/sudocoo/pkg/parser"
+	"github.com/AndrewSav/sudocoo/pkg/rating"
+)
+
+type reportFlags struct {
+	InputFile string // input can come from a file
+	Input     string // or form a string
+	Format    string // text, csv or json
+	Rate      bool   // work out the difficulty distribution
+}
+
+// Formats the report can be printed in
+var reportFormats = []string{"text", "csv", "json"}
+
+// The width of the longest bar in the text histogram
+const histogramWidth = 50
+
+// Aggregate statistics of a puzzle collection
+type datasetReport struct {
+	Puzzles       int              `json:"puzzles"`
+	Invalid       int              `json:"invalid"`    // inconsistent givens
+	NoSolution    int              `json:"noSolution"` // consistent, but not solvable
+	Unique        int              `json:"unique"`
+	Multiple      int              `json:"multiple"`
+	Duplicates    int              `json:"duplicates"` // puzzles equivalent to one earlier in the collection
+	DuplicateRate float64          `json:"duplicateRate"`
+	MinClues      int              `json:"minClues"`
+	MaxClues      int              `json:"maxClues"`
+	MeanClues     float64          `json:"meanClues"`
+	Clues         []clueBucket     `json:"clues"`                // by clue count, only the counts that occur
+	Symmetry      []symmetryBucket `json:"symmetry"`             // by the symmetries of the clue pattern, only those that occur
+	Difficulty    []levelBucket    `json:"difficulty,omitempty"` // by the level of the unique puzzles, with -rate
+}
+
+type clueBucket struct {
+	Clues   int `json:"clues"`
+	Puzzles int `json:"puzzles"`
+}
+
+type levelBucket struct {
+	Level   string `json:"level"`
+	Puzzles int    `json:"puzzles"`
+}
+
+type symmetryBucket struct {
+	Symmetry string `json:"symmetry"`
+	Puzzles  int    `json:"puzzles"`
+}
+
+// What is found out about a single puzzle
+type puzzleFacts struct {
+	clues       int
+	solutions   int // 0, 1 or 2 for multiple, -1 for inconsistent givens
+	fingerprint string
+	symmetry    analysis.Symmetry
+	level       rating.Level // of a unique puzzle with -rate, 0 otherwise
+}
+
+// Scans a collection and prints clue, uniqueness and duplicate statistics
+func runReport(args []string) {
+	var flags reportFlags
+
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Println("Prints aggregate statistics of a puzzle collection: the clue count histogram, uniqueness and duplicates, and with '-rate' the difficulty distribution")
+		fmt.Printf("Usage: %s report [FLAGS...]\n", filepath.Base(os.Args[0]))
+		fmt.Println("Flags:")
+		fs.PrintDefaults()
+	}
+	addInputFlags(fs, &flags.InputFile, &flags.Input)
+	fs.StringVar(&flags.Format, "format", "text", fmt.Sprintf("report format: %s. Default: text", strings.Join(reportFormats, ", ")))
+	fs.BoolVar(&flags.Rate, "rate", false, "also report how many of the unique puzzles are at each difficulty level: easy, medium, hard, extreme, see '-rate' of the solve command. Takes longer")
+	parseFlags(fs, args)
+
+	if !slices.Contains(reportFormats, flags.Format) {
+		fmt.Printf("invalid report format %s\n", flags.Format)
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	out := newOutput(false)
+	defer out.Flush()
+
+	scanner := parser.CreateInputScanner(openInput(fs, flags.InputFile, flags.Input))
+	next := func() ([9][9]int, error) { return parser.ReadNextPuzzleInput(scanner) }
+	examine := func(_ context.Context, puzzle [9][9]int) (puzzleFacts, error) {
+		facts := puzzleFacts{clues: analysis.ClueCount(puzzle), fingerprint: canon.Fingerprint(puzzle), symmetry: analysis.Symmetries(puzzle)}
+		count, err := countSolutions(puzzle, 2)
+		if err != nil {
+			count = -1
+		}
+		facts.solutions = count
+		if flags.Rate && count == 1 {
+			if facts.level, err = rating.LevelOf(puzzle); err != nil {
+				return facts, err
+			}
+		}
+		return facts, nil
+	}
+
+	report := datasetReport{}
+	clues := map[int]int{}
+	symmetries := map[analysis.Symmetry]int{}
+	levels := map[rating.Level]int{}
+	seen := map[string]bool{}
+	totalClues := 0
+	_, err := batch.Run(context.Background(), batch.Config{}, next, examine, func(item batch.Item[puzzleFacts]) bool {
+		facts := item.Value
+		report.Puzzles++
+		switch facts.solutions {
+		case -1:
+			report.Invalid++
+		case 0:
+			report.NoSolution++
+		case 1:
+			report.Unique++
+		default:
+			report.Multiple++
+		}
+		if seen[facts.fingerprint] {
+			report.Duplicates++
+		}
+		seen[facts.fingerprint] = true
+		clues[facts.clues]++
+		symmetries[facts.symmetry]++
+		if facts.level != 0 {
+			levels[facts.level]++
+		}
+		totalClues += facts.clues
+		return true
+	})
+	if err != nil {
+		out.fail(err)
+	}
+	if report.Puzzles == 0 {
+		// Not a single puzzle in the input
+		out.fail(io.EOF)
+	}
+
+	report.DuplicateRate = float64(report.Duplicates) / float64(report.Puzzles)
+	report.MeanClues = float64(totalClues) / float64(report.Puzzles)
+	for count, puzzles := range clues {
+		report.Clues = append(report.Clues, clueBucket{Clues: count, Puzzles: puzzles})
+	}
+	sort.Slice(report.Clues, func(i, j int) bool { return report.Clues[i].Clues < report.Clues[j].Clues })
+	report.MinClues = report.Clues[0].Clues
+	report.MaxClues = report.Clues[len(report.Clues)-1].Clues
+	for _, symmetry := range slices.Sorted(maps.Keys(symmetries)) {
+		report.Symmetry = append(report.Symmetry, symmetryBucket{Symmetry: symmetry.String(), Puzzles: symmetries[symmetry]})
+	}
+	if flags.Rate {
+		// All the levels are listed, including the ones no puzzle is at
+		for level := rating.Easy; level <= rating.Extreme; level++ {
+			report.Difficulty = append(report.Difficulty, levelBucket{Level: level.String(), Puzzles: levels[level]})
+		}
+	}
+
+	switch flags.Format {
+	case "json":
+		data, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Fprintf(out, "%s\n", data)
+	case "csv":
+		writeReportCSV(out, report)
+	default:
+		writeReportText(out, report)
+	}
+}
+
+func writeReportText(w io.Writer, r datasetReport) {
+	fmt.Fprintf(w, "Puzzles: %d\n", r.Puzzles)
+	fmt.Fprintf(w, "Unique: %d\n", r.Unique)
+	fmt.Fprintf(w, "Multiple solutions: %d\n", r.Multiple)
+	fmt.Fprintf(w, "No solution: %d\n", r.NoSolution)
+	fmt.Fprintf(w, "Invalid: %d\n", r.Invalid)
+	fmt.Fprintf(w, "Duplicates: %d (%.1f%%)\n", r.Duplicates, r.DuplicateRate*100)
+	fmt.Fprintf(w, "Clues: %d to %d, %.1f on average\n", r.MinClues, r.MaxClues, r.MeanClues)
+	most := 0
+	for _, b := range r.Clues {
+		most = max(most, b.Puzzles)
+	}
+	for _, b := range r.Clues {
+		// Every clue count that occurs gets at least a bit of a bar
+		bar := max(1, b.Puzzles*histogramWidth/most)
+		fmt.Fprintf(w, "%4d %8d %s\n", b.Clues, b.Puzzles, strings.Repeat("#", bar))
+	}
+	fmt.Fprintln(w, "Symmetry:")
+	for _, b := range r.Symmetry {
+		fmt.Fprintf(w, "%8d %s\n", b.Puzzles, b.Symmetry)
+	}
+	if len(r.Difficulty) != 0 {
+		fmt.Fprintln(w, "Difficulty:")
+		for _, b := range r.Difficulty {
+			fmt.Fprintf(w, "%8d %s\n", b.Puzzles, b.Level)
+		}
+	}
+}
+
+// One row per figure: the section it belongs to, its name and its value
+func writeReportCSV(w io.Writer, r datasetReport) {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"section", "key", "value"})
+	summary := []struct {
+		key   string
+		value string
+	}{
+		{"puzzles", strconv.Itoa(r.Puzzles)},
+		{"unique", strconv.Itoa(r.Unique)},
+		{"multiple", strconv.Itoa(r.Multiple)},
+		{"no_solution", strconv.Itoa(r.NoSolution)},
+		{"invalid", strconv.Itoa(r.Invalid)},
+		{"duplicates", strconv.Itoa(r.Duplicates)},
+		{"duplicate_rate", strconv.FormatFloat(r.DuplicateRate, 'f', 4, 64)},
+		{"min_clues", strconv.Itoa(r.MinClues)},
+		{"max_clues", strconv.Itoa(r.MaxClues)},
+		{"mean_clues", strconv.FormatFloat(r.MeanClues, 'f', 2, 64)},
+	}
+	for _, s := range summary {
+		cw.Write([]string{"summary", s.key, s.value})
+	}
+	for _, b := range r.Clues {
+		cw.Write([]string{"clues", strconv.Itoa(b.Clues), strconv.Itoa(b.Puzzles)})
+	}
+	for _, b := range r.Symmetry {
+		cw.Write([]string{"symmetry", b.Symmetry, strconv.Itoa(b.Puzzles)})
+	}
+	for _, b := range r.Difficulty {
+		cw.Write([]string{"difficulty", b.Level, strconv.Itoa(b.Puzzles)})
+	}
+	cw.Flush()
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+
+	"github.com/AndrewSav/sudocoo/pkg/format"
+)
+
+// The outcomes of a puzzle in the --results file
+const (
+	outcomeNoSolution = "no-solution"
+	outcomeUnique     = "unique"
+	outcomeMultiple   = "multiple"
+	outcomeSolved     = "solved"    // a solution was found, but the search did not go on to tell whether it is the only one
+	outcomeTimedOut   = "timed-out" // the search gave up at -timeout before it could tell any of the above
+)
+
+// A line of the --results file, whatever the standard output shows of the puzzle
+type resultRecord struct {
+	Index        int     `json:"index"` // 1-based number of the puzzle in the input
+	Puzzle       string  `json:"puzzle"`
+	Outcome      string  `json:"outcome"`
+	Count        int     `json:"count"` // solutions found, up to the limits
+	LimitReached bool    `json:"limitReached"`
+	Solution     string  `json:"solution,omitempty"` // the first one
+	Iterations   int     `json:"iterations"`
+	Seconds      float64 `json:"seconds"` // solving and formatting the puzzle took
+	Rating       string  `json:"rating"`  // the level, see rating.Level
+}
+
+// Writes the --results file, a JSON object per line
+type resultsFile struct {
+	file         *os.File
+	w            *bufio.Writer
+	encoder      *json.Encoder
+	lineBuffered bool
+}
+
+func createResults(path string, lineBuffered bool) (*resultsFile, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w := bufio.NewWriter(file)
+	return &resultsFile{file: file, w: w, encoder: json.NewEncoder(w), lineBuffered: lineBuffered}, nil
+}
+
+// Writes the record of the puzzle as the writer accounted for it: the count and the limit after
+// --total-limit, and the iterations of the solutions printed
+func (f *resultsFile) write(flags *Flags, index int, r *puzzleResult, count int, limitReached, totalLimitReached bool, solved int) error {
+	record := resultRecord{
+		Index:        index + 1,
+		Puzzle:       format.Inline(r.puzzle),
+		Count:        count,
+		LimitReached: limitReached,
+		Solution:     r.solution,
+		Seconds:      r.duration.Seconds(),
+		Rating:       r.level.String(),
+	}
+	if solved > 0 {
+		record.Iterations = r.iterationsAfter(solved)
+	}
+	switch {
+	case r.timedOut && count < 2:
+		record.Outcome = outcomeTimedOut
+	case count == 0:
+		record.Outcome = outcomeNoSolution
+	case flags.Sample != 0:
+		// Samples are taken with replacement, they can be the same solution over and over
+		record.Outcome = outcomeSolved
+	case count > 1 || limitReached:
+		record.Outcome = outcomeMultiple
+	case (flags.All || flags.Proof) && !totalLimitReached:
+		record.Outcome = outcomeUnique
+	default:
+		record.Outcome = outcomeSolved
+	}
+	if err := f.encoder.Encode(record); err != nil {
+		return err
+	}
+	if f.lineBuffered {
+		return f.w.Flush()
+	}
+	return nil
+}
+
+func (f *resultsFile) Close() error {
+	err := f.w.Flush()
+	if closeErr := f.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
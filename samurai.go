@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/AndrewSav/sudocoo/pkg/format"
+	"github.com/AndrewSav/sudocoo/pkg/parser"
+	"github.com/AndrewSav/sudocoo/pkg/solver"
+)
+
+type samuraiFlags struct {
+	InputFile  string // input can come from a file
+	Input      string // or form a string
+	All        bool   // we want all solutions, not just the first one
+	Limit      int    // the maximum number of solutions to find
+	CountsOnly bool   // we want only the solution count, not the solutions themselves
+	Color      bool   // highlight the digits the solver filled in
+	DontSolve  bool   // print the givens instead of the solutions
+}
+
+// Solves samurai puzzles, five 9x9 grids overlapping at the corner boxes of the middle one, see
+// parser.ReadNextSamurai for the format, and prints their solutions in the same layout
+func runSamurai(args []string) {
+	var flags samuraiFlags
+
+	fs := flag.NewFlagSet("samurai", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Println("Solves samurai puzzles: five 9x9 grids laid out in a 21x21 square, the middle one sharing its corner boxes with the other four")
+		fmt.Printf("Usage: %s samurai [FLAGS...]\n", filepath.Base(os.Args[0]))
+		fmt.Println("Flags:")
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&flags.InputFile, "f", "", "path to the file with the puzzles. Only one of '-f' and '-i' can be specified")
+	fs.StringVar(&flags.Input, "i", "", "the puzzles, 369 cells each, the ones outside of the grids left out. Only one of '-f' and '-i' can be specified")
+	fs.BoolVar(&flags.All, "a", false, "find all solutions, but no more than specified in the -l flag")
+	fs.IntVar(&flags.Limit, "l", defaultLimit, "the maximum number of solutions to find. 0 is no limit. Requires '-a'")
+	fs.BoolVar(&flags.CountsOnly, "c", false, "do not print out the solutions, only the solution count. Only considered when '-a' is specified")
+	fs.BoolVar(&flags.Color, "color", false, "highlight the digits the solver filled in")
+	fs.BoolVar(&flags.DontSolve, "d", false, "do not solve the puzzles, output their givens instead, e.g. to lay out ones read from a single line")
+	parseFlags(fs, args)
+
+	if flags.Limit < 0 {
+		fmt.Printf("invalid limit %d, want 0 or more\n", flags.Limit)
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	out := newOutput(false)
+	defer out.Flush()
+
+	s := parser.CreateInputScanner(openInput(fs, flags.InputFile, flags.Input))
+	puzzleCount, printed := 0, 0
+	// The grids take many lines each, a blank line goes between them
+	show := func(grid string) {
+		if printed > 0 {
+			fmt.Fprintln(out)
+		}
+		printed++
+		fmt.Fprintln(out, grid)
+	}
+	for {
+		cells, err := parser.ReadNextSamurai(s)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			out.fail(err)
+		}
+		puzzle, err := solver.SamuraiOf(cells)
+		if err != nil {
+			out.fail(err)
+		}
+		puzzleCount++
+		if flags.DontSolve {
+			show(format.FormatSamurai(puzzle, puzzle, false))
+			continue
+		}
+		ss, err := solver.NewSamuraiSolver(puzzle)
+		if err != nil {
+			out.fail(err)
+		}
+		count, limitHit := 0, false
+		for ss.Solve() {
+			if flags.All && flags.Limit != 0 && count == flags.Limit {
+				limitHit = true
+				break
+			}
+			count++
+			if !(flags.All && flags.CountsOnly) {
+				show(format.FormatSamurai(ss.Solution(), puzzle, flags.Color))
+			}
+			if !flags.All {
+				break
+			}
+		}
+		switch {
+		case flags.All && flags.CountsOnly && limitHit:
+			fmt.Fprintf(out, "%d+ (limit reached)\n", count)
+		case flags.All && flags.CountsOnly:
+			fmt.Fprintf(out, "%d\n", count)
+		case count == 0:
+			fmt.Fprintln(out, "No solution")
+		}
+	}
+	if puzzleCount == 0 {
+		out.fail(io.EOF)
+	}
+}
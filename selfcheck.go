@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AndrewSav/sudocoo/pkg/analysis"
+	"github.com/AndrewSav/sudocoo/pkg/format"
+	"github.com/AndrewSav/sudocoo/pkg/packed"
+	"github.com/AndrewSav/sudocoo/pkg/parser"
+	"github.com/AndrewSav/sudocoo/pkg/solver"
+)
+
+// Puzzles with known solutions and counts, see the comments in the file
+//
+//go:embed data/selfcheck.txt
+var selfcheckPuzzles string
+
+// Hard puzzles to time the solver on
+//
+//go:embed data/input2.txt
+var selfcheckBenchmark []byte
+
+// The counts of the reference puzzles stop here
+const selfcheckLimit = 1000
+
+// The formats that read back as the puzzle they were written from, the others are for people only
+var roundTripFormats = []string{"b64", "inline", "sadman", "simple", "solver", "vbforums", "visual", "zeroes"}
+
+type selfcheckFlags struct {
+	Performance bool // time the solver on the benchmark puzzles as well
+	Rounds      int  // times to solve the benchmark puzzles
+	Verbose     bool // print every check, not only the failures
+}
+
+// A line of the reference puzzles
+type selfcheckCase struct {
+	puzzle   [9][9]int
+	count    int  // -1 for an invalid puzzle
+	more     bool // there are more solutions than the count
+	solution [9][9]int
+	unique   bool // the solution is given
+}
+
+// Runs the reference puzzles through the parser, the solver and the formats and reports whether
+// they all give what they should, then how fast this machine solves hard puzzles. Exits with 1 if
+// any of the checks fails
+func runSelfcheck(args []string) {
+	var flags selfcheckFlags
+
+	fs := flag.NewFlagSet("selfcheck", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Println("Checks that the solver, the parser and the formats work on this machine with reference puzzles built in, and measures the solver speed")
+		fmt.Printf("Usage: %s selfcheck [FLAGS...]\n", filepath.Base(os.Args[0]))
+		fmt.Println("Flags:")
+		fs.PrintDefaults()
+	}
+	fs.BoolVar(&flags.Performance, "performance", true, "time the solver on a set of hard puzzles after the checks. Default: true")
+	fs.IntVar(&flags.Rounds, "rounds", 3, "solve the hard puzzles this many times, the fastest round is reported")
+	fs.BoolVar(&flags.Verbose, "v", false, "print every check, not only the failures")
+	parseFlags(fs, args)
+
+	if flags.Rounds < 1 {
+		fmt.Printf("invalid number of rounds %d, want 1 or more\n", flags.Rounds)
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	out := newOutput(false)
+	defer out.Flush()
+
+	cases, err := readSelfcheckCases(selfcheckPuzzles)
+	if err != nil {
+		out.fail(err)
+	}
+	checks, failures := 0, 0
+	report := func(name string, err error) {
+		checks++
+		if err != nil {
+			failures++
+			fmt.Fprintf(out, "FAIL %s: %v\n", name, err)
+		} else if flags.Verbose {
+			fmt.Fprintf(out, "ok   %s\n", name)
+		}
+	}
+	for i, c := range cases {
+		name := fmt.Sprintf("puzzle %d", i+1)
+		report(name+" solutions", checkSolutions(c))
+		for _, f := range roundTripFormats {
+			report(fmt.Sprintf("%s %s format", name, f), checkRoundTrip(c.puzzle, f))
+		}
+		report(name+" packed format", checkPacked(c.puzzle))
+	}
+	fmt.Fprintf(out, "Checks: %d, failed: %d\n", checks, failures)
+
+	if flags.Performance {
+		if err := measurePerformance(out, flags.Rounds); err != nil {
+			out.fail(err)
+		}
+	}
+	if failures != 0 {
+		out.Flush()
+		os.Exit(1)
+	}
+}
+
+func readSelfcheckCases(text string) ([]selfcheckCase, error) {
+	var cases []selfcheckCase
+	lines := bufio.NewScanner(strings.NewReader(text))
+	for lines.Scan() {
+		fields := strings.Fields(lines.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("reference puzzle without a count: %s", lines.Text())
+		}
+		var c selfcheckCase
+		var err error
+		if c.puzzle, err = parser.ParsePuzzleString(fields[0]); err != nil {
+			return nil, err
+		}
+		count, more := strings.CutSuffix(fields[1], "+")
+		switch {
+		case count == "invalid":
+			c.count = -1
+		default:
+			if c.count, err = strconv.Atoi(count); err != nil {
+				return nil, fmt.Errorf("invalid count in reference puzzle: %s", lines.Text())
+			}
+			c.more = more
+		}
+		if len(fields) > 2 {
+			if c.solution, err = parser.ParsePuzzleString(fields[2]); err != nil {
+				return nil, err
+			}
+			c.unique = true
+		}
+		cases = append(cases, c)
+	}
+	return cases, nil
+}
+
+// Checks the solutions the solver finds against the known count and solution, and checks each one
+// against the rules
+func checkSolutions(c selfcheckCase) error {
+	s, err := solver.NewSolver(c.puzzle)
+	if c.count < 0 {
+		if !errors.Is(err, solver.ErrInvalidPuzzle) {
+			return fmt.Errorf("want the puzzle rejected as invalid, have %v", err)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	count := 0
+	for count <= selfcheckLimit && s.Solve() {
+		solution := s.Solution()
+		if !analysis.IsSolutionOf(solution, c.puzzle) {
+			return fmt.Errorf("%s is not a solution", format.Inline(solution))
+		}
+		if c.unique && solution != c.solution {
+			return fmt.Errorf("want solution %s, have %s", format.Inline(c.solution), format.Inline(solution))
+		}
+		count++
+	}
+	if more := count > selfcheckLimit; more != c.more || !more && count != c.count {
+		return fmt.Errorf("want %s solutions, have %s", referenceCount(c.count, c.more), referenceCount(min(count, selfcheckLimit), more))
+	}
+	return nil
+}
+
+// Formats a count the way the reference puzzles have it
+func referenceCount(count int, more bool) string {
+	if more {
+		return fmt.Sprintf("%d+", count)
+	}
+	return strconv.Itoa(count)
+}
+
+// Checks that the puzzle reads back from the format, the way input files are read
+func checkRoundTrip(puzzle [9][9]int, formatName string) error {
+	text, err := format.Format(puzzle, formatName)
+	if err != nil {
+		return err
+	}
+	read, err := parser.ReadNextPuzzleInput(parser.CreateInputScanner(packed.Text(strings.NewReader(text + "\n"))))
+	if err != nil {
+		return err
+	}
+	if read != puzzle {
+		return fmt.Errorf("reads back as %s", format.Inline(read))
+	}
+	return nil
+}
+
+// Checks that the puzzle reads back from a packed stream
+func checkPacked(puzzle [9][9]int) error {
+	var b bytes.Buffer
+	w, err := packed.NewWriter(&b, false)
+	if err != nil {
+		return err
+	}
+	if err := w.Write(puzzle, packed.NoCount); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	read, err := parser.ReadNextPuzzleInput(parser.CreateInputScanner(packed.Text(&b)))
+	if err != nil {
+		return err
+	}
+	if read != puzzle {
+		return fmt.Errorf("reads back as %s", format.Inline(read))
+	}
+	return nil
+}
+
+// Solves the benchmark puzzles on one CPU, counting all their solutions, and prints how fast the fastest round was
+func measurePerformance(out *output, rounds int) error {
+	var puzzles [][9][9]int
+	r := parser.NewBytesReader(selfcheckBenchmark)
+	for {
+		puzzle, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		puzzles = append(puzzles, puzzle)
+	}
+	best, iterations := time.Duration(0), 0
+	for range rounds {
+		start := time.Now()
+		iterations = 0
+		for _, puzzle := range puzzles {
+			s, err := solvers.Get(puzzle)
+			if err != nil {
+				return err
+			}
+			for s.Solve() {
+				iterations += s.Iterations()
+			}
+			solvers.Put(s)
+		}
+		if elapsed := time.Since(start); best == 0 || elapsed < best {
+			best = elapsed
+		}
+	}
+	fmt.Fprintf(out, "Performance: %d hard puzzles on one CPU in %s, %.0f puzzles/s, %.1fM iterations/s\n",
+		len(puzzles), best.Round(time.Microsecond), float64(len(puzzles))/best.Seconds(), float64(iterations)/best.Seconds()/1e6)
+	return nil
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/AndrewSav/sudocoo/pkg/handler"
+	"github.com/AndrewSav/sudocoo/pkg/metrics"
+	"github.com/AndrewSav/sudocoo/pkg/parser"
+	"github.com/AndrewSav/sudocoo/pkg/rpc"
+	"github.com/AndrewSav/sudocoo/pkg/webhook"
+)
+
+// The HTTP API is described in this file, keep it in sync with pkg/handler
+//
+//go:embed api/openapi.yaml
+var openAPISpec []byte
+
+type serveFlags struct {
+	Addr     string // address to listen on
+	GRPCAddr string // address to serve gRPC on, empty if not wanted
+	MaxLimit int    // the most solutions a single request is allowed to ask for
+	Jobs     handler.JobConfig
+	Limits   parser.Limits // the line length and the puzzle size of the input, the jobs have their own size and puzzles
+
+	MaxConcurrent   int           // solve requests in progress at the same time, 0 is no limit
+	ShutdownTimeout time.Duration // how long to wait for requests and the puzzles of running jobs on shutdown
+	WebhookSecret   string        // signs the webhook notifications, webhooks are disabled without it
+}
+
+// Where the webhook secret is taken from if there is no flag, to keep it off the command line
+const envWebhookSecret = "SUDOCOO_WEBHOOK_SECRET"
+
+// Runs an HTTP server solving puzzles sent to it
+func runServe(args []string) {
+	var flags serveFlags
+
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Println("Runs an HTTP server that solves puzzles")
+		fmt.Printf("Usage: %s serve [FLAGS...]\n", filepath.Base(os.Args[0]))
+		fmt.Println("Endpoints (see GET /openapi.yaml for details):")
+		fmt.Println("  POST /solve         {\"puzzle\": \"...\"} returns the first solution")
+		fmt.Println("  POST /count         {\"puzzle\": \"...\", \"limit\": N} returns the number of solutions")
+		fmt.Println("  POST /rate          {\"puzzle\": \"...\"} returns the difficulty level and score of a unique puzzle")
+		fmt.Println("  POST /generate      {\"clues\": N, \"seed\": N} returns a new puzzle with a unique solution")
+		fmt.Println("  GET  /enumerate     ?puzzle=...&limit=N streams solutions as server-sent events")
+		fmt.Println("  POST /jobs          puzzles in the body, a 'file' form field or {\"url\": \"...\"} starts a batch job")
+		fmt.Println("                      add webhook=URL (or \"webhook\" in the JSON) to be notified when it finishes")
+		fmt.Println("  GET  /jobs/ID       job status, /jobs/ID/results the results once done, DELETE cancels")
+		fmt.Println("  GET  /metrics       request metrics in the Prometheus text format")
+		fmt.Println("  GET  /healthz       200 while the server runs, /readyz 200 while it takes requests")
+		fmt.Println("  GET  /openapi.yaml  OpenAPI description of the endpoints")
+		fmt.Println("Flags:")
+		fs.PrintDefaults()
+		fmt.Println("On SIGINT or SIGTERM the server stops taking requests and waits for the ones in progress;")
+		fmt.Println("running jobs stop after their current puzzle, with its result, and queued jobs are canceled")
+	}
+	fs.StringVar(&flags.Addr, "addr", ":8080", "address to listen on. Default: :8080")
+	fs.StringVar(&flags.GRPCAddr, "grpc-addr", "", "also serve the gRPC API from api/sudocoo.proto on this address (HTTP/2 without TLS)")
+	fs.IntVar(&flags.MaxLimit, "max-limit", 10000, "the maximum number of solutions a request can ask for. Default: 10000")
+	fs.IntVar(&flags.Jobs.MaxPuzzles, "max-batch", 100000, "the maximum number of puzzles in a batch job. Default: 100000")
+	fs.Int64Var(&flags.Jobs.MaxSize, "max-batch-size", 16<<20, "the maximum size of the input of a batch job in bytes. Default: 16MB")
+	fs.IntVar(&flags.Limits.MaxLineLength, "max-line-length", parser.DefaultLimits.MaxLineLength, "the maximum length of a line of input in bytes, for requests and batch jobs. 0 is no limit. Default: 64KB")
+	fs.IntVar(&flags.Limits.MaxPuzzleSize, "max-puzzle-size", parser.DefaultLimits.MaxPuzzleSize, "the maximum number of bytes of input a single puzzle can take up, with any comments and separators before it. 0 is no limit. Default: 16KB")
+	fs.IntVar(&flags.Jobs.Workers, "batch-workers", runtime.NumCPU(), "the number of batch jobs running at the same time. Default: number of CPUs")
+	fs.BoolVar(&flags.Jobs.AllowURLs, "batch-urls", false, "let batch jobs fetch their input from http(s) URLs given by the client")
+	fs.BoolVar(&flags.Jobs.AllowPrivateURLs, "allow-private-urls", false, "let the input URLs and the webhooks of batch jobs point to loopback, private and link-local addresses, which are refused otherwise")
+	fs.IntVar(&flags.MaxConcurrent, "max-concurrent", 0, "the maximum number of solve, count and enumerate requests (HTTP and gRPC) in progress, more are rejected with 503. 0 is no limit")
+	fs.DurationVar(&flags.ShutdownTimeout, "shutdown-timeout", 30*time.Second, "how long to wait for requests and the puzzles of running jobs on shutdown. Default: 30s")
+	fs.StringVar(&flags.WebhookSecret, "webhook-secret", os.Getenv(envWebhookSecret), "enables batch job webhooks, notifications are signed with this secret. Default: $"+envWebhookSecret)
+	parseFlags(fs, args)
+
+	if flags.MaxLimit < 1 {
+		fmt.Printf("invalid max limit %d, want 1 or more\n", flags.MaxLimit)
+		fs.Usage()
+		os.Exit(2)
+	}
+	if flags.Jobs.MaxPuzzles < 1 || flags.Jobs.MaxSize < 1 || flags.Jobs.Workers < 1 {
+		fmt.Println("batch job limits must be 1 or more")
+		fs.Usage()
+		os.Exit(2)
+	}
+	if flags.Limits.MaxLineLength < 0 || flags.Limits.MaxPuzzleSize < 0 {
+		fmt.Println("input limits must be 0 or more")
+		fs.Usage()
+		os.Exit(2)
+	}
+	if flags.MaxConcurrent < 0 {
+		fmt.Printf("invalid max concurrent %d, want 0 or more\n", flags.MaxConcurrent)
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	if flags.WebhookSecret != "" {
+		flags.Jobs.Webhooks = webhook.NewSender(flags.WebhookSecret, flags.Jobs.AllowPrivateURLs)
+	}
+
+	m := metrics.New()
+	h := handler.New(flags.MaxLimit, m)
+	h.SetInputLimits(flags.Limits)
+	jobs := h.NewJobs(flags.Jobs)
+	grpc := rpc.NewServer(flags.MaxLimit, m)
+	grpc.SetInputLimits(flags.Limits)
+	var api, grpcAPI http.Handler = h, grpc
+	if flags.MaxConcurrent != 0 {
+		// One limit for both, they share the CPUs
+		limiter := handler.NewLimiter(flags.MaxConcurrent)
+		api, grpcAPI = limiter.Wrap(h), limiter.Wrap(grpc)
+	}
+	var hc health
+
+	mux := http.NewServeMux()
+	mux.Handle("/", api)
+	mux.Handle("/jobs", jobs)
+	mux.Handle("/jobs/", jobs)
+	mux.Handle("/metrics", m)
+	hc.register(mux)
+	mux.HandleFunc("/openapi.yaml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(openAPISpec)
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	servers := []*http.Server{{Addr: flags.Addr, Handler: mux}}
+	if flags.GRPCAddr != "" {
+		servers = append(servers, grpc.HTTPServer(flags.GRPCAddr, grpcAPI))
+	}
+	errs := make(chan error, len(servers))
+	for _, srv := range servers {
+		go func() {
+			if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				errs <- err
+			}
+		}()
+	}
+	log.Printf("listening on %s", flags.Addr)
+	if flags.GRPCAddr != "" {
+		log.Printf("serving gRPC on %s", flags.GRPCAddr)
+	}
+	hc.ready.Store(true)
+
+	select {
+	case err := <-errs:
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	case <-ctx.Done():
+	}
+
+	hc.ready.Store(false)
+	log.Printf("shutting down, waiting up to %s for the requests and the puzzles of the jobs in progress", flags.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), flags.ShutdownTimeout)
+	defer cancel()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		jobs.Shutdown(shutdownCtx)
+	}()
+	for _, srv := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := srv.Shutdown(shutdownCtx); err != nil {
+				log.Printf("%s: %v", srv.Addr, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
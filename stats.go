@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// The formats of the '-s' statistics
+const (
+	statsText = "text"
+	statsJSON = "json"
+)
+
+// What the JSON statistics lines are, so that a reader can tell them apart
+const (
+	statsPuzzle = "puzzle"
+	statsTotal  = "total"
+)
+
+// The statistics of a puzzle with '-stats-format json', a line printed after its output
+type puzzleStats struct {
+	Type         string  `json:"type"`  // statsPuzzle
+	Index        int     `json:"index"` // 1-based number of the puzzle in the input
+	Solutions    int     `json:"solutions"`
+	LimitReached bool    `json:"limitReached"`
+	TimedOut     bool    `json:"timedOut"`
+	Iterations   int     `json:"iterations"`
+	Seconds      float64 `json:"seconds"` // solving and formatting the puzzle took
+}
+
+// The statistics of the run with '-stats-format json', the last line printed
+type runStats struct {
+	Type              string  `json:"type"` // statsTotal
+	Puzzles           int     `json:"puzzles"`
+	LeftOut           int     `json:"leftOut"`  // by --filter
+	TimedOut          int     `json:"timedOut"` // at -timeout
+	Solutions         int     `json:"solutions"`
+	LimitReached      bool    `json:"limitReached"`      // for some puzzles, their counts are higher
+	TotalLimitReached bool    `json:"totalLimitReached"` // the run stopped at --total-limit
+	Iterations        int     `json:"iterations"`
+	Seconds           float64 `json:"seconds"` // the run took
+}
+
+// Prints the statistics of the puzzle as the writer accounted for it: the count and the limit after
+// --total-limit, and the iterations of the solutions printed
+func writePuzzleStats(out *output, index int, r *puzzleResult, count int, limitReached bool, solved int) {
+	stats := puzzleStats{
+		Type:         statsPuzzle,
+		Index:        index + 1,
+		Solutions:    count,
+		LimitReached: limitReached,
+		TimedOut:     r.timedOut,
+		Seconds:      r.duration.Seconds(),
+	}
+	if solved > 0 {
+		stats.Iterations = r.iterationsAfter(solved)
+	}
+	writeStats(out, stats)
+}
+
+// Prints the statistics of the run
+func writeRunStats(out *output, stats runStats, start time.Time) {
+	stats.Type = statsTotal
+	stats.Seconds = time.Since(start).Seconds()
+	writeStats(out, stats)
+}
+
+// Prints a JSON statistics line
+func writeStats(out *output, stats any) {
+	if err := json.NewEncoder(out).Encode(stats); err != nil {
+		out.fail(err)
+	}
+	out.endRecord()
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/AndrewSav/sudocoo/pkg/format"
+	"github.com/AndrewSav/sudocoo/pkg/logic"
+	"github.com/AndrewSav/sudocoo/pkg/parser"
+)
+
+type stepsFlags struct {
+	InputFile string // input can come from a file
+	Input     string // or form a string
+}
+
+// Solves each puzzle with the logic engine and prints the steps in HoDoKu notation, so that they can be
+// compared with HoDoKu or SudokuWiki and posted on forums. Exits with 1 if any of the puzzles is inconsistent
+func runSteps(args []string) {
+	var flags stepsFlags
+
+	fs := flag.NewFlagSet("steps", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Println("Solves puzzles with human techniques and prints the steps in HoDoKu/SudokuWiki notation")
+		fmt.Printf("Usage: %s steps [FLAGS...]\n", filepath.Base(os.Args[0]))
+		fmt.Println("Flags:")
+		fs.PrintDefaults()
+	}
+	addInputFlags(fs, &flags.InputFile, &flags.Input)
+	parseFlags(fs, args)
+
+	out := newOutput(false)
+	defer out.Flush()
+
+	scanner := parser.CreateInputScanner(openInput(fs, flags.InputFile, flags.Input))
+	failed := false
+	for puzzleCount := 0; ; puzzleCount++ {
+		puzzle, err := parser.ReadNextPuzzleInput(scanner)
+		if errors.Is(err, io.EOF) && puzzleCount != 0 {
+			break
+		}
+		if err != nil {
+			out.fail(err)
+		}
+		if puzzleCount != 0 {
+			fmt.Fprintln(out)
+		}
+		fmt.Fprintln(out, format.Inline(puzzle))
+		result, err := logic.Solve(puzzle)
+		if err != nil {
+			fmt.Fprintf(out, "Error: %v\n", err)
+			failed = true
+			continue
+		}
+		for _, step := range result.Steps {
+			fmt.Fprintln(out, step)
+		}
+		if result.Solved {
+			fmt.Fprintf(out, "Solved in %d steps\n", len(result.Steps))
+		} else {
+			fmt.Fprintf(out, "Stuck after %d steps at %s\n", len(result.Steps), format.Inline(result.Grid))
+		}
+	}
+	if failed {
+		out.Flush()
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/AndrewSav/sudocoo/pkg/analysis"
+	"github.com/AndrewSav/sudocoo/pkg/format"
+)
+
+// What the '--template' output of a puzzle is made from
+type templateRecord struct {
+	Index        int    // 0-based position of the puzzle in the input
+	Label        string // 1-based number of the puzzle, the way the store labels puzzles read from text
+	Puzzle       string // inline format
+	Solution     string // the first solution in the inline format, empty if there is none
+	Count        int    // solutions found, up to the limit
+	LimitReached bool   // there are more solutions than the limit
+	Iterations   int
+	Duration     time.Duration
+
+	puzzle [9][9]int
+}
+
+func newTemplateRecord(index int, r *puzzleResult, count int, limitReached bool, solved int) *templateRecord {
+	t := &templateRecord{
+		Index:        index,
+		Label:        strconv.Itoa(index + 1),
+		Puzzle:       format.Inline(r.puzzle),
+		Solution:     r.solution,
+		Count:        count,
+		LimitReached: limitReached,
+		Duration:     r.duration,
+		puzzle:       r.puzzle,
+	}
+	if solved > 0 {
+		t.Iterations = r.iterationsAfter(solved)
+	}
+	return t
+}
+
+// Returns the search hardness score of the puzzle, see the hardness command. It takes several searches,
+// so it is only worked out when the template asks for it. A puzzle that cannot be measured has 0
+func (t *templateRecord) Rating() float64 {
+	h, err := analysis.MeasureSearchHardness(t.puzzle, analysis.DefaultSearchOrders, 1)
+	if err != nil {
+		return 0
+	}
+	return h.DeadEnds
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/AndrewSav/sudocoo/pkg/format"
+	"github.com/AndrewSav/sudocoo/pkg/solver"
+)
+
+// What '-u' says of a puzzle
+const (
+	uniquenessNone     = "none"
+	uniquenessUnique   = "unique"
+	uniquenessMultiple = "multiple"
+)
+
+// Classifies the puzzle for '-u', printing it in the inline format with whether it has no solution,
+// a unique one or several. The search stops at the second solution, there is no need to go further
+func solveUniqueness(flags *Flags, s *solver.Solver, r *puzzleResult) {
+	r.count = s.CountSolutions(1)
+	r.addSolution(flags, s.Iterations())
+	result := uniquenessUnique
+	switch r.count {
+	case 0:
+		result = uniquenessNone
+	case 2:
+		r.limitHit = true
+		result = uniquenessMultiple
+	}
+	if !(flags.ShowStats && flags.Quiet) {
+		r.records = append(r.records, formatRecord(flags, fmt.Sprintf("%s: %s", format.Inline(r.puzzle), result)))
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/AndrewSav/sudocoo/pkg/analysis"
+	"github.com/AndrewSav/sudocoo/pkg/format"
+	"github.com/AndrewSav/sudocoo/pkg/parser"
+	"github.com/AndrewSav/sudocoo/pkg/solver"
+)
+
+type validateFlags struct {
+	InputFile string // input can come from a file
+	Input     string // or form a string
+	Symmetry  bool   // also report whether the clue pattern is symmetric
+	Group     bool   // also report all the symmetries of the clue pattern
+	Only      string // only print the puzzles with this symmetry
+	ShowStats bool   // display totals at the end
+}
+
+// Checks each puzzle's structure and givens without searching for solutions.
+// Prints one line per puzzle and exits with 1 if any of the puzzles is inconsistent
+func runValidate(args []string) {
+	var flags validateFlags
+
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Println("Checks puzzles for clue count and consistency of the givens without solving them")
+		fmt.Printf("Usage: %s validate [FLAGS...]\n", filepath.Base(os.Args[0]))
+		fmt.Println("Flags:")
+		fs.PrintDefaults()
+	}
+	addInputFlags(fs, &flags.InputFile, &flags.Input)
+	fs.BoolVar(&flags.Symmetry, "symmetry", false, "also report whether the clue pattern is symmetric (180 degree rotation)")
+	fs.BoolVar(&flags.Group, "symmetry-group", false, "also report all the symmetries of the clue pattern: rotations, mirrors and diagonals")
+	fs.StringVar(&flags.Only, "only-symmetry", "", fmt.Sprintf("only print the puzzles whose clue pattern has this symmetry: %s", strings.Join(analysis.SymmetryNames(), ", ")))
+	fs.BoolVar(&flags.ShowStats, "s", false, "display total number of puzzles and inconsistent puzzles at the end")
+	parseFlags(fs, args)
+
+	var only analysis.Symmetry
+	if flags.Only != "" {
+		var err error
+		if only, err = analysis.ParseSymmetry(flags.Only); err != nil {
+			fmt.Println(err)
+			fs.Usage()
+			os.Exit(2)
+		}
+	}
+
+	out := newOutput(false)
+	defer out.Flush()
+
+	scanner := parser.CreateInputScanner(openInput(fs, flags.InputFile, flags.Input))
+	puzzleCount := 0
+	inconsistent := 0
+	matching := 0
+	for ; ; puzzleCount++ {
+		puzzle, err := parser.ReadNextPuzzleInput(scanner)
+		if errors.Is(err, io.EOF) && puzzleCount != 0 {
+			break
+		}
+		if err != nil {
+			out.fail(err)
+		}
+		status := "consistent"
+		if _, err := solver.NewSolver(puzzle); err != nil {
+			status = "inconsistent"
+			inconsistent++
+		}
+		symmetries := analysis.Symmetries(puzzle)
+		if flags.Only != "" && !hasSymmetry(symmetries, only) {
+			continue
+		}
+		matching++
+		fmt.Fprintf(out, "%s: %d clues, %s", format.Inline(puzzle), analysis.ClueCount(puzzle), status)
+		if flags.Symmetry {
+			if analysis.IsSymmetric(puzzle) {
+				fmt.Fprintf(out, ", symmetric")
+			} else {
+				fmt.Fprintf(out, ", not symmetric")
+			}
+		}
+		if flags.Group {
+			fmt.Fprintf(out, ", symmetry: %s", symmetries)
+		}
+		fmt.Fprintln(out)
+	}
+	if flags.ShowStats {
+		fmt.Fprintf(out, "Total puzzles: %d\n", puzzleCount)
+		if flags.Only != "" {
+			fmt.Fprintf(out, "Puzzles with %s symmetry: %d\n", flags.Only, matching)
+		}
+		fmt.Fprintf(out, "Inconsistent puzzles: %d\n", inconsistent)
+	}
+	if inconsistent != 0 {
+		out.Flush()
+		os.Exit(1)
+	}
+}
+
+// Checks whether the symmetries include the wanted one, NoSymmetry only matches patterns without any
+func hasSymmetry(symmetries, wanted analysis.Symmetry) bool {
+	if wanted == analysis.NoSymmetry {
+		return symmetries == analysis.NoSymmetry
+	}
+	return symmetries&wanted != 0
+}
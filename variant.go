@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/AndrewSav/sudocoo/pkg/format"
+	"github.com/AndrewSav/sudocoo/pkg/variant"
+)
+
+type variantFlags struct {
+	InputFile    string // input can come from a file
+	Input        string // or form a string
+	All          bool   // we want all solutions, not just the first one
+	Limit        int    // the maximum number of solutions to find
+	CountsOnly   bool   // we want only the solution count, not the solutions themselves
+	OutputFormat string // how to print out a solution
+	DontSolve    bool   // print the givens instead of the solutions
+}
+
+// Solves a variant puzzle read from a JSON file, see package variant for the format,
+// and prints its solutions the way the main command prints classic ones
+func runVariant(args []string) {
+	var flags variantFlags
+	// Before the flags, so that the usage lists the formats of the plugins
+	loadPlugins()
+
+	fs := flag.NewFlagSet("variant", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Println("Solves a variant puzzle: a grid with extra constraints (diagonals, regions, cages, thermometers, parity, sandwich sums) in a JSON file")
+		fmt.Printf("Usage: %s variant [FLAGS...]\n", filepath.Base(os.Args[0]))
+		fmt.Println("Flags:")
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&flags.InputFile, "f", "", "path to the variant puzzle JSON file. Only one of '-f' and '-i' can be specified")
+	fs.StringVar(&flags.Input, "i", "", "the variant puzzle JSON. Only one of '-f' and '-i' can be specified")
+	fs.BoolVar(&flags.All, "a", false, "find all solutions, but no more than specified in the -l flag")
+	fs.IntVar(&flags.Limit, "l", defaultLimit, "the maximum number of solutions to find. 0 is no limit. Requires '-a'")
+	fs.BoolVar(&flags.CountsOnly, "c", false, "do not print out the solutions, only the solution count. Only considered when '-a' is specified")
+	fs.StringVar(&flags.OutputFormat, "v", "visual", fmt.Sprintf("output format for solutions: %s", getAvailableFormats()))
+	fs.BoolVar(&flags.DontSolve, "d", false, "do not solve the puzzle, output its givens instead, e.g. to convert them with '-v'")
+	parseFlags(fs, args)
+
+	if _, err := format.Lookup(flags.OutputFormat); err != nil {
+		fmt.Printf("invalid output format %s\n", flags.OutputFormat)
+		fs.Usage()
+		os.Exit(exitCode(err))
+	}
+	if flags.Limit < 0 {
+		fmt.Printf("invalid limit %d, want 0 or more\n", flags.Limit)
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	out := newOutput(false)
+	defer out.Flush()
+
+	p, err := variant.Read(openInput(fs, flags.InputFile, flags.Input))
+	if err != nil {
+		out.fail(err)
+	}
+	if flags.DontSolve {
+		givens, _ := p.Givens()
+		text, err := format.Format(givens, flags.OutputFormat)
+		if err != nil {
+			out.fail(err)
+		}
+		fmt.Fprintln(out, text)
+		return
+	}
+
+	limit := 1
+	if flags.All {
+		limit = flags.Limit
+	}
+	solutions, more, err := p.Solve(limit)
+	if err != nil {
+		out.fail(err)
+	}
+	if flags.All && flags.CountsOnly {
+		if more {
+			fmt.Fprintf(out, "%d+ (limit reached)\n", len(solutions))
+		} else {
+			fmt.Fprintf(out, "%d\n", len(solutions))
+		}
+		return
+	}
+	for _, solution := range solutions {
+		text, err := format.Format(solution, flags.OutputFormat)
+		if err != nil {
+			out.fail(err)
+		}
+		fmt.Fprintln(out, text)
+	}
+	if len(solutions) == 0 {
+		fmt.Fprintln(out, "No solution")
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/AndrewSav/sudocoo/pkg/batch"
+	"github.com/AndrewSav/sudocoo/pkg/format"
+	"github.com/AndrewSav/sudocoo/pkg/handler"
+	"github.com/AndrewSav/sudocoo/pkg/metrics"
+	"github.com/AndrewSav/sudocoo/pkg/parser"
+)
+
+type watchFlags struct {
+	Dir         string        // directory to take puzzle files from
+	Out         string        // directory the results and the processed files go to
+	Interval    time.Duration // how often to look for new files
+	Limit       int           // the most solutions to look for per puzzle
+	Concurrency int           // puzzles solved at the same time
+	MetricsAddr string        // address to serve /metrics, /healthz and /readyz on, empty if not wanted
+}
+
+// Solves the puzzle files that show up in a directory until interrupted
+func runWatch(args []string) {
+	var flags watchFlags
+
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Println("Watches a directory for puzzle files and solves the puzzles in each file that shows up")
+		fmt.Printf("Usage: %s watch -dir DIR [FLAGS...]\n", filepath.Base(os.Args[0]))
+		fmt.Println("The results of NAME go to NAME.jsonl in the output directory, a JSON line per puzzle as the results of")
+		fmt.Println("'serve' batch jobs have them, and NAME is moved there once it is done. Files whose names start with '.' are")
+		fmt.Println("skipped, so write a file under such a name and rename it when it is complete")
+		fmt.Println("On SIGINT or SIGTERM the file in progress is left where it is, to be solved again on the next run")
+		fmt.Println("Flags:")
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&flags.Dir, "dir", "", "directory to watch for puzzle files")
+	fs.StringVar(&flags.Out, "out", "", "directory for the results and the processed files. Default: 'done' in the watched directory")
+	fs.DurationVar(&flags.Interval, "interval", time.Second, "how often to look for new files. Default: 1s")
+	fs.IntVar(&flags.Limit, "limit", 1, "the most solutions to look for per puzzle, with one more looked for to tell whether there are more. Default: 1")
+	fs.IntVar(&flags.Concurrency, "c", runtime.NumCPU(), "number of puzzles to solve at the same time. Default: number of CPUs")
+	fs.StringVar(&flags.MetricsAddr, "metrics-addr", "", "serve /metrics, /healthz and /readyz over HTTP on this address")
+	parseFlags(fs, args)
+
+	if flags.Dir == "" {
+		fmt.Println("'-dir' is required")
+		fs.Usage()
+		os.Exit(2)
+	}
+	if flags.Limit < 1 {
+		fmt.Printf("invalid limit %d, want 1 or more\n", flags.Limit)
+		fs.Usage()
+		os.Exit(2)
+	}
+	if flags.Concurrency < 1 {
+		fmt.Printf("invalid concurrency %d, want 1 or more\n", flags.Concurrency)
+		fs.Usage()
+		os.Exit(2)
+	}
+	if flags.Interval <= 0 {
+		fmt.Printf("invalid interval %s, want more than 0\n", flags.Interval)
+		fs.Usage()
+		os.Exit(2)
+	}
+	if flags.Out == "" {
+		flags.Out = filepath.Join(flags.Dir, "done")
+	}
+	if err := os.MkdirAll(flags.Out, 0o755); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	m := metrics.New()
+	var hc health
+	if flags.MetricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", m)
+		hc.register(mux)
+		go func() {
+			if err := http.ListenAndServe(flags.MetricsAddr, mux); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		}()
+	}
+
+	w := &watcher{flags: flags, handler: handler.New(flags.Limit, nil), metrics: m, failed: map[string]time.Time{}}
+	log.Printf("watching %s, results go to %s", flags.Dir, flags.Out)
+	hc.ready.Store(true)
+	ticker := time.NewTicker(flags.Interval)
+	defer ticker.Stop()
+	for {
+		if err := w.scan(ctx); err != nil {
+			log.Printf("Error: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			hc.ready.Store(false)
+			log.Printf("stopped watching %s", flags.Dir)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type watcher struct {
+	flags   watchFlags
+	handler *handler.Handler
+	metrics *metrics.Metrics
+	failed  map[string]time.Time // the files that could not be processed, with their modification times
+}
+
+// Solves the files in the watched directory, in the order of their names. A file that cannot be processed
+// is logged and skipped until it changes, so that it does not hold up the files after it
+func (w *watcher) scan(ctx context.Context) error {
+	entries, err := os.ReadDir(w.flags.Dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			// Gone since the directory was read
+			continue
+		}
+		if modified, ok := w.failed[entry.Name()]; ok && modified.Equal(info.ModTime()) {
+			continue
+		}
+		if err := w.process(ctx, entry.Name()); err != nil {
+			if ctx.Err() != nil {
+				log.Printf("%s is left for the next run", entry.Name())
+				return nil
+			}
+			log.Printf("Error: %s: %v, skipped until it changes", entry.Name(), err)
+			w.failed[entry.Name()] = info.ModTime()
+			continue
+		}
+		delete(w.failed, entry.Name())
+	}
+	return nil
+}
+
+// Solves the puzzles of a file, writes their results and moves the file out of the watched directory.
+// A puzzle that cannot be read ends the file, its error is the last line of the results
+func (w *watcher) process(ctx context.Context, name string) error {
+	input, err := os.ReadFile(filepath.Join(w.flags.Dir, name))
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	var results bytes.Buffer
+	reader := parser.NewLimitedReader(bytes.NewReader(input), parser.DefaultLimits)
+	var readErr error
+	source := func() (string, error) {
+		puzzle, err := reader.Next()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				readErr = err
+			}
+			return "", io.EOF
+		}
+		return format.Inline(puzzle), nil
+	}
+	solve := func(ctx context.Context, puzzle string) (watchOutcome, error) {
+		done := w.metrics.Start("watch")
+		result, iterations := w.handler.SolvePuzzle(ctx, puzzle, 0)
+		done(result.Count, iterations, result.Error != "")
+		return watchOutcome{result: result, iterations: iterations}, nil
+	}
+	solved := 0
+	config := batch.Config{Workers: w.flags.Concurrency, Errors: batch.Continue}
+	stats, err := batch.Run(ctx, config, source, solve, func(item batch.Item[watchOutcome]) bool {
+		result := item.Value.result
+		result.Index = item.Index + 1
+		if result.Count > 0 {
+			solved++
+		}
+		line, _ := json.Marshal(result)
+		results.Write(line)
+		results.WriteByte('\n')
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if readErr != nil {
+		line, _ := json.Marshal(handler.JobResult{Index: stats.Items + 1, Error: readErr.Error()})
+		results.Write(line)
+		results.WriteByte('\n')
+	}
+
+	// The results appear under their name complete or not at all
+	resultsPath := filepath.Join(w.flags.Out, name+".jsonl")
+	temp := filepath.Join(w.flags.Out, "."+name+".jsonl")
+	if err := os.WriteFile(temp, results.Bytes(), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(temp, resultsPath); err != nil {
+		return err
+	}
+	if err := os.Rename(filepath.Join(w.flags.Dir, name), filepath.Join(w.flags.Out, name)); err != nil {
+		return err
+	}
+	log.Printf("%s: %d puzzles, %d solved, %d solutions in %s", name, stats.Items, solved, stats.Solutions, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// A puzzle of a file as it is solved, along with the iterations for the batch stats
+type watchOutcome struct {
+	result     handler.JobResult
+	iterations int
+}
+
+func (o watchOutcome) Tally() (solutions, iterations int) {
+	return o.result.Count, o.iterations
+}
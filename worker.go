@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"time"
+
+	"github.com/AndrewSav/sudocoo/pkg/batch"
+	"github.com/AndrewSav/sudocoo/pkg/handler"
+	"github.com/AndrewSav/sudocoo/pkg/queue"
+	"github.com/AndrewSav/sudocoo/pkg/webhook"
+)
+
+type workerFlags struct {
+	Redis            string // address of the Redis server
+	NATS             string // address of the NATS server, used instead of Redis if set
+	Group            string // NATS queue group the workers share the jobs in
+	Jobs             string // list or subject to take jobs from
+	Results          string // list or subject to put results to, unless a job says otherwise
+	Concurrency      int    // jobs processed at the same time
+	MaxLimit         int    // the most solutions a single job is allowed to ask for
+	HealthAddr       string // address to serve /healthz and /readyz on, empty if not wanted
+	WebhookSecret    string // signs the webhook notifications, webhooks are disabled without it
+	AllowPrivateURLs bool   // webhooks may point to private addresses, see webhook.ValidateURL
+}
+
+// How long to wait for a job before checking whether we were asked to stop
+const popTimeout = time.Second
+
+// A job taken off the queue
+type workerJob struct {
+	ID      string `json:"id"`                // copied to the result, so that the producer can match them
+	Op      string `json:"op"`                // 'solve', 'count' or 'rate'
+	Puzzle  string `json:"puzzle"`            // in the inline format
+	Limit   int    `json:"limit,omitempty"`   // for 'count', 0 means the worker maximum
+	Reply   string `json:"reply,omitempty"`   // list or subject to put the result to instead of the default one
+	Webhook string `json:"webhook,omitempty"` // URL to post the result to as well
+}
+
+// A result put on the queue, Error is set if the job failed and then nothing else is
+type workerResult struct {
+	ID           string  `json:"id"`
+	Solution     string  `json:"solution,omitempty"`
+	Count        *int    `json:"count,omitempty"`
+	LimitReached bool    `json:"limitReached,omitempty"`
+	Level        string  `json:"level,omitempty"` // for 'rate'
+	Score        float64 `json:"score,omitempty"`
+	Iterations   int     `json:"iterations"`
+	Error        string  `json:"error,omitempty"`
+}
+
+// A job as it was taken off the queue. A NATS request names the subject its sender waits on
+type queuedJob struct {
+	data  string
+	reply string
+}
+
+// Processes puzzle jobs from a Redis list or a NATS subject until interrupted
+func runWorker(args []string) {
+	var flags workerFlags
+
+	fs := flag.NewFlagSet("worker", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Println("Takes puzzle jobs from a Redis list or a NATS subject and puts the results on another one")
+		fmt.Printf("Usage: %s worker [FLAGS...]\n", filepath.Base(os.Args[0]))
+		fmt.Println("A job is a JSON object: {\"id\": \"...\", \"op\": \"solve|count|rate\", \"puzzle\": \"...\", \"limit\": N, \"reply\": \"list\", \"webhook\": \"URL\"}")
+		fmt.Println("With NATS a job sent as a request gets its result as the reply, unless it names a reply subject")
+		fmt.Println("On SIGINT or SIGTERM no new jobs are taken and the worker exits once the current ones are done")
+		fmt.Println("Flags:")
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&flags.Redis, "redis", "localhost:6379", "address of the Redis server. Default: localhost:6379")
+	fs.StringVar(&flags.NATS, "nats", "", "take the jobs from this NATS server (host:port) instead of Redis, '-jobs' and '-results' are subjects then. Core NATS does not keep messages, jobs published while no worker listens are lost")
+	fs.StringVar(&flags.Group, "group", "sudocoo", "the NATS queue group, the workers in a group share the jobs. Default: sudocoo")
+	fs.StringVar(&flags.Jobs, "jobs", "sudocoo:jobs", "list or subject to take jobs from. Default: sudocoo:jobs")
+	fs.StringVar(&flags.Results, "results", "sudocoo:results", "list or subject to put results to, a job can override it with 'reply'. Default: sudocoo:results")
+	fs.IntVar(&flags.Concurrency, "c", runtime.NumCPU(), "number of jobs to process at the same time. Default: number of CPUs")
+	fs.IntVar(&flags.MaxLimit, "max-limit", 10000, "the maximum number of solutions a job can ask for. Default: 10000")
+	fs.StringVar(&flags.HealthAddr, "health-addr", "", "serve /healthz and /readyz over HTTP on this address, /readyz fails once the worker stops taking jobs")
+	fs.StringVar(&flags.WebhookSecret, "webhook-secret", os.Getenv(envWebhookSecret), "enables job webhooks, notifications are signed with this secret. Default: $"+envWebhookSecret)
+	fs.BoolVar(&flags.AllowPrivateURLs, "allow-private-urls", false, "let webhooks point to loopback, private and link-local addresses, which are refused otherwise")
+	parseFlags(fs, args)
+
+	if flags.Concurrency < 1 {
+		fmt.Printf("invalid concurrency %d, want 1 or more\n", flags.Concurrency)
+		fs.Usage()
+		os.Exit(2)
+	}
+	if flags.MaxLimit < 1 {
+		fmt.Printf("invalid max limit %d, want 1 or more\n", flags.MaxLimit)
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	next, publish, closeQueue, err := openJobQueue(flags)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer closeQueue()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var hc health
+	if flags.HealthAddr != "" {
+		mux := http.NewServeMux()
+		hc.register(mux)
+		go func() {
+			if err := http.ListenAndServe(flags.HealthAddr, mux); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		}()
+	}
+
+	var webhooks *webhook.Sender
+	if flags.WebhookSecret != "" {
+		webhooks = webhook.NewSender(flags.WebhookSecret, flags.AllowPrivateURLs)
+	}
+
+	h := handler.New(flags.MaxLimit, nil)
+	// Jobs are taken until a signal comes, the jobs already taken are finished
+	// after that, so the batch itself is never canceled
+	take := func() (queuedJob, error) {
+		for ctx.Err() == nil {
+			job, ok, err := next()
+			if err != nil {
+				return job, fmt.Errorf("taking a job from %s: %w", flags.Jobs, err)
+			}
+			if ok {
+				return job, nil
+			}
+		}
+		return queuedJob{}, io.EOF
+	}
+	// The result is published from the worker goroutine, results do not wait for each other
+	run := func(ctx context.Context, queued queuedJob) (workerResult, error) {
+		job, result := processJob(ctx, h, queued.data)
+		reply := job.Reply
+		if reply == "" {
+			reply = queued.reply
+		}
+		if reply == "" {
+			reply = flags.Results
+		}
+		encoded, _ := json.Marshal(result)
+		if err := publish(reply, string(encoded)); err != nil {
+			log.Printf("could not publish the result of job %q: %v", result.ID, err)
+		}
+		// Delivered before the next job, so that shutdown waits for it too
+		if job.Webhook != "" {
+			if err := sendJobWebhook(webhooks, flags.AllowPrivateURLs, job.Webhook, result); err != nil {
+				log.Printf("job %q: %v", result.ID, err)
+			}
+		}
+		if result.Error != "" {
+			return result, errors.New(result.Error)
+		}
+		return result, nil
+	}
+
+	server := flags.Redis
+	if flags.NATS != "" {
+		server = flags.NATS
+	}
+	log.Printf("taking jobs from %s on %s with %d workers", flags.Jobs, server, flags.Concurrency)
+	hc.ready.Store(true)
+	go func() {
+		<-ctx.Done()
+		hc.ready.Store(false)
+		log.Printf("waiting for the jobs in progress")
+	}()
+	// A window of one keeps the jobs off the list until there is a worker free for them
+	config := batch.Config{Workers: flags.Concurrency, Window: 1, Unordered: true, Errors: batch.Continue}
+	stats, err := batch.Run(context.Background(), config, take, run, func(batch.Item[workerResult]) bool { return true })
+	hc.ready.Store(false)
+	log.Printf("%d jobs done, %d failed", stats.Items, stats.Failed)
+	if err != nil {
+		log.Printf("Error: %v", err)
+		os.Exit(1)
+	}
+}
+
+// Connects to the queue the flags name. next takes a job, waiting a while for one, and publish puts
+// a result on a list or subject
+func openJobQueue(flags workerFlags) (next func() (queuedJob, bool, error), publish func(to, data string) error, closeQueue func(), err error) {
+	if flags.NATS != "" {
+		nc, err := queue.DialNATS(flags.NATS)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		next = func() (queuedJob, bool, error) {
+			m, ok, err := nc.Next(flags.Jobs, flags.Group, popTimeout)
+			return queuedJob{data: m.Data, reply: m.Reply}, ok, err
+		}
+		return next, nc.Publish, func() { nc.Close() }, nil
+	}
+	// Popping blocks the connection, so results go over a separate one
+	consumer, err := queue.Dial(flags.Redis)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	publisher, err := queue.Dial(flags.Redis)
+	if err != nil {
+		consumer.Close()
+		return nil, nil, nil, err
+	}
+	next = func() (queuedJob, bool, error) {
+		data, ok, err := consumer.Pop(flags.Jobs, popTimeout)
+		return queuedJob{data: data}, ok, err
+	}
+	return next, publisher.Push, func() { consumer.Close(); publisher.Close() }, nil
+}
+
+func sendJobWebhook(webhooks *webhook.Sender, allowPrivate bool, url string, result workerResult) error {
+	if webhooks == nil {
+		return fmt.Errorf("webhooks are disabled, run the worker with -webhook-secret")
+	}
+	if err := webhook.ValidateURL(context.Background(), url, allowPrivate); err != nil {
+		return err
+	}
+	return webhooks.Send(context.Background(), url, result)
+}
+
+// Runs a single job, returning the job and its result
+func processJob(ctx context.Context, h *handler.Handler, data string) (workerJob, workerResult) {
+	var job workerJob
+	if err := json.Unmarshal([]byte(data), &job); err != nil {
+		return job, workerResult{Error: fmt.Sprintf("invalid job: %v", err)}
+	}
+	result := workerResult{ID: job.ID}
+	req := handler.PuzzleRequest{Puzzle: job.Puzzle, Limit: job.Limit}
+	switch job.Op {
+	case "solve":
+		resp, err := h.Solve(ctx, req)
+		if err != nil {
+			result.Error = err.Error()
+		}
+		result.Solution, result.Iterations = resp.Solution, resp.Iterations
+	case "count":
+		resp, err := h.Count(ctx, req)
+		if err != nil {
+			result.Error = err.Error()
+			break
+		}
+		result.Count, result.LimitReached, result.Iterations = &resp.Count, resp.LimitReached, resp.Iterations
+	case "rate":
+		resp, err := h.Rate(ctx, req)
+		if err != nil {
+			result.Error = err.Error()
+		}
+		result.Level, result.Score, result.Iterations = resp.Level, resp.Score, resp.Iterations
+	default:
+		result.Error = fmt.Sprintf("unknown operation %q, want solve, count or rate", job.Op)
+	}
+	return job, result
+}
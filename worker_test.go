@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/AndrewSav/sudocoo/internal/fakeserver"
+	"github.com/AndrewSav/sudocoo/pkg/handler"
+)
+
+// The first puzzle of data/input1.txt and its solution
+const (
+	workerPuzzle   = "4...3.......6..8..........1....5..9..8....6...7.2........1.27..5.3....4.9........"
+	workerSolution = "468931527751624839392578461134756298289413675675289314846192753513867942927345186"
+)
+
+// Returns the RESP encoding of a command, the way the worker sends it
+func resp(args ...string) string {
+	s := "*" + strconv.Itoa(len(args)) + "\r\n"
+	for _, a := range args {
+		s += "$" + strconv.Itoa(len(a)) + "\r\n" + a + "\r\n"
+	}
+	return s
+}
+
+// A job is taken off a Redis list over one connection and its result pushed over the other
+func TestJobQueueRedis(t *testing.T) {
+	job := `{"id":"1","op":"solve","puzzle":"` + workerPuzzle + `"}`
+	addr := fakeserver.Serve(t,
+		func(t *testing.T, r *bufio.Reader, w net.Conn) {
+			fakeserver.Expect(t, r, resp("BLPOP", "in", "1"))
+			io.WriteString(w, "*-1\r\n")
+			fakeserver.Expect(t, r, resp("BLPOP", "in", "1"))
+			// In pieces, the way a large job may come
+			for _, piece := range []string{"*2\r\n$2\r\nin\r\n$", strconv.Itoa(len(job)), "\r\n", job[:10], job[10:], "\r\n"} {
+				io.WriteString(w, piece)
+				time.Sleep(time.Millisecond)
+			}
+			fakeserver.Expect(t, r, resp("BLPOP", "in", "1"))
+			io.WriteString(w, "-ERR connection reset by the test\r\n")
+		},
+		func(t *testing.T, r *bufio.Reader, w net.Conn) {
+			fakeserver.Expect(t, r, resp("RPUSH", "out", `{"id":"1"}`))
+			io.WriteString(w, ":1\r\n")
+		},
+	)
+	next, publish, closeQueue, err := openJobQueue(workerFlags{Redis: addr, Jobs: "in"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeQueue()
+	if queued, ok, err := next(); ok || err != nil {
+		t.Errorf("next = %+v, %v, %v on an empty list, want nothing", queued, ok, err)
+	}
+	queued, ok, err := next()
+	if !ok || err != nil || queued.data != job {
+		t.Errorf("next = %+v, %v, %v, want the job", queued, ok, err)
+	}
+	if _, _, err := next(); err == nil {
+		t.Error("next returned no error for an error reply")
+	}
+	if err := publish("out", `{"id":"1"}`); err != nil {
+		t.Error(err)
+	}
+}
+
+// A NATS request carries the subject its sender waits on
+func TestJobQueueNATS(t *testing.T) {
+	addr := fakeserver.Serve(t, func(t *testing.T, r *bufio.Reader, w net.Conn) {
+		io.WriteString(w, "INFO {}\r\n")
+		r.ReadString('\n') // CONNECT
+		fakeserver.Expect(t, r, "PING\r\n")
+		io.WriteString(w, "PONG\r\n")
+		fakeserver.Expect(t, r, "SUB in g 1\r\nUNSUB 1 1\r\n")
+		io.WriteString(w, "MSG in 1 _INBOX.7 3\r\n{}\n\r\n")
+		fakeserver.Expect(t, r, "PUB _INBOX.7 2\r\nok\r\n")
+	})
+	next, publish, closeQueue, err := openJobQueue(workerFlags{NATS: addr, Jobs: "in", Group: "g"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeQueue()
+	queued, ok, err := next()
+	if !ok || err != nil || queued != (queuedJob{data: "{}\n", reply: "_INBOX.7"}) {
+		t.Errorf("next = %+v, %v, %v, want the job with its reply subject", queued, ok, err)
+	}
+	if err := publish(queued.reply, "ok"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestProcessJob(t *testing.T) {
+	count := func(n int) *int { return &n }
+	tests := []struct {
+		data string
+		want workerResult
+	}{
+		{`{"id":"a","op":"solve","puzzle":"` + workerPuzzle + `"}`, workerResult{ID: "a", Solution: workerSolution}},
+		{`{"id":"b","op":"count","puzzle":"` + workerPuzzle + `"}`, workerResult{ID: "b", Count: count(1)}},
+		{`{"id":"c","op":"count","puzzle":".` + workerPuzzle[1:] + `","limit":1}`, workerResult{ID: "c", Count: count(1), LimitReached: true}},
+		{`{"id":"d","op":"solve","puzzle":"44` + workerPuzzle[2:] + `"}`, workerResult{ID: "d", Error: "invalid (inconsistent) puzzle input"}},
+		{`{"id":"e","op":"guess","puzzle":"` + workerPuzzle + `"}`, workerResult{ID: "e", Error: `unknown operation "guess", want solve, count or rate`}},
+		{`{"id":`, workerResult{Error: "invalid job: unexpected end of JSON input"}},
+	}
+	h := handler.New(10, nil)
+	for _, test := range tests {
+		_, result := processJob(context.Background(), h, test.data)
+		// The iterations are the solver's business
+		result.Iterations = 0
+		if !reflect.DeepEqual(result, test.want) {
+			got, _ := json.Marshal(result)
+			want, _ := json.Marshal(test.want)
+			t.Errorf("job %s: got %s, want %s", test.data, got, want)
+		}
+	}
+	job, result := processJob(context.Background(), h, `{"id":"f","op":"rate","puzzle":"`+workerPuzzle+`","reply":"r","webhook":"http://example.com/"}`)
+	if job.Reply != "r" || job.Webhook != "http://example.com/" {
+		t.Errorf("got job %+v, want the reply and the webhook", job)
+	}
+	if result.Error != "" || result.Level == "" || result.Score <= 0 {
+		t.Errorf("got %+v rating the puzzle, want a level and a score", result)
+	}
+}